@@ -0,0 +1,133 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/danroux/sk8l/internal/auth"
+	"github.com/danroux/sk8l/protos"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+const (
+	// manualJobSuffixLength is the length of the random suffix of triggered Jobs,
+	// their names are at most 63 characters like the job-name label they get.
+	manualJobSuffixLength = 5
+	maxJobNameLength      = 63
+	manualJobInfix        = "-manual-"
+
+	// instantiateAnnotation marks Jobs created by hand, like kubectl create job --from does.
+	instantiateAnnotation = "cronjob.kubernetes.io/instantiate"
+	triggeredByAnnotation = "sk8l/triggered-by"
+)
+
+// TriggerCronjob creates a Job from the job template of a CronJob, outside of its schedule.
+func (s *Sk8lServer) TriggerCronjob(ctx context.Context, in *protos.CronjobRequest) (*protos.TriggerCronjobResponse, error) {
+	if err := s.checkActions("TriggerCronjob"); err != nil {
+		return nil, err
+	}
+	namespace := cmp.Or(in.GetCronjobNamespace(), s.K8sClient.Namespace())
+	cronjob, err := s.K8sClient.GetCronjob(ctx, namespace, in.GetCronjobName())
+	if err != nil {
+		return nil, kubernetesError("TriggerCronjob", err)
+	}
+	caller := auth.FromContext(ctx)
+	job, err := s.K8sClient.CreateJob(ctx, jobFromCronjob(cronjob, caller))
+	if err != nil {
+		return nil, kubernetesError("TriggerCronjob", err)
+	}
+	log.Info().
+		Str("operation", "TriggerCronjob").
+		Str("caller", caller.String()).
+		Msg(fmt.Sprintf("created job %s/%s from cronjob %s", job.Namespace, job.Name, cronjob.Name))
+	return &protos.TriggerCronjobResponse{JobName: job.Name, JobNamespace: job.Namespace}, nil
+}
+
+// SuspendCronjob suspends a CronJob, or resumes it.
+func (s *Sk8lServer) SuspendCronjob(ctx context.Context, in *protos.SuspendCronjobRequest) (*protos.SuspendCronjobResponse, error) {
+	if err := s.checkActions("SuspendCronjob"); err != nil {
+		return nil, err
+	}
+	namespace := cmp.Or(in.GetCronjobNamespace(), s.K8sClient.Namespace())
+	cronjob, err := s.K8sClient.SuspendCronjob(ctx, namespace, in.GetCronjobName(), in.GetSuspend())
+	if err != nil {
+		return nil, kubernetesError("SuspendCronjob", err)
+	}
+	suspended := cronjob.Spec.Suspend != nil && *cronjob.Spec.Suspend
+	log.Info().
+		Str("operation", "SuspendCronjob").
+		Str("caller", auth.FromContext(ctx).String()).
+		Msg(fmt.Sprintf("cronjob %s/%s suspended: %t", cronjob.Namespace, cronjob.Name, suspended))
+	return &protos.SuspendCronjobResponse{Suspended: suspended}, nil
+}
+
+// checkActions fails the calls changing the cluster unless actions are on
+// and the callers are authenticated and authorized.
+func (s *Sk8lServer) checkActions(operation string) error {
+	switch {
+	case s.readOnly:
+		return status.Errorf(codes.FailedPrecondition, "sk8l#%s: an imported snapshot is read-only", operation)
+	case !s.actions:
+		return status.Errorf(codes.FailedPrecondition, "sk8l#%s: actions are disabled, see SK8L_ACTIONS", operation)
+	case !s.authenticator.Enabled() || s.policy == nil:
+		return status.Errorf(codes.FailedPrecondition, "sk8l#%s: actions need authentication and a policy", operation)
+	default:
+		return nil
+	}
+}
+
+// jobFromCronjob returns a Job running the job template of cronjob, owned by
+// it so it's listed and cleaned up with the scheduled ones.
+func jobFromCronjob(cronjob *batchv1.CronJob, caller *auth.Identity) *batchv1.Job {
+	name := cronjob.Name
+	if maxLength := maxJobNameLength - len(manualJobInfix) - manualJobSuffixLength; len(name) > maxLength {
+		name = name[:maxLength]
+	}
+	annotations := maps.Clone(cronjob.Spec.JobTemplate.Annotations)
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[instantiateAnnotation] = "manual"
+	annotations[triggeredByAnnotation] = caller.String()
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name + manualJobInfix + rand.String(manualJobSuffixLength),
+			Namespace:   cronjob.Namespace,
+			Labels:      maps.Clone(cronjob.Spec.JobTemplate.Labels),
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cronjob, batchv1.SchemeGroupVersion.WithKind("CronJob")),
+			},
+		},
+		Spec: *cronjob.Spec.JobTemplate.Spec.DeepCopy(),
+	}
+}
+
+// kubernetesError returns the gRPC status matching an error of the Kubernetes API.
+func kubernetesError(operation string, err error) error {
+	code := codes.Unknown
+	switch {
+	case apierrors.IsNotFound(err):
+		code = codes.NotFound
+	case apierrors.IsForbidden(err):
+		code = codes.PermissionDenied
+	case apierrors.IsAlreadyExists(err):
+		code = codes.AlreadyExists
+	case apierrors.IsConflict(err):
+		code = codes.Aborted
+	case apierrors.IsInvalid(err) || apierrors.IsBadRequest(err):
+		code = codes.InvalidArgument
+	default:
+		log.Error().Err(err).Str("operation", operation).Msg("Kubernetes API call failed")
+	}
+	return status.Errorf(code, "sk8l#%s: %v", operation, err)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/danroux/sk8l/internal/auth"
+	"github.com/danroux/sk8l/internal/k8s"
+	"github.com/danroux/sk8l/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTriggerAndSuspendCronjob(t *testing.T) {
+	server, client := authorizedClient(t)
+	clientset := server.K8sClient.(*k8s.Client).Interface
+	cronjob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "batch", UID: "cronjob-uid"},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "0 * * * *",
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "backup"}},
+			},
+		},
+	}
+	if _, err := clientset.BatchV1().CronJobs("batch").Create(context.Background(), cronjob, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to create cronjob: %v", err)
+	}
+	backup := &protos.CronjobRequest{CronjobName: "backup", CronjobNamespace: "batch"}
+
+	if _, err := client.TriggerCronjob(asCaller("joe"), backup); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition while actions are off, got %v", err)
+	}
+	server.actions = true
+
+	if _, err := client.TriggerCronjob(asCaller("jane"), backup); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected callers without the trigger verb to be denied, got %v", err)
+	}
+	triggered, err := client.TriggerCronjob(asCaller("joe"), backup)
+	if err != nil {
+		t.Fatalf("TriggerCronjob failed: %v", err)
+	}
+	job, err := clientset.BatchV1().Jobs("batch").Get(context.Background(), triggered.GetJobName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the triggered job to be created: %v", err)
+	}
+	if !strings.HasPrefix(job.Name, "backup-manual-") || job.Labels["app"] != "backup" ||
+		job.Annotations[instantiateAnnotation] != "manual" || job.Annotations[triggeredByAnnotation] != "oidc:joe" {
+		t.Errorf("unexpected job %s %v %v", job.Name, job.Labels, job.Annotations)
+	}
+	if owner := metav1.GetControllerOf(job); owner == nil || owner.Kind != "CronJob" || owner.UID != "cronjob-uid" {
+		t.Errorf("expected the job to be owned by the cronjob, got %v", owner)
+	}
+	missing := &protos.CronjobRequest{CronjobName: "missing", CronjobNamespace: "batch"}
+	if _, err := client.TriggerCronjob(asCaller("joe"), missing); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+
+	for _, suspend := range []bool{true, false} {
+		resp, err := client.SuspendCronjob(asCaller("joe"), &protos.SuspendCronjobRequest{
+			CronjobName: "backup", CronjobNamespace: "batch", Suspend: suspend,
+		})
+		if err != nil || resp.GetSuspended() != suspend {
+			t.Errorf("expected the cronjob to be suspended: %t, got %v, %v", suspend, resp, err)
+		}
+	}
+	_, err = client.SuspendCronjob(asCaller("jane"), &protos.SuspendCronjobRequest{CronjobName: "backup"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected callers without the suspend verb to be denied, got %v", err)
+	}
+
+	server.readOnly = true
+	if _, err := client.TriggerCronjob(asCaller("joe"), backup); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected an imported snapshot to be read-only, got %v", err)
+	}
+	server.readOnly = false
+
+	server.policy = nil
+	if _, err := client.TriggerCronjob(asCaller("joe"), backup); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected actions to need a policy, got %v", err)
+	}
+}
+
+func TestJobFromCronjob(t *testing.T) {
+	cronjob := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 70), Namespace: "batch"}}
+	job := jobFromCronjob(cronjob, &auth.Identity{Name: "joe", Method: auth.MethodOIDC})
+	if len(job.Name) != maxJobNameLength || !strings.HasPrefix(job.Name, strings.Repeat("a", 50)+manualJobInfix) {
+		t.Errorf("expected the name to be truncated to %d characters, got %q", maxJobNameLength, job.Name)
+	}
+	if job.Namespace != "batch" || job.Annotations[instantiateAnnotation] != "manual" {
+		t.Errorf("unexpected job %v", job.ObjectMeta)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danroux/sk8l/internal/audit"
+	"github.com/danroux/sk8l/internal/auth"
+	"github.com/danroux/sk8l/internal/policy"
+	"github.com/danroux/sk8l/protos"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const megabyte = 1 << 20
+
+// targetKinds maps the prefix of the name fields of requests to the kind of
+// object they target.
+var targetKinds = map[string]string{
+	"cronjob": "CronJob",
+	"job":     "Job",
+	"pod":     "Pod",
+}
+
+// newAuditLog opens the audit log at audit.path, nil without one.
+func newAuditLog() (*audit.Log, error) {
+	if cfg.Audit.Path == "" {
+		return nil, nil
+	}
+	l, err := audit.Open(
+		cfg.Audit.Path,
+		audit.WithMaxSize(int64(cfg.Audit.MaxSizeMB)*megabyte),
+		audit.WithMaxBackups(cfg.Audit.MaxBackups),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#newAuditLog: %w", err)
+	}
+	return l, nil
+}
+
+func (s *Sk8lServer) GetAuditLog(_ context.Context, in *protos.AuditLogRequest) (*protos.AuditLogResponse, error) {
+	if s.auditLog == nil {
+		return nil, status.Error(codes.FailedPrecondition, "sk8l: the audit log is disabled")
+	}
+	from, err := parseTimeRange(in.GetFrom())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "sk8l: invalid from: %v", err)
+	}
+	to, err := parseTimeRange(in.GetTo())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "sk8l: invalid to: %v", err)
+	}
+
+	entries, err := s.auditLog.Entries(from, to, int(in.GetLimit()))
+	if err != nil {
+		log.Error().Err(err).Str("operation", "GetAuditLog").Msg("Entries")
+		return nil, fmt.Errorf("sk8l#GetAuditLog: %w", err)
+	}
+	response := &protos.AuditLogResponse{Entries: make([]*protos.AuditEntryResponse, 0, len(entries))}
+	for _, entry := range entries {
+		response.Entries = append(response.Entries, &protos.AuditEntryResponse{
+			Time:       entry.Time.Format(time.RFC3339Nano),
+			Caller:     entry.Caller,
+			Rpc:        entry.RPC,
+			Verb:       entry.Verb,
+			Namespace:  entry.Namespace,
+			Kind:       entry.Kind,
+			Name:       entry.Name,
+			Parameters: string(entry.Parameters),
+			Outcome:    entry.Outcome,
+			Error:      entry.Error,
+			DurationMs: entry.DurationMs,
+		})
+	}
+	return response, nil
+}
+
+func parseTimeRange(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("sk8l#parseTimeRange: %w", err)
+	}
+	return t, nil
+}
+
+// audited reports whether the calls of method are recorded: every call but
+// the reads that disclose nothing beyond what the UI lists.
+func audited(method string) bool {
+	rule, ok := rpcPolicies[method]
+	return !ok || rule.audited || rule.verb != policy.VerbRead
+}
+
+// recordAudit records a finished call in the audit log.
+func (s *Sk8lServer) recordAudit(ctx context.Context, method string, req any, start time.Time, callErr error) {
+	entry := s.auditEntry(ctx, method, req, start)
+	entry.Outcome = status.Code(callErr).String()
+	entry.DurationMs = time.Since(start).Milliseconds()
+	if callErr != nil {
+		entry.Error = status.Convert(callErr).Message()
+	}
+	s.writeAudit(entry)
+}
+
+// recordStreamStart records a stream once its request is received, before
+// recordAudit records its outcome.
+func (s *Sk8lServer) recordStreamStart(ctx context.Context, method string, req any, start time.Time) {
+	entry := s.auditEntry(ctx, method, req, start)
+	entry.Outcome = audit.OutcomeStarted
+	s.writeAudit(entry)
+}
+
+func (s *Sk8lServer) auditEntry(ctx context.Context, method string, req any, start time.Time) audit.Entry {
+	entry := audit.Entry{
+		Time:   start,
+		Caller: auth.FromContext(ctx).String(),
+		RPC:    method,
+		Verb:   string(rpcPolicies[method].verb),
+	}
+	if m, ok := req.(proto.Message); ok {
+		entry.Namespace = requestNamespace(m)
+		entry.Kind, entry.Name = requestTarget(m)
+		if parameters, err := protojson.Marshal(m); err == nil {
+			entry.Parameters = parameters
+		}
+	}
+	if entry.Namespace == "" {
+		entry.Namespace = s.K8sClient.Namespace()
+	}
+	return entry
+}
+
+func (s *Sk8lServer) writeAudit(entry audit.Entry) {
+	if err := s.auditLog.Record(entry); err != nil {
+		log.Error().Err(err).Str("operation", "recordAudit").Str("method", entry.RPC).Msg("failed to record the call")
+	}
+}
+
+// requestTarget returns the kind and name of the object a request targets,
+// from the first non-empty field like cronjobName, jobName or podName.
+func requestTarget(m proto.Message) (string, string) {
+	fields := m.ProtoReflect().Descriptor().Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		prefix, ok := strings.CutSuffix(string(fd.Name()), "Name")
+		kind, known := targetKinds[prefix]
+		if !ok || !known || fd.Kind() != protoreflect.StringKind || fd.IsList() {
+			continue
+		}
+		if name := m.ProtoReflect().Get(fd).String(); name != "" {
+			return kind, name
+		}
+	}
+	return "", ""
+}
+
+// auditUnaryInterceptor records the audited unary calls.
+func (s *Sk8lServer) auditUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if s.auditLog == nil || !audited(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		s.recordAudit(ctx, info.FullMethod, req, start, err)
+		return resp, err
+	}
+}
+
+// auditStreamInterceptor records the audited streams once their request is
+// received, so that long follows and watches are audited right away, and
+// again with their outcome once they end.
+func (s *Sk8lServer) auditStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if s.auditLog == nil || !audited(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		start := time.Now()
+		stream := &auditedStream{ServerStream: ss, started: func(req any) {
+			s.recordStreamStart(ss.Context(), info.FullMethod, req, start)
+		}}
+		err := handler(srv, stream)
+		s.recordAudit(ss.Context(), info.FullMethod, stream.req, start, err)
+		return err
+	}
+}
+
+// auditedStream keeps the request of a server-streaming call and calls
+// started once it's received.
+type auditedStream struct {
+	grpc.ServerStream
+	req     any
+	started func(req any)
+}
+
+func (as *auditedStream) RecvMsg(m any) error {
+	if err := as.ServerStream.RecvMsg(m); err != nil {
+		return fmt.Errorf("sk8l#RecvMsg: %w", err)
+	}
+	if as.req == nil {
+		as.req = m
+		as.started(m)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/danroux/sk8l/internal/audit"
+	"github.com/danroux/sk8l/internal/k8s"
+	"github.com/danroux/sk8l/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetAuditLog(t *testing.T) {
+	server, client := authorizedClient(t)
+	if _, err := client.GetAuditLog(asCaller("auditor"), &protos.AuditLogRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition without audit log, got %v", err)
+	}
+
+	l, err := audit.Open(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatalf("audit.Open failed: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	server.auditLog = l
+
+	start := time.Now()
+	if _, err := client.GetPodYAML(asCaller("jane"), &protos.PodRequest{PodName: "pod-a", PodNamespace: "default"}); err == nil {
+		t.Error("expected GetPodYAML of a missing pod to fail")
+	}
+	_, err = client.GetJobYAML(asCaller("jane"), &protos.JobRequest{JobName: "job-a", JobNamespace: "batch"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied, got %v", err)
+	}
+	if _, err := client.GetEvents(asCaller("jane"), &protos.EventsRequest{Namespace: "default"}); err != nil {
+		t.Errorf("GetEvents failed: %v", err)
+	}
+	if _, err := client.GetAuditLog(asCaller("jane"), &protos.AuditLogRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected jane not to read the audit log, got %v", err)
+	}
+
+	response, err := client.GetAuditLog(asCaller("auditor"), &protos.AuditLogRequest{From: start.Format(time.RFC3339Nano)})
+	if err != nil {
+		t.Fatalf("GetAuditLog failed: %v", err)
+	}
+	entries := response.GetEntries()
+	if len(entries) != 3 {
+		t.Fatalf("expected the YAML reads and the denied audit log read, got %+v", entries)
+	}
+	podYAML := entries[0]
+	if podYAML.GetCaller() != "oidc:jane" || podYAML.GetRpc() != "/sk8l.Cronjob/GetPodYAML" || podYAML.GetVerb() != "read" ||
+		podYAML.GetNamespace() != "default" || podYAML.GetKind() != "Pod" || podYAML.GetName() != "pod-a" || podYAML.GetOutcome() == "OK" {
+		t.Errorf("unexpected entry %+v", podYAML)
+	}
+	parameters := map[string]any{}
+	if err := json.Unmarshal([]byte(podYAML.GetParameters()), &parameters); err != nil || parameters["podName"] != "pod-a" {
+		t.Errorf("unexpected parameters %q, %v", podYAML.GetParameters(), err)
+	}
+	if entries[1].GetOutcome() != codes.PermissionDenied.String() || entries[1].GetKind() != "Job" || entries[1].GetNamespace() != "batch" {
+		t.Errorf("expected the denied call to be audited, got %+v", entries[1])
+	}
+	if entries[2].GetRpc() != "/sk8l.Cronjob/GetAuditLog" || entries[2].GetVerb() != "audit" {
+		t.Errorf("expected the audit log read to be audited, got %+v", entries[2])
+	}
+
+	response, err = client.GetAuditLog(asCaller("auditor"), &protos.AuditLogRequest{To: start.Format(time.RFC3339Nano)})
+	if err != nil || len(response.GetEntries()) != 0 {
+		t.Errorf("expected no entries before the calls, got %+v, %v", response, err)
+	}
+	if _, err := client.GetAuditLog(asCaller("auditor"), &protos.AuditLogRequest{From: "yesterday"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestAuditStream(t *testing.T) {
+	server, client := authorizedClient(t)
+	l, err := audit.Open(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatalf("audit.Open failed: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	server.auditLog = l
+	server.K8sClient = &followingClient{
+		Client: k8s.NewClientWithInterface(fake.NewClientset(), k8s.WithNamespace("default")),
+		opts:   make(chan *corev1.PodLogOptions, 1),
+		closed: make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithCancel(asCaller("jane"))
+	stream, err := client.GetPodLogs(ctx, &protos.PodLogsRequest{PodName: "pod-a", PodNamespace: "default", Follow: true})
+	if err != nil {
+		t.Fatalf("GetPodLogs failed: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("stream.Recv failed: %v", err)
+	}
+	entries, err := l.Entries(time.Time{}, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("Entries failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Outcome != audit.OutcomeStarted || entries[0].Name != "pod-a" || entries[0].Caller != "oidc:jane" {
+		t.Fatalf("expected the open stream to be audited, got %+v", entries)
+	}
+
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(entries) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the end of the stream to be audited, got %+v", entries)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if entries, err = l.Entries(time.Time{}, time.Time{}, 0); err != nil {
+			t.Fatalf("Entries failed: %v", err)
+		}
+	}
+	if entries[1].Outcome != codes.OK.String() || !entries[1].Time.Equal(entries[0].Time) {
+		t.Errorf("expected the outcome of the stream, got %+v", entries[1])
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/danroux/sk8l/internal/auth"
+	"github.com/danroux/sk8l/internal/config"
+	"github.com/danroux/sk8l/internal/redact"
+)
+
+// newAuthenticator builds the authenticator configured by cfg.Auth and
+// tls.clientAuth. Without an OIDC issuer, ServiceAccount tokens nor client
+// certificates every call is let through. internalToken authenticates sk8l
+// calling its own API.
+func newAuthenticator(reviewer auth.TokenReviewer, internalToken string) (*auth.Authenticator, error) {
+	opts := []auth.Option{
+		auth.WithContextHook(privilegedGroupsHook(cfg.Auth.PrivilegedGroups)),
+		auth.WithInternalToken(internalToken),
+	}
+	if cfg.TLS.ClientAuth != "" && cfg.TLS.ClientAuth != config.ClientAuthNone {
+		opts = append(opts, auth.WithClientCertificates())
+	}
+	if oidc := cfg.Auth.OIDC; oidc.Issuer != "" {
+		verifier, err := auth.NewOIDCVerifier(auth.OIDCConfig{
+			Issuer:        oidc.Issuer,
+			Audience:      oidc.Audience,
+			JWKSFile:      oidc.JWKSFile,
+			JWKSURL:       oidc.JWKSURL,
+			UsernameClaim: oidc.UsernameClaim,
+			GroupsClaim:   oidc.GroupsClaim,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("sk8l#newAuthenticator: %w", err)
+		}
+		opts = append(opts, auth.WithVerifier(verifier))
+	}
+	if cfg.Auth.ServiceAccountTokens {
+		opts = append(opts, auth.WithVerifier(auth.NewServiceAccountVerifier(reviewer, nil)))
+	}
+	return auth.New(opts...), nil
+}
+
+// privilegedGroupsHook lets the members of groups opt out of redaction.
+func privilegedGroupsHook(groups []string) func(ctx context.Context, id *auth.Identity) context.Context {
+	return func(ctx context.Context, id *auth.Identity) context.Context {
+		for _, group := range id.Groups {
+			if slices.Contains(groups, group) {
+				return redact.WithPrivileged(ctx)
+			}
+		}
+		return ctx
+	}
+}
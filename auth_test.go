@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/danroux/sk8l/internal/auth"
+	"github.com/danroux/sk8l/internal/redact"
+)
+
+func TestNewAuthenticator(t *testing.T) {
+	a, err := newAuthenticator(nil, "internal")
+	if err != nil {
+		t.Fatalf("newAuthenticator failed: %v", err)
+	}
+	if a.Enabled() {
+		t.Error("expected authentication to be disabled without configuration")
+	}
+
+	cfg.Auth.ServiceAccountTokens = true
+	t.Cleanup(func() { cfg.Auth.ServiceAccountTokens = false })
+	if a, err = newAuthenticator(nil, "internal"); err != nil || !a.Enabled() {
+		t.Errorf("expected ServiceAccount tokens to enable authentication, got %v", err)
+	}
+}
+
+func TestPrivilegedGroupsHook(t *testing.T) {
+	hook := privilegedGroupsHook([]string{"sre", "admins"})
+	ctx := hook(context.Background(), &auth.Identity{Name: "jane", Groups: []string{"dev", "admins"}})
+	if !redact.Privileged(ctx) {
+		t.Error("expected members of a privileged group to be privileged")
+	}
+	ctx = hook(context.Background(), &auth.Identity{Name: "joe", Groups: []string{"dev"}})
+	if redact.Privileged(ctx) {
+		t.Error("expected other callers not to be privileged")
+	}
+}
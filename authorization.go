@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/danroux/sk8l/internal/auth"
+	"github.com/danroux/sk8l/internal/policy"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var ErrPolicyWithoutAuth = errors.New("an authorization policy requires authentication")
+
+// rpcPolicy is what an RPC needs to be allowed. The items of the responses of
+// list RPCs are filtered down to the namespaces the caller may read instead.
+// Cluster-wide RPCs return the data of every namespace, they need the verb in
+// all of them. Audited reads are recorded in the audit log like every other verb.
+type rpcPolicy struct {
+	verb        policy.Verb
+	list        bool
+	clusterWide bool
+	audited     bool
+}
+
+// rpcPolicies covers every RPC of the Cronjob service, the ones missing are denied.
+var rpcPolicies = map[string]rpcPolicy{
+	"/sk8l.Cronjob/GetCronjobs":             {verb: policy.VerbRead, list: true},
+	"/sk8l.Cronjob/GetCronjob":              {verb: policy.VerbRead},
+	"/sk8l.Cronjob/GetCronjobPods":          {verb: policy.VerbRead},
+	"/sk8l.Cronjob/GetJobs":                 {verb: policy.VerbRead, list: true},
+	"/sk8l.Cronjob/GetCronjobYAML":          {verb: policy.VerbRead, audited: true},
+	"/sk8l.Cronjob/GetJobYAML":              {verb: policy.VerbRead, audited: true},
+	"/sk8l.Cronjob/GetPodYAML":              {verb: policy.VerbRead, audited: true},
+	"/sk8l.Cronjob/GetDashboardAnnotations": {verb: policy.VerbRead},
+	"/sk8l.Cronjob/ExportSnapshot":          {verb: policy.VerbRead, clusterWide: true, audited: true},
+	"/sk8l.Cronjob/GetPodLogs":              {verb: policy.VerbRead, audited: true},
+	"/sk8l.Cronjob/GetJobFailureLogs":       {verb: policy.VerbRead, audited: true},
+	"/sk8l.Cronjob/GetEvents":               {verb: policy.VerbRead},
+	"/sk8l.Cronjob/GetCronjobRevisions":     {verb: policy.VerbRead, audited: true},
+	"/sk8l.Cronjob/GetAuditLog":             {verb: policy.VerbAudit, clusterWide: true, audited: true},
+	"/sk8l.Cronjob/GetServerInfo":           {verb: policy.VerbRead},
+	"/sk8l.Cronjob/TriggerCronjob":          {verb: policy.VerbTrigger, audited: true},
+	"/sk8l.Cronjob/SuspendCronjob":          {verb: policy.VerbSuspend, audited: true},
+}
+
+// reflectionPrefix is the one of the methods of the reflection services. They
+// only describe the API, so every authenticated caller may use them.
+const reflectionPrefix = "/grpc.reflection."
+
+// newPolicy loads the policy at auth.policy, nil without one.
+func newPolicy(authenticator *auth.Authenticator) (*policy.Policy, error) {
+	if cfg.Auth.Policy == "" {
+		return nil, nil
+	}
+	if !authenticator.Enabled() {
+		return nil, fmt.Errorf("sk8l#newPolicy: %w", ErrPolicyWithoutAuth)
+	}
+	p, err := policy.Load(cfg.Auth.Policy)
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#newPolicy: %w", err)
+	}
+	return p, nil
+}
+
+// authorize checks that the caller of ctx may call method with req.
+func (s *Sk8lServer) authorize(ctx context.Context, method string, req any) error {
+	id := auth.FromContext(ctx)
+	if s.policy == nil || id.Internal() || strings.HasPrefix(method, reflectionPrefix) {
+		return nil
+	}
+	rule, ok := rpcPolicies[method]
+	if !ok {
+		return s.denied(id, method, rule.verb, "")
+	}
+	namespace := requestNamespace(req)
+	switch {
+	case rule.clusterWide:
+		namespace = policy.Wildcard
+	case namespace == "" && rule.list:
+		return nil
+	case namespace == "":
+		namespace = s.serverNamespace()
+	default: // default case to satisfy revive
+	}
+	if !s.policy.Allows(id, namespace, rule.verb) {
+		return s.denied(id, method, rule.verb, namespace)
+	}
+	return nil
+}
+
+func (s *Sk8lServer) denied(id *auth.Identity, method string, verb policy.Verb, namespace string) error {
+	log.Warn().
+		Str("operation", "authorize").
+		Str("method", method).
+		Str("caller", id.String()).
+		Str("namespace", namespace).
+		Msg("permission denied")
+	return status.Errorf(codes.PermissionDenied, "sk8l: %s may not %s in namespace %q", id, verb, namespace)
+}
+
+// serverNamespace is the namespace of the requests and items without one, the
+// one sk8l runs in, or policy.Wildcard when it watches every namespace.
+func (s *Sk8lServer) serverNamespace() string {
+	if namespace := s.K8sClient.Namespace(); namespace != "" {
+		return namespace
+	}
+	return policy.Wildcard
+}
+
+// requestNamespace returns the namespace a request targets, the first
+// non-empty string field named namespace or ending with Namespace.
+func requestNamespace(req any) string {
+	m, ok := req.(proto.Message)
+	if !ok {
+		return ""
+	}
+	fields := m.ProtoReflect().Descriptor().Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		name := string(fd.Name())
+		if fd.Kind() != protoreflect.StringKind || fd.IsList() || !strings.HasSuffix(strings.ToLower(name), "namespace") {
+			continue
+		}
+		if namespace := m.ProtoReflect().Get(fd).String(); namespace != "" {
+			return namespace
+		}
+	}
+	return ""
+}
+
+// filterResponse drops the items of a list response the caller of ctx may not read.
+func (s *Sk8lServer) filterResponse(ctx context.Context, method string, resp any) any {
+	id := auth.FromContext(ctx)
+	m, ok := resp.(proto.Message)
+	if s.policy == nil || id.Internal() || !rpcPolicies[method].list || !ok {
+		return resp
+	}
+	m = proto.Clone(m)
+	msg := m.ProtoReflect()
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if !fd.IsList() || fd.Message() == nil {
+			return true
+		}
+		list := v.List()
+		kept := msg.NewField(fd).List()
+		for i := range list.Len() {
+			namespace := itemNamespace(list.Get(i).Message())
+			if namespace == "" {
+				namespace = s.serverNamespace()
+			}
+			if s.policy.Allows(id, namespace, policy.VerbRead) {
+				kept.Append(list.Get(i))
+			}
+		}
+		msg.Set(fd, protoreflect.ValueOfList(kept))
+		return true
+	})
+	return m
+}
+
+// itemNamespace returns the namespace field of an item, or the one of its metadata.
+func itemNamespace(m protoreflect.Message) string {
+	fields := m.Descriptor().Fields()
+	if fd := fields.ByName("namespace"); fd != nil && fd.Kind() == protoreflect.StringKind {
+		return m.Get(fd).String()
+	}
+	if fd := fields.ByName("metadata"); fd != nil && fd.Message() != nil {
+		return itemNamespace(m.Get(fd).Message())
+	}
+	return ""
+}
+
+// authorizationUnaryInterceptor enforces the policy on every unary call.
+func (s *Sk8lServer) authorizationUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := s.authorize(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+		return s.filterResponse(ctx, info.FullMethod, resp), nil
+	}
+}
+
+// authorizationStreamInterceptor enforces the policy on every stream once its
+// request is received.
+func (s *Sk8lServer) authorizationStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if s.policy == nil {
+			return handler(srv, ss)
+		}
+		return handler(srv, &authorizedStream{ServerStream: ss, server: s, method: info.FullMethod})
+	}
+}
+
+type authorizedStream struct {
+	grpc.ServerStream
+	server *Sk8lServer
+	method string
+}
+
+func (as *authorizedStream) RecvMsg(m any) error {
+	if err := as.ServerStream.RecvMsg(m); err != nil {
+		return fmt.Errorf("sk8l#RecvMsg: %w", err)
+	}
+	return as.server.authorize(as.Context(), as.method, m)
+}
+
+func (as *authorizedStream) SendMsg(m any) error {
+	if err := as.ServerStream.SendMsg(as.server.filterResponse(as.Context(), as.method, m)); err != nil {
+		return fmt.Errorf("sk8l#SendMsg: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/danroux/sk8l/internal/auth"
+	"github.com/danroux/sk8l/internal/k8s"
+	"github.com/danroux/sk8l/internal/policy"
+	"github.com/danroux/sk8l/internal/store"
+	"github.com/danroux/sk8l/protos"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testPolicy = `
+rules:
+  - users: ["jane"]
+    namespaces: ["default"]
+    verbs: ["read"]
+  - groups: ["batch-team"]
+    namespaces: ["batch"]
+    verbs: ["*"]
+  - users: ["auditor"]
+    namespaces: ["*"]
+    verbs: ["audit"]
+`
+
+// testVerifier authenticates the callers named by the sub claim of unsigned tokens.
+type testVerifier struct{}
+
+func (testVerifier) Accepts(map[string]any) bool { return true }
+
+func (testVerifier) Verify(_ context.Context, token string) (*auth.Identity, error) {
+	switch token {
+	case testToken("jane"):
+		return &auth.Identity{Name: "jane", Method: auth.MethodOIDC}, nil
+	case testToken("joe"):
+		return &auth.Identity{Name: "joe", Groups: []string{"batch-team"}, Method: auth.MethodOIDC}, nil
+	case testToken("auditor"):
+		return &auth.Identity{Name: "auditor", Method: auth.MethodOIDC}, nil
+	default:
+		return nil, auth.ErrTokenRejected
+	}
+}
+
+func testToken(sub string) string {
+	claims, _ := json.Marshal(map[string]string{"sub": sub})
+	return "e30." + base64.RawURLEncoding.EncodeToString(claims) + ".c2ln"
+}
+
+func asCaller(sub string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+testToken(sub))
+}
+
+func authorizedClient(t *testing.T) (*Sk8lServer, protos.CronjobClient) {
+	t.Helper()
+	server, conn := authorizedConn(t)
+	return server, protos.NewCronjobClient(conn)
+}
+
+// authorizedConn serves a server enforcing testPolicy like main does.
+func authorizedConn(t *testing.T) (*Sk8lServer, *grpc.ClientConn) {
+	t.Helper()
+	p, err := policy.Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("policy.Parse failed: %v", err)
+	}
+	st, err := store.NewCronJobDBStore(
+		store.WithStorage(store.NewMemoryStorage()),
+		store.WithK8sClient(k8s.NewClientWithInterface(fake.NewClientset(), k8s.WithNamespace("default"))),
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	server := NewSk8lServer("bufnet", st, nil, nil)
+	server.authenticator = auth.New(auth.WithVerifier(testVerifier{}), auth.WithInternalToken("internal"))
+	server.policy = p
+
+	listener := bufconn.Listen(bufSize)
+	s := grpc.NewServer(server.ServerOptions()...)
+	protos.RegisterCronjobServer(s, server)
+	reflection.Register(s)
+	go func() { _ = s.Serve(listener) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return server, conn
+}
+
+func TestAuthorization(t *testing.T) {
+	server, client := authorizedClient(t)
+	for _, namespace := range []string{"default", "batch", "kube-system"} {
+		event := &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "job-a.1", Namespace: namespace},
+			InvolvedObject: corev1.ObjectReference{Kind: "Job", Name: "job-a", Namespace: namespace},
+			LastTimestamp:  metav1.NewTime(time.Now()),
+		}
+		if err := server.PutEvent(event, time.Hour); err != nil {
+			t.Fatalf("PutEvent failed: %v", err)
+		}
+	}
+
+	if _, err := client.GetEvents(context.Background(), &protos.EventsRequest{Namespace: "default"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without a token, got %v", err)
+	}
+	if _, err := client.GetEvents(asCaller("jane"), &protos.EventsRequest{Namespace: "default"}); err != nil {
+		t.Errorf("expected jane to read default, got %v", err)
+	}
+	if _, err := client.GetEvents(asCaller("jane"), &protos.EventsRequest{Namespace: "batch"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected jane not to read batch, got %v", err)
+	}
+	if _, err := client.GetEvents(asCaller("joe"), &protos.EventsRequest{Namespace: "batch"}); err != nil {
+		t.Errorf("expected joe to read batch through his group, got %v", err)
+	}
+	if _, err := client.GetCronjobYAML(asCaller("joe"), &protos.CronjobRequest{CronjobName: "a"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected requests without namespace to target the namespace of sk8l, got %v", err)
+	}
+	if _, err := client.GetAuditLog(asCaller("joe"), &protos.AuditLogRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected cluster-wide RPCs to need the verb in every namespace, got %v", err)
+	}
+	stream, err := client.ExportSnapshot(asCaller("joe"), &protos.ExportSnapshotRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected joe not to export the snapshot of every namespace, got %v", err)
+	}
+
+	internal := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer internal")
+	response, err := client.GetEvents(internal, &protos.EventsRequest{Namespace: "kube-system"})
+	if err != nil || len(response.GetEvents()) != 1 {
+		t.Errorf("expected sk8l itself to read everything, got %+v, %v", response, err)
+	}
+
+	// Watching every namespace, requests without one need the verb in all of them.
+	server.K8sClient = k8s.NewClientWithInterface(fake.NewClientset())
+	if _, err := client.GetCronjobYAML(asCaller("jane"), &protos.CronjobRequest{CronjobName: "a"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected jane not to read every namespace, got %v", err)
+	}
+}
+
+func TestFilterResponse(t *testing.T) {
+	server, _ := authorizedClient(t)
+	ctx := auth.WithIdentity(context.Background(), &auth.Identity{Name: "jane"})
+	response := &protos.CronjobsResponse{
+		Cronjobs:   []*protos.CronjobResponse{{Name: "a", Namespace: "default"}, {Name: "b", Namespace: "batch"}},
+		ActiveJobs: []*protos.JobResponse{{Name: "a-1", Namespace: "batch"}},
+		JobsPods: []*protos.PodResponse{
+			{Metadata: &protos.ObjectMetaResponse{Name: "a-1-x", Namespace: "default"}},
+			{Metadata: &protos.ObjectMetaResponse{Name: "b-1-x", Namespace: "batch"}},
+		},
+	}
+
+	filtered, _ := server.filterResponse(ctx, "/sk8l.Cronjob/GetCronjobs", response).(*protos.CronjobsResponse)
+	if len(filtered.GetCronjobs()) != 1 || filtered.GetCronjobs()[0].GetName() != "a" ||
+		len(filtered.GetActiveJobs()) != 0 || len(filtered.GetJobsPods()) != 1 {
+		t.Errorf("unexpected filtered response %+v", filtered)
+	}
+	if len(response.GetCronjobs()) != 2 {
+		t.Error("expected the original response to be left untouched")
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	cfg.Auth.Policy = "policy.yml"
+	t.Cleanup(func() { cfg.Auth.Policy = "" })
+	if _, err := newPolicy(auth.New()); err == nil {
+		t.Error("expected a policy without authentication to be rejected")
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/credentials"
+)
+
+// certWarningInterval keeps the near expiry warning from flooding the logs.
+const certWarningInterval = time.Hour
+
+var (
+	// grpcNextProtos and httpNextProtos are the ALPN protocols of the listeners,
+	// configs returned by GetConfigForClient don't inherit them.
+	grpcNextProtos = []string{"h2"}
+	httpNextProtos = []string{"h2", "http/1.1"}
+)
+
+// certReloader serves the server certificate and CA pool of its files and
+// reloads both at once when the files change, e.g. after cert-manager rotated
+// them, so that new connections use them without a restart.
+type certReloader struct {
+	config      atomic.Pointer[tls.Config]
+	now         func() time.Time
+	lastWarning time.Time
+	// name prefixes the certificates in the expiry metric and warnings.
+	name     string
+	certFile string
+	keyFile  string
+	caFile   string
+	checksum [sha256.Size]byte
+}
+
+func newCertReloader(name, certFile, keyFile, caFile string) (*certReloader, error) {
+	r := &certReloader{name: name, certFile: certFile, keyFile: keyFile, caFile: caFile, now: time.Now}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload loads the files again when their content changed and reports
+// whether it did. The current certificate is kept when they are invalid.
+func (r *certReloader) reload() (bool, error) {
+	checksum, err := r.filesChecksum()
+	if err != nil {
+		return false, err
+	}
+	if checksum == r.checksum {
+		return false, nil
+	}
+	config, err := setupTLS(r.certFile, r.keyFile, r.caFile, x509.NewCertPool())
+	if err != nil {
+		return false, err
+	}
+	r.config.Store(config)
+	r.checksum = checksum
+	r.lastWarning = time.Time{}
+	r.checkExpiry()
+	return true, nil
+}
+
+func (r *certReloader) filesChecksum() ([sha256.Size]byte, error) {
+	h := sha256.New()
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		data, err := os.ReadFile(filepath.Clean(file))
+		if err != nil {
+			return [sha256.Size]byte{}, &CertError{CertFile: file, Msg: err.Error(), Err: ErrTLS}
+		}
+		h.Write(data)
+	}
+	return [sha256.Size]byte(h.Sum(nil)), nil
+}
+
+// watch reloads the certificates every interval until ctx is done.
+func (r *certReloader) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		reloaded, err := r.reload()
+		switch {
+		case err != nil:
+			log.Error().Err(err).Str("operation", "reloadCertificates").Msg("keeping the current certificates")
+		case reloaded:
+			log.Info().Str("operation", "reloadCertificates").Str("certFile", r.certFile).Msg("certificates reloaded")
+		default:
+			r.checkExpiry()
+		}
+	}
+}
+
+// checkExpiry records when the certificates expire and warns when it's soon.
+func (r *certReloader) checkExpiry() {
+	expiries := map[string]time.Time{}
+	if leaf := r.config.Load().Certificates[0].Leaf; leaf != nil {
+		expiries[r.name+"server"] = leaf.NotAfter
+	}
+	if notAfter, ok := earliestExpiry(r.caFile); ok {
+		expiries[r.name+"ca"] = notAfter
+	}
+
+	now := r.now()
+	for certificate, notAfter := range expiries {
+		certificateExpiryGauge.WithLabelValues(certificate).Set(float64(notAfter.Unix()))
+		if notAfter.Sub(now) < cfg.TLS.ExpiryWarning.Duration && now.Sub(r.lastWarning) >= certWarningInterval {
+			r.lastWarning = now
+			log.Warn().
+				Str("operation", "checkExpiry").
+				Str("certificate", certificate).
+				Time("expiresAt", notAfter).
+				Msg(fmt.Sprintf("%s certificate expires in %s", certificate, notAfter.Sub(now).Round(time.Minute)))
+		}
+	}
+}
+
+// earliestExpiry returns when the first certificate of a PEM bundle expires.
+func earliestExpiry(file string) (time.Time, bool) {
+	data, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var earliest time.Time
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		if earliest.IsZero() || cert.NotAfter.Before(earliest) {
+			earliest = cert.NotAfter
+		}
+	}
+	return earliest, !earliest.IsZero()
+}
+
+// serverConfig returns a config serving the current certificate and
+// verifying client certificates against the current CA pool.
+func (r *certReloader) serverConfig(clientAuth tls.ClientAuthType, nextProtos []string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		MaxVersion: tls.VersionTLS13,
+		NextProtos: nextProtos,
+		ClientAuth: clientAuth,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &r.config.Load().Certificates[0], nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			config := r.config.Load().Clone()
+			config.ClientAuth = clientAuth
+			config.NextProtos = nextProtos
+			return config, nil
+		},
+	}
+}
+
+// clientCredentials returns the credentials sk8l calls its own API with. Every
+// handshake presents the current certificate and verifies the server against
+// the current CA pool.
+func (r *certReloader) clientCredentials() credentials.TransportCredentials {
+	return &reloadingCredentials{TransportCredentials: credentials.NewTLS(r.clientConfig()), reloader: r}
+}
+
+// clientConfig returns a config presenting the current certificate and
+// verifying the server against the current CA pool.
+func (r *certReloader) clientConfig() *tls.Config {
+	current := r.config.Load()
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		MaxVersion: tls.VersionTLS13,
+		RootCAs:    current.RootCAs,
+		ServerName: current.ServerName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &r.config.Load().Certificates[0], nil
+		},
+	}
+}
+
+// reloadingCredentials performs every client handshake with the client config
+// of its reloader at that time.
+type reloadingCredentials struct {
+	credentials.TransportCredentials
+	reloader *certReloader
+}
+
+func (c *reloadingCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	tlsConn, info, err := credentials.NewTLS(c.reloader.clientConfig()).ClientHandshake(ctx, authority, conn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sk8l#ClientHandshake: %w", err)
+	}
+	return tlsConn, info, nil
+}
+
+func (c *reloadingCredentials) Clone() credentials.TransportCredentials {
+	return &reloadingCredentials{TransportCredentials: c.TransportCredentials.Clone(), reloader: c.reloader}
+}
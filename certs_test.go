@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "sk8l-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes a server certificate signed by the CA, with its key and the
+// CA bundle, to dir.
+func (ca testCA) issue(t *testing.T, dir string, serial int64, notAfter time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "sk8l"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("0.0.0.0")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"server-cert.pem": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		"server-key.pem":  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		"ca-cert.pem":     ca.pem,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// servedSerial returns the serial number of the certificate a TLS handshake with addr gets.
+func servedSerial(t *testing.T, addr string, config *tls.Config) int64 {
+	t.Helper()
+	conn, err := tls.Dial("tcp", addr, config)
+	if err != nil {
+		t.Fatalf("tls.Dial failed: %v", err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+}
+
+// serveHandshakes accepts TLS connections with config and closes them once
+// their handshake is done.
+func serveHandshakes(t *testing.T, config *tls.Config) net.Listener {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	return ln
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	ca.issue(t, dir, 2, time.Now().Add(90*24*time.Hour))
+
+	r, err := newCertReloader(
+		"",
+		filepath.Join(dir, "server-cert.pem"),
+		filepath.Join(dir, "server-key.pem"),
+		filepath.Join(dir, "ca-cert.pem"),
+	)
+	if err != nil {
+		t.Fatalf("newCertReloader failed: %v", err)
+	}
+
+	ln := serveHandshakes(t, r.serverConfig(tls.RequireAndVerifyClientCert, httpNextProtos))
+	client := r.clientConfig()
+	client.ServerName = "127.0.0.1"
+	if serial := servedSerial(t, ln.Addr().String(), client); serial != 2 {
+		t.Errorf("expected the initial certificate, got serial %d", serial)
+	}
+
+	if reloaded, err := r.reload(); err != nil || reloaded {
+		t.Errorf("expected unchanged files not to be reloaded, got %v, %v", reloaded, err)
+	}
+
+	notAfter := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	ca.issue(t, dir, 3, notAfter)
+	if reloaded, err := r.reload(); err != nil || !reloaded {
+		t.Fatalf("expected the rotated certificate to be reloaded, got %v, %v", reloaded, err)
+	}
+	if serial := servedSerial(t, ln.Addr().String(), client); serial != 3 {
+		t.Errorf("expected the rotated certificate, got serial %d", serial)
+	}
+	if got := testutil.ToFloat64(certificateExpiryGauge.WithLabelValues("server")); got != float64(notAfter.Unix()) {
+		t.Errorf("expected the expiry metric to be %d, got %v", notAfter.Unix(), got)
+	}
+	if r.lastWarning.IsZero() {
+		t.Error("expected a warning about the near expiry")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "server-key.pem"), []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.reload(); err == nil {
+		t.Error("expected invalid files to fail reloading")
+	}
+	if serial := servedSerial(t, ln.Addr().String(), client); serial != 3 {
+		t.Errorf("expected the current certificate to be kept, got serial %d", serial)
+	}
+}
+
+func TestCertReloader_ClientCredentials(t *testing.T) {
+	dir := t.TempDir()
+	newTestCA(t).issue(t, dir, 2, time.Now().Add(90*24*time.Hour))
+	r, err := newCertReloader(
+		"",
+		filepath.Join(dir, "server-cert.pem"),
+		filepath.Join(dir, "server-key.pem"),
+		filepath.Join(dir, "ca-cert.pem"),
+	)
+	if err != nil {
+		t.Fatalf("newCertReloader failed: %v", err)
+	}
+	ln := serveHandshakes(t, r.serverConfig(tls.RequireAndVerifyClientCert, httpNextProtos))
+	creds := r.clientCredentials()
+	handshake := func() error {
+		t.Helper()
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		_, _, err = creds.ClientHandshake(context.Background(), "127.0.0.1", conn)
+		return err
+	}
+
+	if err := handshake(); err != nil {
+		t.Fatalf("expected the handshake to succeed, got %v", err)
+	}
+	// A new CA replaces the one the credentials were created with.
+	newTestCA(t).issue(t, dir, 3, time.Now().Add(90*24*time.Hour))
+	if reloaded, err := r.reload(); err != nil || !reloaded {
+		t.Fatalf("expected the rotated CA to be reloaded, got %v, %v", reloaded, err)
+	}
+	if err := handshake(); err != nil {
+		t.Errorf("expected the handshake to verify against the rotated CA, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/danroux/sk8l/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// configPath serves the effective configuration on the debug port.
+const configPath = "/debug/config"
+
+// cfg is the configuration of the server, the defaults until main loaded it.
+var cfg = config.Default()
+
+var ErrUnexpectedArgs = errors.New("unexpected arguments")
+
+// loadConfig loads cfg from the config file, the environment and the flags
+// in args. In import mode it returns the snapshot named after the flags.
+func loadConfig(importMode bool, args []string) (string, error) {
+	name := "sk8l"
+	if importMode {
+		name = "sk8l import"
+	}
+	c, args, err := config.Load(name, args, os.Getenv)
+	if err != nil {
+		return "", fmt.Errorf("sk8l#loadConfig: %w", err)
+	}
+	cfg = c
+	if importMode {
+		return importArgs(args)
+	}
+	if len(args) > 0 {
+		return "", fmt.Errorf("sk8l#loadConfig: %w: %v", ErrUnexpectedArgs, args)
+	}
+	return "", nil
+}
+
+// configHandler serves c as YAML, the way a config file holds it.
+func configHandler(c *config.Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		data, err := c.YAML()
+		if err != nil {
+			log.Error().Err(err).Str("operation", "configHandler").Msg("YAML")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(data)
+	})
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danroux/sk8l/internal/config"
+)
+
+func TestLoadConfig(t *testing.T) {
+	defaults := cfg
+	t.Cleanup(func() { cfg = defaults })
+
+	snapshotPath, err := loadConfig(true, []string{"-server.api-port", "9000", "snapshot.tar.gz"})
+	if err != nil || snapshotPath != "snapshot.tar.gz" {
+		t.Fatalf("expected the snapshot after the flags, got %q, %v", snapshotPath, err)
+	}
+	if cfg.Server.APIPort != 9000 {
+		t.Errorf("expected cfg to be loaded, got port %d", cfg.Server.APIPort)
+	}
+	if _, err := loadConfig(true, nil); !errors.Is(err, ErrSnapshotUsage) {
+		t.Errorf("expected ErrSnapshotUsage, got %v", err)
+	}
+	if _, err := loadConfig(false, []string{"snapshot.tar.gz"}); !errors.Is(err, ErrUnexpectedArgs) {
+		t.Errorf("expected ErrUnexpectedArgs, got %v", err)
+	}
+	if _, err := loadConfig(false, []string{"-storage.backend", "etcd"}); !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestConfigHandler(t *testing.T) {
+	c := config.Default()
+	c.Namespace = "batch"
+	rec := httptest.NewRecorder()
+	configHandler(c).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, configPath, nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/yaml" {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	for _, expected := range []string{"namespace: batch", "refreshInterval: 10s"} {
+		if !strings.Contains(rec.Body.String(), expected) {
+			t.Errorf("expected %q in\n%s", expected, rec.Body.String())
+		}
+	}
+}
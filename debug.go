@@ -0,0 +1,215 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/danroux/sk8l/internal/auth"
+	"github.com/danroux/sk8l/internal/config"
+	"github.com/danroux/sk8l/internal/logger"
+	"github.com/danroux/sk8l/internal/policy"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// debugStatePath dumps the internal state of the server on the debug port.
+	debugStatePath = "/debug/state"
+	// logLevelsPath serves the log levels of the components, PUT changes them.
+	logLevelsPath = "/debug/log/levels"
+	// maxLogLevelsBody bounds the body of a PUT to logLevelsPath.
+	maxLogLevelsBody = 4 << 10
+)
+
+// debugState is the internal state served at debugStatePath.
+type debugState struct {
+	Version    string `json:"version"`
+	Backend    string `json:"backend"`
+	Leader     bool   `json:"leader"`
+	Goroutines int    `json:"goroutines"`
+	// MetricGauges is the number of per CronJob and per Job gauges.
+	MetricGauges int                 `json:"metricGauges"`
+	Watchers     []watcherDebugState `json:"watchers"`
+	// StoreKeys is the number of stored keys by key prefix.
+	StoreKeys map[string]int `json:"storeKeys"`
+	// Streams is the number of open streams by method, HealthWatches the
+	// number of health Watch streams by service.
+	Streams       map[string]int `json:"streams"`
+	HealthWatches map[string]int `json:"healthWatches"`
+}
+
+type watcherDebugState struct {
+	Name         string    `json:"name"`
+	Synced       bool      `json:"synced"`
+	FailingSince time.Time `json:"failingSince,omitzero"`
+	LastEvent    time.Time `json:"lastEvent,omitzero"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// streamTracker counts the open streams of every method.
+type streamTracker struct {
+	open map[string]int
+	mu   sync.Mutex
+}
+
+func newStreamTracker() *streamTracker {
+	return &streamTracker{open: map[string]int{}}
+}
+
+// StreamServerInterceptor counts the streams from their start to their end.
+func (st *streamTracker) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		st.add(info.FullMethod, 1)
+		defer st.add(info.FullMethod, -1)
+		return handler(srv, ss)
+	}
+}
+
+func (st *streamTracker) add(method string, delta int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.open[method] += delta
+	if st.open[method] == 0 {
+		delete(st.open, method)
+	}
+}
+
+func (st *streamTracker) counts() map[string]int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	counts := make(map[string]int, len(st.open))
+	for method, open := range st.open {
+		counts[method] = open
+	}
+	return counts
+}
+
+// newDebugServer returns the server of the debug port, serving pprof, expvar,
+// debugStatePath, configPath and logLevelsPath to the callers the policy
+// grants the debug verb. With the mtls transport callers are also
+// authenticated by their client certificate. Its profiles and traces take as
+// long as they are asked for, it has no write timeout.
+func (s *Sk8lServer) newDebugServer(tlsConfig *tls.Config) *http.Server {
+	authenticator := s.authenticator
+	if cfg.TLS.Debug.Transport == config.TransportMTLS {
+		authenticator = authenticator.ClientCertificates()
+	}
+	mux := &http.ServeMux{}
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc(debugStatePath, s.serveDebugState)
+	mux.Handle(configPath, configHandler(cfg))
+	mux.HandleFunc("GET "+logLevelsPath, serveLogLevels)
+	mux.HandleFunc("PUT "+logLevelsPath, setLogLevels)
+	return &http.Server{
+		Addr:        fmt.Sprintf("0.0.0.0:%d", cfg.Server.DebugPort),
+		IdleTimeout: cfg.Server.IdleTimeout.Duration,
+		ReadTimeout: cfg.Server.ReadTimeout.Duration,
+		TLSConfig:   tlsConfig,
+		Handler:     authenticator.Middleware(s.authorizeDebug(mux)),
+	}
+}
+
+// authorizeDebug lets the requests of callers the policy grants the debug
+// verb, in the namespace sk8l runs in or in every namespace when it watches
+// all of them, through to next. Without a policy only sk8l itself is let through.
+func (s *Sk8lServer) authorizeDebug(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := auth.FromContext(r.Context())
+		if id.Internal() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		namespace := s.serverNamespace()
+		if s.policy == nil || !s.policy.Allows(id, namespace, policy.VerbDebug) {
+			err := s.denied(id, r.URL.Path, policy.VerbDebug, namespace)
+			http.Error(w, status.Convert(err).Message(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Sk8lServer) serveDebugState(w http.ResponseWriter, _ *http.Request) {
+	keys, err := s.KeyCounts()
+	if err != nil {
+		log.Error().Err(err).Str("operation", "serveDebugState").Msg("KeyCounts")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	gauges := 0
+	summaryMap.Range(func(_, _ any) bool {
+		gauges++
+		return true
+	})
+	state := debugState{
+		Version:       Version(),
+		Backend:       s.Backend(),
+		Leader:        s.elector == nil || s.elector.IsLeader(),
+		Goroutines:    runtime.NumGoroutine(),
+		MetricGauges:  gauges,
+		Watchers:      s.health.watcherDebugStates(),
+		StoreKeys:     keys,
+		Streams:       s.streams.counts(),
+		HealthWatches: s.health.subscriberCounts(),
+	}
+	writeDebugJSON(w, "serveDebugState", state)
+}
+
+func serveLogLevels(w http.ResponseWriter, _ *http.Request) {
+	writeDebugJSON(w, "serveLogLevels", logger.Levels())
+}
+
+// setLogLevels changes the levels of the components of a JSON object like
+// {"store": "debug"}, none of them when one is invalid.
+func setLogLevels(w http.ResponseWriter, r *http.Request) {
+	levels := map[string]string{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLogLevelsBody)).Decode(&levels); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	current := logger.Levels()
+	for component, level := range levels {
+		if _, ok := current[component]; !ok {
+			http.Error(w, fmt.Sprintf("%s %q", logger.ErrUnknownComponent, component), http.StatusBadRequest)
+			return
+		}
+		if _, err := logger.ParseLevel(level); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	caller := auth.FromContext(r.Context()).String()
+	for component, level := range levels {
+		if err := logger.SetLevel(component, level); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Info().
+			Str("operation", "setLogLevels").
+			Str("caller", caller).
+			Msg(fmt.Sprintf("log level of %s set to %s", component, level))
+	}
+	writeDebugJSON(w, "setLogLevels", logger.Levels())
+}
+
+func writeDebugJSON(w http.ResponseWriter, operation string, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Error().Err(err).Str("operation", operation).Msg("Encode")
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danroux/sk8l/internal/config"
+	"github.com/danroux/sk8l/internal/logger"
+	"github.com/danroux/sk8l/internal/policy"
+	"github.com/danroux/sk8l/internal/store"
+	"google.golang.org/grpc"
+)
+
+func TestDebugServer(t *testing.T) {
+	server, _ := authorizedClient(t)
+	server.health = newHealthTracker(nil, time.Minute, cronjobsWatcher, jobsWatcher)
+	server.health.watcherSynced(cronjobsWatcher)
+	server.health.watcherEvent(cronjobsWatcher)
+	handler := server.newDebugServer(nil).Handler
+
+	get := func(path, caller string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if caller != "" {
+			req.Header.Set("Authorization", "Bearer "+testToken(caller))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, path := range []string{debugStatePath, configPath} {
+		if rec := get(path, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 for %s without credentials, got %d", path, rec.Code)
+		}
+	}
+	if rec := get(debugStatePath, "jane"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without the debug verb, got %d", rec.Code)
+	}
+
+	server.policy = debugPolicy(t)
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline", "/debug/vars", configPath} {
+		if rec := get(path, "jane"); rec.Code != http.StatusOK {
+			t.Errorf("expected 200 for %s, got %d", path, rec.Code)
+		}
+	}
+
+	rec := get(debugStatePath, "jane")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for %s, got %d %s", debugStatePath, rec.Code, rec.Body)
+	}
+	state := debugState{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil {
+		t.Fatalf("failed to decode %s: %v", rec.Body, err)
+	}
+	if len(state.Watchers) != 2 || state.Watchers[0].Name != cronjobsWatcher || state.Watchers[0].LastEvent.IsZero() {
+		t.Errorf("expected the cronjobs watcher with its last event first, got %+v", state.Watchers)
+	}
+	if !state.Watchers[0].Synced || state.Watchers[1].Synced || !state.Watchers[1].LastEvent.IsZero() {
+		t.Errorf("expected only the cronjobs watcher to be synced, got %+v", state.Watchers)
+	}
+	if _, ok := state.StoreKeys[store.CronjobKeyPrefix]; !ok || !state.Leader || state.Backend != "memory" {
+		t.Errorf("unexpected state %+v", state)
+	}
+
+	server.policy = nil
+	if rec := get("/debug/vars", "jane"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without a policy, got %d", rec.Code)
+	}
+}
+
+// debugPolicy is testPolicy granting jane the debug verb, and the client
+// certificate of runner too.
+func debugPolicy(t *testing.T) *policy.Policy {
+	t.Helper()
+	p, err := policy.Parse([]byte(testPolicy + `
+  - users: ["jane", "runner"]
+    namespaces: ["default"]
+    verbs: ["debug"]
+`))
+	if err != nil {
+		t.Fatalf("policy.Parse failed: %v", err)
+	}
+	return p
+}
+
+func TestDebugServer_ClientCertificates(t *testing.T) {
+	server, _ := authorizedClient(t)
+	server.policy = debugPolicy(t)
+	transport := cfg.TLS.Debug.Transport
+	t.Cleanup(func() { cfg.TLS.Debug.Transport = transport })
+	get := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+		leaf := &x509.Certificate{Subject: pkix.Name{CommonName: "runner"}}
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}
+		rec := httptest.NewRecorder()
+		server.newDebugServer(nil).Handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := get(); code != http.StatusUnauthorized {
+		t.Errorf("expected client certificates not to authenticate over tls, got %d", code)
+	}
+	cfg.TLS.Debug.Transport = config.TransportMTLS
+	if code := get(); code != http.StatusOK {
+		t.Errorf("expected the client certificate to authenticate over mtls, got %d", code)
+	}
+}
+
+func TestLogLevels(t *testing.T) {
+	original := logger.Levels()
+	t.Cleanup(func() {
+		for component, level := range original {
+			_ = logger.SetLevel(component, level)
+		}
+	})
+	server, _ := authorizedClient(t)
+	server.policy = debugPolicy(t)
+	handler := server.newDebugServer(nil).Handler
+	do := func(method, body string) (int, map[string]string) {
+		t.Helper()
+		req := httptest.NewRequest(method, logLevelsPath, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testToken("jane"))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		levels := map[string]string{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &levels)
+		return rec.Code, levels
+	}
+
+	if code, levels := do(http.MethodPut, `{"store": "debug", "grpc": "warn"}`); code != http.StatusOK ||
+		levels[logger.Store] != "debug" || levels[logger.GRPC] != "warn" {
+		t.Errorf("expected the levels to change, got %d %v", code, levels)
+	}
+	for _, body := range []string{`{"etcd": "debug"}`, `{"watcher": "debug", "store": "loud"}`, `debug`} {
+		if code, _ := do(http.MethodPut, body); code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", body, code)
+		}
+	}
+	code, levels := do(http.MethodGet, "")
+	if code != http.StatusOK || levels[logger.Store] != "debug" || levels[logger.Watcher] != original[logger.Watcher] {
+		t.Errorf("expected invalid changes not to be applied, got %d %v", code, levels)
+	}
+}
+
+func TestStreamTracker(t *testing.T) {
+	st := newStreamTracker()
+	interceptor := st.StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/sk8l.Cronjob/GetCronjobs"}
+	opened := make(chan struct{})
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+	go func() {
+		errs <- interceptor(nil, nil, info, func(any, grpc.ServerStream) error {
+			close(opened)
+			<-done
+			return nil
+		})
+	}()
+
+	<-opened
+	if got := st.counts()[info.FullMethod]; got != 1 {
+		t.Errorf("expected 1 open stream, got %d", got)
+	}
+	close(done)
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if got := st.counts(); len(got) != 0 {
+		t.Errorf("expected no open stream, got %v", got)
+	}
+}
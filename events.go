@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/danroux/sk8l/internal/mapper"
+	"github.com/danroux/sk8l/internal/store"
+	"github.com/danroux/sk8l/protos"
+	"github.com/rs/zerolog/log"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// recentEventsLimit is how many Events are embedded in cronjob and job responses.
+const recentEventsLimit = 10
+
+// GetEvents returns the stored Events involving the requested object, oldest first.
+func (s *Sk8lServer) GetEvents(_ context.Context, in *protos.EventsRequest) (*protos.EventsResponse, error) {
+	events, err := s.Events(in.GetNamespace(), in.GetKind(), in.GetName())
+	if err != nil {
+		log.Error().Err(err).Str("operation", "GetEvents").Msg("Events")
+		return nil, fmt.Errorf("sk8l#GetEvents: %w", err)
+	}
+	return &protos.EventsResponse{Events: mapEvents(events)}, nil
+}
+
+func (s *Sk8lServer) cronjobEvents(cronJob *batchv1.CronJob) []*protos.EventResponse {
+	events, err := s.Events(cronJob.Namespace, "CronJob", cronJob.Name)
+	if err != nil {
+		log.Error().Err(err).Str("operation", "cronjobEvents").Msg("Events")
+		return []*protos.EventResponse{}
+	}
+	return mapEvents(recentEvents(events))
+}
+
+// jobEvents merges the Events of a Job with the ones of its pods.
+func (s *Sk8lServer) jobEvents(batchJob *batchv1.Job, pods []corev1.Pod) []*protos.EventResponse {
+	events, err := s.Events(batchJob.Namespace, "Job", batchJob.Name)
+	if err != nil {
+		log.Error().Err(err).Str("operation", "jobEvents").Msg("Events")
+		return []*protos.EventResponse{}
+	}
+	for _, pod := range pods {
+		podEvents, err := s.Events(pod.Namespace, "Pod", pod.Name)
+		if err != nil {
+			log.Error().Err(err).Str("operation", "jobEvents").Msg("Events")
+			continue
+		}
+		events = append(events, podEvents...)
+	}
+	store.SortEvents(events)
+	return mapEvents(recentEvents(events))
+}
+
+func recentEvents(events []corev1.Event) []corev1.Event {
+	if len(events) > recentEventsLimit {
+		return events[len(events)-recentEventsLimit:]
+	}
+	return events
+}
+
+func mapEvents(events []corev1.Event) []*protos.EventResponse {
+	responses := make([]*protos.EventResponse, 0, len(events))
+	for i := range events {
+		responses = append(responses, mapper.MapEvent(&events[i]))
+	}
+	return responses
+}
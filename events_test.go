@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danroux/sk8l/internal/k8s"
+	"github.com/danroux/sk8l/protos"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetEvents(t *testing.T) {
+	client := logsClient(t, k8s.NewClientWithInterface(fake.NewClientset(), k8s.WithNamespace("default")))
+	now := time.Now()
+	for _, event := range []*corev1.Event{
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "job-a.1", Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Job", Name: "job-a"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackoffLimitExceeded",
+			Source:         corev1.EventSource{Component: "job-controller"},
+			Count:          2,
+			LastTimestamp:  metav1.NewTime(now),
+		},
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "pod-a.1", Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "pod-a"},
+			LastTimestamp:  metav1.NewTime(now),
+		},
+	} {
+		if err := sk8lServer.PutEvent(event, time.Hour); err != nil {
+			t.Fatalf("PutEvent failed: %v", err)
+		}
+	}
+
+	response, err := client.GetEvents(context.Background(), &protos.EventsRequest{
+		Namespace: "default",
+		Kind:      "Job",
+		Name:      "job-a",
+	})
+	if err != nil {
+		t.Fatalf("GetEvents failed: %v", err)
+	}
+	events := response.GetEvents()
+	if len(events) != 1 {
+		t.Fatalf("expected the event of job-a only, got %+v", events)
+	}
+	if events[0].GetReason() != "BackoffLimitExceeded" || events[0].GetSource() != "job-controller" || events[0].GetCount() != 2 {
+		t.Errorf("unexpected event %+v", events[0])
+	}
+
+	response, err = client.GetEvents(context.Background(), &protos.EventsRequest{Namespace: "default"})
+	if err != nil || len(response.GetEvents()) != 2 {
+		t.Errorf("expected every event in the namespace, got %+v, %v", response, err)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/danroux/sk8l/internal/gateway"
+	"github.com/danroux/sk8l/internal/redact"
+	"github.com/danroux/sk8l/protos"
+)
+
+// newGateway returns the HTTP/JSON gateway of s. Its calls go through the
+// interceptors of the gRPC server.
+func (s *Sk8lServer) newGateway() (*gateway.Gateway, error) {
+	gw, err := gateway.New(
+		&protos.Cronjob_ServiceDesc,
+		s,
+		gateway.WithUnaryInterceptors(s.unaryInterceptors()...),
+		gateway.WithStreamInterceptors(s.streamInterceptors()...),
+		gateway.WithForwardedHeaders(redact.OptOutHeader),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#newGateway: %w", err)
+	}
+	return gw, nil
+}
+
+// runOpenAPI writes the OpenAPI document of the gateway, generated from
+// sk8l.proto, to stdout.
+func runOpenAPI() error {
+	gw, err := gateway.New(&protos.Cronjob_ServiceDesc, nil)
+	if err != nil {
+		return fmt.Errorf("sk8l#runOpenAPI: %w", err)
+	}
+	if _, err := os.Stdout.Write(gw.OpenAPI()); err != nil {
+		return fmt.Errorf("sk8l#runOpenAPI: %w", err)
+	}
+	return nil
+}
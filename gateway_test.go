@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGateway(t *testing.T) {
+	server, _ := authorizedClient(t)
+	gw, err := server.newGateway()
+	if err != nil {
+		t.Fatalf("newGateway failed: %v", err)
+	}
+	ts := httptest.NewServer(gw)
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name     string
+		caller   string
+		expected int
+	}{
+		{name: "anonymous", expected: http.StatusUnauthorized},
+		{name: "denied", caller: "jane", expected: http.StatusForbidden},
+		{name: "allowed", caller: "joe", expected: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, ts.URL+"/api/v1/events?namespace=batch", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.caller != "" {
+				req.Header.Set("Authorization", "Bearer "+testToken(tt.caller))
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.expected {
+				t.Errorf("expected %d, got %d %s", tt.expected, resp.StatusCode, body)
+			}
+		})
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	gw, err := NewSk8lServer("", nil, nil, nil).newGateway()
+	if err != nil {
+		t.Fatalf("newGateway failed: %v", err)
+	}
+	committed, err := os.ReadFile(filepath.Join("protos", "sk8l.openapi.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(committed, gw.OpenAPI()) {
+		t.Error("protos/sk8l.openapi.json is out of date with sk8l.proto, run make openapi")
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"github.com/danroux/sk8l/internal/grpcweb"
+	"github.com/danroux/sk8l/internal/redact"
+	"google.golang.org/grpc"
+)
+
+// newAPIServer returns the HTTP server serving grpcS to gRPC and gRPC-Web
+// clients on the API port, nil when gRPC-Web is off and grpcS serves the
+// port on its own. Its streams last, it has no read or write timeout. Without
+// tlsConfig it serves gRPC over HTTP/2 without TLS.
+func newAPIServer(grpcS *grpc.Server, tlsConfig *tls.Config) *http.Server {
+	if !cfg.GRPCWeb.Enabled {
+		return nil
+	}
+	return &http.Server{
+		IdleTimeout:       cfg.Server.IdleTimeout.Duration,
+		ReadHeaderTimeout: cfg.Server.ReadTimeout.Duration,
+		TLSConfig:         tlsConfig,
+		Protocols:         h2cProtocols(tlsConfig),
+		Handler: grpcweb.New(
+			grpcS,
+			grpcweb.WithAllowedOrigins(cfg.GRPCWeb.AllowedOrigins...),
+			grpcweb.WithAllowedHeaders(redact.OptOutHeader),
+		),
+	}
+}
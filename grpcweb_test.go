@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danroux/sk8l/internal/config"
+	"github.com/danroux/sk8l/protos"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestNewAPIServer(t *testing.T) {
+	server, _ := authorizedClient(t)
+	grpcS := grpc.NewServer(server.ServerOptions()...)
+	protos.RegisterCronjobServer(grpcS, server)
+
+	if apiS := newAPIServer(grpcS, nil); apiS != nil {
+		t.Fatal("expected no HTTP server without gRPC-Web")
+	}
+	cfg.GRPCWeb = config.GRPCWeb{Enabled: true, AllowedOrigins: []string{"https://sk8l.example.com"}}
+	t.Cleanup(func() { cfg.GRPCWeb = config.GRPCWeb{} })
+	apiS := newAPIServer(grpcS, nil)
+	if apiS == nil {
+		t.Fatal("expected an HTTP server with gRPC-Web")
+	}
+	ts := httptest.NewServer(apiS.Handler)
+	t.Cleanup(ts.Close)
+
+	data, err := proto.Marshal(&protos.EventsRequest{Namespace: "batch"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(data)))
+	body = append(body, data...)
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, ts.URL+"/sk8l.Cronjob/GetEvents", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	req.Header.Set("Origin", "https://sk8l.example.com")
+	req.Header.Set("Authorization", "Bearer "+testToken("jane"))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	response, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(response), "grpc-status: 7\r\n") {
+		t.Errorf("expected the policy to deny jane, got %q", response)
+	}
+	if resp.Header.Get("Access-Control-Allow-Origin") != "https://sk8l.example.com" {
+		t.Errorf("expected the allowed origin to read the response, got %v", resp.Header)
+	}
+}
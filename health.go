@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danroux/sk8l/protos"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	cronjobsWatcher = "cronjobs"
+	jobsWatcher     = "jobs"
+	podsWatcher     = "pods"
+	eventsWatcher   = "events"
+
+	// overallHealthService is the empty service name used by kubelet gRPC probes.
+	// It only reports NOT_SERVING when the store cannot be read, so a temporarily
+	// unreachable API server does not get the pod restarted.
+	overallHealthService = ""
+
+	// healthzPath and readyzPath report the overall and the Cronjob service
+	// to HTTP probes, on the health port.
+	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
+)
+
+// cronjobHealthService is reported NOT_SERVING while the cached view can't be trusted:
+// before the initial sync, when a watcher keeps failing or when the store is unreadable.
+var cronjobHealthService = protos.Cronjob_ServiceDesc.ServiceName
+
+type watcherState struct {
+	failingSince time.Time
+	lastEvent    time.Time
+	lastErr      error
+	synced       bool
+}
+
+type healthTracker struct {
+	watchers         map[string]*watcherState
+	statuses         map[string]healthpb.HealthCheckResponse_ServingStatus
+	subscribers      map[string]map[chan healthpb.HealthCheckResponse_ServingStatus]struct{}
+	dbCheck          func() error
+	now              func() time.Time
+	failureThreshold time.Duration
+	mu               sync.Mutex
+}
+
+func newHealthTracker(dbCheck func() error, failureThreshold time.Duration, watchers ...string) *healthTracker {
+	ht := &healthTracker{
+		watchers:         make(map[string]*watcherState, len(watchers)),
+		statuses:         make(map[string]healthpb.HealthCheckResponse_ServingStatus),
+		subscribers:      make(map[string]map[chan healthpb.HealthCheckResponse_ServingStatus]struct{}),
+		dbCheck:          dbCheck,
+		now:              time.Now,
+		failureThreshold: failureThreshold,
+	}
+	for _, name := range watchers {
+		ht.watchers[name] = &watcherState{}
+	}
+	ht.statuses[overallHealthService] = healthpb.HealthCheckResponse_NOT_SERVING
+	ht.statuses[cronjobHealthService] = healthpb.HealthCheckResponse_NOT_SERVING
+	return ht
+}
+
+// watcherSynced marks a watcher as healthy and records that it completed its initial sync.
+func (ht *healthTracker) watcherSynced(name string) {
+	ht.mu.Lock()
+	ws := ht.watcher(name)
+	ws.synced = true
+	ws.failingSince = time.Time{}
+	ws.lastErr = nil
+	ht.mu.Unlock()
+	ht.evaluate()
+}
+
+// watcherFailed records a watcher failure. The watcher only turns the service
+// NOT_SERVING once it has been failing for longer than failureThreshold.
+func (ht *healthTracker) watcherFailed(name string, err error) {
+	ht.mu.Lock()
+	ws := ht.watcher(name)
+	if ws.failingSince.IsZero() {
+		ws.failingSince = ht.now()
+	}
+	ws.lastErr = err
+	ht.mu.Unlock()
+	ht.evaluate()
+}
+
+// watcherEvent records when a watcher last received a watch event.
+func (ht *healthTracker) watcherEvent(name string) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	ht.watcher(name).lastEvent = ht.now()
+}
+
+func (ht *healthTracker) watcher(name string) *watcherState {
+	ws, ok := ht.watchers[name]
+	if !ok {
+		ws = &watcherState{}
+		ht.watchers[name] = ws
+	}
+	return ws
+}
+
+// evaluate recomputes the status of every service and notifies Watch subscribers of transitions.
+func (ht *healthTracker) evaluate() {
+	var dbErr error
+	if ht.dbCheck != nil {
+		dbErr = ht.dbCheck()
+	}
+
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	overall := healthpb.HealthCheckResponse_SERVING
+	if dbErr != nil {
+		overall = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	cronjob := overall
+	now := ht.now()
+	for name, ws := range ht.watchers {
+		if !ws.synced {
+			cronjob = healthpb.HealthCheckResponse_NOT_SERVING
+			continue
+		}
+		if !ws.failingSince.IsZero() && now.Sub(ws.failingSince) > ht.failureThreshold {
+			log.Warn().
+				Err(ws.lastErr).
+				Str("component", "probe").
+				Str("watcher", name).
+				Msg(fmt.Sprintf("watcher failing since %s", ws.failingSince.UTC().Format(time.RFC3339)))
+			cronjob = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+	}
+
+	ht.setStatus(overallHealthService, overall)
+	ht.setStatus(cronjobHealthService, cronjob)
+}
+
+func (ht *healthTracker) setStatus(service string, servingStatus healthpb.HealthCheckResponse_ServingStatus) {
+	if ht.statuses[service] == servingStatus {
+		return
+	}
+	log.Info().
+		Str("component", "probe").
+		Str("service", service).
+		Msg(fmt.Sprintf("health status changed from %s to %s", ht.statuses[service], servingStatus))
+	ht.statuses[service] = servingStatus
+	for ch := range ht.subscribers[service] {
+		// Only the latest status matters to a subscriber, drop a stale pending one.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- servingStatus
+	}
+}
+
+// watcherStatuses returns the state of every watcher, sorted by name.
+func (ht *healthTracker) watcherStatuses() []*protos.WatcherStatusResponse {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	statuses := make([]*protos.WatcherStatusResponse, 0, len(ht.watchers))
+	for name, ws := range ht.watchers {
+		watcherStatus := &protos.WatcherStatusResponse{Name: name, Synced: ws.synced}
+		if !ws.failingSince.IsZero() {
+			watcherStatus.FailingSince = ws.failingSince.UTC().Format(time.RFC3339)
+		}
+		if ws.lastErr != nil {
+			watcherStatus.Error = ws.lastErr.Error()
+		}
+		statuses = append(statuses, watcherStatus)
+	}
+	slices.SortFunc(statuses, func(a, b *protos.WatcherStatusResponse) int {
+		return strings.Compare(a.GetName(), b.GetName())
+	})
+	return statuses
+}
+
+// watcherDebugStates returns the state of every watcher for /debug/state,
+// sorted by name.
+func (ht *healthTracker) watcherDebugStates() []watcherDebugState {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	states := make([]watcherDebugState, 0, len(ht.watchers))
+	for name, ws := range ht.watchers {
+		state := watcherDebugState{
+			Name:         name,
+			Synced:       ws.synced,
+			FailingSince: ws.failingSince.UTC(),
+			LastEvent:    ws.lastEvent.UTC(),
+		}
+		if ws.lastErr != nil {
+			state.Error = ws.lastErr.Error()
+		}
+		states = append(states, state)
+	}
+	slices.SortFunc(states, func(a, b watcherDebugState) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return states
+}
+
+// subscriberCounts returns the number of Watch streams of every service.
+func (ht *healthTracker) subscriberCounts() map[string]int {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	counts := make(map[string]int, len(ht.subscribers))
+	for service, subscribers := range ht.subscribers {
+		counts[service] = len(subscribers)
+	}
+	return counts
+}
+
+func (ht *healthTracker) status(service string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	servingStatus, ok := ht.statuses[service]
+	return servingStatus, ok
+}
+
+func (ht *healthTracker) subscribe(service string) (<-chan healthpb.HealthCheckResponse_ServingStatus, func()) {
+	ch := make(chan healthpb.HealthCheckResponse_ServingStatus, 1)
+	ht.mu.Lock()
+	if ht.subscribers[service] == nil {
+		ht.subscribers[service] = make(map[chan healthpb.HealthCheckResponse_ServingStatus]struct{})
+	}
+	ht.subscribers[service][ch] = struct{}{}
+	ht.mu.Unlock()
+
+	return ch, func() {
+		ht.mu.Lock()
+		delete(ht.subscribers[service], ch)
+		ht.mu.Unlock()
+	}
+}
+
+// run re-evaluates health every interval so time based transitions, like a watcher
+// crossing the failure threshold or the store becoming unreadable, are pushed to Watch streams.
+func (ht *healthTracker) run(ctx context.Context, interval time.Duration) {
+	ht.evaluate()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ht.evaluate()
+			}
+		}
+	}()
+}
+
+func (s *Sk8lServer) Check(
+	ctx context.Context,
+	req *healthpb.HealthCheckRequest,
+) (*healthpb.HealthCheckResponse, error) {
+	s.health.evaluate()
+	servingStatus, ok := s.health.status(req.GetService())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "sk8l#Check: unknown service %q", req.GetService())
+	}
+	log.Debug().
+		Str("component", "probe").
+		Str("operation", "health").
+		Str("service", req.GetService()).
+		Msg(servingStatus.String())
+	return &healthpb.HealthCheckResponse{Status: servingStatus}, nil
+}
+
+func (s *Sk8lServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	service := req.GetService()
+	updates, unsubscribe := s.health.subscribe(service)
+	defer unsubscribe()
+
+	current, ok := s.health.status(service)
+	if !ok {
+		current = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+	}
+
+	for {
+		if err := stream.Send(&healthpb.HealthCheckResponse{Status: current}); err != nil {
+			log.Error().
+				Err(err).
+				Str("operation", "Watch#stream.Send").
+				Send()
+			return fmt.Errorf("sk8l#Watch: stream.Send failed: %w", err)
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return fmt.Errorf("sk8l#Watch: %w", stream.Context().Err())
+		case next := <-updates:
+			for next == current {
+				select {
+				case <-stream.Context().Done():
+					return fmt.Errorf("sk8l#Watch: %w", stream.Context().Err())
+				case next = <-updates:
+				}
+			}
+			current = next
+		}
+	}
+}
+
+// newProbeServer returns the server of the health port. It serves gRPC health
+// checks with probeS and /healthz and /readyz to HTTP probes. Its Watch
+// streams last, it has no write timeout.
+func (s *Sk8lServer) newProbeServer(probeS *grpc.Server, tlsConfig *tls.Config) *http.Server {
+	mux := &http.ServeMux{}
+	mux.Handle(healthzPath, s.healthHandler(overallHealthService))
+	mux.Handle(readyzPath, s.healthHandler(cronjobHealthService))
+	return &http.Server{
+		IdleTimeout:       cfg.Server.IdleTimeout.Duration,
+		ReadHeaderTimeout: cfg.Server.ReadTimeout.Duration,
+		TLSConfig:         tlsConfig,
+		Protocols:         h2cProtocols(tlsConfig),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+				probeS.ServeHTTP(w, r)
+				return
+			}
+			mux.ServeHTTP(w, r)
+		}),
+	}
+}
+
+// healthHandler answers 200 while service is SERVING and 503 otherwise, with
+// the status as body.
+func (s *Sk8lServer) healthHandler(service string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		s.health.evaluate()
+		servingStatus, _ := s.health.status(service)
+		code := http.StatusOK
+		if servingStatus != healthpb.HealthCheckResponse_SERVING {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(code)
+		_, _ = fmt.Fprintln(w, servingStatus)
+	})
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var errWatch = errors.New("watch failed")
+
+func TestHealthTracker_InitialSync(t *testing.T) {
+	ht := newHealthTracker(nil, time.Minute, cronjobsWatcher, jobsWatcher)
+	ht.evaluate()
+
+	if got, _ := ht.status(overallHealthService); got != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("expected overall SERVING, got %s", got)
+	}
+	if got, _ := ht.status(cronjobHealthService); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("expected %s NOT_SERVING before initial sync, got %s", cronjobHealthService, got)
+	}
+
+	ht.watcherSynced(cronjobsWatcher)
+	if got, _ := ht.status(cronjobHealthService); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("expected NOT_SERVING while jobs watcher is not synced, got %s", got)
+	}
+
+	ht.watcherSynced(jobsWatcher)
+	if got, _ := ht.status(cronjobHealthService); got != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("expected SERVING after initial sync, got %s", got)
+	}
+}
+
+func TestHealthTracker_WatcherFailureThreshold(t *testing.T) {
+	now := time.Now()
+	ht := newHealthTracker(nil, 30*time.Second, cronjobsWatcher)
+	ht.now = func() time.Time { return now }
+	ht.watcherSynced(cronjobsWatcher)
+
+	ht.watcherFailed(cronjobsWatcher, errWatch)
+	if got, _ := ht.status(cronjobHealthService); got != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("expected SERVING within the failure threshold, got %s", got)
+	}
+
+	now = now.Add(31 * time.Second)
+	ht.watcherFailed(cronjobsWatcher, errWatch)
+	if got, _ := ht.status(cronjobHealthService); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("expected NOT_SERVING past the failure threshold, got %s", got)
+	}
+
+	ht.watcherSynced(cronjobsWatcher)
+	if got, _ := ht.status(cronjobHealthService); got != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("expected SERVING after the watcher recovered, got %s", got)
+	}
+}
+
+func TestHealthTracker_DBUnreadable(t *testing.T) {
+	var dbErr error
+	ht := newHealthTracker(func() error { return dbErr }, time.Minute, cronjobsWatcher)
+	ht.watcherSynced(cronjobsWatcher)
+
+	dbErr = errors.New("DB Closed")
+	ht.evaluate()
+	for _, service := range []string{overallHealthService, cronjobHealthService} {
+		if got, _ := ht.status(service); got != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("expected %q NOT_SERVING with an unreadable DB, got %s", service, got)
+		}
+	}
+}
+
+func TestHealthCheckAndWatch(t *testing.T) {
+	healthLis := bufconn.Listen(bufSize)
+	s := grpc.NewServer()
+	srv := NewSk8lServer("bufnet", nil, nil, nil)
+	srv.health = newHealthTracker(nil, time.Minute, cronjobsWatcher)
+	healthpb.RegisterHealthServer(s, srv)
+	go func() {
+		_ = s.Serve(healthLis)
+	}()
+	defer s.Stop()
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return healthLis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := healthpb.NewHealthClient(conn)
+
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for an unknown service, got %v", err)
+	}
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: cronjobHealthService})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("stream.Recv failed: %v", err)
+	}
+	if first.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("expected first status NOT_SERVING, got %s", first.Status)
+	}
+
+	srv.health.watcherSynced(cronjobsWatcher)
+
+	second, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("stream.Recv failed: %v", err)
+	}
+	if second.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("expected transition to SERVING, got %s", second.Status)
+	}
+
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: cronjobHealthService})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("expected Check SERVING, got %s", resp.Status)
+	}
+}
+
+func TestProbeServer(t *testing.T) {
+	srv := NewSk8lServer("bufnet", nil, nil, nil)
+	srv.health = newHealthTracker(nil, time.Minute, cronjobsWatcher)
+	probeS := grpc.NewServer()
+	healthpb.RegisterHealthServer(probeS, srv)
+	probeHTTP := srv.newProbeServer(probeS, nil)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = serveHTTP(probeHTTP, ln)
+	}()
+	t.Cleanup(func() { _ = probeHTTP.Close() })
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "http://"+ln.Addr().String()+path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(body))
+	}
+	if code, body := get(healthzPath); code != http.StatusOK || body != "SERVING" {
+		t.Errorf("expected /healthz to be SERVING, got %d %s", code, body)
+	}
+	if code, body := get(readyzPath); code != http.StatusServiceUnavailable || body != "NOT_SERVING" {
+		t.Errorf("expected /readyz to be NOT_SERVING before the initial sync, got %d %s", code, body)
+	}
+	srv.health.watcherSynced(cronjobsWatcher)
+	if code, _ := get(readyzPath); code != http.StatusOK {
+		t.Errorf("expected /readyz to be SERVING after the initial sync, got %d", code)
+	}
+
+	// gRPC probes share the plaintext port, over HTTP/2 without TLS.
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer conn.Close()
+	resp, err := healthpb.NewHealthClient(conn).Check(t.Context(), &healthpb.HealthCheckRequest{Service: cronjobHealthService})
+	if err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("expected the gRPC Check to be SERVING, got %v, %v", resp, err)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"google.golang.org/grpc"
+)
+
+// ServerOptions returns the interceptors of the gRPC server serving s.
+func (s *Sk8lServer) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.unaryInterceptors()...),
+		grpc.ChainStreamInterceptor(s.streamInterceptors()...),
+	}
+}
+
+// unaryInterceptors run around every call of s, whether it comes through the
+// gRPC server or the HTTP gateway. Callers are authenticated first, their
+// calls audited and authorized by the policy, then responses are redacted on
+// their way out, whatever handler built them.
+func (s *Sk8lServer) unaryInterceptors() []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{
+		s.authenticator.UnaryServerInterceptor(),
+		s.auditUnaryInterceptor(),
+		s.authorizationUnaryInterceptor(),
+		s.redactor.UnaryServerInterceptor(),
+	}
+}
+
+// streamInterceptors are the streaming counterparts of unaryInterceptors.
+// Open streams are counted first, for /debug/state.
+func (s *Sk8lServer) streamInterceptors() []grpc.StreamServerInterceptor {
+	return []grpc.StreamServerInterceptor{
+		s.streams.StreamServerInterceptor(),
+		s.authenticator.StreamServerInterceptor(),
+		s.auditStreamInterceptor(),
+		s.authorizationStreamInterceptor(),
+		s.redactor.StreamServerInterceptor(),
+	}
+}
@@ -0,0 +1,285 @@
+// Package audit records who did what through the API to a JSON-lines file
+// that is rotated by size.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxSize is the size in bytes above which the log is rotated.
+	DefaultMaxSize = 100 << 20
+	// DefaultMaxBackups is the number of rotated files kept.
+	DefaultMaxBackups = 5
+	// DefaultLimit is the number of entries Entries returns without a limit,
+	// MaxLimit the most it returns.
+	DefaultLimit = 1000
+	MaxLimit     = 5000
+	// maxLineSize bounds the entries read back, requests are small.
+	maxLineSize = 1 << 20
+	filePerm    = 0o600
+)
+
+// OutcomeStarted is the outcome of the entry recorded when a stream starts,
+// the entry recorded when it ends holds its status code.
+const OutcomeStarted = "Started"
+
+var (
+	ErrDisabled      = errors.New("audit log disabled")
+	ErrInvalidOption = errors.New("invalid audit log option")
+)
+
+// Entry is an audited call.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Caller string    `json:"caller"`
+	RPC    string    `json:"rpc"`
+	Verb   string    `json:"verb"`
+	// Namespace, Kind and Name identify the target object.
+	Namespace string `json:"namespace,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Parameters are the JSON encoded request.
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+	// Outcome is the gRPC status code of the call, OutcomeStarted for the
+	// start of a stream.
+	Outcome    string `json:"outcome"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// Log appends entries to a JSON-lines file. Once the file grows past its max
+// size it is renamed with a .1 suffix, older files being shifted up to the
+// max number of backups. A nil Log records nothing.
+type Log struct {
+	file       *os.File
+	path       string
+	size       int64
+	maxSize    int64
+	maxBackups int
+	mu         sync.Mutex
+}
+
+type Option func(*Log) error
+
+// WithMaxSize sets the size in bytes above which the log is rotated.
+func WithMaxSize(size int64) Option {
+	return func(l *Log) error {
+		if size <= 0 {
+			return fmt.Errorf("%w: max size %d", ErrInvalidOption, size)
+		}
+		l.maxSize = size
+		return nil
+	}
+}
+
+// WithMaxBackups sets the number of rotated files kept, 0 drops the entries
+// of the full file.
+func WithMaxBackups(backups int) Option {
+	return func(l *Log) error {
+		if backups < 0 {
+			return fmt.Errorf("%w: max backups %d", ErrInvalidOption, backups)
+		}
+		l.maxBackups = backups
+		return nil
+	}
+}
+
+// Open opens the log at path, appending to it when it exists.
+func Open(path string, opts ...Option) (*Log, error) {
+	l := &Log{path: filepath.Clean(path), maxSize: DefaultMaxSize, maxBackups: DefaultMaxBackups}
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return nil, fmt.Errorf("audit#Open: %w", err)
+		}
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, filePerm)
+	if err != nil {
+		return fmt.Errorf("audit#open: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("audit#open: %w", err)
+	}
+	l.file = file
+	l.size = info.Size()
+	return l.terminateLastLine()
+}
+
+// terminateLastLine ends a line cut short by a crash, so that it doesn't
+// swallow the next entry.
+func (l *Log) terminateLastLine() error {
+	if l.size == 0 {
+		return nil
+	}
+	last := make([]byte, 1)
+	reader, err := os.Open(l.path)
+	if err != nil {
+		return fmt.Errorf("audit#open: %w", err)
+	}
+	defer reader.Close()
+	if _, err := reader.ReadAt(last, l.size-1); err != nil {
+		return fmt.Errorf("audit#open: %w", err)
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	n, err := l.file.Write([]byte{'\n'})
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("audit#open: %w", err)
+	}
+	return nil
+}
+
+// Record appends entry to the log.
+func (l *Log) Record(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("audit#Record: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("audit#Record: %w", err)
+	}
+	return nil
+}
+
+// rotate shifts the files by one backup and starts a new one. Callers hold l.mu.
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("audit#rotate: %w", err)
+	}
+	if err := os.Remove(l.backup(l.maxBackups)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("audit#rotate: %w", err)
+	}
+	for i := l.maxBackups - 1; i >= 0; i-- {
+		if err := os.Rename(l.backup(i), l.backup(i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("audit#rotate: %w", err)
+		}
+	}
+	return l.open()
+}
+
+// backup returns the path of the i-th rotated file, the current one for 0.
+func (l *Log) backup(i int) string {
+	if i == 0 {
+		return l.path
+	}
+	return l.path + "." + strconv.Itoa(i)
+}
+
+// Entries returns the most recent entries recorded in [from, to), oldest
+// first. Zero times leave the range open. The limit defaults to DefaultLimit
+// and is capped at MaxLimit. The files are read from the newest one and only
+// until enough entries or the start of the range were found.
+func (l *Log) Entries(from, to time.Time, limit int) ([]Entry, error) {
+	if l == nil {
+		return nil, ErrDisabled
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := []Entry{}
+	for i := 0; i <= l.maxBackups && len(entries) < limit; i++ {
+		fileEntries, reachedFrom, err := readEntries(l.backup(i), from, to, limit-len(entries))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(fileEntries, entries...)
+		if reachedFrom {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// readEntries returns the last limit entries of the file at path recorded in
+// [from, to), and whether it holds entries before from, which the older files
+// only hold.
+func readEntries(path string, from, to time.Time, limit int) ([]Entry, bool, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("audit#Entries: %w", err)
+	}
+	defer file.Close()
+
+	entries := []Entry{}
+	reachedFrom := false
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+	for scanner.Scan() {
+		entry := Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A line cut short by a crash, the next ones are still valid.
+			continue
+		}
+		if !from.IsZero() && entry.Time.Before(from) {
+			reachedFrom = true
+			continue
+		}
+		if !to.IsZero() && !entry.Time.Before(to) {
+			continue
+		}
+		entries = append(entries, entry)
+		if len(entries) > 2*limit {
+			entries = entries[:copy(entries, entries[len(entries)-limit:])]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, false, fmt.Errorf("audit#Entries: %w", err)
+	}
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, reachedFrom, nil
+}
+
+// Close closes the file of the log.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("audit#Close: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func entryAt(t time.Time, name string) Entry {
+	return Entry{
+		Time:       t,
+		Caller:     "oidc:jane",
+		RPC:        "/sk8l.Cronjob/GetPodYAML",
+		Verb:       "read",
+		Namespace:  "default",
+		Kind:       "Pod",
+		Name:       name,
+		Parameters: json.RawMessage(`{"podName":"` + name + `"}`),
+		Outcome:    "OK",
+	}
+}
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path, WithMaxSize(600), WithMaxBackups(2))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 10 {
+		if err := l.Record(entryAt(start.Add(time.Duration(i)*time.Minute), string(rune('a'+i)))); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+	for _, file := range []string{path, path + ".1", path + ".2"} {
+		if info, err := os.Stat(file); err != nil || info.Size() > 600 {
+			t.Errorf("expected %s to be rotated below the max size, got %v, %v", file, info, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected at most 2 backups, got %v", err)
+	}
+
+	entries, err := l.Entries(time.Time{}, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("Entries failed: %v", err)
+	}
+	if len(entries) == 0 || len(entries) == 10 || entries[len(entries)-1].Name != "j" {
+		t.Fatalf("expected the most recent entries to be kept, got %d", len(entries))
+	}
+	for i := 1; i < len(entries); i++ {
+		if !entries[i-1].Time.Before(entries[i].Time) {
+			t.Fatalf("expected the entries oldest first, got %v", entries)
+		}
+	}
+
+	entries, err = l.Entries(start.Add(7*time.Minute), start.Add(9*time.Minute), 0)
+	if err != nil || len(entries) != 2 || entries[0].Name != "h" || entries[1].Name != "i" {
+		t.Errorf("expected the entries of [7m, 9m), got %+v, %v", entries, err)
+	}
+	entries, err = l.Entries(time.Time{}, time.Time{}, 1)
+	if err != nil || len(entries) != 1 || entries[0].Name != "j" {
+		t.Errorf("expected the most recent entry, got %+v, %v", entries, err)
+	}
+	if string(entries[0].Parameters) != `{"podName":"j"}` {
+		t.Errorf("unexpected parameters %s", entries[0].Parameters)
+	}
+}
+
+func TestLog_EntriesLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range MaxLimit + 10 {
+		if err := l.Record(entryAt(start.Add(time.Duration(i)*time.Second), "pod")); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+	last := start.Add((MaxLimit + 9) * time.Second)
+
+	for limit, expected := range map[int]int{0: DefaultLimit, 10: 10, 2 * MaxLimit: MaxLimit} {
+		entries, err := l.Entries(time.Time{}, time.Time{}, limit)
+		if err != nil {
+			t.Fatalf("Entries failed: %v", err)
+		}
+		if len(entries) != expected || !entries[len(entries)-1].Time.Equal(last) {
+			t.Errorf("expected the %d most recent entries for limit %d, got %d", expected, limit, len(entries))
+		}
+	}
+
+	// The older files are not read once the start of the range was found.
+	if err := os.Mkdir(path+".1", 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Entries(time.Time{}, time.Time{}, 0); err != nil {
+		t.Errorf("expected the newest file to hold enough entries, got %v", err)
+	}
+	entries, err := l.Entries(last.Add(-time.Second), time.Time{}, MaxLimit)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("expected the last 2 entries, got %d, %v", len(entries), err)
+	}
+}
+
+func TestLog_ReopenAndPartialLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := os.WriteFile(path, []byte(`{"time":"2026-01-01T00:00:00Z","name":"a","outcome":"OK"}`+"\n"+`{"time":"2026-01-`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer l.Close()
+	if err := l.Record(entryAt(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), "b")); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	entries, err := l.Entries(time.Time{}, time.Time{}, 0)
+	if err != nil || len(entries) != 2 || entries[0].Name != "a" || entries[1].Name != "b" {
+		t.Errorf("expected the complete entries to be read, got %+v, %v", entries, err)
+	}
+
+	var disabled *Log
+	if err := disabled.Record(entryAt(time.Now(), "c")); err != nil {
+		t.Errorf("expected a nil log to record nothing, got %v", err)
+	}
+	if _, err := disabled.Entries(time.Time{}, time.Time{}, 0); !errors.Is(err, ErrDisabled) {
+		t.Errorf("expected ErrDisabled, got %v", err)
+	}
+	if _, err := Open(path, WithMaxSize(0)); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("expected ErrInvalidOption, got %v", err)
+	}
+}
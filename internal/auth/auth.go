@@ -0,0 +1,402 @@
+// Package auth authenticates the callers of the gRPC API and of the HTTP
+// endpoints with OIDC/JWT bearer tokens, Kubernetes ServiceAccount tokens or
+// TLS client certificates and carries their identity in the request context.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/danroux/sk8l/internal/logger"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Authentication methods an Identity was established with.
+const (
+	MethodOIDC              = "oidc"
+	MethodServiceAccount    = "serviceaccount"
+	MethodClientCertificate = "mtls"
+	// MethodInternal identifies sk8l calling its own API, e.g. to collect metrics.
+	MethodInternal = "internal"
+)
+
+// InternalName is the name of the Identity of sk8l calling its own API.
+const InternalName = "sk8l"
+
+const (
+	authorizationHeader = "authorization"
+	bearerPrefix        = "bearer "
+	jwtParts            = 3
+)
+
+var (
+	ErrMissingToken  = errors.New("missing bearer token")
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrTokenRejected = errors.New("token rejected")
+	ErrNoVerifier    = errors.New("no verifier accepts the token")
+)
+
+// rpcLog logs the calls and the authentication failures.
+var rpcLog = logger.For(logger.GRPC)
+
+// Identity is an authenticated caller.
+type Identity struct {
+	// Name is the user name, e.g. the subject of a token.
+	Name   string
+	Groups []string
+	// SANs are the subject alternative names of a client certificate.
+	SANs []string
+	// Method is how the caller was authenticated.
+	Method string
+}
+
+// Internal reports whether id is sk8l calling its own API.
+func (id *Identity) Internal() bool {
+	return id != nil && id.Method == MethodInternal
+}
+
+func (id *Identity) String() string {
+	if id == nil {
+		return "anonymous"
+	}
+	return fmt.Sprintf("%s:%s", id.Method, id.Name)
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying id.
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the caller identity, nil for anonymous callers.
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
+
+// Verifier authenticates a bearer token. Accepts tells apart the tokens a
+// Verifier is responsible for from its unverified claims.
+type Verifier interface {
+	Accepts(claims map[string]any) bool
+	Verify(ctx context.Context, token string) (*Identity, error)
+}
+
+// Authenticator authenticates the callers of the gRPC API.
+type Authenticator struct {
+	verifiers []Verifier
+	// internalToken authenticates sk8l calling its own API.
+	internalToken string
+	// clientCertificates authenticates callers without a bearer token by
+	// their verified TLS client certificate.
+	clientCertificates bool
+	// onAuthenticated is called with the context of every authenticated call.
+	onAuthenticated func(ctx context.Context, id *Identity) context.Context
+}
+
+type Option func(*Authenticator)
+
+// WithVerifier adds a Verifier. Verifiers are tried in the order they were added.
+func WithVerifier(v Verifier) Option {
+	return func(a *Authenticator) {
+		a.verifiers = append(a.verifiers, v)
+	}
+}
+
+// WithContextHook lets callers derive the request context from the identity,
+// e.g. to grant privileges.
+func WithContextHook(hook func(ctx context.Context, id *Identity) context.Context) Option {
+	return func(a *Authenticator) {
+		a.onAuthenticated = hook
+	}
+}
+
+// WithClientCertificates authenticates callers that present no bearer token
+// by their verified TLS client certificate.
+func WithClientCertificates() Option {
+	return func(a *Authenticator) {
+		a.clientCertificates = true
+	}
+}
+
+// WithInternalToken sets the bearer token sk8l authenticates with when calling its own API.
+func WithInternalToken(token string) Option {
+	return func(a *Authenticator) {
+		a.internalToken = token
+	}
+}
+
+func New(opts ...Option) *Authenticator {
+	a := &Authenticator{}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// ClientCertificates returns a copy of a that also authenticates callers
+// without a bearer token by their verified TLS client certificate.
+func (a *Authenticator) ClientCertificates() *Authenticator {
+	if a == nil {
+		return New(WithClientCertificates())
+	}
+	clone := *a
+	clone.clientCertificates = true
+	return &clone
+}
+
+// Enabled reports whether callers have to authenticate.
+func (a *Authenticator) Enabled() bool {
+	return a != nil && (len(a.verifiers) > 0 || a.clientCertificates)
+}
+
+// Authenticate verifies the bearer token of the incoming request in ctx, or
+// its client certificate when it has no token.
+func (a *Authenticator) Authenticate(ctx context.Context) (*Identity, error) {
+	token, err := bearerToken(ctx)
+	if errors.Is(err, ErrMissingToken) && a.clientCertificates {
+		if id := CertificateIdentity(ctx); id != nil {
+			return id, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if a.internalToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.internalToken)) == 1 {
+		return &Identity{Name: InternalName, Method: MethodInternal}, nil
+	}
+	claims, err := unverifiedClaims(token)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range a.verifiers {
+		if !v.Accepts(claims) {
+			continue
+		}
+		id, err := v.Verify(ctx, token)
+		if err != nil {
+			return nil, fmt.Errorf("auth#Authenticate: %w", err)
+		}
+		return id, nil
+	}
+	return nil, ErrNoVerifier
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(authorizationHeader) {
+		if len(value) > len(bearerPrefix) && strings.EqualFold(value[:len(bearerPrefix)], bearerPrefix) {
+			return strings.TrimSpace(value[len(bearerPrefix):]), nil
+		}
+	}
+	return "", ErrMissingToken
+}
+
+// CertificateIdentity returns the identity of the verified TLS client
+// certificate of the incoming request in ctx, nil without one. The name is
+// the subject common name, or the first SAN without one, and the groups are
+// the subject organizations.
+func CertificateIdentity(ctx context.Context) *Identity {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	leaf := info.State.VerifiedChains[0][0]
+
+	sans := append([]string{}, leaf.DNSNames...)
+	sans = append(sans, leaf.EmailAddresses...)
+	for _, ip := range leaf.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, uri := range leaf.URIs {
+		sans = append(sans, uri.String())
+	}
+	id := &Identity{
+		Name:   leaf.Subject.CommonName,
+		Groups: leaf.Subject.Organization,
+		SANs:   sans,
+		Method: MethodClientCertificate,
+	}
+	if id.Name == "" && len(sans) > 0 {
+		id.Name = sans[0]
+	}
+	if id.Name == "" {
+		return nil
+	}
+	return id
+}
+
+// unverifiedClaims decodes the payload of a JWT without checking its signature.
+func unverifiedClaims(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != jwtParts {
+		return nil, fmt.Errorf("%w: not a JWT", ErrInvalidToken)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload: %w", ErrInvalidToken, err)
+	}
+	claims := map[string]any{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: payload: %w", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// authenticate returns the context of an authenticated call, carrying the
+// identity and a logger tagged with it.
+func (a *Authenticator) authenticate(ctx context.Context, method string) (context.Context, *Identity, error) {
+	id, err := a.Authenticate(ctx)
+	if err != nil {
+		rpcLog.Warn().Err(err).Str("operation", "Authenticate").Str("method", method).Msg("unauthenticated call")
+		return nil, nil, status.Error(codes.Unauthenticated, "sk8l: authentication failed")
+	}
+	return a.withIdentity(ctx, id), id, nil
+}
+
+func (a *Authenticator) withIdentity(ctx context.Context, id *Identity) context.Context {
+	ctx = WithIdentity(ctx, id)
+	ctx = log.With().Str("caller", id.String()).Logger().WithContext(ctx)
+	if a.onAuthenticated != nil {
+		ctx = a.onAuthenticated(ctx, id)
+	}
+	return ctx
+}
+
+// UnaryServerInterceptor rejects unauthenticated calls and logs every call
+// with its caller. It lets every call through when no Verifier is configured.
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !a.Enabled() {
+			return handler(ctx, req)
+		}
+		ctx, _, err := a.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor rejects unauthenticated streams and logs every stream with its caller.
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !a.Enabled() {
+			return handler(srv, ss)
+		}
+		ctx, id, err := a.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		err = handler(srv, &identityStream{ServerStream: ss, authenticator: a, id: id})
+		logCall(ctx, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// Middleware rejects unauthenticated HTTP requests to next with 401, from
+// their authorization header or their verified client certificate like the
+// gRPC calls. It lets every request through when no Verifier is configured.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, _, err := a.authenticate(requestContext(r), r.URL.Path)
+		if err != nil {
+			http.Error(w, "sk8l: authentication failed", http.StatusUnauthorized)
+			return
+		}
+		start := time.Now()
+		next.ServeHTTP(w, r.WithContext(ctx))
+		logCall(ctx, r.URL.Path, start, nil)
+	})
+}
+
+// requestContext carries the authorization header of r as incoming metadata
+// and its TLS connection state as peer, like the gRPC server would.
+func requestContext(r *http.Request) context.Context {
+	md := metadata.MD{}
+	if values := r.Header.Values(authorizationHeader); len(values) > 0 {
+		md.Append(authorizationHeader, values...)
+	}
+	p := &peer.Peer{}
+	if r.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{State: *r.TLS}
+	}
+	return peer.NewContext(metadata.NewIncomingContext(r.Context(), md), p)
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	rpcLog.Info().
+		Str("operation", "rpc").
+		Str("method", method).
+		Str("caller", FromContext(ctx).String()).
+		Str("code", status.Code(err).String()).
+		Dur("duration", time.Since(start)).
+		Msg("call finished")
+}
+
+// identityStream carries the identity of its caller in its context.
+type identityStream struct {
+	grpc.ServerStream
+	authenticator *Authenticator
+	id            *Identity
+}
+
+func (s *identityStream) Context() context.Context {
+	return s.authenticator.withIdentity(s.ServerStream.Context(), s.id)
+}
+
+// bearerCredentials authenticate the calls of a gRPC client with a bearer token.
+type bearerCredentials string
+
+// BearerCredentials returns the per-RPC credentials sending token as bearer token.
+func BearerCredentials(token string) credentials.PerRPCCredentials {
+	return bearerCredentials(token)
+}
+
+func (c bearerCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{authorizationHeader: "Bearer " + string(c)}, nil
+}
+
+func (c bearerCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+// insecureBearerCredentials also send their token over plaintext connections.
+type insecureBearerCredentials struct {
+	bearerCredentials
+}
+
+// InsecureBearerCredentials returns BearerCredentials that also work on
+// plaintext connections, for a server calling its own plaintext API.
+func InsecureBearerCredentials(token string) credentials.PerRPCCredentials {
+	return insecureBearerCredentials{bearerCredentials(token)}
+}
+
+func (c insecureBearerCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,426 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/danroux/sk8l/internal/k8s"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testIssuer = "https://issuer.example.com"
+
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKeys{rsa: rsaKey, ec: ecKey}
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// writeJWKS writes the public keys as a JWKS file and returns its path.
+func (k testKeys) writeJWKS(t *testing.T) string {
+	t.Helper()
+	jwks := map[string]any{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA", "kid": "rsa", "use": "sig",
+				"n": b64(k.rsa.N.Bytes()), "e": b64(big.NewInt(int64(k.rsa.E)).Bytes()),
+			},
+			{
+				"kty": "EC", "kid": "ec", "crv": "P-256",
+				"x": b64(k.ec.X.FillBytes(make([]byte, 32))), "y": b64(k.ec.Y.FillBytes(make([]byte, 32))),
+			},
+		},
+	}
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func (k testKeys) sign(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	var err error
+	switch alg {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k.rsa, crypto.SHA256, digest[:])
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k.ec, digest[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	default:
+		signature = []byte("signature")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + b64(signature)
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"iss":    testIssuer,
+		"aud":    []string{"sk8l", "other"},
+		"sub":    "jane",
+		"groups": []string{"sre", "dev"},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"nbf":    time.Now().Add(-time.Minute).Unix(),
+	}
+}
+
+func withClaim(key string, value any) map[string]any {
+	claims := validClaims()
+	if value == nil {
+		delete(claims, key)
+	} else {
+		claims[key] = value
+	}
+	return claims
+}
+
+func TestOIDCVerifier(t *testing.T) {
+	keys := newTestKeys(t)
+	verifier, err := NewOIDCVerifier(OIDCConfig{Issuer: testIssuer, Audience: "sk8l", JWKSFile: keys.writeJWKS(t)})
+	if err != nil {
+		t.Fatalf("NewOIDCVerifier failed: %v", err)
+	}
+	other := newTestKeys(t)
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"RS256", keys.sign(t, "RS256", "rsa", validClaims()), nil},
+		{"ES256", keys.sign(t, "ES256", "ec", validClaims()), nil},
+		{"expired", keys.sign(t, "RS256", "rsa", withClaim("exp", time.Now().Add(-time.Hour).Unix())), ErrTokenExpired},
+		{"without exp", keys.sign(t, "RS256", "rsa", withClaim("exp", nil)), ErrTokenExpired},
+		{"not yet valid", keys.sign(t, "RS256", "rsa", withClaim("nbf", time.Now().Add(time.Hour).Unix())), ErrTokenNotYetValid},
+		{"wrong issuer", keys.sign(t, "RS256", "rsa", withClaim("iss", "https://evil.example.com")), ErrInvalidToken},
+		{"wrong audience", keys.sign(t, "RS256", "rsa", withClaim("aud", "other")), ErrInvalidAudience},
+		{"without audience", keys.sign(t, "RS256", "rsa", withClaim("aud", nil)), ErrInvalidAudience},
+		{"without subject", keys.sign(t, "RS256", "rsa", withClaim("sub", nil)), ErrMissingUsernameClaim},
+		{"bad signature", other.sign(t, "RS256", "rsa", validClaims()), ErrInvalidSignature},
+		{"unknown kid", keys.sign(t, "RS256", "unknown", validClaims()), ErrUnknownKey},
+		{"alg none", keys.sign(t, "none", "rsa", validClaims()), ErrUnsupportedAlgorithm},
+		{"alg HS256", keys.sign(t, "HS256", "rsa", validClaims()), ErrUnsupportedAlgorithm},
+		{"key type mismatch", keys.sign(t, "ES256", "rsa", validClaims()), ErrUnsupportedAlgorithm},
+		{"not a JWT", "token", ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := verifier.Verify(context.Background(), tt.token)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify failed: %v", err)
+			}
+			if id.Name != "jane" || id.Method != MethodOIDC || !slices.Equal(id.Groups, []string{"sre", "dev"}) {
+				t.Errorf("unexpected identity %+v", id)
+			}
+		})
+	}
+
+	if _, err := NewOIDCVerifier(OIDCConfig{}); !errors.Is(err, ErrMissingIssuer) {
+		t.Errorf("expected ErrMissingIssuer, got %v", err)
+	}
+	if _, err := NewOIDCVerifier(OIDCConfig{Issuer: testIssuer}); !errors.Is(err, ErrMissingAudience) {
+		t.Errorf("expected ErrMissingAudience, got %v", err)
+	}
+}
+
+func TestParseKeySet(t *testing.T) {
+	keys, err := ParseKeySet([]byte(`{"keys": [{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"}, {"kty": "RSA", "kid": "enc", "use": "enc"}]}`))
+	if err != nil {
+		t.Fatalf("ParseKeySet failed: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("expected symmetric and encryption keys to be ignored, got %v", keys)
+	}
+	invalid := `{"keys": [{"kty": "EC", "kid": "ec", "crv": "P-256", "x": "AQ", "y": "AQ"}]}`
+	if _, err := ParseKeySet([]byte(invalid)); !errors.Is(err, ErrInvalidKeySet) {
+		t.Errorf("expected ErrInvalidKeySet for a point off the curve, got %v", err)
+	}
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	jwks, err := os.ReadFile(newTestKeys(t).writeJWKS(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu       sync.Mutex
+		requests int
+		failing  = true
+	)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		requests++
+		fail := failing
+		mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		<-release
+		_, _ = w.Write(jwks)
+	}))
+	defer server.Close()
+	ks := NewRemoteKeySet(server.URL)
+
+	if _, err := ks.Key(context.Background(), "rsa"); !errors.Is(err, errUnexpectedCode) {
+		t.Fatalf("expected the fetch to fail, got %v", err)
+	}
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+
+	// A failed fetch doesn't delay the next one, concurrent callers share it.
+	errs := make(chan error, 3)
+	for range cap(errs) {
+		go func() {
+			_, err := ks.Key(context.Background(), "rsa")
+			errs <- err
+		}()
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ks.Key(canceled, "rsa"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a canceled caller not to wait for the fetch, got %v", err)
+	}
+	close(release)
+	for range cap(errs) {
+		if err := <-errs; err != nil {
+			t.Errorf("Key failed: %v", err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 2 {
+		t.Errorf("expected a single fetch after the failed one, got %d requests", requests)
+	}
+}
+
+func saToken(exp time.Time) string {
+	payload, _ := json.Marshal(map[string]any{
+		"iss":           "https://kubernetes.default.svc",
+		"kubernetes.io": map[string]any{"namespace": "default"},
+		"exp":           exp.Unix(),
+	})
+	return b64([]byte(`{"alg":"RS256"}`)) + "." + b64(payload) + ".c2lnbmF0dXJl"
+}
+
+func TestServiceAccountVerifier(t *testing.T) {
+	clientset := fake.NewClientset()
+	reviews := 0
+	clientset.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		review, _ := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		review.Status = authenticationv1.TokenReviewStatus{Error: "invalid bearer token"}
+		if strings.HasSuffix(review.Spec.Token, ".c2lnbmF0dXJl") {
+			review.Status = authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User: authenticationv1.UserInfo{
+					Username: "system:serviceaccount:default:ci",
+					Groups:   []string{"system:serviceaccounts"},
+				},
+			}
+		}
+		return true, review, nil
+	})
+	verifier := NewServiceAccountVerifier(k8s.NewClientWithInterface(clientset, k8s.WithNamespace("default")), nil)
+
+	token := saToken(time.Now().Add(time.Hour))
+	claims, _ := unverifiedClaims(token)
+	if !verifier.Accepts(claims) || verifier.Accepts(map[string]any{"iss": testIssuer}) {
+		t.Fatal("expected only ServiceAccount tokens to be accepted")
+	}
+	for range 2 {
+		id, err := verifier.Verify(context.Background(), token)
+		if err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+		if id.Name != "system:serviceaccount:default:ci" || id.Method != MethodServiceAccount {
+			t.Errorf("unexpected identity %+v", id)
+		}
+	}
+	if reviews != 1 {
+		t.Errorf("expected the review to be cached, got %d reviews", reviews)
+	}
+
+	if _, err := verifier.Verify(context.Background(), saToken(time.Now().Add(time.Hour))+"x"); !errors.Is(err, ErrTokenRejected) {
+		t.Errorf("expected ErrTokenRejected, got %v", err)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	keys := newTestKeys(t)
+	verifier, err := NewOIDCVerifier(OIDCConfig{Issuer: testIssuer, Audience: "sk8l", JWKSFile: keys.writeJWKS(t)})
+	if err != nil {
+		t.Fatalf("NewOIDCVerifier failed: %v", err)
+	}
+	hooked := false
+	a := New(WithVerifier(verifier), WithContextHook(func(ctx context.Context, id *Identity) context.Context {
+		hooked = slices.Contains(id.Groups, "sre")
+		return ctx
+	}))
+	interceptor := a.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/sk8l.Cronjob/GetCronjob"}
+	handler := func(ctx context.Context, _ any) (any, error) {
+		return FromContext(ctx), nil
+	}
+
+	_, err = interceptor(context.Background(), nil, info, handler)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without a token, got %v", err)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("authorization", "Bearer "+keys.sign(t, "RS256", "rsa", validClaims())))
+	resp, err := interceptor(ctx, nil, info, handler)
+	if err != nil {
+		t.Fatalf("interceptor failed: %v", err)
+	}
+	if id, _ := resp.(*Identity); id.String() != "oidc:jane" || !hooked {
+		t.Errorf("expected the identity in the context, got %v", resp)
+	}
+
+	resp, err = New().UnaryServerInterceptor()(context.Background(), nil, info, handler)
+	if err != nil || resp.(*Identity) != nil {
+		t.Errorf("expected anonymous calls without verifiers, got %v, %v", resp, err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var id *Identity
+	next := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		id = FromContext(r.Context())
+	})
+	handler := New(WithInternalToken("internal"), WithClientCertificates()).Middleware(next)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/state", nil))
+	if rec.Code != http.StatusUnauthorized || id != nil {
+		t.Errorf("expected 401 without credentials, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/state", nil)
+	req.Header.Set("Authorization", "Bearer internal")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !id.Internal() {
+		t.Errorf("expected the identity of the token in the context, got %d %v", rec.Code, id)
+	}
+
+	leaf := &x509.Certificate{Subject: pkix.Name{CommonName: "runner"}}
+	req = httptest.NewRequest(http.MethodGet, "/debug/state", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || id.Name != "runner" {
+		t.Errorf("expected the identity of the client certificate, got %d %v", rec.Code, id)
+	}
+}
+
+func TestAuthenticate_ClientCertificate(t *testing.T) {
+	leaf := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "runner", Organization: []string{"ci"}},
+		DNSNames:       []string{"ci.example.com"},
+		EmailAddresses: []string{"ci@example.com"},
+	}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}},
+	})
+
+	if _, err := New(WithVerifier(&OIDCVerifier{})).Authenticate(ctx); !errors.Is(err, ErrMissingToken) {
+		t.Errorf("expected client certificates to be ignored unless enabled, got %v", err)
+	}
+	a := New(WithClientCertificates(), WithInternalToken("internal"))
+	if !a.Enabled() {
+		t.Fatal("expected client certificates to enable authentication")
+	}
+	id, err := a.Authenticate(ctx)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if id.Name != "runner" || id.Method != MethodClientCertificate ||
+		!slices.Equal(id.Groups, []string{"ci"}) || !slices.Equal(id.SANs, []string{"ci.example.com", "ci@example.com"}) {
+		t.Errorf("unexpected identity %+v", id)
+	}
+
+	leaf.Subject.CommonName = ""
+	if id, _ = a.Authenticate(ctx); id.Name != "ci.example.com" {
+		t.Errorf("expected the first SAN without common name, got %+v", id)
+	}
+	if _, err := a.Authenticate(context.Background()); !errors.Is(err, ErrMissingToken) {
+		t.Errorf("expected ErrMissingToken without certificate, got %v", err)
+	}
+
+	internal := metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer internal"))
+	if id, err = a.Authenticate(internal); err != nil || !id.Internal() {
+		t.Errorf("expected the internal identity, got %+v, %v", id, err)
+	}
+}
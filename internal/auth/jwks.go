@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// minRefreshInterval rate limits the refreshes of remote key sets
+	// triggered by tokens signed with unknown keys.
+	minRefreshInterval = time.Minute
+	fetchTimeout       = 10 * time.Second
+	maxKeySetSize      = 1 << 20
+	discoveryPath      = "/.well-known/openid-configuration"
+)
+
+var (
+	ErrInvalidKeySet  = errors.New("invalid JWKS")
+	ErrUnknownKey     = errors.New("unknown signing key")
+	errUnexpectedCode = errors.New("unexpected status code")
+)
+
+// KeySet holds the public keys of a JSON Web Key Set by their key ID.
+// Remote key sets are fetched on first use and refreshed when a token is
+// signed with a key they don't know.
+type KeySet struct {
+	// fetchedAt is the time of the last successful fetch.
+	fetchedAt time.Time
+	keys      map[string]crypto.PublicKey
+	// fetch is nil for static key sets.
+	fetch func(ctx context.Context) ([]byte, error)
+	// refreshing is the fetch in flight, shared by the callers waiting for it.
+	refreshing *keyRefresh
+	mu         sync.Mutex
+}
+
+// keyRefresh is a fetch of a remote key set, done is closed once err is set.
+type keyRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+// LoadKeySetFile reads a static key set from a JWKS file.
+func LoadKeySetFile(path string) (*KeySet, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("auth#LoadKeySetFile: %w", err)
+	}
+	keys, err := ParseKeySet(data)
+	if err != nil {
+		return nil, err
+	}
+	return &KeySet{keys: keys}, nil
+}
+
+// NewRemoteKeySet returns a key set fetched from url.
+func NewRemoteKeySet(url string) *KeySet {
+	return &KeySet{
+		fetch: func(ctx context.Context) ([]byte, error) {
+			return get(ctx, url)
+		},
+	}
+}
+
+// NewDiscoveredKeySet returns a key set fetched from the jwks_uri of the
+// OpenID provider configuration of issuer.
+func NewDiscoveredKeySet(issuer string) *KeySet {
+	return &KeySet{
+		fetch: func(ctx context.Context) ([]byte, error) {
+			data, err := get(ctx, strings.TrimSuffix(issuer, "/")+discoveryPath)
+			if err != nil {
+				return nil, err
+			}
+			discovery := struct {
+				JWKSURI string `json:"jwks_uri"`
+			}{}
+			if err := json.Unmarshal(data, &discovery); err != nil || discovery.JWKSURI == "" {
+				return nil, fmt.Errorf("auth#NewDiscoveredKeySet: %w: no jwks_uri in %s", ErrInvalidKeySet, discoveryPath)
+			}
+			return get(ctx, discovery.JWKSURI)
+		},
+	}
+}
+
+// Key returns the key with the given ID. A token without key ID is accepted
+// when the set holds a single key.
+func (ks *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	ks.mu.Lock()
+	if key, ok := ks.lookup(kid); ok {
+		ks.mu.Unlock()
+		return key, nil
+	}
+	if ks.fetch == nil || time.Since(ks.fetchedAt) < minRefreshInterval {
+		ks.mu.Unlock()
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+	}
+	refresh := ks.refreshing
+	if refresh == nil {
+		refresh = &keyRefresh{done: make(chan struct{})}
+		ks.refreshing = refresh
+		// The fetch is bounded by fetchTimeout, not by the caller starting it.
+		go ks.refresh(context.WithoutCancel(ctx), refresh)
+	}
+	ks.mu.Unlock()
+
+	select {
+	case <-refresh.done:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("auth#Key: %w", ctx.Err())
+	}
+	if refresh.err != nil {
+		return nil, refresh.err
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if key, ok := ks.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+}
+
+// refresh fetches the key set without holding ks.mu and swaps the keys in
+// when it succeeds.
+func (ks *KeySet) refresh(ctx context.Context, refresh *keyRefresh) {
+	defer close(refresh.done)
+	data, err := ks.fetch(ctx)
+	var keys map[string]crypto.PublicKey
+	if err == nil {
+		keys, err = ParseKeySet(data)
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.refreshing = nil
+	refresh.err = err
+	if err != nil {
+		return
+	}
+	ks.keys = keys
+	ks.fetchedAt = time.Now()
+}
+
+func (ks *KeySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseKeySet parses the RSA and EC signing keys of a JWKS document, other
+// keys are ignored.
+func ParseKeySet(data []byte) (map[string]crypto.PublicKey, error) {
+	set := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("auth#ParseKeySet: %w: %w", ErrInvalidKeySet, err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		var (
+			key crypto.PublicKey
+			err error
+		)
+		switch jwk.Kty {
+		case "RSA":
+			key, err = jwk.rsaKey()
+		case "EC":
+			key, err = jwk.ecKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("auth#ParseKeySet: %w: key %q: %w", ErrInvalidKeySet, jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func (jwk jsonWebKey) rsaKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("n: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("e: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > int64(^uint32(0)>>1) {
+		return nil, fmt.Errorf("%w: exponent out of range", ErrInvalidKeySet)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+func (jwk jsonWebKey) ecKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("%w: curve %q", ErrInvalidKeySet, jwk.Crv)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("x: %w", err)
+	}
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("y: %w", err)
+	}
+	key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if _, err := key.ECDH(); err != nil {
+		return nil, fmt.Errorf("%w: point not on curve: %w", ErrInvalidKeySet, err)
+	}
+	return key, nil
+}
+
+func get(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("auth#get: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("auth#get: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auth#get: %s: %w %d", url, errUnexpectedCode, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxKeySetSize))
+	if err != nil {
+		return nil, fmt.Errorf("auth#get: %w", err)
+	}
+	return data, nil
+}
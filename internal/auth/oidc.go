@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultUsernameClaim = "sub"
+	defaultGroupsClaim   = "groups"
+	// clockSkew is tolerated between sk8l and the token issuer.
+	clockSkew = time.Minute
+)
+
+var (
+	ErrMissingIssuer         = errors.New("an OIDC issuer is required")
+	ErrMissingAudience       = errors.New("an OIDC audience is required")
+	ErrUnsupportedAlgorithm  = errors.New("unsupported signing algorithm")
+	ErrInvalidSignature      = errors.New("invalid signature")
+	ErrTokenExpired          = errors.New("token expired")
+	ErrTokenNotYetValid      = errors.New("token not yet valid")
+	ErrInvalidAudience       = errors.New("token not issued for this audience")
+	ErrMissingUsernameClaim  = errors.New("token has no username claim")
+	errUnexpectedIssuerClaim = errors.New("unexpected issuer")
+)
+
+// OIDCConfig configures the verification of JWTs issued by an OIDC provider.
+type OIDCConfig struct {
+	// Issuer must match the iss claim.
+	Issuer string
+	// Audience must be one of the aud claim, so that the tokens the issuer
+	// grants other clients are rejected.
+	Audience string
+	// JWKSFile is a JSON Web Key Set file holding the signing keys, for
+	// offline setups and tests.
+	JWKSFile string
+	// JWKSURL is where the signing keys are fetched from. Without it and
+	// JWKSFile the keys are discovered from the issuer.
+	JWKSURL       string
+	UsernameClaim string
+	GroupsClaim   string
+}
+
+// OIDCVerifier verifies JWTs signed by the keys of an OIDC issuer.
+type OIDCVerifier struct {
+	keys   *KeySet
+	now    func() time.Time
+	config OIDCConfig
+}
+
+var _ Verifier = (*OIDCVerifier)(nil)
+
+func NewOIDCVerifier(config OIDCConfig) (*OIDCVerifier, error) {
+	if config.Issuer == "" {
+		return nil, ErrMissingIssuer
+	}
+	if config.Audience == "" {
+		return nil, ErrMissingAudience
+	}
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = defaultUsernameClaim
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = defaultGroupsClaim
+	}
+
+	var keys *KeySet
+	switch {
+	case config.JWKSFile != "":
+		var err error
+		if keys, err = LoadKeySetFile(config.JWKSFile); err != nil {
+			return nil, err
+		}
+	case config.JWKSURL != "":
+		keys = NewRemoteKeySet(config.JWKSURL)
+	default:
+		keys = NewDiscoveredKeySet(config.Issuer)
+	}
+	return &OIDCVerifier{keys: keys, now: time.Now, config: config}, nil
+}
+
+// Accepts the tokens of the configured issuer.
+func (v *OIDCVerifier) Accepts(claims map[string]any) bool {
+	iss, _ := claims["iss"].(string)
+	return iss == v.config.Issuer
+}
+
+func (v *OIDCVerifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	claims, err := v.verifySignature(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.verifyClaims(claims); err != nil {
+		return nil, err
+	}
+
+	name, _ := claims[v.config.UsernameClaim].(string)
+	if name == "" {
+		return nil, fmt.Errorf("%w %q", ErrMissingUsernameClaim, v.config.UsernameClaim)
+	}
+	return &Identity{
+		Name:   name,
+		Groups: stringsClaim(claims[v.config.GroupsClaim]),
+		Method: MethodOIDC,
+	}, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func (v *OIDCVerifier) verifySignature(ctx context.Context, token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != jwtParts {
+		return nil, fmt.Errorf("%w: not a JWT", ErrInvalidToken)
+	}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %w", ErrInvalidToken, err)
+	}
+	header := jwtHeader{}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %w", ErrInvalidToken, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %w", ErrInvalidToken, err)
+	}
+
+	key, err := v.keys.Key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWS(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+	return unverifiedClaims(token)
+}
+
+func (v *OIDCVerifier) verifyClaims(claims map[string]any) error {
+	if iss, _ := claims["iss"].(string); iss != v.config.Issuer {
+		return fmt.Errorf("%w: %w %q", ErrInvalidToken, errUnexpectedIssuerClaim, iss)
+	}
+	now := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return ErrTokenExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return ErrTokenNotYetValid
+	}
+	if !slices.Contains(stringsClaim(claims["aud"]), v.config.Audience) {
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+// stringsClaim reads a claim that is either a string or a list of strings.
+func stringsClaim(claim any) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, value := range v {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+type signingAlgorithm struct {
+	hash crypto.Hash
+	// pss selects RSASSA-PSS instead of PKCS #1 v1.5 for RSA keys.
+	pss bool
+	ec  bool
+}
+
+// algorithms are the asymmetric JWS algorithms OIDC providers sign with.
+var algorithms = map[string]signingAlgorithm{
+	"RS256": {hash: crypto.SHA256},
+	"RS384": {hash: crypto.SHA384},
+	"RS512": {hash: crypto.SHA512},
+	"PS256": {hash: crypto.SHA256, pss: true},
+	"PS384": {hash: crypto.SHA384, pss: true},
+	"PS512": {hash: crypto.SHA512, pss: true},
+	"ES256": {hash: crypto.SHA256, ec: true},
+	"ES384": {hash: crypto.SHA384, ec: true},
+	"ES512": {hash: crypto.SHA512, ec: true},
+}
+
+func verifyJWS(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	algorithm, ok := algorithms[alg]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnsupportedAlgorithm, alg)
+	}
+	h := algorithm.hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if algorithm.ec {
+			return fmt.Errorf("%w: %s with an RSA key", ErrUnsupportedAlgorithm, alg)
+		}
+		var err error
+		if algorithm.pss {
+			err = rsa.VerifyPSS(k, algorithm.hash, digest, signature, nil)
+		} else {
+			err = rsa.VerifyPKCS1v15(k, algorithm.hash, digest, signature)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if !algorithm.ec {
+			return fmt.Errorf("%w: %s with an EC key", ErrUnsupportedAlgorithm, alg)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("%w: unexpected length", ErrInvalidSignature)
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return ErrInvalidSignature
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: key type %T", ErrUnsupportedAlgorithm, key)
+	}
+}
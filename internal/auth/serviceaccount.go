@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+const (
+	// legacyServiceAccountIssuer is the issuer of the secret based tokens of
+	// ServiceAccounts, bound tokens carry a kubernetes.io claim instead.
+	legacyServiceAccountIssuer = "kubernetes/serviceaccount"
+	boundTokenClaim            = "kubernetes.io"
+	// reviewCacheTTL bounds how long a TokenReview result is reused.
+	reviewCacheTTL = time.Minute
+)
+
+// TokenReviewer asks the Kubernetes API server to authenticate a token.
+type TokenReviewer interface {
+	ReviewToken(ctx context.Context, token string, audiences []string) (*authenticationv1.TokenReviewStatus, error)
+}
+
+// ServiceAccountVerifier authenticates Kubernetes ServiceAccount tokens with
+// a TokenReview. Results are cached for a minute to spare the API server.
+type ServiceAccountVerifier struct {
+	reviewer  TokenReviewer
+	cache     map[[sha256.Size]byte]cachedReview
+	now       func() time.Time
+	audiences []string
+	mu        sync.Mutex
+}
+
+type cachedReview struct {
+	expiresAt time.Time
+	id        *Identity
+}
+
+var _ Verifier = (*ServiceAccountVerifier)(nil)
+
+// NewServiceAccountVerifier returns a verifier that reviews tokens issued for
+// audiences, the API server audiences when empty.
+func NewServiceAccountVerifier(reviewer TokenReviewer, audiences []string) *ServiceAccountVerifier {
+	return &ServiceAccountVerifier{
+		reviewer:  reviewer,
+		cache:     make(map[[sha256.Size]byte]cachedReview),
+		now:       time.Now,
+		audiences: audiences,
+	}
+}
+
+// Accepts legacy and bound ServiceAccount tokens.
+func (v *ServiceAccountVerifier) Accepts(claims map[string]any) bool {
+	if iss, _ := claims["iss"].(string); iss == legacyServiceAccountIssuer {
+		return true
+	}
+	_, ok := claims[boundTokenClaim]
+	return ok
+}
+
+func (v *ServiceAccountVerifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	key := sha256.Sum256([]byte(token))
+	now := v.now()
+
+	v.mu.Lock()
+	cached, ok := v.cache[key]
+	v.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.id, nil
+	}
+
+	result, err := v.reviewer.ReviewToken(ctx, token, v.audiences)
+	if err != nil {
+		return nil, fmt.Errorf("auth#Verify: %w", err)
+	}
+	if !result.Authenticated {
+		return nil, fmt.Errorf("%w: %s", ErrTokenRejected, result.Error)
+	}
+	id := &Identity{
+		Name:   result.User.Username,
+		Groups: result.User.Groups,
+		Method: MethodServiceAccount,
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for k, c := range v.cache {
+		if !now.Before(c.expiresAt) {
+			delete(v.cache, k)
+		}
+	}
+	v.cache[key] = cachedReview{expiresAt: v.expiresAt(token, now), id: id}
+	return id, nil
+}
+
+// expiresAt caches a review until the token expires, at most reviewCacheTTL.
+func (v *ServiceAccountVerifier) expiresAt(token string, now time.Time) time.Time {
+	expiresAt := now.Add(reviewCacheTTL)
+	claims, err := unverifiedClaims(token)
+	if err != nil {
+		return expiresAt
+	}
+	if exp, ok := claims["exp"].(float64); ok && time.Unix(int64(exp), 0).Before(expiresAt) {
+		return time.Unix(int64(exp), 0)
+	}
+	return expiresAt
+}
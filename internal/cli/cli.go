@@ -0,0 +1,323 @@
+// Package cli implements sk8lctl, the command-line client of the sk8l API. It
+// works like kubectl, but speaks the sk8l Cronjob service: it reads from the
+// cache of a sk8l server instead of the Kubernetes API.
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/danroux/sk8l/internal/auth"
+	"github.com/danroux/sk8l/internal/redact"
+	"github.com/danroux/sk8l/protos"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// Set with LDFLAGS.
+var version = "unset"
+
+// The exit codes of Main.
+const (
+	exitOK = iota
+	exitFailure
+	exitUsage
+)
+
+const usage = `sk8lctl controls the CronJobs watched by a sk8l server.
+
+Usage:
+  sk8lctl [flags] <command> [args]
+
+Commands:
+  get cronjobs [NAME]       list the CronJobs
+  get jobs                  list the Jobs not owned by a CronJob
+  describe cronjob NAME     show the details, Jobs and Events of a CronJob
+  history NAME              list the Jobs run by a CronJob, most recent first
+  logs job NAME             print the logs of the Pods of a Job
+  logs pod NAME             print the logs of a Pod
+  trigger NAME              run a CronJob now
+  suspend NAME              suspend a CronJob
+  resume NAME               resume a suspended CronJob
+  watch                     list the CronJobs and their changes as they happen
+  tui                       browse the CronJobs, their Jobs, Pods, logs and YAML live
+  version                   print the client and server versions
+
+Flags:
+`
+
+var (
+	ErrUsage         = errors.New("invalid usage")
+	ErrInvalidOutput = errors.New("invalid output format")
+	ErrNotFound      = errors.New("not found")
+	ErrNoTerminal    = errors.New("stdin is not a terminal")
+)
+
+// Options are the flags of sk8lctl. The TLS and auth ones mirror the
+// settings of the server: its CA, client certificates and bearer tokens.
+type Options struct {
+	// Server is the address of the sk8l API.
+	Server string
+	// CACert verifies the certificate of the server, the system roots are used without one.
+	CACert string
+	// Cert and Key are the client certificate, for servers asking for one.
+	Cert string
+	Key  string
+	// ServerName overrides the name the certificate of the server is verified against.
+	ServerName string
+	// Token is sent as bearer token, TokenFile is read when it's empty.
+	Token     string
+	TokenFile string
+	// NoRedaction asks for unredacted responses, only privileged callers get them.
+	NoRedaction bool
+	// Namespace is the namespace of the objects, the one sk8l runs in when empty.
+	Namespace string
+	// Output is table, json or yaml.
+	Output string
+	// Follow keeps streaming the logs of running containers.
+	Follow    bool
+	Container string
+	TailLines int64
+}
+
+// flagSet returns the flags of sk8lctl, set in opts. Their defaults come from
+// the environment.
+func flagSet(opts *Options, output io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("sk8lctl", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&opts.Server, "server", envOr("SK8L_SERVER", "localhost:8585"), "address of the sk8l API, $SK8L_SERVER")
+	fs.StringVar(&opts.CACert, "cacert", os.Getenv("SK8L_CA_CERT"), "CA certificate of the server, $SK8L_CA_CERT")
+	fs.StringVar(&opts.Cert, "cert", os.Getenv("SK8L_CLIENT_CERT"), "client certificate, $SK8L_CLIENT_CERT")
+	fs.StringVar(&opts.Key, "key", os.Getenv("SK8L_CLIENT_KEY"), "key of the client certificate, $SK8L_CLIENT_KEY")
+	fs.StringVar(&opts.ServerName, "server-name", "", "name to verify the certificate of the server against")
+	fs.StringVar(&opts.Token, "token", os.Getenv("SK8L_TOKEN"), "bearer token, $SK8L_TOKEN")
+	fs.StringVar(&opts.TokenFile, "token-file", os.Getenv("SK8L_TOKEN_FILE"), "file to read the bearer token from, $SK8L_TOKEN_FILE")
+	fs.BoolVar(&opts.NoRedaction, "no-redaction", false, "ask for unredacted responses, only privileged callers get them")
+	fs.StringVar(&opts.Namespace, "n", "", "namespace, the one sk8l runs in when empty")
+	fs.StringVar(&opts.Output, "o", outputTable, "output format: table, json or yaml")
+	fs.BoolVar(&opts.Follow, "f", false, "logs: keep streaming the logs of running containers")
+	fs.StringVar(&opts.Container, "c", "", "logs: container, required for pods with several containers")
+	fs.Int64Var(&opts.TailLines, "tail", 0, "logs: only print the last lines, 0 prints every line")
+	fs.Usage = func() {
+		fmt.Fprint(output, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// parseArgs parses the flags of args, wherever they are, and returns the
+// other arguments: the command and its arguments.
+func parseArgs(args []string, output io.Writer) (*Options, []string, error) {
+	opts := &Options{}
+	fs := flagSet(opts, output)
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, nil, fmt.Errorf("cli#parseArgs: %w: %w", ErrUsage, err)
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	switch opts.Output {
+	case outputTable, outputJSON, outputYAML:
+	default:
+		return nil, nil, fmt.Errorf("cli#parseArgs: %w %q", ErrInvalidOutput, opts.Output)
+	}
+	return opts, positional, nil
+}
+
+// Main runs sk8lctl with args and returns its exit code.
+func Main(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	err := Run(ctx, args, stdout, stderr)
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, ErrUsage):
+		fmt.Fprintf(stderr, "sk8lctl: %v\nRun sk8lctl -h for usage.\n", err)
+		return exitUsage
+	default:
+		fmt.Fprintf(stderr, "sk8lctl: %v\n", err)
+		return exitFailure
+	}
+}
+
+// Run parses args, connects to the server and runs the command.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	opts, command, err := parseArgs(args, stderr)
+	if err != nil {
+		return err
+	}
+	if len(command) == 0 {
+		fs := flagSet(&Options{}, stderr)
+		fs.Usage()
+		return nil
+	}
+	conn, err := Dial(opts)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return New(protos.NewCronjobClient(conn), opts, stdout).Execute(ctx, command)
+}
+
+// Dial connects to the server of opts.
+func Dial(opts *Options) (*grpc.ClientConn, error) {
+	tlsConfig, err := clientTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	dialOptions := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
+	token := opts.Token
+	if token == "" && opts.TokenFile != "" {
+		data, err := os.ReadFile(filepath.Clean(opts.TokenFile))
+		if err != nil {
+			return nil, fmt.Errorf("cli#Dial: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(auth.BearerCredentials(token)))
+	}
+	conn, err := grpc.NewClient(opts.Server, dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("cli#Dial: grpc.NewClient(%s) failed: %w", opts.Server, err)
+	}
+	return conn, nil
+}
+
+func clientTLSConfig(opts *Options) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: opts.ServerName,
+	}
+	if opts.CACert != "" {
+		ca, err := os.ReadFile(filepath.Clean(opts.CACert))
+		if err != nil {
+			return nil, fmt.Errorf("cli#clientTLSConfig: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("cli#clientTLSConfig: %w: no certificate in %s", ErrUsage, opts.CACert)
+		}
+	}
+	if opts.Cert != "" || opts.Key != "" {
+		cert, err := tls.LoadX509KeyPair(opts.Cert, opts.Key)
+		if err != nil {
+			return nil, fmt.Errorf("cli#clientTLSConfig: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// CLI runs the commands of sk8lctl with a client of the Cronjob service.
+type CLI struct {
+	client protos.CronjobClient
+	opts   *Options
+	out    io.Writer
+	now    func() time.Time
+}
+
+func New(client protos.CronjobClient, opts *Options, out io.Writer) *CLI {
+	return &CLI{client: client, opts: opts, out: out, now: time.Now}
+}
+
+// Execute runs command, the command name followed by its arguments.
+func (c *CLI) Execute(ctx context.Context, command []string) error {
+	if c.opts.NoRedaction {
+		ctx = metadata.AppendToOutgoingContext(ctx, redact.OptOutHeader, "off")
+	}
+	name, args := command[0], command[1:]
+	switch {
+	case name == "get" && len(args) >= 1 && isCronjobs(args[0]) && len(args) <= 2:
+		return c.getCronjobs(ctx, args[1:])
+	case name == "get" && len(args) == 1 && isJobs(args[0]):
+		return c.getJobs(ctx)
+	case name == "describe" && len(args) == 2 && isCronjobs(args[0]):
+		return c.describeCronjob(ctx, args[1])
+	case name == "history" && len(args) == 1:
+		return c.history(ctx, args[0])
+	case name == "logs" && len(args) == 2 && isJobs(args[0]):
+		return c.jobLogs(ctx, args[1])
+	case name == "logs" && len(args) == 2 && isPods(args[0]):
+		return c.podLogs(ctx, args[1])
+	case name == "trigger" && len(args) == 1:
+		return c.trigger(ctx, args[0])
+	case name == "suspend" && len(args) == 1:
+		return c.suspend(ctx, args[0], true)
+	case name == "resume" && len(args) == 1:
+		return c.suspend(ctx, args[0], false)
+	case name == "watch" && (len(args) == 0 || len(args) == 1 && isCronjobs(args[0])):
+		return c.watch(ctx)
+	case name == "tui" && len(args) == 0:
+		return c.tui(ctx)
+	case name == "version" && len(args) == 0:
+		return c.version(ctx)
+	default:
+		return fmt.Errorf("cli#Execute: %w: unknown command %q", ErrUsage, strings.Join(command, " "))
+	}
+}
+
+func isCronjobs(kind string) bool {
+	switch kind {
+	case "cronjob", "cronjobs", "cj":
+		return true
+	default:
+		return false
+	}
+}
+
+func isJobs(kind string) bool {
+	switch kind {
+	case "job", "jobs":
+		return true
+	default:
+		return false
+	}
+}
+
+func isPods(kind string) bool {
+	switch kind {
+	case "pod", "pods", "po":
+		return true
+	default:
+		return false
+	}
+}
+
+// namespace returns the namespace of the command, asking the server for the
+// one it runs in when it isn't set.
+func (c *CLI) namespace(ctx context.Context) (string, error) {
+	if c.opts.Namespace != "" {
+		return c.opts.Namespace, nil
+	}
+	info, err := c.client.GetServerInfo(ctx, &protos.ServerInfoRequest{})
+	if err != nil {
+		return "", fmt.Errorf("cli#namespace: %w", err)
+	}
+	if len(info.GetNamespaces()) == 0 {
+		return "", fmt.Errorf("cli#namespace: %w: the server reports no namespace, set one with -n", ErrUsage)
+	}
+	c.opts.Namespace = info.GetNamespaces()[0]
+	return c.opts.Namespace, nil
+}
@@ -0,0 +1,330 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danroux/sk8l/protos"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	gyaml "sigs.k8s.io/yaml"
+)
+
+const bufSize = 1024 * 1024
+
+var testNow = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+func testCronjob(name string) *protos.CronjobResponse {
+	return &protos.CronjobResponse{
+		Name:             name,
+		Namespace:        "batch",
+		LastScheduleTime: "2026-10-19T11:55:00Z",
+		LastDuration:     65,
+		Spec:             &protos.CronJobSpecResponse{Schedule: "*/5 * * * *", ConcurrencyPolicy: "Forbid"},
+		ContainerCommands: map[string]*protos.ContainerCommands{
+			"main": {Commands: []string{"backup.sh", "--all"}},
+		},
+		Jobs: []*protos.JobResponse{
+			{
+				Name: name + "-1", Namespace: "batch", CreationTimestamp: "2026-10-19T11:50:00Z", Succeeded: true, DurationInS: 65,
+				Status: &protos.JobStatus{StartTime: "2026-10-19T11:50:00Z"},
+				Pods:   []*protos.PodResponse{{Metadata: &protos.ObjectMetaResponse{Name: name + "-1-pod", Namespace: "batch"}}},
+			},
+			{
+				Name: name + "-2", Namespace: "batch", CreationTimestamp: "2026-10-19T11:55:00Z", Failed: true, DurationInS: 3,
+				Status:          &protos.JobStatus{StartTime: "2026-10-19T11:55:00Z"},
+				FailureCategory: protos.FailureCategory_FAILURE_CATEGORY_OOM_KILLED,
+			},
+		},
+	}
+}
+
+// testServer serves two CronJobs of the batch namespace, the second update
+// sent by GetCronjobs suspends the second one.
+type testServer struct {
+	protos.UnimplementedCronjobServer
+	suspended map[string]bool
+	md        metadata.MD
+}
+
+func (ts *testServer) GetServerInfo(ctx context.Context, _ *protos.ServerInfoRequest) (*protos.ServerInfoResponse, error) {
+	ts.md, _ = metadata.FromIncomingContext(ctx)
+	return &protos.ServerInfoResponse{Version: "v1.2.3", Namespaces: []string{"batch"}, Features: []string{"actions", "reflection"}}, nil
+}
+
+func (ts *testServer) GetCronjobs(_ *protos.CronjobsRequest, stream protos.Cronjob_GetCronjobsServer) error {
+	cronjobs := []*protos.CronjobResponse{testCronjob("backup"), testCronjob("report")}
+	if err := stream.Send(&protos.CronjobsResponse{Cronjobs: cronjobs}); err != nil {
+		return err
+	}
+	cronjobs = []*protos.CronjobResponse{testCronjob("backup"), testCronjob("report")}
+	cronjobs[1].Spec.Suspend = true
+	if err := stream.Send(&protos.CronjobsResponse{Cronjobs: cronjobs}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ts *testServer) GetCronjob(in *protos.CronjobRequest, stream protos.Cronjob_GetCronjobServer) error {
+	if in.GetCronjobNamespace() != "batch" || in.GetCronjobName() != "backup" {
+		return status.Errorf(codes.NotFound, "cronjob %s/%s not found", in.GetCronjobNamespace(), in.GetCronjobName())
+	}
+	cronjob := testCronjob("backup")
+	cronjob.Events = []*protos.EventResponse{{Type: "Normal", Reason: "SawCompletedJob", LastTimestamp: "2026-10-19T11:51:05Z", Message: "done"}}
+	if err := stream.Send(cronjob); err != nil {
+		return err
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+func (ts *testServer) GetJobs(_ *protos.JobsRequest, stream protos.Cronjob_GetJobsServer) error {
+	return stream.Send(&protos.JobsResponse{Jobs: []*protos.JobResponse{{Name: "adhoc", Namespace: "batch", Succeeded: true}}})
+}
+
+func (ts *testServer) GetPodLogs(in *protos.PodLogsRequest, stream protos.Cronjob_GetPodLogsServer) error {
+	if in.GetPodName() != "backup-1-pod" {
+		return status.Error(codes.NotFound, "pod not found")
+	}
+	for i := range 2 {
+		if err := stream.Send(&protos.PodLogsResponse{Line: fmt.Sprintf("%s line %d", in.GetPodName(), i)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ts *testServer) GetJobFailureLogs(_ context.Context, in *protos.JobRequest) (*protos.JobFailureLogsResponse, error) {
+	return &protos.JobFailureLogsResponse{Containers: []*protos.ContainerLogsResponse{
+		{PodName: in.GetJobName() + "-pod", ContainerName: "main", CapturedAt: "2026-10-19T11:55:03Z", Logs: "out of memory\n"},
+	}}, nil
+}
+
+func (ts *testServer) TriggerCronjob(_ context.Context, in *protos.CronjobRequest) (*protos.TriggerCronjobResponse, error) {
+	return &protos.TriggerCronjobResponse{JobName: in.GetCronjobName() + "-manual-abcde", JobNamespace: in.GetCronjobNamespace()}, nil
+}
+
+func (ts *testServer) SuspendCronjob(_ context.Context, in *protos.SuspendCronjobRequest) (*protos.SuspendCronjobResponse, error) {
+	ts.suspended[in.GetCronjobNamespace()+"/"+in.GetCronjobName()] = in.GetSuspend()
+	return &protos.SuspendCronjobResponse{Suspended: in.GetSuspend()}, nil
+}
+
+func newTestClient(t *testing.T) (*testServer, protos.CronjobClient) {
+	t.Helper()
+	ts := &testServer{suspended: map[string]bool{}}
+	listener := bufconn.Listen(bufSize)
+	s := grpc.NewServer()
+	protos.RegisterCronjobServer(s, ts)
+	go func() { _ = s.Serve(listener) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return ts, protos.NewCronjobClient(conn)
+}
+
+// run runs the command line args against the test server.
+func run(t *testing.T, client protos.CronjobClient, args ...string) (string, error) {
+	t.Helper()
+	opts, command, err := parseArgs(args, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("parseArgs(%v) failed: %v", args, err)
+	}
+	out := &bytes.Buffer{}
+	c := New(client, opts, out)
+	c.now = func() time.Time { return testNow }
+	err = c.Execute(context.Background(), command)
+	return out.String(), err
+}
+
+// fields returns the whitespace separated fields of each line of out.
+func fields(out string) [][]string {
+	lines := [][]string{}
+	for line := range strings.Lines(out) {
+		lines = append(lines, strings.Fields(line))
+	}
+	return lines
+}
+
+func TestGetCronjobs(t *testing.T) {
+	_, client := newTestClient(t)
+
+	out, err := run(t, client, "get", "cronjobs")
+	if err != nil {
+		t.Fatalf("get cronjobs failed: %v", err)
+	}
+	lines := fields(out)
+	if len(lines) != 3 || lines[0][0] != "NAMESPACE" {
+		t.Fatalf("expected a header and 2 rows, got %q", out)
+	}
+	if got := strings.Join(lines[1], " "); got != "batch backup */5 * * * * false 0 5m0s 1m5s <none> Scheduled" {
+		t.Errorf("unexpected row %q", got)
+	}
+
+	out, err = run(t, client, "get", "cj", "report", "-o", "json")
+	if err != nil {
+		t.Fatalf("get cronjobs -o json failed: %v", err)
+	}
+	resp := map[string][]map[string]any{}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil || len(resp["cronjobs"]) != 1 || resp["cronjobs"][0]["name"] != "report" {
+		t.Errorf("unexpected JSON output %s, %v", out, err)
+	}
+
+	out, err = run(t, client, "-o", "yaml", "get", "cronjobs", "-n", "other")
+	if err != nil || strings.TrimSpace(out) != "{}" {
+		t.Errorf("expected no cronjobs in namespace other, got %q, %v", out, err)
+	}
+
+	if _, err := run(t, client, "get", "cronjobs", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDescribeAndHistory(t *testing.T) {
+	_, client := newTestClient(t)
+
+	out, err := run(t, client, "describe", "cronjob", "backup")
+	if err != nil {
+		t.Fatalf("describe failed: %v", err)
+	}
+	for _, expected := range []string{"Namespace:", "batch", "Concurrency Policy:", "Forbid", "main:", "backup.sh --all", "SawCompletedJob", "8m55s"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in\n%s", expected, out)
+		}
+	}
+
+	out, err = run(t, client, "history", "backup")
+	if err != nil {
+		t.Fatalf("history failed: %v", err)
+	}
+	lines := fields(out)
+	if len(lines) != 3 || strings.Join(lines[1], " ") != "backup-2 Failed 5m0s <none> 3s OOM_KILLED" ||
+		strings.Join(lines[2], " ") != "backup-1 Succeeded 10m0s <none> 1m5s" {
+		t.Errorf("expected the runs most recent first, got %q", out)
+	}
+
+	out, err = run(t, client, "history", "backup", "-o", "yaml")
+	jobs := map[string][]map[string]any{}
+	if err != nil || gyaml.Unmarshal([]byte(out), &jobs) != nil || len(jobs["jobs"]) != 2 || jobs["jobs"][0]["name"] != "backup-2" {
+		t.Errorf("unexpected YAML output %s, %v", out, err)
+	}
+
+	if _, err := run(t, client, "history", "missing"); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+}
+
+func TestLogs(t *testing.T) {
+	_, client := newTestClient(t)
+
+	out, err := run(t, client, "logs", "job", "backup-1")
+	if err != nil || out != "backup-1-pod line 0\nbackup-1-pod line 1\n" {
+		t.Errorf("unexpected logs %q, %v", out, err)
+	}
+	out, err = run(t, client, "logs", "job", "backup-2")
+	if err != nil || !strings.Contains(out, "container main") || !strings.HasSuffix(out, "out of memory\n") {
+		t.Errorf("expected the captured logs of a job without pods, got %q, %v", out, err)
+	}
+	if _, err := run(t, client, "logs", "job", "adhoc", "-n", "other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := run(t, client, "logs", "pod", "gone"); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+}
+
+func TestActions(t *testing.T) {
+	ts, client := newTestClient(t)
+
+	out, err := run(t, client, "trigger", "backup")
+	if err != nil || out != "job.batch/backup-manual-abcde created\n" {
+		t.Errorf("unexpected trigger output %q, %v", out, err)
+	}
+	out, err = run(t, client, "suspend", "backup", "-n", "batch")
+	if err != nil || out != "cronjob.batch/backup suspended\n" || !ts.suspended["batch/backup"] {
+		t.Errorf("unexpected suspend output %q, %v", out, err)
+	}
+	out, err = run(t, client, "resume", "backup", "-o", "json")
+	if err != nil || strings.TrimSpace(out) != "{}" || ts.suspended["batch/backup"] {
+		t.Errorf("unexpected resume output %q, %v", out, err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	_, client := newTestClient(t)
+
+	out, err := run(t, client, "watch")
+	if err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+	lines := fields(out)
+	if len(lines) != 4 || lines[3][1] != "report" || lines[3][7] != "true" {
+		t.Errorf("expected the header, both cronjobs and the suspended one, got %q", out)
+	}
+
+	out, err = run(t, client, "watch", "-o", "json")
+	if err != nil || strings.Count(out, "\n") != 2 {
+		t.Errorf("expected one line per update, got %q, %v", out, err)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	ts, client := newTestClient(t)
+
+	out, err := run(t, client, "version", "--no-redaction")
+	if err != nil || !strings.Contains(out, "v1.2.3") || !strings.Contains(out, "actions, reflection") {
+		t.Errorf("unexpected version output %q, %v", out, err)
+	}
+	if values := ts.md.Get("sk8l-redaction"); len(values) != 1 || values[0] != "off" {
+		t.Errorf("expected --no-redaction to opt out of redaction, got %v", values)
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	opts, command, err := parseArgs([]string{"-n", "batch", "logs", "-f", "job", "backup-1", "--tail=10"}, &bytes.Buffer{})
+	if err != nil || opts.Namespace != "batch" || !opts.Follow || opts.TailLines != 10 || strings.Join(command, " ") != "logs job backup-1" {
+		t.Errorf("unexpected options %+v and command %v, %v", opts, command, err)
+	}
+	if _, _, err := parseArgs([]string{"-o", "xml", "get", "cronjobs"}, &bytes.Buffer{}); !errors.Is(err, ErrInvalidOutput) {
+		t.Errorf("expected ErrInvalidOutput, got %v", err)
+	}
+	if _, _, err := parseArgs([]string{"--unknown"}, &bytes.Buffer{}); !errors.Is(err, ErrUsage) {
+		t.Errorf("expected ErrUsage, got %v", err)
+	}
+	_, client := newTestClient(t)
+	if _, err := run(t, client, "describe", "job", "backup-1"); !errors.Is(err, ErrUsage) {
+		t.Errorf("expected ErrUsage for an unknown command, got %v", err)
+	}
+}
+
+func TestShortDuration(t *testing.T) {
+	for d, expected := range map[time.Duration]string{
+		-time.Second:                   "0s",
+		42 * time.Second:               "42s",
+		5*time.Minute + 10*time.Second: "5m10s",
+		2*time.Hour + 5*time.Minute:    "2h5m",
+		50 * time.Hour:                 "2d2h",
+	} {
+		if got := shortDuration(d); got != expected {
+			t.Errorf("shortDuration(%s) = %q, expected %q", d, got, expected)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/danroux/sk8l/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watchColumnWidth is the minimum width of the columns of watch.
+const watchColumnWidth = 18
+
+// cronjobs returns the first CronJobs sent by GetCronjobs, of the namespace
+// of the command when it's set.
+func (c *CLI) cronjobs(ctx context.Context) ([]*protos.CronjobResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.client.GetCronjobs(ctx, &protos.CronjobsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("cli#cronjobs: %w", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("cli#cronjobs: %w", err)
+	}
+	return c.inNamespace(resp.GetCronjobs()), nil
+}
+
+func (c *CLI) inNamespace(cronjobs []*protos.CronjobResponse) []*protos.CronjobResponse {
+	if c.opts.Namespace == "" {
+		return cronjobs
+	}
+	return slices.DeleteFunc(slices.Clone(cronjobs), func(cronjob *protos.CronjobResponse) bool {
+		return cronjob.GetNamespace() != c.opts.Namespace
+	})
+}
+
+// cronjob returns the CronJob name of the namespace of the command.
+func (c *CLI) cronjob(ctx context.Context, name string) (*protos.CronjobResponse, error) {
+	namespace, err := c.namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.client.GetCronjob(ctx, &protos.CronjobRequest{CronjobName: name, CronjobNamespace: namespace})
+	if err != nil {
+		return nil, fmt.Errorf("cli#cronjob: %w", err)
+	}
+	cronjob, err := stream.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("cli#cronjob: cronjob %s/%s: %w", namespace, name, err)
+	}
+	return cronjob, nil
+}
+
+func (c *CLI) getCronjobs(ctx context.Context, names []string) error {
+	cronjobs, err := c.cronjobs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		cronjobs = slices.DeleteFunc(cronjobs, func(cronjob *protos.CronjobResponse) bool {
+			return cronjob.GetName() != names[0]
+		})
+		if len(cronjobs) == 0 {
+			return fmt.Errorf("cli#getCronjobs: cronjob %s %w", names[0], ErrNotFound)
+		}
+	}
+	return c.print(&protos.CronjobsResponse{Cronjobs: cronjobs}, func(w io.Writer) {
+		c.cronjobsTable(w, cronjobs, true)
+	})
+}
+
+func (c *CLI) cronjobsTable(w io.Writer, cronjobs []*protos.CronjobResponse, header bool) {
+	if header {
+		row(w, "NAMESPACE", "NAME", "SCHEDULE", "SUSPEND", "ACTIVE", "LAST SCHEDULE", "LAST DURATION", "CURRENT DURATION", "STATUS")
+	}
+	for _, cronjob := range cronjobs {
+		row(w, c.cronjobRow(cronjob)...)
+	}
+}
+
+func (c *CLI) cronjobRow(cronjob *protos.CronjobResponse) []string {
+	return []string{
+		cronjob.GetNamespace(),
+		cronjob.GetName(),
+		cronjob.GetSpec().GetSchedule(),
+		strconv.FormatBool(cronjob.GetSpec().GetSuspend()),
+		strconv.Itoa(len(cronjob.GetRunningJobs())),
+		c.age(cronjob.GetLastScheduleTime()),
+		seconds(cronjob.GetLastDuration()),
+		seconds(cronjob.GetCurrentDuration()),
+		cronjobStatus(cronjob),
+	}
+}
+
+func (c *CLI) getJobs(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.client.GetJobs(ctx, &protos.JobsRequest{})
+	if err != nil {
+		return fmt.Errorf("cli#getJobs: %w", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return fmt.Errorf("cli#getJobs: %w", err)
+	}
+	jobs := slices.DeleteFunc(resp.GetJobs(), func(job *protos.JobResponse) bool {
+		return c.opts.Namespace != "" && job.GetNamespace() != c.opts.Namespace
+	})
+	return c.print(&protos.JobsResponse{Jobs: jobs}, func(w io.Writer) {
+		row(w, "NAMESPACE", "NAME", "STATUS", "STARTED", "DURATION", "FAILURE")
+		for _, job := range jobs {
+			row(w, job.GetNamespace(), job.GetName(), jobStatus(job), c.age(job.GetStatus().GetStartTime()),
+				seconds(job.GetDurationInS()), failureReason(job))
+		}
+	})
+}
+
+func (c *CLI) describeCronjob(ctx context.Context, name string) error {
+	cronjob, err := c.cronjob(ctx, name)
+	if err != nil {
+		return err
+	}
+	return c.print(cronjob, func(w io.Writer) {
+		spec := cronjob.GetSpec()
+		row(w, "Name:", cronjob.GetName())
+		row(w, "Namespace:", cronjob.GetNamespace())
+		row(w, "Schedule:", spec.GetSchedule())
+		row(w, "Time Zone:", orNone(spec.GetTimezone()))
+		row(w, "Concurrency Policy:", spec.GetConcurrencyPolicy())
+		row(w, "Suspend:", strconv.FormatBool(spec.GetSuspend()))
+		row(w, "Status:", cronjobStatus(cronjob))
+		row(w, "Last Schedule Time:", orNone(cronjob.GetLastScheduleTime()))
+		row(w, "Last Successful Time:", orNone(cronjob.GetLastSuccessfulTime()))
+		row(w, "Last Duration:", seconds(cronjob.GetLastDuration()))
+		row(w, "Current Duration:", seconds(cronjob.GetCurrentDuration()))
+		row(w, "Containers:")
+		commands := cronjob.GetContainerCommands()
+		for _, container := range slices.Sorted(maps.Keys(commands)) {
+			row(w, "  "+container+":", strings.Join(commands[container].GetCommands(), " "))
+		}
+		fmt.Fprintln(w)
+		row(w, "Jobs:")
+		c.jobsTable(w, cronjob.GetJobs())
+		fmt.Fprintln(w)
+		row(w, "Events:")
+		row(w, "  TYPE", "REASON", "AGE", "MESSAGE")
+		for _, event := range cronjob.GetEvents() {
+			row(w, "  "+event.GetType(), event.GetReason(), c.age(event.GetLastTimestamp()), event.GetMessage())
+		}
+	})
+}
+
+// jobsTable writes the runs of a CronJob, most recent first.
+func (c *CLI) jobsTable(w io.Writer, jobs []*protos.JobResponse) {
+	row(w, "  NAME", "STATUS", "STARTED", "DURATION", "FAILURE")
+	for _, job := range newestFirst(jobs) {
+		row(w, "  "+job.GetName(), jobStatus(job), c.age(job.GetStatus().GetStartTime()), seconds(job.GetDurationInS()), failureReason(job))
+	}
+}
+
+func newestFirst(jobs []*protos.JobResponse) []*protos.JobResponse {
+	jobs = slices.Clone(jobs)
+	slices.SortStableFunc(jobs, func(a, b *protos.JobResponse) int {
+		return cmp.Compare(b.GetCreationTimestamp(), a.GetCreationTimestamp())
+	})
+	return jobs
+}
+
+func (c *CLI) history(ctx context.Context, name string) error {
+	cronjob, err := c.cronjob(ctx, name)
+	if err != nil {
+		return err
+	}
+	jobs := newestFirst(cronjob.GetJobs())
+	return c.print(&protos.JobsResponse{Jobs: jobs}, func(w io.Writer) {
+		row(w, "NAME", "STATUS", "STARTED", "COMPLETED", "DURATION", "FAILURE")
+		for _, job := range jobs {
+			row(w, job.GetName(), jobStatus(job), c.age(job.GetStatus().GetStartTime()), c.age(job.GetStatus().GetCompletionTime()),
+				seconds(job.GetDurationInS()), failureReason(job))
+		}
+	})
+}
+
+// job returns the Job name of the namespace of the command, looked up in the
+// runs of the CronJobs first.
+func (c *CLI) job(ctx context.Context, name string) (*protos.JobResponse, error) {
+	namespace, err := c.namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cronjobs, err := c.cronjobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, cronjob := range cronjobs {
+		for _, job := range cronjob.GetJobs() {
+			if job.GetName() == name && job.GetNamespace() == namespace {
+				return job, nil
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.client.GetJobs(ctx, &protos.JobsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("cli#job: %w", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("cli#job: %w", err)
+	}
+	for _, job := range resp.GetJobs() {
+		if job.GetName() == name && job.GetNamespace() == namespace {
+			return job, nil
+		}
+	}
+	return nil, fmt.Errorf("cli#job: job %s/%s %w", namespace, name, ErrNotFound)
+}
+
+// jobLogs prints the logs of the pods of a Job, or the logs sk8l captured
+// when it failed once its pods are gone.
+func (c *CLI) jobLogs(ctx context.Context, name string) error {
+	job, err := c.job(ctx, name)
+	if err != nil {
+		return err
+	}
+	pods := job.GetPods()
+	if len(pods) == 0 {
+		return c.failureLogs(ctx, job)
+	}
+	for i, pod := range pods {
+		if len(pods) > 1 {
+			fmt.Fprintf(c.out, "==> pod %s <==\n", pod.GetMetadata().GetName())
+		}
+		// Only the last pod can still be running.
+		err := c.streamLogs(ctx, pod.GetMetadata().GetNamespace(), pod.GetMetadata().GetName(), c.opts.Follow && i == len(pods)-1)
+		switch status.Code(err) {
+		case codes.OK:
+		case codes.NotFound, codes.FailedPrecondition:
+			// The pods are gone, or the server serves a snapshot without them.
+			return c.failureLogs(ctx, job)
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *CLI) failureLogs(ctx context.Context, job *protos.JobResponse) error {
+	logs, err := c.client.GetJobFailureLogs(ctx, &protos.JobRequest{JobName: job.GetName(), JobNamespace: job.GetNamespace()})
+	if err != nil {
+		return fmt.Errorf("cli#failureLogs: %w", err)
+	}
+	if len(logs.GetContainers()) == 0 {
+		return fmt.Errorf("cli#failureLogs: logs of job %s/%s %w, its pods are gone", job.GetNamespace(), job.GetName(), ErrNotFound)
+	}
+	writeFailureLogs(c.out, logs)
+	return nil
+}
+
+func writeFailureLogs(w io.Writer, logs *protos.JobFailureLogsResponse) {
+	for _, container := range logs.GetContainers() {
+		fmt.Fprintf(w, "==> pod %s, container %s, captured at %s <==\n",
+			container.GetPodName(), container.GetContainerName(), container.GetCapturedAt())
+		if container.GetError() != "" {
+			fmt.Fprintf(w, "logs not captured: %s\n", container.GetError())
+			continue
+		}
+		fmt.Fprint(w, container.GetLogs())
+	}
+}
+
+func (c *CLI) podLogs(ctx context.Context, name string) error {
+	namespace, err := c.namespace(ctx)
+	if err != nil {
+		return err
+	}
+	return c.streamLogs(ctx, namespace, name, c.opts.Follow)
+}
+
+func (c *CLI) streamLogs(ctx context.Context, namespace, pod string, follow bool) error {
+	stream, err := c.client.GetPodLogs(ctx, &protos.PodLogsRequest{
+		PodName:      pod,
+		PodNamespace: namespace,
+		Container:    c.opts.Container,
+		Follow:       follow,
+		TailLines:    c.opts.TailLines,
+	})
+	if err != nil {
+		return fmt.Errorf("cli#streamLogs: %w", err)
+	}
+	for {
+		line, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cli#streamLogs: %w", err)
+		}
+		fmt.Fprintln(c.out, line.GetLine())
+	}
+}
+
+func (c *CLI) trigger(ctx context.Context, name string) error {
+	namespace, err := c.namespace(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.TriggerCronjob(ctx, &protos.CronjobRequest{CronjobName: name, CronjobNamespace: namespace})
+	if err != nil {
+		return fmt.Errorf("cli#trigger: %w", err)
+	}
+	return c.print(resp, func(w io.Writer) {
+		fmt.Fprintf(w, "job.batch/%s created\n", resp.GetJobName())
+	})
+}
+
+func (c *CLI) suspend(ctx context.Context, name string, suspend bool) error {
+	namespace, err := c.namespace(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.SuspendCronjob(ctx, &protos.SuspendCronjobRequest{
+		CronjobName:      name,
+		CronjobNamespace: namespace,
+		Suspend:          suspend,
+	})
+	if err != nil {
+		return fmt.Errorf("cli#suspend: %w", err)
+	}
+	return c.print(resp, func(w io.Writer) {
+		state := "resumed"
+		if resp.GetSuspended() {
+			state = "suspended"
+		}
+		fmt.Fprintf(w, "cronjob.batch/%s %s\n", name, state)
+	})
+}
+
+// watch prints the CronJobs, then each CronJob again every time it changes.
+// The json and yaml outputs print every update of the list instead.
+func (c *CLI) watch(ctx context.Context) error {
+	stream, err := c.client.GetCronjobs(ctx, &protos.CronjobsRequest{})
+	if err != nil {
+		return fmt.Errorf("cli#watch: %w", err)
+	}
+	printed := map[string]string{}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if status.Code(err) == codes.Canceled || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("cli#watch: %w", err)
+		}
+		cronjobs := c.inNamespace(resp.GetCronjobs())
+		if err := c.printUpdate(cronjobs, printed); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *CLI) printUpdate(cronjobs []*protos.CronjobResponse, printed map[string]string) error {
+	switch c.opts.Output {
+	case outputJSON:
+		data, err := marshalJSON(&protos.CronjobsResponse{Cronjobs: cronjobs})
+		if err != nil {
+			return err
+		}
+		return write(c.out, append(data, '\n'))
+	case outputYAML:
+		data, err := marshalYAML(&protos.CronjobsResponse{Cronjobs: cronjobs})
+		if err != nil {
+			return err
+		}
+		return write(c.out, append([]byte("---\n"), data...))
+	default:
+		// The rows printed later can't be aligned with the first ones, the
+		// columns get a minimum width instead.
+		w := tabwriter.NewWriter(c.out, watchColumnWidth, tableTabWidth, 1, ' ', 0)
+		if len(printed) == 0 {
+			c.cronjobsTable(w, nil, true)
+		}
+		for _, cronjob := range cronjobs {
+			line := strings.Join(c.cronjobRow(cronjob), "\t")
+			key := cronjob.GetNamespace() + "/" + cronjob.GetName()
+			if printed[key] != line {
+				printed[key] = line
+				row(w, line)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("cli#printUpdate: %w", err)
+		}
+		return nil
+	}
+}
+
+func (c *CLI) version(ctx context.Context) error {
+	info, err := c.client.GetServerInfo(ctx, &protos.ServerInfoRequest{})
+	if err != nil {
+		return fmt.Errorf("cli#version: %w", err)
+	}
+	return c.print(info, func(w io.Writer) {
+		row(w, "Client Version:", version)
+		row(w, "Server Version:", info.GetVersion())
+		row(w, "Server Commit:", info.GetCommit())
+		row(w, "Namespaces:", strings.Join(info.GetNamespaces(), ", "))
+		row(w, "Storage:", info.GetStorageBackend())
+		row(w, "Features:", strings.Join(info.GetFeatures(), ", "))
+	})
+}
@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/danroux/sk8l/protos"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	gyaml "sigs.k8s.io/yaml"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+
+	tableMinWidth = 0
+	tableTabWidth = 8
+	tablePadding  = 3
+
+	none = "<none>"
+	day  = 24 * time.Hour
+)
+
+// print writes m in the output format, table writing it as a table.
+func (c *CLI) print(m proto.Message, table func(w io.Writer)) error {
+	switch c.opts.Output {
+	case outputJSON:
+		data, err := marshalJSON(m)
+		if err != nil {
+			return err
+		}
+		buf := &bytes.Buffer{}
+		if err := json.Indent(buf, data, "", "  "); err != nil {
+			return fmt.Errorf("cli#print: %w", err)
+		}
+		buf.WriteByte('\n')
+		return write(c.out, buf.Bytes())
+	case outputYAML:
+		data, err := marshalYAML(m)
+		if err != nil {
+			return err
+		}
+		return write(c.out, data)
+	default:
+		w := newTableWriter(c.out)
+		table(w)
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("cli#print: %w", err)
+		}
+		return nil
+	}
+}
+
+// marshalJSON returns the compact JSON mapping of m, protojson output is
+// deliberately unstable.
+func marshalJSON(m proto.Message) ([]byte, error) {
+	data, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("cli#marshalJSON: %w", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := json.Compact(buf, data); err != nil {
+		return nil, fmt.Errorf("cli#marshalJSON: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func marshalYAML(m proto.Message) ([]byte, error) {
+	data, err := marshalJSON(m)
+	if err != nil {
+		return nil, err
+	}
+	data, err = gyaml.JSONToYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("cli#marshalYAML: %w", err)
+	}
+	return data, nil
+}
+
+func write(w io.Writer, data []byte) error {
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("cli#write: %w", err)
+	}
+	return nil
+}
+
+func newTableWriter(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, tableMinWidth, tableTabWidth, tablePadding, ' ', 0)
+}
+
+func row(w io.Writer, columns ...string) {
+	fmt.Fprintln(w, strings.Join(columns, "\t"))
+}
+
+// age returns how long ago the RFC 3339 timestamp was, like kubectl does.
+func (c *CLI) age(timestamp string) string {
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if timestamp == "" || err != nil {
+		return none
+	}
+	return shortDuration(c.now().Sub(t))
+}
+
+// seconds returns a duration in seconds as a short duration, none for 0.
+func seconds(s int64) string {
+	if s == 0 {
+		return none
+	}
+	return shortDuration(time.Duration(s) * time.Second)
+}
+
+// shortDuration returns d in its two most significant units, like 3m20s or 2d5h.
+func shortDuration(d time.Duration) string {
+	d = max(d.Round(time.Second), 0)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", d/time.Second)
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%ds", d/time.Minute, d%time.Minute/time.Second)
+	case d < day:
+		return fmt.Sprintf("%dh%dm", d/time.Hour, d%time.Hour/time.Minute)
+	default:
+		return fmt.Sprintf("%dd%dh", d/day, d%day/time.Hour)
+	}
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return none
+	}
+	return value
+}
+
+// cronjobStatus sums up the state of the latest runs of a CronJob.
+func cronjobStatus(cronjob *protos.CronjobResponse) string {
+	switch {
+	case cronjob.GetSpec().GetSuspend():
+		return "Suspended"
+	case cronjob.GetActive():
+		return "Running"
+	case cronjob.GetFailed():
+		return "Failed"
+	case cronjob.GetLastSuccessfulTime() != "":
+		return "Succeeded"
+	default:
+		return "Scheduled"
+	}
+}
+
+func jobStatus(job *protos.JobResponse) string {
+	switch {
+	case job.GetFailed():
+		return "Failed"
+	case job.GetSucceeded():
+		return "Succeeded"
+	default:
+		return "Running"
+	}
+}
+
+// failureReason is the root cause of a failed Job, the reason of its Failed
+// condition when sk8l couldn't categorize it.
+func failureReason(job *protos.JobResponse) string {
+	if !job.GetFailed() {
+		return ""
+	}
+	category := job.GetFailureCategory()
+	if category != protos.FailureCategory_FAILURE_CATEGORY_UNSPECIFIED && category != protos.FailureCategory_FAILURE_CATEGORY_UNKNOWN {
+		return strings.TrimPrefix(category.String(), "FAILURE_CATEGORY_")
+	}
+	return orNone(job.GetFailureCondition().GetReason())
+}
@@ -0,0 +1,715 @@
+package cli
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danroux/sk8l/internal/schedule"
+	"github.com/danroux/sk8l/protos"
+	"golang.org/x/term"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// tuiTailLines is the number of log lines shown when -tail isn't set.
+	tuiTailLines = 500
+	// tuiRetryDelay is how long the TUI waits before subscribing again to
+	// GetCronjobs after the stream failed.
+	tuiRetryDelay = 5 * time.Second
+	// tuiTick redraws the screen, for the ages and durations to move on.
+	tuiTick = time.Second
+	// tuiMinHeight fits the title, a header, a row and the status line.
+	tuiMinHeight   = 4
+	readBufferSize = 64
+)
+
+// The escape sequences of the TUI, it draws every frame from the top left
+// corner over the previous one.
+const (
+	enterScreen  = "\x1b[?1049h\x1b[?25l"
+	leaveScreen  = "\x1b[?25h\x1b[?1049l"
+	cursorHome   = "\x1b[H"
+	clearLine    = "\x1b[K"
+	clearBelow   = "\x1b[J"
+	reverseVideo = "\x1b[7m"
+	resetStyle   = "\x1b[0m"
+)
+
+// key is an action of the TUI, bound to one or more keys.
+type key int
+
+const (
+	keyNone key = iota
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyOpen
+	keyBack
+	keyYAML
+	keyLogs
+	keyQuit
+)
+
+var (
+	escapeSequences = map[string]key{
+		"\x1b[A":  keyUp,
+		"\x1b[B":  keyDown,
+		"\x1b[C":  keyOpen,
+		"\x1b[D":  keyBack,
+		"\x1b[5~": keyPageUp,
+		"\x1b[6~": keyPageDown,
+	}
+	byteKeys = map[byte]key{
+		'k':  keyUp,
+		'j':  keyDown,
+		0x02: keyPageUp,   // Ctrl-B
+		0x06: keyPageDown, // Ctrl-F
+		' ':  keyPageDown,
+		'\r': keyOpen,
+		'\n': keyOpen,
+		'h':  keyBack,
+		0x7f: keyBack, // Backspace
+		0x08: keyBack,
+		'y':  keyYAML,
+		'l':  keyLogs,
+		'q':  keyQuit,
+		0x03: keyQuit, // Ctrl-C, raw terminals don't turn it into a signal
+	}
+)
+
+// parseKeys returns the keys pressed in data, read from a raw terminal. A lone
+// escape goes back, unknown escape sequences are dropped.
+func parseKeys(data []byte) []key {
+	keys := []key{}
+	for len(data) > 0 {
+		if data[0] != 0x1b {
+			if k, ok := byteKeys[data[0]]; ok {
+				keys = append(keys, k)
+			}
+			data = data[1:]
+			continue
+		}
+		if len(data) == 1 {
+			return append(keys, keyBack)
+		}
+		matched := false
+		for sequence, k := range escapeSequences {
+			if rest, ok := bytes.CutPrefix(data, []byte(sequence)); ok {
+				keys = append(keys, k)
+				data = rest
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return keys
+		}
+	}
+	return keys
+}
+
+type view int
+
+const (
+	viewCronjobs view = iota
+	viewJobs
+	viewPods
+	viewText
+)
+
+// page is a screen of the TUI. The pages opened by drilling down are stacked
+// on the CronJobs table, going back pops them.
+type page struct {
+	view  view
+	title string
+	// namespace, cronjob and job are the objects the page shows.
+	namespace string
+	cronjob   string
+	job       string
+	pods      []*protos.PodResponse
+	lines     []string
+	// selected is the selected row of tables and the first line shown of texts.
+	selected int
+	// cancel stops the stream feeding the page, if any.
+	cancel context.CancelFunc
+}
+
+// TUI is the terminal dashboard of sk8lctl: a live table of the CronJobs to
+// drill down from into their Jobs, Pods, logs and YAML.
+type TUI struct {
+	*CLI
+	in   io.Reader
+	size func() (width, height int, err error)
+	// updates are applied by the loop of Run, the streams feeding the TUI
+	// never touch its state themselves.
+	updates   chan func()
+	cronjobs  []*protos.CronjobResponse
+	updated   time.Time
+	streamErr error
+	message   string
+	pages     []*page
+	width     int
+	height    int
+}
+
+// NewTUI returns a TUI reading its keys from in, a terminal in raw mode, and
+// drawing on the output of c. size returns the size of the terminal.
+func NewTUI(c *CLI, in io.Reader, size func() (width, height int, err error)) *TUI {
+	return &TUI{
+		CLI:     c,
+		in:      in,
+		size:    size,
+		updates: make(chan func()),
+		pages:   []*page{{view: viewCronjobs, title: "cronjobs"}},
+	}
+}
+
+// tui runs the TUI in the terminal of stdin.
+func (c *CLI) tui(ctx context.Context) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("cli#tui: %w", ErrNoTerminal)
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("cli#tui: %w", err)
+	}
+	defer func() { _ = term.Restore(fd, state) }()
+	return NewTUI(c, os.Stdin, func() (int, int, error) { return term.GetSize(fd) }).Run(ctx)
+}
+
+// Run draws the TUI until q is pressed, its input ends or ctx is done.
+func (t *TUI) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := write(t.out, []byte(enterScreen)); err != nil {
+		return err
+	}
+	defer func() { _ = write(t.out, []byte(leaveScreen)) }()
+
+	go t.subscribeCronjobs(ctx)
+	keys := make(chan key)
+	go t.readKeys(ctx, keys)
+	ticker := time.NewTicker(tuiTick)
+	defer ticker.Stop()
+	for {
+		if err := t.render(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case update := <-t.updates:
+			update()
+			t.drain()
+		case k, ok := <-keys:
+			if !ok || k == keyQuit {
+				return nil
+			}
+			t.handle(ctx, k)
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain applies the pending updates, followed logs send one per line.
+func (t *TUI) drain() {
+	for {
+		select {
+		case update := <-t.updates:
+			update()
+		default:
+			return
+		}
+	}
+}
+
+// send hands update over to the loop of Run.
+func (t *TUI) send(ctx context.Context, update func()) {
+	select {
+	case t.updates <- update:
+	case <-ctx.Done():
+	}
+}
+
+func (t *TUI) readKeys(ctx context.Context, keys chan<- key) {
+	defer close(keys)
+	buf := make([]byte, readBufferSize)
+	for {
+		n, err := t.in.Read(buf)
+		for _, k := range parseKeys(buf[:n]) {
+			select {
+			case keys <- k:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// subscribeCronjobs keeps the CronJobs up to date with GetCronjobs, and
+// subscribes again when the stream fails.
+func (t *TUI) subscribeCronjobs(ctx context.Context) {
+	for {
+		err := t.receiveCronjobs(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		t.send(ctx, func() { t.streamErr = err })
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(tuiRetryDelay):
+		}
+	}
+}
+
+func (t *TUI) receiveCronjobs(ctx context.Context) error {
+	stream, err := t.client.GetCronjobs(ctx, &protos.CronjobsRequest{})
+	if err != nil {
+		return fmt.Errorf("cli#receiveCronjobs: %w", err)
+	}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("cli#receiveCronjobs: %w", err)
+		}
+		cronjobs := t.inNamespace(resp.GetCronjobs())
+		t.send(ctx, func() {
+			t.cronjobs = cronjobs
+			t.updated = t.now()
+			t.streamErr = nil
+		})
+	}
+}
+
+// receivePods keeps the pods of the Job of p up to date with GetCronjobPods.
+func (t *TUI) receivePods(ctx context.Context, p *page) {
+	stream, err := t.client.GetCronjobPods(ctx, &protos.CronjobPodsRequest{CronjobName: p.cronjob, CronjobNamespace: p.namespace})
+	for err == nil {
+		var resp *protos.CronjobPodsResponse
+		resp, err = stream.Recv()
+		if err != nil {
+			break
+		}
+		pods := slices.DeleteFunc(resp.GetPods(), func(pod *protos.PodResponse) bool {
+			return !ownedBy(pod, p.job)
+		})
+		t.send(ctx, func() { p.pods = pods })
+	}
+	if ctx.Err() == nil {
+		t.send(ctx, func() { t.message = fmt.Sprintf("pods of job %s: %v", p.job, err) })
+	}
+}
+
+func ownedBy(pod *protos.PodResponse, job string) bool {
+	for _, owner := range pod.GetMetadata().GetOwnerReferences() {
+		if owner.GetKind() == "Job" && owner.GetName() == job {
+			return true
+		}
+	}
+	return false
+}
+
+// receiveLogs follows the logs of pod on p.
+func (t *TUI) receiveLogs(ctx context.Context, p *page, pod *protos.PodResponse) {
+	tailLines := cmp.Or(t.opts.TailLines, tuiTailLines)
+	container := t.opts.Container
+	if containers := pod.GetSpec().GetContainers(); container == "" && len(containers) > 1 {
+		container = containers[0].GetName()
+	}
+	stream, err := t.client.GetPodLogs(ctx, &protos.PodLogsRequest{
+		PodName:      pod.GetMetadata().GetName(),
+		PodNamespace: pod.GetMetadata().GetNamespace(),
+		Container:    container,
+		Follow:       true,
+		TailLines:    tailLines,
+	})
+	for err == nil {
+		var line *protos.PodLogsResponse
+		line, err = stream.Recv()
+		if err != nil {
+			break
+		}
+		t.send(ctx, func() { t.appendLine(p, line.GetLine()) })
+	}
+	if ctx.Err() == nil && !errors.Is(err, io.EOF) && status.Code(err) != codes.Canceled {
+		t.send(ctx, func() { t.message = fmt.Sprintf("logs of pod %s: %v", pod.GetMetadata().GetName(), err) })
+	}
+}
+
+// appendLine appends line to the text of p, scrolling along when its end
+// was shown.
+func (t *TUI) appendLine(p *page, line string) {
+	atEnd := p.selected >= len(p.lines)-t.visibleRows(p)
+	p.lines = append(p.lines, line)
+	if atEnd {
+		p.selected = max(len(p.lines)-t.visibleRows(p), 0)
+	}
+}
+
+func (t *TUI) page() *page {
+	return t.pages[len(t.pages)-1]
+}
+
+func (t *TUI) push(p *page) {
+	t.pages = append(t.pages, p)
+}
+
+func (t *TUI) back() {
+	if len(t.pages) == 1 {
+		return
+	}
+	if p := t.page(); p.cancel != nil {
+		p.cancel()
+	}
+	t.pages = t.pages[:len(t.pages)-1]
+}
+
+func (t *TUI) handle(ctx context.Context, k key) {
+	t.message = ""
+	p := t.page()
+	switch k {
+	case keyUp:
+		p.selected--
+	case keyDown:
+		p.selected++
+	case keyPageUp:
+		p.selected -= t.visibleRows(p)
+	case keyPageDown:
+		p.selected += t.visibleRows(p)
+	case keyBack:
+		t.back()
+	case keyOpen:
+		t.open(ctx, p)
+	case keyYAML:
+		t.yaml(ctx, p)
+	case keyLogs:
+		t.logs(ctx, p)
+	case keyNone, keyQuit:
+	}
+	t.clamp(t.page())
+}
+
+// clamp keeps the selection of p within its rows or lines.
+func (t *TUI) clamp(p *page) {
+	_, rows := t.table(p)
+	limit := len(rows) - 1
+	if p.view == viewText {
+		limit = len(p.lines) - t.visibleRows(p)
+	}
+	p.selected = max(min(p.selected, limit), 0)
+}
+
+// open drills down into the selected object.
+func (t *TUI) open(ctx context.Context, p *page) {
+	switch p.view {
+	case viewCronjobs:
+		if cronjob := t.selectedCronjob(p); cronjob != nil {
+			t.push(&page{
+				view:      viewJobs,
+				title:     "cronjob " + cronjob.GetName(),
+				namespace: cronjob.GetNamespace(),
+				cronjob:   cronjob.GetName(),
+			})
+		}
+	case viewJobs:
+		if job := t.selectedJob(p); job != nil {
+			pods := &page{
+				view:      viewPods,
+				title:     "job " + job.GetName(),
+				namespace: p.namespace,
+				cronjob:   p.cronjob,
+				job:       job.GetName(),
+			}
+			var podsCtx context.Context
+			podsCtx, pods.cancel = context.WithCancel(ctx)
+			t.push(pods)
+			go t.receivePods(podsCtx, pods)
+		}
+	case viewPods:
+		t.logs(ctx, p)
+	case viewText:
+	}
+}
+
+// yaml shows the YAML of the selected object.
+func (t *TUI) yaml(ctx context.Context, p *page) {
+	var name, yaml string
+	var err error
+	switch p.view {
+	case viewCronjobs:
+		cronjob := t.selectedCronjob(p)
+		if cronjob == nil {
+			return
+		}
+		var resp *protos.CronjobYAMLResponse
+		name = "cronjob " + cronjob.GetName()
+		resp, err = t.client.GetCronjobYAML(ctx, &protos.CronjobRequest{CronjobName: cronjob.GetName(), CronjobNamespace: cronjob.GetNamespace()})
+		yaml = resp.GetCronjob()
+	case viewJobs:
+		job := t.selectedJob(p)
+		if job == nil {
+			return
+		}
+		var resp *protos.JobYAMLResponse
+		name = "job " + job.GetName()
+		resp, err = t.client.GetJobYAML(ctx, &protos.JobRequest{JobName: job.GetName(), JobNamespace: job.GetNamespace()})
+		yaml = resp.GetJob()
+	case viewPods:
+		pod := t.selectedPod(p)
+		if pod == nil {
+			return
+		}
+		var resp *protos.PodYAMLResponse
+		name = "pod " + pod.GetMetadata().GetName()
+		resp, err = t.client.GetPodYAML(ctx, &protos.PodRequest{PodName: pod.GetMetadata().GetName(), PodNamespace: pod.GetMetadata().GetNamespace()})
+		yaml = resp.GetPod()
+	case viewText:
+		return
+	}
+	if err != nil {
+		t.message = fmt.Sprintf("yaml of %s: %v", name, err)
+		return
+	}
+	t.push(&page{view: viewText, title: name + " yaml", lines: strings.Split(strings.TrimSuffix(yaml, "\n"), "\n")})
+}
+
+// logs follows the logs of the selected pod, or shows the logs sk8l captured
+// when the selected Job failed.
+func (t *TUI) logs(ctx context.Context, p *page) {
+	switch p.view {
+	case viewJobs:
+		job := t.selectedJob(p)
+		if job == nil {
+			return
+		}
+		logs, err := t.client.GetJobFailureLogs(ctx, &protos.JobRequest{JobName: job.GetName(), JobNamespace: job.GetNamespace()})
+		if err != nil {
+			t.message = fmt.Sprintf("logs of job %s: %v", job.GetName(), err)
+			return
+		}
+		if len(logs.GetContainers()) == 0 {
+			t.message = fmt.Sprintf("no logs captured for job %s, open it to follow the logs of its pods", job.GetName())
+			return
+		}
+		buf := &bytes.Buffer{}
+		writeFailureLogs(buf, logs)
+		t.push(&page{view: viewText, title: "job " + job.GetName() + " logs", lines: strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")})
+	case viewPods:
+		pod := t.selectedPod(p)
+		if pod == nil {
+			return
+		}
+		logs := &page{view: viewText, title: "pod " + pod.GetMetadata().GetName() + " logs"}
+		var logsCtx context.Context
+		logsCtx, logs.cancel = context.WithCancel(ctx)
+		t.push(logs)
+		go t.receiveLogs(logsCtx, logs, pod)
+	case viewCronjobs, viewText:
+	}
+}
+
+func (t *TUI) cronjob(namespace, name string) *protos.CronjobResponse {
+	for _, cronjob := range t.cronjobs {
+		if cronjob.GetNamespace() == namespace && cronjob.GetName() == name {
+			return cronjob
+		}
+	}
+	return nil
+}
+
+func (t *TUI) selectedCronjob(p *page) *protos.CronjobResponse {
+	if p.selected < len(t.cronjobs) {
+		return t.cronjobs[p.selected]
+	}
+	return nil
+}
+
+// jobs returns the runs of the CronJob of p, most recent first.
+func (t *TUI) jobs(p *page) []*protos.JobResponse {
+	return newestFirst(t.cronjob(p.namespace, p.cronjob).GetJobs())
+}
+
+func (t *TUI) selectedJob(p *page) *protos.JobResponse {
+	if jobs := t.jobs(p); p.selected < len(jobs) {
+		return jobs[p.selected]
+	}
+	return nil
+}
+
+func (t *TUI) selectedPod(p *page) *protos.PodResponse {
+	if p.selected < len(p.pods) {
+		return p.pods[p.selected]
+	}
+	return nil
+}
+
+// table returns the header and the rows of the table of p, the lines of texts.
+func (t *TUI) table(p *page) (string, []string) {
+	buf := &bytes.Buffer{}
+	w := newTableWriter(buf)
+	switch p.view {
+	case viewCronjobs:
+		row(w, "NAMESPACE", "NAME", "SCHEDULE", "STATUS", "LAST DURATION", "CURRENT DURATION", "NEXT RUN", "FAILURE")
+		for _, cronjob := range t.cronjobs {
+			failure := none
+			if jobs := newestFirst(cronjob.GetJobs()); len(jobs) > 0 && jobs[0].GetFailed() {
+				failure = failureReason(jobs[0])
+			}
+			row(w, cronjob.GetNamespace(), cronjob.GetName(), cronjob.GetSpec().GetSchedule(), cronjobStatus(cronjob),
+				seconds(cronjob.GetLastDuration()), seconds(cronjob.GetCurrentDuration()), t.nextRun(cronjob), failure)
+		}
+	case viewJobs:
+		row(w, "NAME", "STATUS", "STARTED", "DURATION", "PODS", "FAILURE")
+		for _, job := range t.jobs(p) {
+			row(w, job.GetName(), jobStatus(job), t.age(job.GetStatus().GetStartTime()), seconds(job.GetDurationInS()),
+				strconv.Itoa(len(job.GetPods())), orNone(failureReason(job)))
+		}
+	case viewPods:
+		row(w, "NAME", "PHASE", "STARTED", "FINISHED", "REASON")
+		for _, pod := range p.pods {
+			reason := none
+			if reasons := pod.GetTerminationReasons(); len(reasons) > 0 {
+				reason = orNone(reasons[0].GetTerminationDetails().GetReason())
+			}
+			row(w, pod.GetMetadata().GetName(), pod.GetStatus().GetPhase(), t.age(pod.GetStatus().GetStartTime()),
+				t.age(pod.GetFinishedAt()), reason)
+		}
+	case viewText:
+		return "", p.lines
+	}
+	_ = w.Flush()
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	return lines[0], lines[1:]
+}
+
+// nextRun returns when cronjob runs next, computed from its schedule when the
+// server didn't send it or it has passed since.
+func (t *TUI) nextRun(cronjob *protos.CronjobResponse) string {
+	spec := cronjob.GetSpec()
+	if spec.GetSuspend() {
+		return none
+	}
+	now := t.now()
+	next, err := time.Parse(time.RFC3339, cronjob.GetNextScheduleTime())
+	if err != nil || !next.After(now) {
+		s, err := schedule.Parse(spec.GetSchedule(), spec.GetTimezone())
+		if err != nil {
+			return none
+		}
+		next = s.Next(now)
+	}
+	if next.IsZero() {
+		return none
+	}
+	return "in " + shortDuration(next.Sub(now))
+}
+
+// visibleRows returns how many rows or lines of p fit on the screen.
+func (t *TUI) visibleRows(p *page) int {
+	// The title and status lines, and the header of tables.
+	chrome := 2
+	if p.view != viewText {
+		chrome++
+	}
+	return max(t.height-chrome, 1)
+}
+
+// frame returns the lines of the screen, fitted to its size, and the index of
+// the selected one, -1 without.
+func (t *TUI) frame() ([]string, int) {
+	p := t.page()
+	header, rows := t.table(p)
+	visible := t.visibleRows(p)
+	lines := []string{t.titleLine()}
+	selected := -1
+	if p.view == viewText {
+		lines = append(lines, rows[p.selected:min(p.selected+visible, len(rows))]...)
+	} else {
+		// The selected row is kept on the screen, scrolling down follows it.
+		offset := max(p.selected-visible+1, 0)
+		lines = append(lines, header)
+		if len(rows) > 0 {
+			selected = len(lines) + p.selected - offset
+		}
+		lines = append(lines, rows[offset:min(offset+visible, len(rows))]...)
+	}
+	for len(lines) < t.height-1 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, t.statusLine(p))
+	for i, line := range lines {
+		if runes := []rune(line); len(runes) > t.width {
+			lines[i] = string(runes[:t.width])
+		}
+	}
+	return lines, selected
+}
+
+func (t *TUI) titleLine() string {
+	titles := make([]string, 0, len(t.pages))
+	for _, p := range t.pages {
+		titles = append(titles, p.title)
+	}
+	updated := "connecting"
+	if !t.updated.IsZero() {
+		updated = "updated " + shortDuration(t.now().Sub(t.updated)) + " ago"
+	}
+	return fmt.Sprintf("sk8l %s | %s | %s", t.opts.Server, strings.Join(titles, " > "), updated)
+}
+
+func (t *TUI) statusLine(p *page) string {
+	switch {
+	case t.message != "":
+		return t.message
+	case t.streamErr != nil:
+		return t.streamErr.Error()
+	}
+	switch p.view {
+	case viewCronjobs:
+		return "j/k select  enter jobs  y yaml  q quit"
+	case viewJobs:
+		return "j/k select  enter pods  l captured logs  y yaml  esc back  q quit"
+	case viewPods:
+		return "j/k select  enter logs  y yaml  esc back  q quit"
+	default:
+		return "j/k scroll  space/ctrl-b page  esc back  q quit"
+	}
+}
+
+// render draws the frame over the previous one.
+func (t *TUI) render() error {
+	if width, height, err := t.size(); err == nil {
+		t.width, t.height = width, max(height, tuiMinHeight)
+	}
+	t.clamp(t.page())
+	lines, selected := t.frame()
+	buf := &bytes.Buffer{}
+	buf.WriteString(cursorHome)
+	for i, line := range lines {
+		if i > 0 {
+			buf.WriteString("\r\n")
+		}
+		if i == selected {
+			line = reverseVideo + line + resetStyle
+		}
+		buf.WriteString(line + clearLine)
+	}
+	buf.WriteString(clearBelow)
+	return write(t.out, buf.Bytes())
+}
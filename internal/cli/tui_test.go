@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseKeys(t *testing.T) {
+	keys := parseKeys([]byte("jk\x1b[A\x1b[6~ \ryl\x7fq"))
+	expected := []key{keyDown, keyUp, keyUp, keyPageDown, keyPageDown, keyOpen, keyYAML, keyLogs, keyBack, keyQuit}
+	if !slices.Equal(keys, expected) {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+	if keys := parseKeys([]byte("\x1b")); !slices.Equal(keys, []key{keyBack}) {
+		t.Errorf("expected a lone escape to go back, got %v", keys)
+	}
+	if keys := parseKeys([]byte("j\x1b[1;5Ak")); !slices.Equal(keys, []key{keyDown}) {
+		t.Errorf("expected unknown escape sequences to be dropped, got %v", keys)
+	}
+}
+
+func newTestTUI(t *testing.T, height int) *TUI {
+	t.Helper()
+	_, client := newTestClient(t)
+	c := New(client, &Options{Server: "bufnet"}, io.Discard)
+	c.now = func() time.Time { return testNow }
+	tui := NewTUI(c, strings.NewReader(""), func() (int, int, error) { return 120, height, nil })
+	tui.width, tui.height = 120, height
+	return tui
+}
+
+func TestTUI_Frame(t *testing.T) {
+	tui := newTestTUI(t, 8)
+	backup, report := testCronjob("backup"), testCronjob("report")
+	report.Jobs = report.Jobs[:1]
+	report.NextScheduleTime = "2026-10-19T13:00:00Z"
+	tui.cronjobs = append(tui.cronjobs, backup, report)
+	tui.updated = testNow.Add(-3 * time.Second)
+
+	lines, selected := tui.frame()
+	if len(lines) != 8 || selected != 2 {
+		t.Fatalf("expected 8 lines with the first row selected, got %d, %d:\n%s", len(lines), selected, strings.Join(lines, "\n"))
+	}
+	if lines[0] != "sk8l bufnet | cronjobs | updated 3s ago" {
+		t.Errorf("unexpected title %q", lines[0])
+	}
+	for i, expected := range []string{
+		"NAMESPACE NAME SCHEDULE STATUS LAST DURATION CURRENT DURATION NEXT RUN FAILURE",
+		"batch backup */5 * * * * Scheduled 1m5s <none> in 5m0s OOM_KILLED",
+		"batch report */5 * * * * Scheduled 1m5s <none> in 1h0m <none>",
+	} {
+		if got := strings.Join(strings.Fields(lines[i+1]), " "); got != expected {
+			t.Errorf("expected line %d to be %q, got %q", i+1, expected, got)
+		}
+	}
+	if !strings.HasPrefix(lines[7], "j/k select") {
+		t.Errorf("expected the keys of the table in the status line, got %q", lines[7])
+	}
+
+	ctx := context.Background()
+	tui.handle(ctx, keyDown)
+	tui.handle(ctx, keyDown)
+	tui.handle(ctx, keyOpen)
+	lines, selected = tui.frame()
+	if lines[0] != "sk8l bufnet | cronjobs > cronjob report | updated 3s ago" || selected != 2 || !strings.HasPrefix(lines[2], "report-1") {
+		t.Errorf("expected the jobs of the last cronjob, got %d:\n%s", selected, strings.Join(lines, "\n"))
+	}
+	tui.handle(ctx, keyBack)
+	tui.handle(ctx, keyBack)
+	if len(tui.pages) != 1 || tui.page().selected != 1 {
+		t.Errorf("expected going back to stop at the cronjobs with report selected, got %d pages", len(tui.pages))
+	}
+}
+
+func TestTUI_Logs(t *testing.T) {
+	tui := newTestTUI(t, 6)
+	tui.cronjobs = append(tui.cronjobs, testCronjob("backup"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tui.handle(ctx, keyOpen)
+	tui.handle(ctx, keyLogs)
+	lines, selected := tui.frame()
+	if selected != -1 || lines[0] != "sk8l bufnet | cronjobs > cronjob backup > job backup-2 logs | connecting" ||
+		!strings.Contains(lines[1], "container main") || lines[2] != "out of memory" {
+		t.Errorf("expected the captured logs of the failed job, got:\n%s", strings.Join(lines, "\n"))
+	}
+
+	p := tui.page()
+	for i := range 10 {
+		tui.appendLine(p, fmt.Sprintf("line %d", i))
+	}
+	if lines, _ := tui.frame(); lines[4] != "line 9" {
+		t.Errorf("expected the text to follow its end, got:\n%s", strings.Join(lines, "\n"))
+	}
+	tui.handle(ctx, keyPageUp)
+	tui.handle(ctx, keyUp)
+	if lines, _ := tui.frame(); lines[1] != "line 1" {
+		t.Errorf("expected the text to scroll up, got:\n%s", strings.Join(lines, "\n"))
+	}
+	tui.handle(ctx, keyBack)
+	tui.handle(ctx, keyDown)
+	tui.handle(ctx, keyOpen)
+	if tui.message != "" || tui.page().view != viewPods {
+		t.Fatalf("expected the pods of backup-1, got %q", tui.message)
+	}
+}
+
+// syncBuffer is written by the loop of Run while the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestTUI_Run(t *testing.T) {
+	_, client := newTestClient(t)
+	keys, pressed := io.Pipe()
+	out := &syncBuffer{}
+	c := New(client, &Options{Server: "bufnet"}, out)
+	c.now = func() time.Time { return testNow }
+	tui := NewTUI(c, keys, func() (int, int, error) { return 120, 10, nil })
+	done := make(chan error, 1)
+	go func() { done <- tui.Run(context.Background()) }()
+
+	// The second update of the test server suspends report.
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "Suspended") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected report to be suspended, got %q", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := pressed.Write([]byte("q")); err != nil {
+		t.Fatalf("failed to press q: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Run failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), enterScreen) || !strings.HasSuffix(out.String(), leaveScreen) {
+		t.Errorf("expected the TUI to draw on the alternate screen")
+	}
+}
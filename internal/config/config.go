@@ -0,0 +1,441 @@
+// Package config holds the settings of the sk8l server. Every setting has a
+// default, overridden in turn by the YAML file at -config or SK8L_CONFIG, by
+// its environment variable and by its command-line flag.
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/danroux/sk8l/internal/audit"
+	"github.com/danroux/sk8l/internal/k8s"
+	"github.com/danroux/sk8l/internal/leader"
+	"github.com/danroux/sk8l/internal/logger"
+	"github.com/danroux/sk8l/internal/store"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	gyaml "sigs.k8s.io/yaml"
+)
+
+// Client certificate modes of TLS.ClientAuth.
+const (
+	ClientAuthNone          = "none"
+	ClientAuthVerifyIfGiven = "verify-if-given"
+	ClientAuthRequire       = "require"
+)
+
+// Transports of a Listener. mtls requires client certificates verified
+// against the CA.
+const (
+	TransportPlaintext = "plaintext"
+	TransportTLS       = "tls"
+	TransportMTLS      = "mtls"
+)
+
+const (
+	maxPort  = 65535
+	megabyte = 1 << 20
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Config is the whole configuration. Each setting is read from the YAML key
+// of its json tag, from the environment variable of its env tag and from the
+// flag named after its path, like -storage.event-ttl.
+type Config struct {
+	// Namespace is the namespace whose CronJobs are watched, all of them when empty.
+	Namespace string   `json:"namespace" env:"K8_NAMESPACE" usage:"namespace to watch, all namespaces when empty"`
+	Server    Server   `json:"server"`
+	TLS       TLS      `json:"tls"`
+	Storage   Storage  `json:"storage"`
+	Watchers  Watchers `json:"watchers"`
+	Metrics   Metrics  `json:"metrics"`
+	// LeaderElection elects the replica that runs the singleton duties.
+	LeaderElection LeaderElection `json:"leaderElection"`
+	Auth           Auth           `json:"auth"`
+	Redaction      Redaction      `json:"redaction"`
+	GRPCWeb        GRPCWeb        `json:"grpcWeb"`
+	Audit          Audit          `json:"audit"`
+	Log            Log            `json:"log"`
+}
+
+// Server configures the listeners and the streams they serve.
+type Server struct {
+	APIPort     int `json:"apiPort" env:"SK8L_SERVICE_PORT_SK8L_API" usage:"port of the gRPC API"`
+	HealthPort  int `json:"healthPort" env:"SK8L_SERVICE_PORT_SK8L_API_HEALTH" usage:"port of the gRPC health probes"`
+	MetricsPort int `json:"metricsPort" env:"SK8L_SERVICE_PORT_SK8L_API_METRICS" usage:"port of /metrics"`
+	// GatewayPort serves the HTTP/JSON gateway, disabled when 0.
+	GatewayPort int `json:"gatewayPort" env:"SK8L_SERVICE_PORT_SK8L_API_GATEWAY" usage:"port of the HTTP/JSON gateway, 0 disables it"`
+	// DebugPort serves pprof, expvar, /debug/state and /debug/config, disabled when 0.
+	DebugPort    int             `json:"debugPort" env:"SK8L_SERVICE_PORT_SK8L_API_DEBUG" usage:"port of the debug endpoints, 0 disables it"`
+	ReadTimeout  metav1.Duration `json:"readTimeout" env:"SK8L_READ_TIMEOUT" usage:"read timeout of the HTTP servers"`
+	WriteTimeout metav1.Duration `json:"writeTimeout" env:"SK8L_WRITE_TIMEOUT" usage:"write timeout of the HTTP servers"`
+	IdleTimeout  metav1.Duration `json:"idleTimeout" env:"SK8L_IDLE_TIMEOUT" usage:"idle timeout of the HTTP servers"`
+	// ShutdownTimeout is given to the leader to release its Lease, then to the servers to stop.
+	ShutdownTimeout metav1.Duration `json:"shutdownTimeout" env:"SK8L_SHUTDOWN_TIMEOUT" usage:"time given to the servers to stop"`
+	// RefreshInterval is how often the streaming RPCs send an update.
+	RefreshInterval metav1.Duration `json:"refreshInterval" env:"SK8L_REFRESH_INTERVAL" usage:"interval between the updates of streams"`
+	// Actions needs an authentication method and a policy.
+	Actions bool `json:"actions" env:"SK8L_ACTIONS" usage:"let callers trigger and suspend CronJobs"`
+}
+
+// TLS configures the certificates of every listener and the transport of each.
+type TLS struct {
+	CertFile   string `json:"certFile" env:"SK8L_TLS_CERT_FILE" usage:"server certificate"`
+	KeyFile    string `json:"keyFile" env:"SK8L_TLS_KEY_FILE" usage:"key of the server certificate"`
+	CAFile     string `json:"caFile" env:"SK8L_TLS_CA_FILE" usage:"CA certificate of the server and client certificates"`
+	ClientAuth string `json:"clientAuth" env:"SK8L_TLS_CLIENT_AUTH" usage:"client certificates: none, verify-if-given or require"`
+	// ReloadInterval is how often the certificate files are checked for changes.
+	ReloadInterval metav1.Duration `json:"reloadInterval" env:"SK8L_TLS_RELOAD_INTERVAL" usage:"interval between checks of the certificate files"`
+	ExpiryWarning  metav1.Duration `json:"expiryWarning" env:"SK8L_TLS_EXPIRY_WARNING" usage:"warn about certificates expiring within"`
+	// API is the listener of the API, the gateway shares its transport.
+	API     Listener `json:"api" env:"SK8L_TLS_API"`
+	Health  Listener `json:"health" env:"SK8L_TLS_HEALTH"`
+	Metrics Listener `json:"metrics" env:"SK8L_TLS_METRICS"`
+	Debug   Listener `json:"debug" env:"SK8L_TLS_DEBUG"`
+}
+
+// Listener configures the transport of a listener. Its certificate files
+// default to those of the TLS section, the env tags are suffixes of the env
+// tag of the listener.
+type Listener struct {
+	Transport string `json:"transport" env:"_TRANSPORT" usage:"transport: plaintext, tls or mtls"`
+	CertFile  string `json:"certFile" env:"_CERT_FILE" usage:"server certificate, tls.certFile when empty"`
+	KeyFile   string `json:"keyFile" env:"_KEY_FILE" usage:"key of the server certificate, tls.keyFile when empty"`
+	CAFile    string `json:"caFile" env:"_CA_FILE" usage:"CA certificate of the client certificates, tls.caFile when empty"`
+}
+
+// Storage configures the cache of the watched objects.
+type Storage struct {
+	Backend    string          `json:"backend" env:"SK8L_STORAGE" usage:"storage backend: badger or memory"`
+	BadgerPath string          `json:"badgerPath" env:"SK8L_STORAGE_BADGER_PATH" usage:"directory of the Badger DB"`
+	EventTTL   metav1.Duration `json:"eventTTL" env:"SK8L_EVENTS_TTL" usage:"how long Events are kept after they were last seen"`
+	RunRecords int             `json:"runRecords" env:"SK8L_STORAGE_RUN_RECORDS" usage:"run records kept per CronJob"`
+	Revisions  int             `json:"revisions" env:"SK8L_STORAGE_REVISIONS" usage:"spec revisions kept per CronJob"`
+}
+
+// Watchers configures the list-then-watch reflectors and the health they report.
+type Watchers struct {
+	RetryDelay   metav1.Duration `json:"retryDelay" env:"SK8L_WATCHERS_RETRY_DELAY" usage:"delay before retrying a failed list or watch"`
+	WatchTimeout metav1.Duration `json:"watchTimeout" env:"SK8L_WATCHERS_WATCH_TIMEOUT" usage:"how long the API server keeps a watch open"`
+	// FailureThreshold is how long a watcher may fail before the API is reported NOT_SERVING.
+	FailureThreshold metav1.Duration `json:"failureThreshold" env:"SK8L_WATCHERS_FAILURE_THRESHOLD" usage:"failing time tolerated before NOT_SERVING"`
+	HealthInterval   metav1.Duration `json:"healthInterval" env:"SK8L_WATCHERS_HEALTH_INTERVAL" usage:"interval between health evaluations"`
+}
+
+// Metrics configures the collection of the Prometheus metrics.
+type Metrics struct {
+	Interval   metav1.Duration `json:"interval" env:"SK8L_METRICS_INTERVAL" usage:"interval between metric updates"`
+	RetryDelay metav1.Duration `json:"retryDelay" env:"SK8L_METRICS_RETRY_DELAY" usage:"delay before retrying a failed collection"`
+}
+
+// LeaderElection configures the election of the replica recording the
+// history and collecting the metrics, every replica does when it's disabled.
+type LeaderElection struct {
+	Enabled bool `json:"enabled" env:"SK8L_LEADER_ELECTION" usage:"elect a leader among the replicas"`
+	// Identity tells the replicas apart, the hostname when empty.
+	Identity      string          `json:"identity" env:"SK8L_LEADER_ELECTION_IDENTITY" usage:"identity of the replica, the hostname when empty"`
+	LeaseName     string          `json:"leaseName" env:"SK8L_LEADER_ELECTION_LEASE_NAME" usage:"name of the Lease held by the leader"`
+	LeaseDuration metav1.Duration `json:"leaseDuration" env:"SK8L_LEADER_ELECTION_LEASE_DURATION" usage:"time before an unrenewed Lease is taken over"`
+	RenewDeadline metav1.Duration `json:"renewDeadline" env:"SK8L_LEADER_ELECTION_RENEW_DEADLINE" usage:"time the leader retries renewing the Lease"`
+	RetryPeriod   metav1.Duration `json:"retryPeriod" env:"SK8L_LEADER_ELECTION_RETRY_PERIOD" usage:"interval between attempts to acquire or renew"`
+	// LeaseNamespace is the namespace of the Lease, the watched one when empty.
+	LeaseNamespace string `json:"leaseNamespace" env:"SK8L_LEADER_ELECTION_LEASE_NAMESPACE" usage:"namespace of the Lease, the watched one if empty"`
+}
+
+// Auth configures the authentication and authorization of the callers.
+type Auth struct {
+	OIDC                 OIDC     `json:"oidc"`
+	ServiceAccountTokens bool     `json:"serviceAccountTokens" env:"SK8L_AUTH_SERVICEACCOUNT_TOKENS" usage:"accept ServiceAccount tokens"`
+	PrivilegedGroups     []string `json:"privilegedGroups" env:"SK8L_AUTH_PRIVILEGED_GROUPS" usage:"groups allowed to opt out of redaction"`
+	// Policy is the path of the authorization policy, everything is allowed without one.
+	Policy string `json:"policy" env:"SK8L_AUTH_POLICY" usage:"authorization policy file"`
+}
+
+// OIDC configures the verification of OIDC/JWT bearer tokens, disabled without an issuer.
+type OIDC struct {
+	Issuer        string `json:"issuer" env:"SK8L_AUTH_OIDC_ISSUER" usage:"OIDC issuer"`
+	Audience      string `json:"audience" env:"SK8L_AUTH_OIDC_AUDIENCE" usage:"expected audience of the tokens, required with an issuer"`
+	JWKSFile      string `json:"jwksFile" env:"SK8L_AUTH_OIDC_JWKS_FILE" usage:"file of the issuer keys"`
+	JWKSURL       string `json:"jwksURL" env:"SK8L_AUTH_OIDC_JWKS_URL" usage:"URL of the issuer keys"`
+	UsernameClaim string `json:"usernameClaim" env:"SK8L_AUTH_OIDC_USERNAME_CLAIM" usage:"claim of the username"`
+	GroupsClaim   string `json:"groupsClaim" env:"SK8L_AUTH_OIDC_GROUPS_CLAIM" usage:"claim of the groups"`
+}
+
+// Redaction configures the removal of secrets from responses.
+type Redaction struct {
+	Enabled bool `json:"enabled" env:"SK8L_REDACTION" usage:"redact secrets from responses"`
+	// Config is the path of additional redaction rules.
+	Config string `json:"config" env:"SK8L_REDACTION_CONFIG" usage:"redaction rules file"`
+}
+
+// GRPCWeb configures gRPC-Web on the API port.
+type GRPCWeb struct {
+	Enabled        bool     `json:"enabled" env:"SK8L_GRPC_WEB" usage:"serve gRPC-Web next to gRPC on the API port"`
+	AllowedOrigins []string `json:"allowedOrigins" env:"SK8L_GRPC_WEB_ALLOWED_ORIGINS" usage:"origins allowed to call the API"`
+}
+
+// Audit configures the audit log, disabled without a path.
+type Audit struct {
+	Path       string `json:"path" env:"SK8L_AUDIT_LOG" usage:"audit log file"`
+	MaxSizeMB  int    `json:"maxSizeMB" env:"SK8L_AUDIT_LOG_MAX_SIZE_MB" usage:"size in MB above which the audit log is rotated"`
+	MaxBackups int    `json:"maxBackups" env:"SK8L_AUDIT_LOG_MAX_BACKUPS" usage:"rotated audit logs kept"`
+}
+
+// Log configures the format, the levels and the sampling of the logs.
+type Log struct {
+	Format string `json:"format" env:"SK8L_LOG_FORMAT" usage:"log format: json or console"`
+	Level  string `json:"level" env:"SK8L_LOG_LEVEL" usage:"log level: trace, debug, info, warn or error"`
+	// Levels are the levels of the components, Level where empty.
+	Levels LogLevels `json:"levels" env:"SK8L_LOG_LEVEL"`
+	// SampleBurst is the number of debug and info logs each component writes
+	// every SamplePeriod, all of them when 0.
+	SampleBurst  int             `json:"sampleBurst" env:"SK8L_LOG_SAMPLE_BURST" usage:"debug and info logs per component and period, 0 keeps all"`
+	SamplePeriod metav1.Duration `json:"samplePeriod" env:"SK8L_LOG_SAMPLE_PERIOD" usage:"period of the log sampling"`
+}
+
+// LogLevels are the levels of the log components, the env tags are suffixes
+// of SK8L_LOG_LEVEL.
+type LogLevels struct {
+	Watcher string `json:"watcher" env:"_WATCHER" usage:"level of the watcher logs, log.level when empty"`
+	Store   string `json:"store" env:"_STORE" usage:"level of the store logs, log.level when empty"`
+	Metrics string `json:"metrics" env:"_METRICS" usage:"level of the metrics logs, log.level when empty"`
+	GRPC    string `json:"grpc" env:"_GRPC" usage:"level of the RPC logs, log.level when empty"`
+	Badger  string `json:"badger" env:"_BADGER" usage:"level of the Badger DB logs, log.level when empty"`
+}
+
+// Default returns the configuration sk8l runs with when nothing is set.
+func Default() *Config {
+	certsDir := filepath.Join("/etc", "sk8l-certs")
+	return &Config{
+		Server: Server{
+			APIPort:         8585,
+			HealthPort:      8588,
+			MetricsPort:     8590,
+			ReadTimeout:     metav1.Duration{Duration: 10 * time.Second},
+			WriteTimeout:    metav1.Duration{Duration: 30 * time.Second},
+			IdleTimeout:     metav1.Duration{Duration: time.Minute},
+			ShutdownTimeout: metav1.Duration{Duration: 5 * time.Second},
+			RefreshInterval: metav1.Duration{Duration: 10 * time.Second},
+		},
+		TLS: TLS{
+			CertFile:       filepath.Join(certsDir, "server-cert.pem"),
+			KeyFile:        filepath.Join(certsDir, "server-key.pem"),
+			CAFile:         filepath.Join(certsDir, "ca-cert.pem"),
+			ClientAuth:     ClientAuthNone,
+			ReloadInterval: metav1.Duration{Duration: 30 * time.Second},
+			ExpiryWarning:  metav1.Duration{Duration: 14 * 24 * time.Hour},
+			API:            Listener{Transport: TransportTLS},
+			Health:         Listener{Transport: TransportPlaintext},
+			Metrics:        Listener{Transport: TransportTLS},
+			Debug:          Listener{Transport: TransportTLS},
+		},
+		Storage: Storage{
+			Backend:    store.BackendBadger,
+			BadgerPath: store.DefaultBadgerPath,
+			EventTTL:   metav1.Duration{Duration: store.DefaultEventTTL},
+			RunRecords: store.DefaultLimits.RunRecords,
+			Revisions:  store.DefaultLimits.Revisions,
+		},
+		Watchers: Watchers{
+			RetryDelay:       metav1.Duration{Duration: k8s.DefaultRetryDelay},
+			WatchTimeout:     metav1.Duration{Duration: k8s.DefaultWatchTimeout},
+			FailureThreshold: metav1.Duration{Duration: 30 * time.Second},
+			HealthInterval:   metav1.Duration{Duration: 5 * time.Second},
+		},
+		Metrics: Metrics{
+			Interval:   metav1.Duration{Duration: 10 * time.Second},
+			RetryDelay: metav1.Duration{Duration: 5 * time.Second},
+		},
+		LeaderElection: LeaderElection{
+			LeaseName:     "sk8l-api",
+			LeaseDuration: metav1.Duration{Duration: leader.DefaultLeaseDuration},
+			RenewDeadline: metav1.Duration{Duration: leader.DefaultRenewDeadline},
+			RetryPeriod:   metav1.Duration{Duration: leader.DefaultRetryPeriod},
+		},
+		Redaction: Redaction{Enabled: true},
+		Audit: Audit{
+			MaxSizeMB:  audit.DefaultMaxSize / megabyte,
+			MaxBackups: audit.DefaultMaxBackups,
+		},
+		Log: Log{
+			Format: logger.FormatJSON,
+			Level:  "info",
+			// Badger is chatty about its tables at info.
+			Levels:       LogLevels{Badger: "warn"},
+			SamplePeriod: metav1.Duration{Duration: time.Second},
+		},
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	errs := []error{}
+	invalid := func(msg string) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidConfig, msg))
+	}
+
+	ports := map[int]string{}
+	for _, port := range []struct {
+		name     string
+		value    int
+		optional bool
+	}{
+		{"server.apiPort", c.Server.APIPort, false},
+		{"server.healthPort", c.Server.HealthPort, false},
+		{"server.metricsPort", c.Server.MetricsPort, false},
+		{"server.gatewayPort", c.Server.GatewayPort, true},
+		{"server.debugPort", c.Server.DebugPort, true},
+	} {
+		if port.optional && port.value == 0 {
+			continue
+		}
+		if port.value <= 0 || port.value > maxPort {
+			invalid(fmt.Sprintf("%s %d is not a port", port.name, port.value))
+			continue
+		}
+		if other, ok := ports[port.value]; ok {
+			invalid(fmt.Sprintf("%s and %s both use port %d", other, port.name, port.value))
+		}
+		ports[port.value] = port.name
+	}
+
+	for _, f := range c.fields() {
+		if d, ok := f.value.Interface().(metav1.Duration); ok && d.Duration <= 0 {
+			invalid(fmt.Sprintf("%s must be a positive duration, got %s", f.path, d.Duration))
+		}
+	}
+
+	switch c.TLS.ClientAuth {
+	case ClientAuthNone, ClientAuthVerifyIfGiven, ClientAuthRequire:
+	default:
+		invalid(fmt.Sprintf("unknown tls.clientAuth %q", c.TLS.ClientAuth))
+	}
+	for name, l := range c.TLS.Listeners() {
+		switch l.Transport {
+		case TransportPlaintext, TransportTLS, TransportMTLS:
+		default:
+			invalid(fmt.Sprintf("unknown tls.%s.transport %q", name, l.Transport))
+		}
+	}
+	if c.TLS.API.Transport == TransportPlaintext && c.TLS.ClientAuth != ClientAuthNone {
+		invalid("tls.clientAuth needs the tls transport of tls.api")
+	}
+	switch c.Storage.Backend {
+	case store.BackendBadger, store.BackendMemory:
+	default:
+		invalid(fmt.Sprintf("unknown storage.backend %q", c.Storage.Backend))
+	}
+	if c.Storage.Backend == store.BackendBadger && c.Storage.BadgerPath == "" {
+		invalid("storage.badgerPath is required by the badger backend")
+	}
+	if c.Storage.RunRecords <= 0 || c.Storage.Revisions <= 0 {
+		invalid("storage.runRecords and storage.revisions must be positive")
+	}
+	if c.Audit.MaxSizeMB <= 0 || c.Audit.MaxBackups < 0 {
+		invalid("audit.maxSizeMB must be positive and audit.maxBackups not negative")
+	}
+	if le := c.LeaderElection; le.LeaseDuration.Duration <= le.RenewDeadline.Duration ||
+		float64(le.RenewDeadline.Duration) <= leaderelection.JitterFactor*float64(le.RetryPeriod.Duration) {
+		invalid(fmt.Sprintf("leaderElection.leaseDuration must exceed renewDeadline, which must exceed %.1f times retryPeriod",
+			leaderelection.JitterFactor))
+	}
+	if c.LeaderElection.Enabled && c.LeaderElection.LeaseName == "" {
+		invalid("leaderElection.leaseName is required by the leader election")
+	}
+	if c.LeaderElection.Enabled && c.LeaderElection.LeaseNamespace == "" && c.Namespace == "" {
+		invalid("leaderElection.leaseNamespace is required when watching all namespaces")
+	}
+	if c.Auth.OIDC.Issuer == "" && c.Auth.OIDC != (OIDC{}) {
+		invalid("auth.oidc needs an issuer")
+	}
+	if c.Auth.OIDC.Issuer != "" && c.Auth.OIDC.Audience == "" {
+		invalid("auth.oidc.audience is required by the issuer")
+	}
+	authenticated := c.Auth.OIDC.Issuer != "" || c.Auth.ServiceAccountTokens ||
+		(c.TLS.ClientAuth != "" && c.TLS.ClientAuth != ClientAuthNone)
+	// Without a policy granting the debug verb, only sk8l itself may use the debug port.
+	if c.Server.DebugPort != 0 && (!authenticated || c.Auth.Policy == "") {
+		invalid("server.debugPort needs an authentication method and auth.policy")
+	}
+	// Without both every caller, anonymous ones included, could create Jobs and suspend CronJobs.
+	if c.Server.Actions && (!authenticated || c.Auth.Policy == "") {
+		invalid("server.actions needs an authentication method and auth.policy")
+	}
+	switch c.Log.Format {
+	case logger.FormatJSON, logger.FormatConsole:
+	default:
+		invalid(fmt.Sprintf("unknown log.format %q", c.Log.Format))
+	}
+	if _, err := logger.ParseLevel(c.Log.Level); err != nil {
+		invalid(fmt.Sprintf("log.level: %s", err))
+	}
+	for component, level := range c.LogSettings().Levels {
+		if _, err := logger.ParseLevel(level); err != nil {
+			invalid(fmt.Sprintf("log.levels.%s: %s", component, err))
+		}
+	}
+	if c.Log.SampleBurst < 0 {
+		invalid("log.sampleBurst must not be negative")
+	}
+	if c.Log.SampleBurst > 0 && c.Log.SamplePeriod.Duration <= 0 {
+		invalid("log.sampleBurst needs a positive log.samplePeriod")
+	}
+	return errors.Join(errs...)
+}
+
+// Listeners returns the listeners by name.
+func (t TLS) Listeners() map[string]Listener {
+	return map[string]Listener{"api": t.API, "health": t.Health, "metrics": t.Metrics, "debug": t.Debug}
+}
+
+// Files returns the certificate files of l, those of the TLS section where
+// it has none.
+func (t TLS) Files(l Listener) (certFile, keyFile, caFile string) {
+	return cmp.Or(l.CertFile, t.CertFile), cmp.Or(l.KeyFile, t.KeyFile), cmp.Or(l.CAFile, t.CAFile)
+}
+
+// StorageLimits returns what the storage keeps per CronJob.
+func (c *Config) StorageLimits() store.Limits {
+	return store.Limits{RunRecords: c.Storage.RunRecords, Revisions: c.Storage.Revisions}
+}
+
+// LogSettings returns the settings of the logs, without the components
+// whose level isn't set.
+func (c *Config) LogSettings() logger.Settings {
+	levels := map[string]string{}
+	for component, level := range map[string]string{
+		logger.Watcher: c.Log.Levels.Watcher,
+		logger.Store:   c.Log.Levels.Store,
+		logger.Metrics: c.Log.Levels.Metrics,
+		logger.GRPC:    c.Log.Levels.GRPC,
+		logger.Badger:  c.Log.Levels.Badger,
+	} {
+		if level != "" {
+			levels[component] = level
+		}
+	}
+	return logger.Settings{
+		Format:       c.Log.Format,
+		Level:        c.Log.Level,
+		Levels:       levels,
+		SampleBurst:  c.Log.SampleBurst,
+		SamplePeriod: c.Log.SamplePeriod.Duration,
+	}
+}
+
+// YAML returns the configuration as a config file would hold it.
+func (c *Config) YAML() ([]byte, error) {
+	data, err := gyaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("config#YAML: %w", err)
+	}
+	return data, nil
+}
+
+// durationType is the type of every duration setting.
+var durationType = reflect.TypeFor[metav1.Duration]()
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danroux/sk8l/internal/logger"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func getenv(env map[string]string) func(string) string {
+	return func(name string) string { return env[name] }
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	file := writeFile(t, `
+namespace: batch
+server:
+  apiPort: 9000
+storage:
+  eventTTL: 2h
+  runRecords: 10
+grpcWeb:
+  allowedOrigins: [https://a.example.com]
+`)
+	env := map[string]string{
+		FileEnv:           file,
+		"SK8L_EVENTS_TTL": "3h",
+		"SK8L_GRPC_WEB":   "on",
+		"SK8L_REDACTION":  "off",
+	}
+	c, args, err := Load("sk8l", []string{"-storage.event-ttl", "4h", "-server.gateway-port=9001", "snapshot.tar.gz"}, getenv(env))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !slices.Equal(args, []string{"snapshot.tar.gz"}) {
+		t.Errorf("expected the arguments after the flags, got %v", args)
+	}
+	if c.Namespace != "batch" || c.Server.APIPort != 9000 || c.Storage.RunRecords != 10 {
+		t.Errorf("expected the settings of the file, got %+v", c)
+	}
+	if c.Server.HealthPort != 8588 || c.Storage.Revisions != Default().Storage.Revisions {
+		t.Errorf("expected the defaults of the settings missing from the file, got %+v", c)
+	}
+	if !c.GRPCWeb.Enabled || c.Redaction.Enabled || !slices.Equal(c.GRPCWeb.AllowedOrigins, []string{"https://a.example.com"}) {
+		t.Errorf("expected the environment to override the file, got %+v %+v", c.GRPCWeb, c.Redaction)
+	}
+	if c.Storage.EventTTL.Duration != 4*time.Hour || c.Server.GatewayPort != 9001 {
+		t.Errorf("expected the flags to override the environment, got %s and %d", c.Storage.EventTTL.Duration, c.Server.GatewayPort)
+	}
+
+	env["SK8L_GRPC_WEB_ALLOWED_ORIGINS"] = " https://b.example.com, ,https://c.example.com"
+	other := writeFile(t, "namespace: other\n")
+	if c, _, err = Load("sk8l", []string{"-config", other}, getenv(env)); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if c.Namespace != "other" || c.Server.APIPort != 8585 {
+		t.Errorf("expected -config to replace %s, got %+v", FileEnv, c)
+	}
+	if !slices.Equal(c.GRPCWeb.AllowedOrigins, []string{"https://b.example.com", "https://c.example.com"}) {
+		t.Errorf("expected a comma separated list, got %v", c.GRPCWeb.AllowedOrigins)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		file string
+		env  map[string]string
+		args []string
+	}{
+		"unknown key":    {file: "storage:\n  ttl: 1h\n"},
+		"file duration":  {file: "storage:\n  eventTTL: soon\n"},
+		"env duration":   {env: map[string]string{"SK8L_EVENTS_TTL": "1d"}},
+		"env bool":       {env: map[string]string{"SK8L_ACTIONS": "yes please"}},
+		"flag port":      {args: []string{"-server.api-port", "api"}},
+		"port range":     {args: []string{"-server.metrics-port", "70000"}},
+		"port clash":     {args: []string{"-server.gateway-port", "8585"}},
+		"zero duration":  {args: []string{"-watchers.retry-delay", "0s"}},
+		"client auth":    {args: []string{"-tls.client-auth", "optional"}},
+		"backend":        {env: map[string]string{"SK8L_STORAGE": "etcd"}},
+		"no badger path": {args: []string{"-storage.badger-path", ""}},
+		"run records":    {args: []string{"-storage.run-records", "0"}},
+		"oidc issuer":    {args: []string{"-auth.oidc.audience", "sk8l"}},
+		"oidc audience":  {args: []string{"-auth.oidc.issuer", "https://issuer.example.com"}},
+		"renew deadline": {args: []string{"-leader-election.renew-deadline", "20s"}},
+		"no lease name":  {args: []string{"-leader-election.enabled=on", "-leader-election.lease-name="}},
+		"transport":      {env: map[string]string{"SK8L_TLS_METRICS_TRANSPORT": "http"}},
+		"plaintext mtls": {args: []string{"-tls.api.transport=plaintext", "-tls.client-auth=require"}},
+		"log format":     {env: map[string]string{"SK8L_LOG_FORMAT": "logfmt"}},
+		"log level":      {args: []string{"-log.level", "verbose"}},
+		"store level":    {env: map[string]string{"SK8L_LOG_LEVEL_STORE": "loud"}},
+		"sample burst":   {args: []string{"-log.sample-burst", "-1"}},
+		"sample period":  {args: []string{"-log.sample-burst", "5", "-log.sample-period", "0s"}},
+		"open actions":   {env: map[string]string{"SK8L_ACTIONS": "on", "SK8L_AUTH_SERVICEACCOUNT_TOKENS": "on"}},
+		"open debug":     {args: []string{"-server.debug-port", "8595"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			env := map[string]string{}
+			for k, v := range tc.env {
+				env[k] = v
+			}
+			if tc.file != "" {
+				env[FileEnv] = writeFile(t, tc.file)
+			}
+			if _, _, err := Load("sk8l", tc.args, getenv(env)); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidate_SamplePeriod(t *testing.T) {
+	c := Default()
+	c.Log.SampleBurst = 5
+	c.Log.SamplePeriod.Duration = 0
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "log.sampleBurst needs a positive log.samplePeriod") {
+		t.Errorf("expected the sampling to need a period, got %v", err)
+	}
+	c.Log.SamplePeriod.Duration = time.Second
+	if err := c.Validate(); err != nil {
+		t.Errorf("expected the sampling to be valid, got %v", err)
+	}
+}
+
+func TestValidate_Actions(t *testing.T) {
+	c := Default()
+	c.Server.Actions = true
+	c.Auth.Policy = "/etc/sk8l/policy.yaml"
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "server.actions") {
+		t.Errorf("expected actions to need authentication, got %v", err)
+	}
+	c.Auth.ServiceAccountTokens = true
+	if err := c.Validate(); err != nil {
+		t.Errorf("expected actions to be allowed with authentication and a policy, got %v", err)
+	}
+}
+
+func TestValidate_DebugPort(t *testing.T) {
+	for name, tc := range map[string]struct {
+		set   func(c *Config)
+		valid bool
+	}{
+		"mtls":             {func(c *Config) { c.TLS.Debug.Transport = TransportMTLS }, false},
+		"no policy":        {func(c *Config) { c.Auth.ServiceAccountTokens, c.Auth.Policy = true, "" }, false},
+		"client certs":     {func(c *Config) { c.TLS.ClientAuth = ClientAuthRequire }, true},
+		"oidc":             {func(c *Config) { c.Auth.OIDC = OIDC{Issuer: "https://issuer.example.com", Audience: "sk8l"} }, true},
+		"service accounts": {func(c *Config) { c.Auth.ServiceAccountTokens = true }, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := Default()
+			c.Server.DebugPort = 8595
+			c.Auth.Policy = "/etc/sk8l/policy.yaml"
+			tc.set(c)
+			if err := c.Validate(); (err == nil) != tc.valid {
+				t.Errorf("expected valid: %t, got %v", tc.valid, err)
+			}
+		})
+	}
+}
+
+func TestValidate_ReportsEverySetting(t *testing.T) {
+	c := Default()
+	c.Server.HealthPort = c.Server.APIPort
+	c.Metrics.Interval.Duration = -time.Second
+	err := c.Validate()
+	for _, expected := range []string{"server.apiPort and server.healthPort", "metrics.interval"} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q to be reported, got %v", expected, err)
+		}
+	}
+}
+
+func TestYAML(t *testing.T) {
+	data, err := Default().YAML()
+	if err != nil {
+		t.Fatalf("YAML failed: %v", err)
+	}
+	for _, expected := range []string{"eventTTL: 1h0m0s", "apiPort: 8585", "certFile: /etc/sk8l-certs/server-cert.pem"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected %q in\n%s", expected, data)
+		}
+	}
+	c, _, err := Load("sk8l", nil, getenv(map[string]string{FileEnv: writeFile(t, string(data))}))
+	if err != nil {
+		t.Fatalf("expected the dump to load as a config file: %v", err)
+	}
+	if !reflect.DeepEqual(c, Default()) {
+		t.Errorf("expected the dump of the defaults to load as the defaults, got %+v", c)
+	}
+}
+
+func TestFields(t *testing.T) {
+	paths, envs := []string{}, map[string]string{}
+	for _, f := range Default().fields() {
+		if f.env == "" || f.usage == "" {
+			t.Errorf("expected %s to have an environment variable and a usage", f.path)
+		}
+		if other, ok := envs[f.env]; ok {
+			t.Errorf("expected %s and %s to have different environment variables", other, f.path)
+		}
+		paths, envs[f.env] = append(paths, f.path), f.path
+	}
+	if envs["SK8L_TLS_METRICS_CA_FILE"] != "tls.metrics.ca-file" {
+		t.Errorf("expected the listeners to prefix their environment variables, got %v", envs)
+	}
+	for _, expected := range []string{"namespace", "server.api-port", "storage.event-ttl", "auth.oidc.jwks-url", "audit.max-size-mb"} {
+		if !slices.Contains(paths, expected) {
+			t.Errorf("expected a %s flag, got %v", expected, paths)
+		}
+	}
+}
+
+func TestLogSettings(t *testing.T) {
+	c, _, err := Load("sk8l", []string{"-log.levels.grpc", "warn"}, getenv(map[string]string{"SK8L_LOG_LEVEL_STORE": "debug"}))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	s := c.LogSettings()
+	expected := map[string]string{logger.Store: "debug", logger.GRPC: "warn", logger.Badger: "warn"}
+	if !maps.Equal(s.Levels, expected) || s.Level != "info" || s.Format != logger.FormatJSON {
+		t.Errorf("expected the levels set, got %+v", s)
+	}
+}
+
+func TestTLS_Files(t *testing.T) {
+	c := Default()
+	c.TLS.Metrics.CertFile = "/etc/metrics-certs/tls.crt"
+	certFile, keyFile, caFile := c.TLS.Files(c.TLS.Metrics)
+	if certFile != "/etc/metrics-certs/tls.crt" || keyFile != c.TLS.KeyFile || caFile != c.TLS.CAFile {
+		t.Errorf("expected the files of the listener over those of the tls section, got %s %s %s", certFile, keyFile, caFile)
+	}
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gyaml "sigs.k8s.io/yaml"
+)
+
+// FileEnv holds the path of the config file when -config isn't given.
+const FileEnv = "SK8L_CONFIG"
+
+// field is a single setting of a Config.
+type field struct {
+	// path is the flag name, the kebab-cased YAML keys joined by dots.
+	path  string
+	env   string
+	usage string
+	value reflect.Value
+}
+
+// fields returns every setting of c, in declaration order.
+func (c *Config) fields() []field {
+	return collectFields(reflect.ValueOf(c).Elem(), "", "")
+}
+
+// collectFields returns the settings of v. The env tag of a nested struct
+// prefixes the env tags of its settings, so that a type like Listener can be
+// used for several of them.
+func collectFields(v reflect.Value, prefix, envPrefix string) []field {
+	fields := []field{}
+	for i := range v.NumField() {
+		sf := v.Type().Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		path := prefix + kebabCase(name)
+		env := sf.Tag.Get("env")
+		if sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+			fields = append(fields, collectFields(v.Field(i), path+".", envPrefix+env)...)
+			continue
+		}
+		if env != "" {
+			env = envPrefix + env
+		}
+		fields = append(fields, field{path: path, env: env, usage: sf.Tag.Get("usage"), value: v.Field(i)})
+	}
+	return fields
+}
+
+// set parses s into the setting. Lists are comma separated and booleans
+// accept on and off next to the values of strconv.ParseBool.
+func (f field) set(s string) error {
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(s)
+	case reflect.Bool:
+		b, err := parseBool(s)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidConfig, f.path, err)
+		}
+		f.value.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidConfig, f.path, err)
+		}
+		f.value.SetInt(int64(n))
+	case reflect.Slice:
+		f.value.Set(reflect.ValueOf(splitList(s)))
+	case reflect.Struct:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidConfig, f.path, err)
+		}
+		f.value.Set(reflect.ValueOf(metav1.Duration{Duration: d}))
+	default:
+		return fmt.Errorf("%w: %s has an unsupported type %s", ErrInvalidConfig, f.path, f.value.Type())
+	}
+	return nil
+}
+
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	default:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return false, fmt.Errorf("parseBool: %w", err)
+		}
+		return b, nil
+	}
+}
+
+// splitList splits a comma separated list, dropping empty items.
+func splitList(s string) []string {
+	items := []string{}
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// kebabCase turns YAML keys like eventTTL into flag names like event-ttl.
+func kebabCase(name string) string {
+	runes := []rune(name)
+	b := strings.Builder{}
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			previousLower := !unicode.IsUpper(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if previousLower || nextLower {
+				b.WriteByte('-')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Load builds the configuration from the defaults, the config file, the
+// environment read through getenv and the flags in args, in that order, and
+// validates it. It returns the arguments left after the flags.
+func Load(name string, args []string, getenv func(string) string) (*Config, []string, error) {
+	c := Default()
+	fields := c.fields()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	file := fs.String("config", getenv(FileEnv), "YAML config file, "+FileEnv+" when not given")
+	// Flags are applied last, after the file and environment they override.
+	flagged := []func() error{}
+	for _, f := range fields {
+		usage := f.usage
+		if f.env != "" {
+			usage += " (" + f.env + ")"
+		}
+		fs.Func(f.path, fmt.Sprintf("%s (default %s)", usage, f.display()), func(s string) error {
+			flagged = append(flagged, func() error { return f.set(s) })
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, fmt.Errorf("config#Load: %w", err)
+	}
+
+	if *file != "" {
+		if err := c.loadFile(*file); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, f := range fields {
+		if s := getenv(f.env); f.env != "" && s != "" {
+			if err := f.set(s); err != nil {
+				return nil, nil, fmt.Errorf("config#Load: %s: %w", f.env, err)
+			}
+		}
+	}
+	for _, set := range flagged {
+		if err := set(); err != nil {
+			return nil, nil, fmt.Errorf("config#Load: %w", err)
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("config#Load: %w", err)
+	}
+	return c, fs.Args(), nil
+}
+
+// loadFile merges the YAML file at path into c, unknown keys are rejected.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("config#loadFile: %w", err)
+	}
+	if err := gyaml.UnmarshalStrict(data, c); err != nil {
+		return fmt.Errorf("config#loadFile: %w: %s: %w", ErrInvalidConfig, path, err)
+	}
+	return nil
+}
+
+// display formats the value of the setting the way it is set.
+func (f field) display() string {
+	switch v := f.value.Interface().(type) {
+	case metav1.Duration:
+		return v.Duration.String()
+	case []string:
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
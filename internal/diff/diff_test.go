@@ -0,0 +1,58 @@
+package diff
+
+import (
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			a:    "schedule: '* * * * *'\nsuspend: false\n",
+			b:    "schedule: '*/5 * * * *'\nsuspend: false\n",
+			want: "--- a\n+++ b\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				"-schedule: '* * * * *'\n" +
+				"+schedule: '*/5 * * * *'\n" +
+				" suspend: false\n",
+		},
+		{
+			name: "separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			b:    "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			want: "--- a\n+++ b\n" +
+				"@@ -1,3 +1,4 @@\n" +
+				"+0\n 1\n 2\n 3\n" +
+				"@@ -7,4 +8,3 @@\n" +
+				" 7\n 8\n 9\n" +
+				"-10\n",
+		},
+		{
+			name: "insert into empty",
+			a:    "",
+			b:    "a",
+			want: "--- a\n+++ b\n" +
+				"@@ -0,0 +1 @@\n" +
+				"+a\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("a", "b", tt.a, tt.b); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
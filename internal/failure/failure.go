@@ -0,0 +1,176 @@
+// Package failure classifies failed Jobs into normalized root-cause categories
+// from their conditions and the waiting and termination states of their pods' containers.
+package failure
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/danroux/sk8l/protos"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Exit codes of a container killed by a signal are 128 + the signal number.
+const (
+	exitCodeSIGKILL = 137
+	exitCodeSIGTERM = 143
+)
+
+const categoryPrefix = "FAILURE_CATEGORY_"
+
+// Classification is the root cause of a Job failure.
+type Classification struct {
+	Category protos.FailureCategory
+	// Hint is a human readable explanation of the failure.
+	Hint string
+}
+
+// Classify returns the most specific cause found in job and its pods. Causes
+// that explain others win: a Job that ran out of time has its containers
+// killed by a signal, and a container that was OOM killed makes the Job hit
+// its backoff limit. It returns FAILURE_CATEGORY_UNKNOWN when nothing
+// explains the failure, callers only classify Jobs they know have failed.
+func Classify(job *batchv1.Job, pods []corev1.Pod) Classification {
+	for i := range pods {
+		if c, ok := classifyPod(&pods[i]); ok {
+			return c
+		}
+	}
+	if hasFailedCondition(job, batchv1.JobReasonDeadlineExceeded) {
+		return newClassification(protos.FailureCategory_FAILURE_CATEGORY_DEADLINE_EXCEEDED, "")
+	}
+	for i := range pods {
+		if c, ok := classifyContainers(&pods[i]); ok {
+			return c
+		}
+	}
+	if hasFailedCondition(job, batchv1.JobReasonBackoffLimitExceeded) {
+		return newClassification(protos.FailureCategory_FAILURE_CATEGORY_BACKOFF_LIMIT_EXCEEDED, "")
+	}
+	return newClassification(protos.FailureCategory_FAILURE_CATEGORY_UNKNOWN, "")
+}
+
+// Label returns the category as used in metric labels, e.g. oom_killed.
+func Label(category protos.FailureCategory) string {
+	return strings.ToLower(strings.TrimPrefix(category.String(), categoryPrefix))
+}
+
+func classifyPod(pod *corev1.Pod) (Classification, bool) {
+	switch {
+	case pod.Status.Reason == "Evicted":
+		return newClassification(protos.FailureCategory_FAILURE_CATEGORY_EVICTED, pod.Status.Message), true
+	case isNodeShutdown(pod):
+		return newClassification(protos.FailureCategory_FAILURE_CATEGORY_NODE_SHUTDOWN, pod.Status.Message), true
+	default:
+		return Classification{}, false
+	}
+}
+
+// isNodeShutdown recognizes pods terminated by the kubelet's graceful node shutdown.
+func isNodeShutdown(pod *corev1.Pod) bool {
+	switch pod.Status.Reason {
+	case "Shutdown", "NodeShutdown":
+		return true
+	case "Terminated":
+		return strings.Contains(strings.ToLower(pod.Status.Message), "node shutdown")
+	default:
+		return false
+	}
+}
+
+func classifyContainers(pod *corev1.Pod) (Classification, bool) {
+	statuses := make([]corev1.ContainerStatus, 0,
+		len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses)+len(pod.Status.EphemeralContainerStatuses))
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	statuses = append(statuses, pod.Status.EphemeralContainerStatuses...)
+
+	for _, containerStatus := range statuses {
+		if c, ok := classifyWaiting(containerStatus.Name, containerStatus.State.Waiting); ok {
+			return c, true
+		}
+		if c, ok := classifyTerminated(containerStatus.Name, containerStatus.State.Terminated); ok {
+			return c, true
+		}
+		// A restarting container is waiting in CrashLoopBackOff, the cause is its last termination.
+		if c, ok := classifyTerminated(containerStatus.Name, containerStatus.LastTerminationState.Terminated); ok {
+			return c, true
+		}
+	}
+	return Classification{}, false
+}
+
+func classifyWaiting(containerName string, waiting *corev1.ContainerStateWaiting) (Classification, bool) {
+	if waiting == nil {
+		return Classification{}, false
+	}
+	switch waiting.Reason {
+	case "ImagePullBackOff", "ErrImagePull", "InvalidImageName", "ErrImageNeverPull":
+		return newClassification(protos.FailureCategory_FAILURE_CATEGORY_IMAGE_PULL_BACK_OFF, containerName), true
+	case "CreateContainerConfigError":
+		return newClassification(protos.FailureCategory_FAILURE_CATEGORY_CREATE_CONTAINER_CONFIG_ERROR, containerName), true
+	default:
+		return Classification{}, false
+	}
+}
+
+func classifyTerminated(containerName string, terminated *corev1.ContainerStateTerminated) (Classification, bool) {
+	if terminated == nil {
+		return Classification{}, false
+	}
+	switch {
+	case terminated.Reason == "OOMKilled":
+		return newClassification(protos.FailureCategory_FAILURE_CATEGORY_OOM_KILLED, containerName), true
+	case terminated.ExitCode == exitCodeSIGKILL:
+		return newClassification(protos.FailureCategory_FAILURE_CATEGORY_SIGKILL, containerName), true
+	case terminated.ExitCode == exitCodeSIGTERM:
+		return newClassification(protos.FailureCategory_FAILURE_CATEGORY_SIGTERM, containerName), true
+	case terminated.ExitCode != 0:
+		hint := fmt.Sprintf("%s exited with code %d", containerName, terminated.ExitCode)
+		return newClassification(protos.FailureCategory_FAILURE_CATEGORY_NON_ZERO_EXIT, hint), true
+	default:
+		return Classification{}, false
+	}
+}
+
+func hasFailedCondition(job *batchv1.Job, reason string) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue && condition.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// newClassification builds the hint of category. detail names the container
+// involved or carries the message reported by Kubernetes.
+func newClassification(category protos.FailureCategory, detail string) Classification {
+	hint := hints[category]
+	if detail != "" {
+		hint = fmt.Sprintf("%s (%s)", hint, detail)
+	}
+	return Classification{Category: category, Hint: hint}
+}
+
+var hints = map[protos.FailureCategory]string{
+	protos.FailureCategory_FAILURE_CATEGORY_OOM_KILLED: "A container used more memory than its limit and was killed. " +
+		"Raise resources.limits.memory or reduce the memory the job needs",
+	protos.FailureCategory_FAILURE_CATEGORY_IMAGE_PULL_BACK_OFF: "The container image could not be pulled. " +
+		"Check the image name and tag, the registry's availability and the imagePullSecrets",
+	protos.FailureCategory_FAILURE_CATEGORY_CREATE_CONTAINER_CONFIG_ERROR: "The container could not be created from its spec. " +
+		"Usually a referenced ConfigMap, Secret or key does not exist",
+	protos.FailureCategory_FAILURE_CATEGORY_DEADLINE_EXCEEDED:      "The job ran longer than its activeDeadlineSeconds and was terminated",
+	protos.FailureCategory_FAILURE_CATEGORY_BACKOFF_LIMIT_EXCEEDED: "The job's pods failed more often than its backoffLimit allows",
+	protos.FailureCategory_FAILURE_CATEGORY_EVICTED: "The pod was evicted from its node, " +
+		"usually because the node ran low on memory or disk",
+	protos.FailureCategory_FAILURE_CATEGORY_NODE_SHUTDOWN: "The pod was terminated because its node shut down",
+	protos.FailureCategory_FAILURE_CATEGORY_NON_ZERO_EXIT: "The application exited with a non-zero code. " +
+		"Its logs should tell why",
+	protos.FailureCategory_FAILURE_CATEGORY_SIGKILL: "A container was killed with SIGKILL (exit code 137), " +
+		"e.g. because it did not stop within its termination grace period",
+	protos.FailureCategory_FAILURE_CATEGORY_SIGTERM: "A container was terminated with SIGTERM (exit code 143), " +
+		"e.g. because its pod was deleted or preempted",
+	protos.FailureCategory_FAILURE_CATEGORY_UNKNOWN: "The job failed for a reason sk8l does not recognize. " +
+		"Check its conditions and events",
+}
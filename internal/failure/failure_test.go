@@ -0,0 +1,129 @@
+package failure
+
+import (
+	"testing"
+
+	"github.com/danroux/sk8l/protos"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func failedJob(reason string) *batchv1.Job {
+	return &batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+		Type:   batchv1.JobFailed,
+		Status: corev1.ConditionTrue,
+		Reason: reason,
+	}}}}
+}
+
+func podWithState(state corev1.ContainerState) corev1.Pod {
+	return corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "main", State: state}}}}
+}
+
+func terminated(reason string, exitCode int32) corev1.ContainerState {
+	return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: reason, ExitCode: exitCode}}
+}
+
+func waiting(reason string) corev1.ContainerState {
+	return corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name     string
+		job      *batchv1.Job
+		pods     []corev1.Pod
+		expected protos.FailureCategory
+	}{
+		{
+			name:     "OOMKilled wins over the backoff limit it caused",
+			job:      failedJob(batchv1.JobReasonBackoffLimitExceeded),
+			pods:     []corev1.Pod{podWithState(terminated("OOMKilled", 137))},
+			expected: protos.FailureCategory_FAILURE_CATEGORY_OOM_KILLED,
+		},
+		{
+			name:     "image pull back off",
+			job:      failedJob(""),
+			pods:     []corev1.Pod{podWithState(waiting("ErrImagePull"))},
+			expected: protos.FailureCategory_FAILURE_CATEGORY_IMAGE_PULL_BACK_OFF,
+		},
+		{
+			name:     "create container config error",
+			job:      failedJob(""),
+			pods:     []corev1.Pod{podWithState(waiting("CreateContainerConfigError"))},
+			expected: protos.FailureCategory_FAILURE_CATEGORY_CREATE_CONTAINER_CONFIG_ERROR,
+		},
+		{
+			name:     "deadline exceeded wins over the SIGTERM it caused",
+			job:      failedJob(batchv1.JobReasonDeadlineExceeded),
+			pods:     []corev1.Pod{podWithState(terminated("Error", 143))},
+			expected: protos.FailureCategory_FAILURE_CATEGORY_DEADLINE_EXCEEDED,
+		},
+		{
+			name:     "backoff limit exceeded without container details",
+			job:      failedJob(batchv1.JobReasonBackoffLimitExceeded),
+			expected: protos.FailureCategory_FAILURE_CATEGORY_BACKOFF_LIMIT_EXCEEDED,
+		},
+		{
+			name:     "evicted",
+			job:      failedJob(batchv1.JobReasonBackoffLimitExceeded),
+			pods:     []corev1.Pod{{Status: corev1.PodStatus{Reason: "Evicted", Message: "low on memory"}}},
+			expected: protos.FailureCategory_FAILURE_CATEGORY_EVICTED,
+		},
+		{
+			name: "node shutdown",
+			job:  failedJob(""),
+			pods: []corev1.Pod{{Status: corev1.PodStatus{
+				Reason:  "Terminated",
+				Message: "Pod was terminated in response to imminent node shutdown.",
+			}}},
+			expected: protos.FailureCategory_FAILURE_CATEGORY_NODE_SHUTDOWN,
+		},
+		{
+			name:     "application non-zero exit",
+			job:      failedJob(""),
+			pods:     []corev1.Pod{podWithState(terminated("Error", 2))},
+			expected: protos.FailureCategory_FAILURE_CATEGORY_NON_ZERO_EXIT,
+		},
+		{
+			name:     "SIGKILL",
+			job:      failedJob(""),
+			pods:     []corev1.Pod{podWithState(terminated("Error", 137))},
+			expected: protos.FailureCategory_FAILURE_CATEGORY_SIGKILL,
+		},
+		{
+			name: "SIGTERM in the last termination of a restarting container",
+			job:  failedJob(""),
+			pods: []corev1.Pod{{Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name:                 "main",
+				State:                waiting("CrashLoopBackOff"),
+				LastTerminationState: terminated("Error", 143),
+			}}}}},
+			expected: protos.FailureCategory_FAILURE_CATEGORY_SIGTERM,
+		},
+		{
+			name:     "unknown",
+			job:      failedJob("PodFailurePolicy"),
+			pods:     []corev1.Pod{podWithState(terminated("Completed", 0))},
+			expected: protos.FailureCategory_FAILURE_CATEGORY_UNKNOWN,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Classify(tt.job, tt.pods)
+			if c.Category != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, c.Category)
+			}
+			if c.Hint == "" {
+				t.Error("expected a hint")
+			}
+		})
+	}
+}
+
+func TestLabel(t *testing.T) {
+	if label := Label(protos.FailureCategory_FAILURE_CATEGORY_OOM_KILLED); label != "oom_killed" {
+		t.Errorf("expected oom_killed, got %q", label)
+	}
+}
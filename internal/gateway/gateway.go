@@ -0,0 +1,466 @@
+// Package gateway serves the RPCs of a gRPC service as HTTP/JSON under
+// /api/v1. Server-streaming RPCs are served as newline delimited JSON, or as
+// Server-Sent Events to clients accepting text/event-stream.
+//
+// Calls don't go through the network: they are handed to the service handlers
+// with the interceptors of the gRPC server, so they are authenticated,
+// authorized and audited the same way.
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const (
+	// Prefix is the path the RPCs are served under.
+	Prefix = "/api/v1/"
+	// OpenAPIPath serves the OpenAPI document of the routes.
+	OpenAPIPath = Prefix + "openapi.json"
+
+	contentTypeJSON   = "application/json"
+	contentTypeNDJSON = "application/x-ndjson"
+	contentTypeSSE    = "text/event-stream"
+
+	maxBodySize = 1 << 20
+	// statusClientClosedRequest is reported when the client went away.
+	statusClientClosedRequest = 499
+)
+
+var (
+	ErrUnknownService   = errors.New("unknown service")
+	ErrInvalidParameter = errors.New("invalid parameter")
+	ErrBodyTooLarge     = errors.New("request body too large")
+)
+
+// httpStatus maps the gRPC codes to HTTP status codes, like grpc-gateway does.
+var httpStatus = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           statusClientClosedRequest,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+}
+
+// Route is the HTTP endpoint of an RPC.
+type Route struct {
+	Method protoreflect.MethodDescriptor
+	// FullMethod is the gRPC name of the RPC, like /sk8l.Cronjob/GetCronjobs.
+	FullMethod string
+	// Path is Prefix followed by the kebab-cased name of the RPC, without its
+	// Get prefix: GetCronjobYAML is served at /api/v1/cronjob-yaml.
+	Path string
+	// HTTPMethods are GET and POST for the Get RPCs, POST for the others.
+	HTTPMethods []string
+	input       protoreflect.MessageType
+	unary       grpc.MethodHandler
+	stream      grpc.StreamHandler
+}
+
+// Gateway is the http.Handler serving the routes of a service.
+type Gateway struct {
+	srv       any
+	routes    []Route
+	unary     []grpc.UnaryServerInterceptor
+	streaming []grpc.StreamServerInterceptor
+	headers   []string
+	mux       *http.ServeMux
+	openAPI   []byte
+}
+
+type Option func(*Gateway)
+
+// WithUnaryInterceptors runs interceptors around the unary calls, in order.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(g *Gateway) {
+		g.unary = append(g.unary, interceptors...)
+	}
+}
+
+// WithStreamInterceptors runs interceptors around the streaming calls, in order.
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(g *Gateway) {
+		g.streaming = append(g.streaming, interceptors...)
+	}
+}
+
+// WithForwardedHeaders passes headers to the handlers as incoming metadata,
+// on top of the authorization header.
+func WithForwardedHeaders(headers ...string) Option {
+	return func(g *Gateway) {
+		for _, header := range headers {
+			g.headers = append(g.headers, strings.ToLower(header))
+		}
+	}
+}
+
+// New returns the gateway of the service described by desc and implemented by
+// srv. Client-streaming RPCs are not served.
+func New(desc *grpc.ServiceDesc, srv any, opts ...Option) (*Gateway, error) {
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(desc.ServiceName))
+	if err != nil {
+		return nil, fmt.Errorf("gateway#New: %w: %s: %w", ErrUnknownService, desc.ServiceName, err)
+	}
+	service, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("gateway#New: %w: %s is not a service", ErrUnknownService, desc.ServiceName)
+	}
+
+	g := &Gateway{srv: srv, headers: []string{"authorization"}, mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	unary := map[string]grpc.MethodHandler{}
+	for _, method := range desc.Methods {
+		unary[method.MethodName] = method.Handler
+	}
+	streams := map[string]grpc.StreamHandler{}
+	for _, stream := range desc.Streams {
+		if !stream.ClientStreams {
+			streams[stream.StreamName] = stream.Handler
+		}
+	}
+
+	methods := service.Methods()
+	for i := range methods.Len() {
+		md := methods.Get(i)
+		name := string(md.Name())
+		route := Route{
+			Method:      md,
+			FullMethod:  fmt.Sprintf("/%s/%s", desc.ServiceName, name),
+			Path:        Prefix + kebabCase(strings.TrimPrefix(name, "Get")),
+			HTTPMethods: []string{http.MethodPost},
+			unary:       unary[name],
+			stream:      streams[name],
+		}
+		if route.unary == nil && route.stream == nil {
+			continue
+		}
+		if strings.HasPrefix(name, "Get") {
+			route.HTTPMethods = []string{http.MethodGet, http.MethodPost}
+		}
+		if route.input, err = protoregistry.GlobalTypes.FindMessageByName(md.Input().FullName()); err != nil {
+			return nil, fmt.Errorf("gateway#New: %s: %w", name, err)
+		}
+		g.routes = append(g.routes, route)
+		g.mux.Handle(route.Path, g.handler(route))
+	}
+
+	if g.openAPI, err = openAPIDocument(service, g.routes); err != nil {
+		return nil, err
+	}
+	g.mux.HandleFunc(OpenAPIPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentTypeJSON)
+		_, _ = w.Write(g.openAPI)
+	})
+	return g, nil
+}
+
+// Routes returns the routes of the RPCs, in the order of the service.
+func (g *Gateway) Routes() []Route {
+	return g.routes
+}
+
+// OpenAPI returns the OpenAPI document of the routes, served at OpenAPIPath.
+func (g *Gateway) OpenAPI() []byte {
+	return g.openAPI
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+func (g *Gateway) handler(route Route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(route.HTTPMethods, r.Method) {
+			w.Header().Set("Allow", strings.Join(route.HTTPMethods, ", "))
+			writeStatus(w, http.StatusMethodNotAllowed, status.Newf(codes.Unimplemented, "%s %s is not supported", r.Method, route.Path))
+			return
+		}
+		// Forms can't POST JSON across sites without a CORS preflight.
+		if r.Method == http.MethodPost && !isJSON(r.Header.Get("Content-Type")) {
+			writeStatus(w, http.StatusUnsupportedMediaType, status.Newf(codes.InvalidArgument, "POST bodies must be %s", contentTypeJSON))
+			return
+		}
+		in, err := request(route, r)
+		if err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, err.Error()))
+			return
+		}
+		r = r.WithContext(g.incomingContext(r))
+		if route.stream != nil {
+			g.serveStream(w, r, route, in)
+			return
+		}
+		g.serveUnary(w, r, route, in)
+	})
+}
+
+// isJSON reports whether contentType is application/json, parameters aside.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == contentTypeJSON
+}
+
+// request builds the request message of route from the JSON body of r and
+// its query parameters, named after the JSON or proto names of the fields.
+func request(route Route, r *http.Request) (proto.Message, error) {
+	in := route.input.New()
+	if r.Method == http.MethodPost {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+		if err != nil {
+			return nil, fmt.Errorf("gateway#request: %w", err)
+		}
+		if len(body) > maxBodySize {
+			return nil, fmt.Errorf("gateway#request: %w", ErrBodyTooLarge)
+		}
+		if len(bytes.TrimSpace(body)) > 0 {
+			if err := protojson.Unmarshal(body, in.Interface()); err != nil {
+				return nil, fmt.Errorf("gateway#request: %w", err)
+			}
+		}
+	}
+	for name, values := range r.URL.Query() {
+		if err := setField(in, name, values); err != nil {
+			return nil, err
+		}
+	}
+	return in.Interface(), nil
+}
+
+func setField(m protoreflect.Message, name string, values []string) error {
+	fields := m.Descriptor().Fields()
+	fd := fields.ByJSONName(name)
+	if fd == nil {
+		fd = fields.ByTextName(name)
+	}
+	if fd == nil || fd.IsMap() || fd.Message() != nil {
+		return fmt.Errorf("gateway#setField: %w: %q", ErrInvalidParameter, name)
+	}
+	if !fd.IsList() && len(values) > 1 {
+		return fmt.Errorf("gateway#setField: %w: %q is not repeated", ErrInvalidParameter, name)
+	}
+	for _, value := range values {
+		v, err := parseScalar(fd, value)
+		if err != nil {
+			return fmt.Errorf("gateway#setField: %w: %q: %w", ErrInvalidParameter, name, err)
+		}
+		if fd.IsList() {
+			m.Mutable(fd).List().Append(v)
+		} else {
+			m.Set(fd, v)
+		}
+	}
+	return nil
+}
+
+func parseScalar(fd protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	var err error
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BoolKind:
+		var b bool
+		if b, err = strconv.ParseBool(value); err == nil {
+			return protoreflect.ValueOfBool(b), nil
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		var i int64
+		if i, err = strconv.ParseInt(value, 10, 32); err == nil {
+			return protoreflect.ValueOfInt32(int32(i)), nil
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		var i int64
+		if i, err = strconv.ParseInt(value, 10, 64); err == nil {
+			return protoreflect.ValueOfInt64(i), nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		var u uint64
+		if u, err = strconv.ParseUint(value, 10, 32); err == nil {
+			return protoreflect.ValueOfUint32(uint32(u)), nil
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		var u uint64
+		if u, err = strconv.ParseUint(value, 10, 64); err == nil {
+			return protoreflect.ValueOfUint64(u), nil
+		}
+	case protoreflect.FloatKind:
+		var f float64
+		if f, err = strconv.ParseFloat(value, 32); err == nil {
+			return protoreflect.ValueOfFloat32(float32(f)), nil
+		}
+	case protoreflect.DoubleKind:
+		var f float64
+		if f, err = strconv.ParseFloat(value, 64); err == nil {
+			return protoreflect.ValueOfFloat64(f), nil
+		}
+	case protoreflect.BytesKind:
+		var b []byte
+		if b, err = base64.StdEncoding.DecodeString(value); err == nil {
+			return protoreflect.ValueOfBytes(b), nil
+		}
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(value)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		var i int64
+		if i, err = strconv.ParseInt(value, 10, 32); err == nil {
+			return protoreflect.ValueOfEnum(protoreflect.EnumNumber(i)), nil
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		err = ErrInvalidParameter
+	default:
+		err = ErrInvalidParameter
+	}
+	return protoreflect.Value{}, fmt.Errorf("gateway#parseScalar: %w", err)
+}
+
+// incomingContext carries the forwarded headers of r as incoming metadata and
+// its TLS connection state as peer, like the gRPC server would.
+func (g *Gateway) incomingContext(r *http.Request) context.Context {
+	md := metadata.MD{}
+	for _, header := range g.headers {
+		if values := r.Header.Values(header); len(values) > 0 {
+			md.Append(header, values...)
+		}
+	}
+	p := &peer.Peer{Addr: remoteAddr(r.RemoteAddr)}
+	if r.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{
+			State:          *r.TLS,
+			CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity},
+		}
+	}
+	return peer.NewContext(metadata.NewIncomingContext(r.Context(), md), p)
+}
+
+func (g *Gateway) serveUnary(w http.ResponseWriter, r *http.Request, route Route, in proto.Message) {
+	dec := func(m any) error {
+		proto.Merge(m.(proto.Message), in)
+		return nil
+	}
+	resp, err := route.unary(g.srv, r.Context(), dec, chainUnary(g.unary))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	data, err := protojson.Marshal(resp.(proto.Message))
+	if err != nil {
+		writeError(w, status.Error(codes.Internal, err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	_, _ = w.Write(data)
+}
+
+func (g *Gateway) serveStream(w http.ResponseWriter, r *http.Request, route Route, in proto.Message) {
+	// Streams last longer than the write timeout of the server.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+	stream := &serverStream{
+		w:   w,
+		rc:  rc,
+		r:   r,
+		in:  in,
+		sse: strings.Contains(r.Header.Get("Accept"), contentTypeSSE),
+	}
+	info := &grpc.StreamServerInfo{FullMethod: route.FullMethod, IsServerStream: true}
+	if err := chainStream(g.streaming)(g.srv, stream, info, route.stream); err != nil {
+		stream.writeError(err)
+	}
+}
+
+func chainUnary(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	if len(interceptors) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, req any) (any, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+func chainStream(interceptors []grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(srv any, ss grpc.ServerStream) error {
+				return interceptor(srv, ss, info, inner)
+			}
+		}
+		return next(srv, ss)
+	}
+}
+
+// writeError writes the status of err as JSON, {"code": 5, "message": "..."}.
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	code, ok := httpStatus[st.Code()]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+	writeStatus(w, code, st)
+}
+
+func writeStatus(w http.ResponseWriter, code int, st *status.Status) {
+	data, _ := protojson.Marshal(st.Proto())
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
+
+// kebabCase turns CronjobYAML into cronjob-yaml.
+func kebabCase(name string) string {
+	runes := []rune(name)
+	var sb strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			sb.WriteByte('-')
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
@@ -0,0 +1,251 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danroux/sk8l/protos"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type testServer struct {
+	protos.UnimplementedCronjobServer
+}
+
+func (testServer) GetCronjobYAML(ctx context.Context, in *protos.CronjobRequest) (*protos.CronjobYAMLResponse, error) {
+	if in.GetCronjobName() == "missing" {
+		return nil, status.Error(codes.NotFound, "cronjob missing not found")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	return &protos.CronjobYAMLResponse{
+		Cronjob: fmt.Sprintf("%s/%s %v", in.GetCronjobNamespace(), in.GetCronjobName(), md.Get("authorization")),
+	}, nil
+}
+
+func (testServer) GetPodLogs(in *protos.PodLogsRequest, stream protos.Cronjob_GetPodLogsServer) error {
+	for i := range in.GetTailLines() {
+		if err := stream.Send(&protos.PodLogsResponse{Line: fmt.Sprintf("%s line %d", in.GetPodName(), i)}); err != nil {
+			return err
+		}
+	}
+	if in.GetFollow() {
+		return status.Error(codes.Unavailable, "pod went away")
+	}
+	return nil
+}
+
+func newTestGateway(t *testing.T, opts ...Option) *httptest.Server {
+	t.Helper()
+	gw, err := New(&protos.Cronjob_ServiceDesc, testServer{}, opts...)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ts := httptest.NewServer(gw)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func do(t *testing.T, method, url, body string, header http.Header) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, compactLines(t, string(data))
+}
+
+// compactLines compacts the JSON of body, or of each of its lines for
+// streams: protojson output is deliberately unstable.
+func compactLines(t *testing.T, body string) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if json.Compact(buf, []byte(body)) == nil {
+		return buf.String()
+	}
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		start := strings.Index(line, "{")
+		if start < 0 {
+			continue
+		}
+		buf.Reset()
+		if err := json.Compact(buf, []byte(line[start:])); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		lines[i] = line[:start] + buf.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestGateway_Unary(t *testing.T) {
+	var intercepted []string
+	ts := newTestGateway(t, WithUnaryInterceptors(
+		func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			intercepted = append(intercepted, "first "+info.FullMethod)
+			return handler(ctx, req)
+		},
+		func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			intercepted = append(intercepted, "second")
+			return handler(ctx, req)
+		},
+	))
+	header := http.Header{"Authorization": {"Bearer token"}, "X-Other": {"dropped"}}
+
+	resp, body := do(t, http.MethodGet, ts.URL+"/api/v1/cronjob-yaml?cronjobName=backup&cronjobNamespace=batch", "", header)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != contentTypeJSON {
+		t.Fatalf("unexpected response %d %s", resp.StatusCode, body)
+	}
+	if body != `{"cronjob":"batch/backup [Bearer token]"}` {
+		t.Errorf("unexpected body %s", body)
+	}
+	if strings.Join(intercepted, ",") != "first /sk8l.Cronjob/GetCronjobYAML,second" {
+		t.Errorf("expected the interceptors to run in order, got %v", intercepted)
+	}
+
+	jsonHeader := http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+	resp, body = do(t, http.MethodPost, ts.URL+"/api/v1/cronjob-yaml", `{"cronjobName": "backup"}`, jsonHeader)
+	if resp.StatusCode != http.StatusOK || body != `{"cronjob":"/backup []"}` {
+		t.Errorf("unexpected POST response %d %s", resp.StatusCode, body)
+	}
+	for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded"} {
+		resp, body = do(t, http.MethodPost, ts.URL+"/api/v1/cronjob-yaml", `{"cronjobName": "backup"}`, http.Header{"Content-Type": {contentType}})
+		if resp.StatusCode != http.StatusUnsupportedMediaType {
+			t.Errorf("expected a POST of %q to be rejected, got %d %s", contentType, resp.StatusCode, body)
+		}
+	}
+
+	resp, body = do(t, http.MethodGet, ts.URL+"/api/v1/cronjob-yaml?cronjobName=missing", "", nil)
+	if resp.StatusCode != http.StatusNotFound || body != `{"code":5,"message":"cronjob missing not found"}` {
+		t.Errorf("expected NotFound, got %d %s", resp.StatusCode, body)
+	}
+
+	for _, query := range []string{"unknown=1", "cronjobName=a&cronjobName=b"} {
+		if resp, body = do(t, http.MethodGet, ts.URL+"/api/v1/cronjob-yaml?"+query, "", nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected %s to be rejected, got %d %s", query, resp.StatusCode, body)
+		}
+	}
+	if resp, body = do(t, http.MethodPost, ts.URL+"/api/v1/cronjob-yaml", `{"cronjobName": 1}`, jsonHeader); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected an invalid body to be rejected, got %d %s", resp.StatusCode, body)
+	}
+	if resp, _ = do(t, http.MethodDelete, ts.URL+"/api/v1/cronjob-yaml", "", nil); resp.StatusCode != http.StatusMethodNotAllowed ||
+		resp.Header.Get("Allow") != "GET, POST" {
+		t.Errorf("expected DELETE not to be allowed, got %d %v", resp.StatusCode, resp.Header)
+	}
+	if resp, body = do(t, http.MethodGet, ts.URL+"/api/v1/job-yaml", "", nil); resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("expected Unimplemented, got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestGateway_Stream(t *testing.T) {
+	var streamed []string
+	ts := newTestGateway(t, WithStreamInterceptors(
+		func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			streamed = append(streamed, info.FullMethod)
+			return handler(srv, ss)
+		},
+	))
+
+	resp, body := do(t, http.MethodGet, ts.URL+"/api/v1/pod-logs?podName=pod-a&tail_lines=2", "", nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != contentTypeNDJSON {
+		t.Fatalf("unexpected response %d %s", resp.StatusCode, body)
+	}
+	expected := "{\"result\":{\"line\":\"pod-a line 0\"}}\n{\"result\":{\"line\":\"pod-a line 1\"}}\n"
+	if body != expected {
+		t.Errorf("expected %q, got %q", expected, body)
+	}
+	if len(streamed) != 1 || streamed[0] != "/sk8l.Cronjob/GetPodLogs" {
+		t.Errorf("expected the stream interceptor to run, got %v", streamed)
+	}
+
+	resp, body = do(t, http.MethodGet, ts.URL+"/api/v1/pod-logs?podName=pod-a&tailLines=1&follow=true", "",
+		http.Header{"Accept": {contentTypeSSE}})
+	if resp.Header.Get("Content-Type") != contentTypeSSE {
+		t.Fatalf("expected Server-Sent Events, got %v", resp.Header)
+	}
+	expected = "data: {\"line\":\"pod-a line 0\"}\n\nevent: error\ndata: {\"code\":14,\"message\":\"pod went away\"}\n\n"
+	if body != expected {
+		t.Errorf("expected %q, got %q", expected, body)
+	}
+
+	resp, body = do(t, http.MethodGet, ts.URL+"/api/v1/pod-logs?follow=true", "", nil)
+	if resp.StatusCode != http.StatusServiceUnavailable || body != `{"code":14,"message":"pod went away"}` {
+		t.Errorf("expected a stream failing before any message to fail the request, got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestGateway_OpenAPI(t *testing.T) {
+	ts := newTestGateway(t)
+	resp, body := do(t, http.MethodGet, ts.URL+OpenAPIPath, "", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", resp.StatusCode, body)
+	}
+	doc := &openAPI{}
+	if err := json.Unmarshal([]byte(body), doc); err != nil {
+		t.Fatalf("invalid document: %v", err)
+	}
+	for _, method := range protos.Cronjob_ServiceDesc.Methods {
+		found := false
+		for _, operations := range doc.Paths {
+			// RPCs without the Get prefix are only served as POST.
+			for _, verb := range []string{"get", "post"} {
+				if operations[verb] != nil && operations[verb].OperationID == method.MethodName {
+					found = true
+				}
+			}
+		}
+		if !found {
+			t.Errorf("expected an operation for %s", method.MethodName)
+		}
+	}
+	logs := doc.Paths["/api/v1/pod-logs"]
+	if logs["get"] == nil || logs["post"] == nil || logs["post"].OperationID != "GetPodLogsWithBody" {
+		t.Fatalf("unexpected pod logs operations %+v", logs)
+	}
+	if _, ok := logs["get"].Responses["200"].Content[contentTypeSSE]; !ok {
+		t.Error("expected the stream to be served as Server-Sent Events")
+	}
+	schema := doc.Components.Schemas["sk8l.PodLogsRequest"]
+	if schema == nil || schema.Properties["tail_lines"].Format != "int64" || schema.Properties["follow"].Type != "boolean" {
+		t.Errorf("unexpected request schema %+v", schema)
+	}
+	if doc.Components.Schemas["sk8l.CronjobResponse"] == nil {
+		t.Error("expected the nested messages to be described")
+	}
+}
+
+func TestKebabCase(t *testing.T) {
+	for name, expected := range map[string]string{
+		"Cronjobs":             "cronjobs",
+		"CronjobYAML":          "cronjob-yaml",
+		"JobFailureLogs":       "job-failure-logs",
+		"ExportSnapshot":       "export-snapshot",
+		"YAMLOfCronjob":        "yaml-of-cronjob",
+		"DashboardAnnotations": "dashboard-annotations",
+	} {
+		if got := kebabCase(name); got != expected {
+			t.Errorf("kebabCase(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
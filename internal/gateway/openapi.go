@@ -0,0 +1,229 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	openAPIVersion = "3.0.3"
+	// apiVersion is the version of the routes, the one of Prefix.
+	apiVersion = "v1"
+	statusRef  = "google.rpc.Status"
+)
+
+type openAPI struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIContent            `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIContent `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name   string         `json:"name"`
+	In     string         `json:"in"`
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPIContent struct {
+	Description string                      `json:"description,omitempty"`
+	Content     map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+// openAPIDocument describes the routes of service, their requests and
+// responses being the JSON mapping of its messages.
+func openAPIDocument(service protoreflect.ServiceDescriptor, routes []Route) ([]byte, error) {
+	doc := &openAPI{
+		OpenAPI: openAPIVersion,
+		Info:    openAPIInfo{Title: string(service.FullName()), Version: apiVersion},
+		Paths:   map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{Schemas: map[string]*openAPISchema{
+			statusRef: {
+				Type: "object",
+				Properties: map[string]*openAPISchema{
+					"code":    {Type: "integer", Format: "int32"},
+					"message": {Type: "string"},
+					"details": {Type: "array", Items: &openAPISchema{Type: "object"}},
+				},
+			},
+		}},
+	}
+	for _, route := range routes {
+		input, output := route.Method.Input(), route.Method.Output()
+		addSchema(doc.Components.Schemas, input)
+		addSchema(doc.Components.Schemas, output)
+
+		operations := map[string]*openAPIOperation{}
+		for _, method := range route.HTTPMethods {
+			operation := &openAPIOperation{
+				OperationID: string(route.Method.Name()),
+				Responses: map[string]*openAPIContent{
+					"200":     responseContent(route.Method),
+					"default": jsonContent("An error status.", ref(statusRef)),
+				},
+			}
+			if method == http.MethodGet {
+				operation.Parameters = queryParameters(input)
+			} else {
+				operation.RequestBody = jsonContent("", ref(string(input.FullName())))
+			}
+			// Operation IDs are unique, the GET one of a Get RPC keeps its name.
+			if method == http.MethodPost && len(route.HTTPMethods) > 1 {
+				operation.OperationID += "WithBody"
+			}
+			operations[strings.ToLower(method)] = operation
+		}
+		doc.Paths[route.Path] = operations
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("gateway#openAPIDocument: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+func responseContent(md protoreflect.MethodDescriptor) *openAPIContent {
+	output := ref(string(md.Output().FullName()))
+	if !md.IsStreamingServer() {
+		return jsonContent("A "+string(md.Output().Name())+".", output)
+	}
+	return &openAPIContent{
+		Description: "A stream of messages: {\"result\": ...} lines, or the data of Server-Sent Events with " +
+			"Accept: text/event-stream. A failing stream ends with an {\"error\": ...} line or an error event.",
+		Content: map[string]openAPIMediaType{
+			contentTypeNDJSON: {Schema: &openAPISchema{
+				Type:       "object",
+				Properties: map[string]*openAPISchema{"result": output, "error": ref(statusRef)},
+			}},
+			contentTypeSSE: {Schema: output},
+		},
+	}
+}
+
+func jsonContent(description string, schema *openAPISchema) *openAPIContent {
+	return &openAPIContent{
+		Description: description,
+		Content:     map[string]openAPIMediaType{contentTypeJSON: {Schema: schema}},
+	}
+}
+
+func ref(name string) *openAPISchema {
+	return &openAPISchema{Ref: "#/components/schemas/" + name}
+}
+
+// queryParameters are the scalar fields of a request, the ones GET requests can set.
+func queryParameters(md protoreflect.MessageDescriptor) []openAPIParameter {
+	parameters := []openAPIParameter{}
+	fields := md.Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		if fd.IsMap() || fd.Message() != nil {
+			continue
+		}
+		parameters = append(parameters, openAPIParameter{Name: fd.JSONName(), In: "query", Schema: fieldSchema(fd)})
+	}
+	return parameters
+}
+
+// addSchema adds the schema of md and of the messages it refers to.
+func addSchema(schemas map[string]*openAPISchema, md protoreflect.MessageDescriptor) {
+	name := string(md.FullName())
+	if _, ok := schemas[name]; ok {
+		return
+	}
+	schema := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+	schemas[name] = schema
+	fields := md.Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		schema.Properties[fd.JSONName()] = fieldSchema(fd)
+		if fd.IsMap() {
+			fd = fd.MapValue()
+		}
+		if fd.Message() != nil {
+			addSchema(schemas, fd.Message())
+		}
+	}
+}
+
+func fieldSchema(fd protoreflect.FieldDescriptor) *openAPISchema {
+	switch {
+	case fd.IsMap():
+		return &openAPISchema{Type: "object", AdditionalProperties: valueSchema(fd.MapValue())}
+	case fd.IsList():
+		return &openAPISchema{Type: "array", Items: valueSchema(fd)}
+	default:
+		return valueSchema(fd)
+	}
+}
+
+// valueSchema is the schema of a single value of fd in the protobuf JSON mapping.
+func valueSchema(fd protoreflect.FieldDescriptor) *openAPISchema {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return &openAPISchema{Type: "string"}
+	case protoreflect.BoolKind:
+		return &openAPISchema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		// 64 bit integers are JSON strings.
+		return &openAPISchema{Type: "string", Format: "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &openAPISchema{Type: "string", Format: "uint64"}
+	case protoreflect.FloatKind:
+		return &openAPISchema{Type: "number", Format: "float"}
+	case protoreflect.DoubleKind:
+		return &openAPISchema{Type: "number", Format: "double"}
+	case protoreflect.BytesKind:
+		return &openAPISchema{Type: "string", Format: "byte"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		schema := &openAPISchema{Type: "string"}
+		for i := range values.Len() {
+			schema.Enum = append(schema.Enum, string(values.Get(i).Name()))
+		}
+		return schema
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return ref(string(fd.Message().FullName()))
+	default:
+		return &openAPISchema{}
+	}
+}
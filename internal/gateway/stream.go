@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// serverStream is the grpc.ServerStream of a server-streaming call served
+// over HTTP. Its only request is the one built from the HTTP request, its
+// messages are written as they are sent: as {"result": ...} lines, or as the
+// data of Server-Sent Events.
+type serverStream struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	r       *http.Request
+	in      proto.Message
+	sse     bool
+	started bool
+	read    bool
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.r.Context()
+}
+
+func (s *serverStream) SetHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *serverStream) SendHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *serverStream) SetTrailer(metadata.MD) {}
+
+func (s *serverStream) RecvMsg(m any) error {
+	if s.read {
+		return io.EOF
+	}
+	s.read = true
+	proto.Merge(m.(proto.Message), s.in)
+	return nil
+}
+
+func (s *serverStream) SendMsg(m any) error {
+	data, err := protojson.Marshal(m.(proto.Message))
+	if err != nil {
+		return fmt.Errorf("gateway#SendMsg: %w", err)
+	}
+	if !s.started {
+		s.started = true
+		if s.sse {
+			s.w.Header().Set("Content-Type", contentTypeSSE)
+			s.w.Header().Set("Cache-Control", "no-cache")
+		} else {
+			s.w.Header().Set("Content-Type", contentTypeNDJSON)
+		}
+	}
+	if s.sse {
+		_, err = fmt.Fprintf(s.w, "data: %s\n\n", data)
+	} else {
+		_, err = fmt.Fprintf(s.w, "{\"result\":%s}\n", data)
+	}
+	if err != nil {
+		return fmt.Errorf("gateway#SendMsg: %w", err)
+	}
+	if err := s.rc.Flush(); err != nil {
+		return fmt.Errorf("gateway#SendMsg: %w", err)
+	}
+	return nil
+}
+
+// writeError ends the stream with err: as an HTTP error before any message,
+// as a last {"error": ...} line or error event after.
+func (s *serverStream) writeError(err error) {
+	if !s.started {
+		writeError(s.w, err)
+		return
+	}
+	data, _ := protojson.Marshal(status.Convert(err).Proto())
+	if s.sse {
+		_, _ = fmt.Fprintf(s.w, "event: error\ndata: %s\n\n", data)
+	} else {
+		_, _ = fmt.Fprintf(s.w, "{\"error\":%s}\n", data)
+	}
+	_ = s.rc.Flush()
+}
+
+// remoteAddr is the address of the HTTP client, as the peer of the calls.
+type remoteAddr string
+
+func (a remoteAddr) Network() string {
+	return "tcp"
+}
+
+func (a remoteAddr) String() string {
+	return string(a)
+}
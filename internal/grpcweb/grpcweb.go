@@ -0,0 +1,221 @@
+// Package grpcweb serves gRPC-Web requests, from browsers, with a gRPC
+// server served over HTTP, next to the native gRPC requests.
+//
+// gRPC-Web requests are translated into gRPC ones: the message frames are the
+// same, the trailers are sent as a last frame of the body instead of HTTP
+// trailers, which browsers can't read. Streams work over HTTP/1.1 and HTTP/2.
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	contentTypeGRPC    = "application/grpc"
+	contentTypeWeb     = "application/grpc-web"
+	contentTypeWebText = "application/grpc-web-text"
+
+	// trailerFrame flags the frame carrying the trailers.
+	trailerFrame    = 0x80
+	frameHeaderSize = 5
+
+	corsMaxAge = 10 * time.Minute
+)
+
+var (
+	// allowedHeaders are the request headers of gRPC-Web clients.
+	allowedHeaders = []string{
+		"authorization", "content-type", "grpc-timeout", "x-grpc-web", "x-user-agent",
+	}
+	exposedHeaders = []string{"grpc-status", "grpc-message", "grpc-status-details-bin"}
+)
+
+// Handler serves gRPC-Web requests, and native gRPC ones over HTTP/2, with
+// its gRPC server.
+type Handler struct {
+	grpcServer     http.Handler
+	allowedOrigins []string
+	allowedHeaders []string
+}
+
+type Option func(*Handler)
+
+// WithAllowedOrigins lets pages of origins call the server, "*" allowing any.
+// Without one only same origin pages can.
+func WithAllowedOrigins(origins ...string) Option {
+	return func(h *Handler) {
+		h.allowedOrigins = append(h.allowedOrigins, origins...)
+	}
+}
+
+// WithAllowedHeaders lets pages of the allowed origins send headers, like
+// custom metadata, on top of the ones of gRPC-Web.
+func WithAllowedHeaders(headers ...string) Option {
+	return func(h *Handler) {
+		for _, header := range headers {
+			h.allowedHeaders = append(h.allowedHeaders, strings.ToLower(header))
+		}
+	}
+}
+
+// New returns the handler of grpcServer, a *grpc.Server.
+func New(grpcServer http.Handler, opts ...Option) *Handler {
+	h := &Handler{grpcServer: grpcServer, allowedHeaders: slices.Clone(allowedHeaders)}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// IsGRPCWebRequest reports whether r is a gRPC-Web request.
+func IsGRPCWebRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeWeb)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case IsGRPCWebRequest(r):
+		h.setCORSHeaders(w, r)
+		h.serveGRPCWeb(w, r)
+	case r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "":
+		h.servePreflight(w, r)
+	case r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeGRPC):
+		h.grpcServer.ServeHTTP(w, r)
+	default:
+		http.Error(w, "sk8l serves gRPC and gRPC-Web requests", http.StatusUnsupportedMediaType)
+	}
+}
+
+// serveGRPCWeb hands r to the gRPC server as a gRPC request, and translates
+// its response back.
+func (h *Handler) serveGRPCWeb(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	text := strings.HasPrefix(contentType, contentTypeWebText)
+	subtype := strings.TrimPrefix(strings.TrimPrefix(contentType, contentTypeWebText), contentTypeWeb)
+
+	req := r.Clone(r.Context())
+	req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2"
+	req.Header.Set("Content-Type", contentTypeGRPC+subtype)
+	req.Header.Del("Content-Length")
+	req.ContentLength = -1
+	if text {
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{base64.NewDecoder(base64.StdEncoding, r.Body), r.Body}
+	}
+
+	rw := &responseWriter{w: w, header: http.Header{}, text: text, contentType: contentType}
+	h.grpcServer.ServeHTTP(rw, req)
+	rw.finish()
+}
+
+func (h *Handler) servePreflight(w http.ResponseWriter, r *http.Request) {
+	if !h.setCORSHeaders(w, r) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Methods", http.MethodPost)
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join(h.allowedHeaders, ", "))
+	w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(corsMaxAge.Seconds())))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setCORSHeaders lets the page of the origin of r read the response, and
+// reports whether it may.
+func (h *Handler) setCORSHeaders(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || !(slices.Contains(h.allowedOrigins, "*") || slices.Contains(h.allowedOrigins, origin)) {
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Set("Access-Control-Allow-Credentials", "true")
+	w.Header().Set("Access-Control-Expose-Headers", strings.Join(exposedHeaders, ", "))
+	w.Header().Add("Vary", "Origin")
+	return true
+}
+
+// responseWriter is the http.ResponseWriter of the gRPC server. The headers
+// set by the server before it writes the body are sent as headers, the
+// trailers it sets after are written as the last frame of the body.
+type responseWriter struct {
+	w             http.ResponseWriter
+	header        http.Header
+	contentType   string
+	text          bool
+	headerWritten bool
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.headerWritten {
+		return
+	}
+	rw.headerWritten = true
+	for name, values := range rw.header {
+		if name == "Trailer" || strings.HasPrefix(name, http.TrailerPrefix) {
+			continue
+		}
+		rw.w.Header()[name] = values
+	}
+	rw.w.Header().Set("Content-Type", rw.contentType)
+	rw.w.WriteHeader(code)
+	// The headers set from now on are the trailers.
+	rw.header = http.Header{}
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+	if !rw.text {
+		n, err := rw.w.Write(b)
+		if err != nil {
+			return n, fmt.Errorf("grpcweb#Write: %w", err)
+		}
+		return n, nil
+	}
+	if _, err := rw.w.Write([]byte(base64.StdEncoding.EncodeToString(b))); err != nil {
+		return 0, fmt.Errorf("grpcweb#Write: %w", err)
+	}
+	return len(b), nil
+}
+
+func (rw *responseWriter) Flush() {
+	rw.WriteHeader(http.StatusOK)
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish writes the trailer frame: the headers set by the server once the
+// body started, as lower case HTTP/1 headers.
+func (rw *responseWriter) finish() {
+	rw.WriteHeader(http.StatusOK)
+	trailers := &bytes.Buffer{}
+	for name, values := range rw.header {
+		if name == "Trailer" {
+			continue
+		}
+		name = strings.ToLower(strings.TrimPrefix(name, http.TrailerPrefix))
+		for _, value := range values {
+			fmt.Fprintf(trailers, "%s: %s\r\n", name, value)
+		}
+	}
+	frame := make([]byte, frameHeaderSize, frameHeaderSize+trailers.Len())
+	frame[0] = trailerFrame
+	binary.BigEndian.PutUint32(frame[1:], uint32(trailers.Len()))
+	frame = append(frame, trailers.Bytes()...)
+	_, _ = rw.Write(frame)
+	rw.Flush()
+}
@@ -0,0 +1,229 @@
+package grpcweb
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danroux/sk8l/protos"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+type testServer struct {
+	protos.UnimplementedCronjobServer
+}
+
+func (testServer) GetCronjobYAML(ctx context.Context, in *protos.CronjobRequest) (*protos.CronjobYAMLResponse, error) {
+	if in.GetCronjobName() == "missing" {
+		return nil, status.Error(codes.NotFound, "cronjob missing not found")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	return &protos.CronjobYAMLResponse{Cronjob: fmt.Sprintf("%s %v", in.GetCronjobName(), md.Get("authorization"))}, nil
+}
+
+func (testServer) GetPodLogs(in *protos.PodLogsRequest, stream protos.Cronjob_GetPodLogsServer) error {
+	for i := range in.GetTailLines() {
+		if err := stream.Send(&protos.PodLogsResponse{Line: fmt.Sprintf("line %d", i)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestHandler(t *testing.T, opts ...Option) *Handler {
+	t.Helper()
+	grpcS := grpc.NewServer()
+	protos.RegisterCronjobServer(grpcS, testServer{})
+	return New(grpcS, opts...)
+}
+
+func frame(t *testing.T, m proto.Message) []byte {
+	t.Helper()
+	data, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := make([]byte, frameHeaderSize)
+	binary.BigEndian.PutUint32(header[1:], uint32(len(data)))
+	return append(header, data...)
+}
+
+// readFrames returns the messages and the trailers of a gRPC-Web response body.
+func readFrames(t *testing.T, body []byte) ([][]byte, string) {
+	t.Helper()
+	var messages [][]byte
+	for len(body) >= frameHeaderSize {
+		size := binary.BigEndian.Uint32(body[1:frameHeaderSize])
+		data := body[frameHeaderSize : frameHeaderSize+size]
+		if body[0] == trailerFrame {
+			return messages, string(data)
+		}
+		messages = append(messages, data)
+		body = body[frameHeaderSize+size:]
+	}
+	t.Fatalf("missing trailer frame in %q", body)
+	return nil, ""
+}
+
+func post(t *testing.T, url, contentType string, body []byte, header http.Header) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header = header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, data
+}
+
+func TestHandler_GRPCWeb(t *testing.T) {
+	ts := httptest.NewServer(newTestHandler(t))
+	t.Cleanup(ts.Close)
+
+	resp, body := post(t, ts.URL+"/sk8l.Cronjob/GetCronjobYAML", "application/grpc-web+proto",
+		frame(t, &protos.CronjobRequest{CronjobName: "backup"}), http.Header{"Authorization": {"Bearer token"}})
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/grpc-web+proto" {
+		t.Fatalf("unexpected response %d %v", resp.StatusCode, resp.Header)
+	}
+	messages, trailers := readFrames(t, body)
+	if len(messages) != 1 {
+		t.Fatalf("expected one message, got %d", len(messages))
+	}
+	yaml := &protos.CronjobYAMLResponse{}
+	if err := proto.Unmarshal(messages[0], yaml); err != nil || yaml.GetCronjob() != "backup [Bearer token]" {
+		t.Errorf("unexpected message %v, %v", yaml, err)
+	}
+	if !strings.Contains(trailers, "grpc-status: 0\r\n") {
+		t.Errorf("expected an OK status trailer, got %q", trailers)
+	}
+
+	_, body = post(t, ts.URL+"/sk8l.Cronjob/GetCronjobYAML", "application/grpc-web",
+		frame(t, &protos.CronjobRequest{CronjobName: "missing"}), nil)
+	messages, trailers = readFrames(t, body)
+	if len(messages) != 0 || !strings.Contains(trailers, "grpc-status: 5\r\n") ||
+		!strings.Contains(trailers, "grpc-message: cronjob missing not found\r\n") {
+		t.Errorf("expected a NotFound trailer only, got %d messages and %q", len(messages), trailers)
+	}
+}
+
+func TestHandler_GRPCWebTextStream(t *testing.T) {
+	ts := httptest.NewServer(newTestHandler(t))
+	t.Cleanup(ts.Close)
+
+	request := base64.StdEncoding.EncodeToString(frame(t, &protos.PodLogsRequest{TailLines: 3}))
+	resp, body := post(t, ts.URL+"/sk8l.Cronjob/GetPodLogs", "application/grpc-web-text", []byte(request), nil)
+	if resp.Header.Get("Content-Type") != "application/grpc-web-text" {
+		t.Fatalf("unexpected response %d %v", resp.StatusCode, resp.Header)
+	}
+	// Every write is encoded on its own, with its own padding, but each
+	// group of 4 characters decodes on its own.
+	decoded := []byte{}
+	for i := 0; i+4 <= len(body); i += 4 {
+		data, err := base64.StdEncoding.DecodeString(string(body[i : i+4]))
+		if err != nil {
+			t.Fatalf("invalid base64 %q: %v", body[i:i+4], err)
+		}
+		decoded = append(decoded, data...)
+	}
+	messages, trailers := readFrames(t, decoded)
+	if len(messages) != 3 || !strings.Contains(trailers, "grpc-status: 0\r\n") {
+		t.Fatalf("expected 3 messages and an OK status, got %d and %q", len(messages), trailers)
+	}
+	line := &protos.PodLogsResponse{}
+	if err := proto.Unmarshal(messages[2], line); err != nil || line.GetLine() != "line 2" {
+		t.Errorf("unexpected message %v, %v", line, err)
+	}
+}
+
+func TestHandler_NativeGRPC(t *testing.T) {
+	ts := httptest.NewUnstartedServer(newTestHandler(t))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	conn, err := grpc.NewClient(
+		strings.TrimPrefix(ts.URL, "https://"),
+		grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(pool, "example.com")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	client := protos.NewCronjobClient(conn)
+
+	yaml, err := client.GetCronjobYAML(context.Background(), &protos.CronjobRequest{CronjobName: "backup"})
+	if err != nil || yaml.GetCronjob() != "backup []" {
+		t.Errorf("unexpected response %v, %v", yaml, err)
+	}
+	if _, err := client.GetCronjobYAML(context.Background(), &protos.CronjobRequest{CronjobName: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+}
+
+func TestHandler_CORS(t *testing.T) {
+	ts := httptest.NewServer(newTestHandler(t, WithAllowedOrigins("https://sk8l.example.com"), WithAllowedHeaders("Sk8l-Redaction")))
+	t.Cleanup(ts.Close)
+
+	preflight := func(origin string) *http.Response {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodOptions, ts.URL+"/sk8l.Cronjob/GetCronjobs", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := preflight("https://sk8l.example.com")
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Access-Control-Allow-Origin") != "https://sk8l.example.com" ||
+		!strings.Contains(resp.Header.Get("Access-Control-Allow-Headers"), "x-grpc-web") ||
+		!strings.Contains(resp.Header.Get("Access-Control-Allow-Headers"), "sk8l-redaction") {
+		t.Errorf("unexpected preflight response %d %v", resp.StatusCode, resp.Header)
+	}
+	if resp := preflight("https://evil.example.com"); resp.StatusCode != http.StatusForbidden || resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected other origins to be denied, got %d %v", resp.StatusCode, resp.Header)
+	}
+
+	resp, _ = post(t, ts.URL+"/sk8l.Cronjob/GetCronjobYAML", "application/grpc-web+proto",
+		frame(t, &protos.CronjobRequest{}), http.Header{"Origin": {"https://sk8l.example.com"}})
+	if resp.Header.Get("Access-Control-Allow-Origin") != "https://sk8l.example.com" ||
+		!strings.Contains(resp.Header.Get("Access-Control-Expose-Headers"), "grpc-status") {
+		t.Errorf("expected the response to be readable by the origin, got %v", resp.Header)
+	}
+
+	if resp, _ := post(t, ts.URL+"/sk8l.Cronjob/GetCronjobYAML", "application/json", nil, nil); resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("expected other requests to be rejected, got %d", resp.StatusCode)
+	}
+}
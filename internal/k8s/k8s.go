@@ -5,11 +5,14 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"io"
 
 	"github.com/rs/zerolog"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -17,12 +20,26 @@ import (
 
 type ClientInterface interface {
 	GetCronjob(ctx context.Context, cronjobNamespace, cronjobName string) (*batchv1.CronJob, error)
-	WatchCronjobs(ctx context.Context) (watch.Interface, error)
-	WatchJobs(ctx context.Context) (watch.Interface, error)
-	WatchPods(ctx context.Context) (watch.Interface, error)
+	ListCronjobs(ctx context.Context, opts metav1.ListOptions) (*batchv1.CronJobList, error)
+	ListJobs(ctx context.Context, opts metav1.ListOptions) (*batchv1.JobList, error)
+	ListPods(ctx context.Context, opts metav1.ListOptions) (*corev1.PodList, error)
+	WatchCronjobs(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	WatchJobs(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	WatchPods(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	ListEvents(ctx context.Context, opts metav1.ListOptions) (*corev1.EventList, error)
+	WatchEvents(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
 	GetPod(ctx context.Context, jobNamespace, podName string) (*corev1.Pod, error)
 	GetJob(ctx context.Context, jobNamespace, jobName string) (*batchv1.Job, error)
 	GetAllJobs(ctx context.Context) (*batchv1.JobList, error)
+	// StreamPodLogs opens the log stream of a pod container. The stream ends
+	// when ctx is cancelled, callers must close it.
+	StreamPodLogs(ctx context.Context, podNamespace, podName string, opts *corev1.PodLogOptions) (io.ReadCloser, error)
+	// ReviewToken asks the API server to authenticate a bearer token, e.g. a ServiceAccount token.
+	ReviewToken(ctx context.Context, token string, audiences []string) (*authenticationv1.TokenReviewStatus, error)
+	// CreateJob creates job, e.g. one triggered from a CronJob.
+	CreateJob(ctx context.Context, job *batchv1.Job) (*batchv1.Job, error)
+	// SuspendCronjob sets the suspend field of a CronJob spec.
+	SuspendCronjob(ctx context.Context, cronjobNamespace, cronjobName string, suspend bool) (*batchv1.CronJob, error)
 	Namespace() string
 }
 
@@ -107,8 +124,21 @@ func (kc *Client) GetCronjob(ctx context.Context, cronjobNamespace, cronjobName
 	return cronJob, nil
 }
 
-func (kc *Client) WatchCronjobs(ctx context.Context) (watch.Interface, error) {
-	watcher, err := kc.BatchV1().CronJobs(kc.namespace).Watch(ctx, metav1.ListOptions{})
+func (kc *Client) ListCronjobs(ctx context.Context, opts metav1.ListOptions) (*batchv1.CronJobList, error) {
+	list, err := kc.BatchV1().CronJobs(kc.namespace).List(ctx, opts)
+	if err != nil {
+		kc.l.Error().
+			Err(err).
+			Str("operation", "ListCronjobs").
+			Msg("failed to list CronJobs")
+		return nil, fmt.Errorf("failed to list CronJobs: %w", err)
+	}
+
+	return list, nil
+}
+
+func (kc *Client) WatchCronjobs(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	watcher, err := kc.BatchV1().CronJobs(kc.namespace).Watch(ctx, opts)
 	if err != nil {
 		kc.l.Error().
 			Err(err).
@@ -120,8 +150,21 @@ func (kc *Client) WatchCronjobs(ctx context.Context) (watch.Interface, error) {
 	return watcher, nil
 }
 
-func (kc *Client) WatchJobs(ctx context.Context) (watch.Interface, error) {
-	watcher, err := kc.BatchV1().Jobs(kc.namespace).Watch(ctx, metav1.ListOptions{})
+func (kc *Client) ListJobs(ctx context.Context, opts metav1.ListOptions) (*batchv1.JobList, error) {
+	list, err := kc.BatchV1().Jobs(kc.namespace).List(ctx, opts)
+	if err != nil {
+		kc.l.Error().
+			Err(err).
+			Str("operation", "ListJobs").
+			Msg("failed to list Jobs")
+		return nil, fmt.Errorf("failed to list Jobs: %w", err)
+	}
+
+	return list, nil
+}
+
+func (kc *Client) WatchJobs(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	watcher, err := kc.BatchV1().Jobs(kc.namespace).Watch(ctx, opts)
 	if err != nil {
 		kc.l.Error().
 			Err(err).
@@ -133,8 +176,21 @@ func (kc *Client) WatchJobs(ctx context.Context) (watch.Interface, error) {
 	return watcher, nil
 }
 
-func (kc *Client) WatchPods(ctx context.Context) (watch.Interface, error) {
-	watcher, err := kc.CoreV1().Pods(kc.namespace).Watch(ctx, metav1.ListOptions{})
+func (kc *Client) ListPods(ctx context.Context, opts metav1.ListOptions) (*corev1.PodList, error) {
+	list, err := kc.CoreV1().Pods(kc.namespace).List(ctx, opts)
+	if err != nil {
+		kc.l.Error().
+			Err(err).
+			Str("operation", "ListPods").
+			Msg("failed to list Pods")
+		return nil, fmt.Errorf("failed to list Pods: %w", err)
+	}
+
+	return list, nil
+}
+
+func (kc *Client) WatchPods(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	watcher, err := kc.CoreV1().Pods(kc.namespace).Watch(ctx, opts)
 	if err != nil {
 		kc.l.Error().
 			Err(err).
@@ -146,6 +202,32 @@ func (kc *Client) WatchPods(ctx context.Context) (watch.Interface, error) {
 	return watcher, nil
 }
 
+func (kc *Client) ListEvents(ctx context.Context, opts metav1.ListOptions) (*corev1.EventList, error) {
+	list, err := kc.CoreV1().Events(kc.namespace).List(ctx, opts)
+	if err != nil {
+		kc.l.Error().
+			Err(err).
+			Str("operation", "ListEvents").
+			Msg("failed to list Events")
+		return nil, fmt.Errorf("failed to list Events: %w", err)
+	}
+
+	return list, nil
+}
+
+func (kc *Client) WatchEvents(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	watcher, err := kc.CoreV1().Events(kc.namespace).Watch(ctx, opts)
+	if err != nil {
+		kc.l.Error().
+			Err(err).
+			Str("operation", "WatchEvents").
+			Msg("failed to start watching Events")
+		return nil, fmt.Errorf("failed to start watching Events: %w", err)
+	}
+
+	return watcher, nil
+}
+
 func (kc *Client) GetPod(ctx context.Context, jobNamespace, podName string) (*corev1.Pod, error) {
 	pod, err := kc.CoreV1().Pods(jobNamespace).Get(ctx, podName, metav1.GetOptions{})
 	if err != nil {
@@ -192,3 +274,67 @@ func (kc *Client) GetAllJobs(ctx context.Context) (*batchv1.JobList, error) {
 
 	return jobs, nil
 }
+
+func (kc *Client) StreamPodLogs(
+	ctx context.Context,
+	podNamespace, podName string,
+	opts *corev1.PodLogOptions,
+) (io.ReadCloser, error) {
+	stream, err := kc.CoreV1().Pods(podNamespace).GetLogs(podName, opts).Stream(ctx)
+	if err != nil {
+		kc.l.Error().
+			Err(err).
+			Str("operation", "StreamPodLogs").
+			Msg(fmt.Sprintf("failed to stream logs of Pod %s in namespace %s", podName, podNamespace))
+		return nil, fmt.Errorf("failed to stream logs of Pod %s in namespace %s: %w", podName, podNamespace, err)
+	}
+
+	return stream, nil
+}
+
+func (kc *Client) ReviewToken(ctx context.Context, token string, audiences []string) (*authenticationv1.TokenReviewStatus, error) {
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: audiences},
+	}
+	result, err := kc.AuthenticationV1().TokenReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		kc.l.Error().
+			Err(err).
+			Str("operation", "ReviewToken").
+			Msg("failed to create TokenReview")
+		return nil, fmt.Errorf("failed to create TokenReview: %w", err)
+	}
+
+	return &result.Status, nil
+}
+
+func (kc *Client) CreateJob(ctx context.Context, job *batchv1.Job) (*batchv1.Job, error) {
+	created, err := kc.BatchV1().Jobs(job.Namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		kc.l.Error().
+			Err(err).
+			Str("operation", "CreateJob").
+			Msg(fmt.Sprintf("failed to create Job %s in namespace %s", job.Name, job.Namespace))
+		return nil, fmt.Errorf("failed to create Job %s in namespace %s: %w", job.Name, job.Namespace, err)
+	}
+
+	return created, nil
+}
+
+func (kc *Client) SuspendCronjob(
+	ctx context.Context,
+	cronjobNamespace, cronjobName string,
+	suspend bool,
+) (*batchv1.CronJob, error) {
+	patch := fmt.Appendf(nil, `{"spec":{"suspend":%t}}`, suspend)
+	cronJob, err := kc.BatchV1().CronJobs(cronjobNamespace).Patch(ctx, cronjobName, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		kc.l.Error().
+			Err(err).
+			Str("operation", "SuspendCronjob").
+			Msg(fmt.Sprintf("failed to patch CronJob %s in namespace %s", cronjobName, cronjobNamespace))
+		return nil, fmt.Errorf("failed to patch CronJob %s in namespace %s: %w", cronjobName, cronjobNamespace, err)
+	}
+
+	return cronJob, nil
+}
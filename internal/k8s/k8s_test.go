@@ -2,6 +2,7 @@ package k8s
 
 import (
 	"context"
+	"io"
 	"testing"
 
 	batchv1 "k8s.io/api/batch/v1"
@@ -126,19 +127,19 @@ func TestWatchMethods(t *testing.T) {
 
 	ctx := context.Background()
 
-	cjWatcher, err := client.WatchCronjobs(ctx)
+	cjWatcher, err := client.WatchCronjobs(ctx, metav1.ListOptions{})
 	if err != nil {
 		t.Fatalf("WatchCronjobs failed: %v", err)
 	}
 	cjWatcher.Stop()
 
-	jobWatcher, err := client.WatchJobs(ctx)
+	jobWatcher, err := client.WatchJobs(ctx, metav1.ListOptions{})
 	if err != nil {
 		t.Fatalf("WatchJobs failed: %v", err)
 	}
 	jobWatcher.Stop()
 
-	podWatcher, err := client.WatchPods(ctx)
+	podWatcher, err := client.WatchPods(ctx, metav1.ListOptions{})
 	if err != nil {
 		t.Fatalf("WatchPods failed: %v", err)
 	}
@@ -152,3 +153,79 @@ func TestNamespace(t *testing.T) {
 		t.Errorf("expected custom-ns, got %s", client.Namespace())
 	}
 }
+
+func TestStreamPodLogs(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: "default",
+		},
+	}
+	client := NewClientWithInterface(fake.NewClientset(pod), WithNamespace("default"))
+
+	tailLines := int64(10)
+	stream, err := client.StreamPodLogs(context.Background(), "default", "test-pod", &corev1.PodLogOptions{TailLines: &tailLines})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer stream.Close()
+
+	logs, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("expected no error reading logs, got %v", err)
+	}
+	if string(logs) != "fake logs" {
+		t.Errorf("expected fake logs, got %q", logs)
+	}
+}
+
+func TestListAndWatchEvents(t *testing.T) {
+	event := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "cj-1.1", Namespace: "default"},
+		InvolvedObject: corev1.ObjectReference{Kind: "CronJob", Name: "cj-1", Namespace: "default"},
+		Reason:         "SuccessfulCreate",
+	}
+	client := NewClientWithInterface(fake.NewClientset(event), WithNamespace("default"))
+	ctx := context.Background()
+
+	events, err := client.ListEvents(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("ListEvents failed: %v", err)
+	}
+	if len(events.Items) != 1 || events.Items[0].Reason != "SuccessfulCreate" {
+		t.Errorf("expected the stored event, got %+v", events.Items)
+	}
+
+	watcher, err := client.WatchEvents(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("WatchEvents failed: %v", err)
+	}
+	watcher.Stop()
+}
+
+func TestCreateJobAndSuspendCronjob(t *testing.T) {
+	cronJob := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "test-cronjob", Namespace: "default"}}
+	client := NewClientWithInterface(fake.NewClientset(cronJob), WithNamespace("default"))
+	ctx := context.Background()
+
+	job, err := client.CreateJob(ctx, &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "test-job", Namespace: "default"}})
+	if err != nil || job.Name != "test-job" {
+		t.Fatalf("CreateJob failed: %v, %v", job, err)
+	}
+	if _, err := client.CreateJob(ctx, job); err == nil {
+		t.Error("expected creating an existing job to fail")
+	}
+
+	for _, suspend := range []bool{true, false} {
+		patched, err := client.SuspendCronjob(ctx, "default", "test-cronjob", suspend)
+		if err != nil {
+			t.Fatalf("SuspendCronjob failed: %v", err)
+		}
+		if patched.Spec.Suspend == nil || *patched.Spec.Suspend != suspend {
+			t.Errorf("expected suspend to be %t, got %v", suspend, patched.Spec.Suspend)
+		}
+	}
+	if _, err := client.SuspendCronjob(ctx, "default", "missing", true); err == nil {
+		t.Error("expected suspending a missing cronjob to fail")
+	}
+}
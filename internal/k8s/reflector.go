@@ -0,0 +1,373 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	// DefaultRetryDelay is the time waited before retrying a failed list or watch.
+	DefaultRetryDelay = 5 * time.Second
+	// DefaultWatchTimeout is how long the API server keeps a watch open.
+	DefaultWatchTimeout = 5 * time.Minute
+	minWatchDuration    = time.Second
+)
+
+var ErrWatchClosed = errors.New("watch closed immediately without delivering any event")
+
+// ListerWatcher lists and watches a single resource type.
+type ListerWatcher interface {
+	List(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+}
+
+// EventHandler receives the state tracked by a Reflector.
+type EventHandler interface {
+	// Replace is called with every object after each (re)list. Objects missing
+	// from objs no longer exist in the cluster.
+	Replace(objs []runtime.Object) error
+	// Handle is called for every Added, Modified and Deleted watch event. An
+	// error restarts the watch before the event, which is delivered again.
+	Handle(event watch.Event) error
+}
+
+// EventHandlerFuncs adapts plain functions to an EventHandler.
+type EventHandlerFuncs struct {
+	ReplaceFunc func(objs []runtime.Object) error
+	HandleFunc  func(event watch.Event) error
+}
+
+func (f EventHandlerFuncs) Replace(objs []runtime.Object) error {
+	if f.ReplaceFunc == nil {
+		return nil
+	}
+	return f.ReplaceFunc(objs)
+}
+
+func (f EventHandlerFuncs) Handle(event watch.Event) error {
+	if f.HandleFunc == nil {
+		return nil
+	}
+	return f.HandleFunc(event)
+}
+
+type listerWatcherFuncs struct {
+	listFn  func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error)
+	watchFn func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+}
+
+func (lw listerWatcherFuncs) List(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+	return lw.listFn(ctx, opts)
+}
+
+func (lw listerWatcherFuncs) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return lw.watchFn(ctx, opts)
+}
+
+func NewCronjobsListerWatcher(c ClientInterface) ListerWatcher {
+	return listerWatcherFuncs{
+		listFn: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return c.ListCronjobs(ctx, opts)
+		},
+		watchFn: c.WatchCronjobs,
+	}
+}
+
+func NewJobsListerWatcher(c ClientInterface) ListerWatcher {
+	return listerWatcherFuncs{
+		listFn: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return c.ListJobs(ctx, opts)
+		},
+		watchFn: c.WatchJobs,
+	}
+}
+
+func NewPodsListerWatcher(c ClientInterface) ListerWatcher {
+	return listerWatcherFuncs{
+		listFn: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return c.ListPods(ctx, opts)
+		},
+		watchFn: c.WatchPods,
+	}
+}
+
+func NewEventsListerWatcher(c ClientInterface) ListerWatcher {
+	return listerWatcherFuncs{
+		listFn: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return c.ListEvents(ctx, opts)
+		},
+		watchFn: c.WatchEvents,
+	}
+}
+
+// Reflector keeps an EventHandler in sync with the cluster: it lists once, then
+// watches from the list's resourceVersion, resuming from the last seen
+// resourceVersion (including bookmarks) when the watch closes, and relisting
+// only when the API server answers 410 Gone.
+type Reflector struct {
+	lw              ListerWatcher
+	handler         EventHandler
+	onSynced        func()
+	onError         func(error)
+	synced          chan struct{}
+	lastEventAt     time.Time
+	name            string
+	resourceVersion string
+	l               zerolog.Logger
+	retryDelay      time.Duration
+	watchTimeout    time.Duration
+	syncedOnce      sync.Once
+	mu              sync.RWMutex
+}
+
+type ReflectorOption func(*Reflector)
+
+func WithReflectorLogger(l zerolog.Logger) ReflectorOption {
+	return func(r *Reflector) {
+		r.l = l
+	}
+}
+
+// WithRetryDelay sets the time to wait before retrying a failed list or watch.
+func WithRetryDelay(d time.Duration) ReflectorOption {
+	return func(r *Reflector) {
+		r.retryDelay = d
+	}
+}
+
+// WithWatchTimeout sets how long the API server keeps each watch open before
+// it is started again from the last resourceVersion.
+func WithWatchTimeout(d time.Duration) ReflectorOption {
+	return func(r *Reflector) {
+		r.watchTimeout = d
+	}
+}
+
+// WithHealthHooks registers callbacks for health reporting. onSynced is called
+// after every successful list and watch, onError after every failed one.
+func WithHealthHooks(onSynced func(), onError func(error)) ReflectorOption {
+	return func(r *Reflector) {
+		r.onSynced = onSynced
+		r.onError = onError
+	}
+}
+
+func NewReflector(name string, lw ListerWatcher, handler EventHandler, options ...ReflectorOption) *Reflector {
+	r := &Reflector{
+		name:         name,
+		lw:           lw,
+		handler:      handler,
+		synced:       make(chan struct{}),
+		retryDelay:   DefaultRetryDelay,
+		watchTimeout: DefaultWatchTimeout,
+		l:            zerolog.Nop(),
+		onSynced:     func() {},
+		onError:      func(error) {},
+	}
+	for _, optionFn := range options {
+		optionFn(r)
+	}
+	return r
+}
+
+func (r *Reflector) Name() string {
+	return r.name
+}
+
+// Synced is closed once the first list has been handed to the EventHandler.
+func (r *Reflector) Synced() <-chan struct{} {
+	return r.synced
+}
+
+func (r *Reflector) HasSynced() bool {
+	select {
+	case <-r.synced:
+		return true
+	default:
+		return false
+	}
+}
+
+// LastSyncResourceVersion is the resourceVersion the next watch resumes from.
+func (r *Reflector) LastSyncResourceVersion() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.resourceVersion
+}
+
+// LastEventAt is the time the last list or watch event was handled.
+func (r *Reflector) LastEventAt() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lastEventAt
+}
+
+func (r *Reflector) setResourceVersion(rv string) {
+	r.mu.Lock()
+	r.resourceVersion = rv
+	r.lastEventAt = time.Now()
+	r.mu.Unlock()
+}
+
+// Run lists and watches until ctx is canceled.
+func (r *Reflector) Run(ctx context.Context) {
+	needsList := true
+	for {
+		if ctx.Err() != nil {
+			r.l.Info().
+				Str("operation", "Run").
+				Str("reflector", r.name).
+				Msg("context canceled, stopping reflector")
+			return
+		}
+
+		if needsList {
+			if err := r.list(ctx); err != nil {
+				r.fail(ctx, fmt.Errorf("%s: list failed: %w", r.name, err))
+				continue
+			}
+			needsList = false
+		}
+
+		gone, err := r.watch(ctx)
+		switch {
+		case gone:
+			r.l.Info().
+				Str("operation", "Run").
+				Str("reflector", r.name).
+				Msg("resourceVersion expired, relisting")
+			needsList = true
+		case err != nil:
+			r.fail(ctx, fmt.Errorf("%s: watch failed: %w", r.name, err))
+		}
+	}
+}
+
+func (r *Reflector) fail(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	r.l.Error().
+		Err(err).
+		Str("operation", "Run").
+		Str("reflector", r.name).
+		Msg(fmt.Sprintf("retrying in %s", r.retryDelay))
+	r.onError(err)
+	select {
+	case <-ctx.Done():
+	case <-time.After(r.retryDelay):
+	}
+}
+
+func (r *Reflector) list(ctx context.Context) error {
+	listObj, err := r.lw.List(ctx, metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		return fmt.Errorf("lw.List() failed: %w", err)
+	}
+	listMeta, err := meta.ListAccessor(listObj)
+	if err != nil {
+		return fmt.Errorf("meta.ListAccessor() failed: %w", err)
+	}
+	items, err := meta.ExtractList(listObj)
+	if err != nil {
+		return fmt.Errorf("meta.ExtractList() failed: %w", err)
+	}
+	if err := r.handler.Replace(items); err != nil {
+		return fmt.Errorf("handler.Replace() failed: %w", err)
+	}
+
+	r.setResourceVersion(listMeta.GetResourceVersion())
+	r.syncedOnce.Do(func() { close(r.synced) })
+	r.onSynced()
+	r.l.Info().
+		Str("operation", "list").
+		Str("reflector", r.name).
+		Msg(fmt.Sprintf("synced %d objects at resourceVersion %q", len(items), listMeta.GetResourceVersion()))
+	return nil
+}
+
+// watch consumes one watch and reports whether the resourceVersion expired and a relist is needed.
+func (r *Reflector) watch(ctx context.Context) (bool, error) {
+	timeout := int64(r.watchTimeout.Seconds())
+	w, err := r.lw.Watch(ctx, metav1.ListOptions{
+		ResourceVersion:     r.LastSyncResourceVersion(),
+		AllowWatchBookmarks: true,
+		TimeoutSeconds:      &timeout,
+	})
+	if err != nil {
+		if isExpired(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("lw.Watch() failed: %w", err)
+	}
+	defer w.Stop()
+	r.onSynced()
+
+	startedAt := time.Now()
+	received := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case event, more := <-w.ResultChan():
+			if !more {
+				// An immediately closed watch would otherwise be reopened in a tight loop.
+				if received == 0 && time.Since(startedAt) < minWatchDuration {
+					return false, ErrWatchClosed
+				}
+				return false, nil
+			}
+			received++
+
+			gone, err := r.handleEvent(event)
+			if gone || err != nil {
+				return gone, err
+			}
+		}
+	}
+}
+
+func (r *Reflector) handleEvent(event watch.Event) (bool, error) {
+	//revive:disable:identical-switch-branches
+	switch event.Type {
+	case watch.Error:
+		err := apierrors.FromObject(event.Object)
+		if isExpired(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("watch error event: %w", err)
+	case watch.Bookmark:
+		// bookmarks only move the resourceVersion forward
+	case watch.Added, watch.Modified, watch.Deleted:
+		// The resourceVersion stays at the last applied event, the next watch delivers this one again.
+		if err := r.handler.Handle(event); err != nil {
+			return false, fmt.Errorf("handler.Handle() failed for %s event: %w", event.Type, err)
+		}
+	default:
+		// default case to satisfy revive
+	}
+	//revive:enable:identical-switch-branches
+
+	accessor, err := meta.Accessor(event.Object)
+	if err != nil {
+		return false, nil
+	}
+	if rv := accessor.GetResourceVersion(); rv != "" {
+		r.setResourceVersion(rv)
+	}
+	return false, nil
+}
+
+func isExpired(err error) bool {
+	return apierrors.IsResourceExpired(err) || apierrors.IsGone(err)
+}
@@ -0,0 +1,264 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type fakeListerWatcher struct {
+	watchers   chan *watch.FakeWatcher
+	listRVs    []string
+	watchRVs   []string
+	listResult string
+	mu         sync.Mutex
+}
+
+func (f *fakeListerWatcher) List(_ context.Context, _ metav1.ListOptions) (runtime.Object, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listRVs = append(f.listRVs, f.listResult)
+	return &batchv1.JobList{
+		ListMeta: metav1.ListMeta{ResourceVersion: f.listResult},
+		Items:    []batchv1.Job{{ObjectMeta: metav1.ObjectMeta{Name: "listed", ResourceVersion: f.listResult}}},
+	}, nil
+}
+
+func (f *fakeListerWatcher) Watch(_ context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	f.mu.Lock()
+	f.watchRVs = append(f.watchRVs, opts.ResourceVersion)
+	f.mu.Unlock()
+	return <-f.watchers, nil
+}
+
+func (f *fakeListerWatcher) calls() (lists []string, watches []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.listRVs...), append([]string{}, f.watchRVs...)
+}
+
+var errHandle = errors.New("handle failed")
+
+type recordingHandler struct {
+	replaced [][]runtime.Object
+	events   []watch.Event
+	// failures is the number of Handle calls left to fail.
+	failures int
+	mu       sync.Mutex
+}
+
+func (h *recordingHandler) Replace(objs []runtime.Object) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.replaced = append(h.replaced, objs)
+	return nil
+}
+
+func (h *recordingHandler) Handle(event watch.Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.failures > 0 {
+		h.failures--
+		return errHandle
+	}
+	h.events = append(h.events, event)
+	return nil
+}
+
+func (h *recordingHandler) counts() (replaced int, events int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.replaced), len(h.events)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func jobWithRV(name, rv string) *batchv1.Job {
+	return &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: rv}}
+}
+
+func TestReflector_ListThenWatchResumesFromResourceVersion(t *testing.T) {
+	lw := &fakeListerWatcher{watchers: make(chan *watch.FakeWatcher, 2), listResult: "10"}
+	first := watch.NewFakeWithChanSize(3, false)
+	second := watch.NewFake()
+	lw.watchers <- first
+	lw.watchers <- second
+
+	handler := &recordingHandler{}
+	r := NewReflector("jobs", lw, handler, WithRetryDelay(time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+
+	select {
+	case <-r.Synced():
+	case <-time.After(5 * time.Second):
+		t.Fatal("reflector never synced")
+	}
+
+	first.Add(jobWithRV("job-1", "11"))
+	first.Action(watch.Bookmark, jobWithRV("", "15"))
+	first.Stop()
+
+	waitFor(t, func() bool {
+		_, watches := lw.calls()
+		return len(watches) == 2
+	})
+
+	lists, watches := lw.calls()
+	if len(lists) != 1 {
+		t.Errorf("expected a single list, got %d", len(lists))
+	}
+	if watches[0] != "10" {
+		t.Errorf("expected first watch from list resourceVersion 10, got %q", watches[0])
+	}
+	if watches[1] != "15" {
+		t.Errorf("expected watch to resume from bookmark resourceVersion 15, got %q", watches[1])
+	}
+
+	replaced, events := handler.counts()
+	if replaced != 1 || events != 1 {
+		t.Errorf("expected 1 replace and 1 event, got %d replaces and %d events", replaced, events)
+	}
+}
+
+func TestReflector_RelistsOnGone(t *testing.T) {
+	lw := &fakeListerWatcher{watchers: make(chan *watch.FakeWatcher, 2), listResult: "10"}
+	first := watch.NewFakeWithChanSize(1, false)
+	lw.watchers <- first
+	lw.watchers <- watch.NewFake()
+
+	handler := &recordingHandler{}
+	r := NewReflector("jobs", lw, handler, WithRetryDelay(time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+	<-r.Synced()
+
+	gone := apierrors.NewResourceExpired("too old resource version")
+	first.Error(&gone.ErrStatus)
+
+	waitFor(t, func() bool {
+		replaced, _ := handler.counts()
+		return replaced == 2
+	})
+
+	lists, _ := lw.calls()
+	if len(lists) != 2 {
+		t.Errorf("expected a relist after 410 Gone, got %d lists", len(lists))
+	}
+}
+
+func TestReflector_RewatchesAfterHandleFailure(t *testing.T) {
+	lw := &fakeListerWatcher{watchers: make(chan *watch.FakeWatcher, 2), listResult: "10"}
+	first := watch.NewFakeWithChanSize(1, false)
+	second := watch.NewFakeWithChanSize(1, false)
+	lw.watchers <- first
+	lw.watchers <- second
+
+	handler := &recordingHandler{failures: 1}
+	r := NewReflector("jobs", lw, handler, WithRetryDelay(time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+	<-r.Synced()
+
+	first.Add(jobWithRV("job-1", "11"))
+	waitFor(t, func() bool {
+		_, watches := lw.calls()
+		return len(watches) == 2
+	})
+	second.Add(jobWithRV("job-1", "11"))
+	// The resourceVersion moves to 11 once the event is applied.
+	waitFor(t, func() bool { return r.LastSyncResourceVersion() == "11" })
+
+	_, watches := lw.calls()
+	if watches[1] != "10" {
+		t.Errorf("expected the watch to resume before the failed event, got %q", watches[1])
+	}
+	if _, events := handler.counts(); events != 1 {
+		t.Errorf("expected the event to be applied once, got %d", events)
+	}
+}
+
+func TestReflector_HealthHooks(t *testing.T) {
+	lw := &fakeListerWatcher{watchers: make(chan *watch.FakeWatcher, 1), listResult: "1"}
+	lw.watchers <- watch.NewFake()
+
+	var mu sync.Mutex
+	synced := 0
+	r := NewReflector("jobs", lw, &recordingHandler{},
+		WithHealthHooks(func() {
+			mu.Lock()
+			synced++
+			mu.Unlock()
+		}, func(error) {}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return synced >= 2
+	})
+	if !r.HasSynced() {
+		t.Error("expected HasSynced to be true")
+	}
+	if r.LastSyncResourceVersion() != "1" {
+		t.Errorf("expected resourceVersion 1, got %q", r.LastSyncResourceVersion())
+	}
+}
+
+func TestReflector_WithClientset(t *testing.T) {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "job-1", Namespace: "default"}}
+	clientSet := fake.NewClientset(job)
+	client := NewClientWithInterface(clientSet, WithNamespace("default"))
+
+	handler := &recordingHandler{}
+	r := NewReflector("jobs", NewJobsListerWatcher(client), handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+	<-r.Synced()
+
+	handler.mu.Lock()
+	listed := handler.replaced[0]
+	handler.mu.Unlock()
+	if len(listed) != 1 {
+		t.Fatalf("expected 1 listed job, got %d", len(listed))
+	}
+
+	_, err := clientSet.BatchV1().Jobs("default").Create(ctx, jobWithRV("job-2", ""), metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	waitFor(t, func() bool {
+		_, events := handler.counts()
+		return events > 0
+	})
+}
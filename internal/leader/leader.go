@@ -0,0 +1,142 @@
+// Package leader elects a single replica of sk8l, the one holding a
+// Kubernetes Lease, to run the duties that must not run once per replica.
+package leader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	// DefaultLeaseDuration is how long followers wait before taking over a Lease that isn't renewed.
+	DefaultLeaseDuration = 15 * time.Second
+	// DefaultRenewDeadline is how long the leader keeps retrying to renew the Lease before giving up.
+	DefaultRenewDeadline = 10 * time.Second
+	// DefaultRetryPeriod is how often the Lease is acquired or renewed.
+	DefaultRetryPeriod = 2 * time.Second
+)
+
+// A Duty runs while this replica is the leader, until ctx is canceled.
+type Duty func(ctx context.Context)
+
+// Elector campaigns for a Lease against the other replicas.
+type Elector struct {
+	config  leaderelection.LeaderElectionConfig
+	l       zerolog.Logger
+	leading atomic.Bool
+	leader  string
+	mu      sync.RWMutex
+}
+
+type Option func(*Elector)
+
+// WithTimings sets the lease duration, renew deadline and retry period of the election.
+func WithTimings(leaseDuration, renewDeadline, retryPeriod time.Duration) Option {
+	return func(e *Elector) {
+		e.config.LeaseDuration = leaseDuration
+		e.config.RenewDeadline = renewDeadline
+		e.config.RetryPeriod = retryPeriod
+	}
+}
+
+func WithLogger(l zerolog.Logger) Option {
+	return func(e *Elector) {
+		e.l = l
+	}
+}
+
+// NewElector returns an Elector campaigning as identity for the Lease name in namespace.
+func NewElector(client kubernetes.Interface, namespace, name, identity string, opts ...Option) (*Elector, error) {
+	e := &Elector{
+		config: leaderelection.LeaderElectionConfig{
+			Lock: &resourcelock.LeaseLock{
+				LeaseMeta:  metav1.ObjectMeta{Namespace: namespace, Name: name},
+				Client:     client.CoordinationV1(),
+				LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+			},
+			LeaseDuration: DefaultLeaseDuration,
+			RenewDeadline: DefaultRenewDeadline,
+			RetryPeriod:   DefaultRetryPeriod,
+			// Lets a follower take over right away when the leader shuts down.
+			ReleaseOnCancel: true,
+			Name:            name,
+		},
+		l: zerolog.Nop(),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	// Validates the configuration, Run builds a new elector per term.
+	config := e.config
+	config.Callbacks = e.callbacks(nil)
+	if _, err := leaderelection.NewLeaderElector(config); err != nil {
+		return nil, fmt.Errorf("leader#NewElector: %w", err)
+	}
+	return e, nil
+}
+
+// Run campaigns for the Lease until ctx is canceled. Every time this replica
+// becomes the leader it starts duties, with a context canceled as soon as it
+// loses the Lease. The Lease is released once ctx is canceled.
+func (e *Elector) Run(ctx context.Context, duties ...Duty) {
+	config := e.config
+	config.Callbacks = e.callbacks(duties)
+	for ctx.Err() == nil {
+		le, err := leaderelection.NewLeaderElector(config)
+		if err != nil {
+			e.l.Error().Err(err).Str("operation", "Run").Msg("leaderelection.NewLeaderElector")
+			return
+		}
+		// Returns once the Lease is lost, campaign again for the next term.
+		le.Run(ctx)
+	}
+}
+
+func (e *Elector) callbacks(duties []Duty) leaderelection.LeaderCallbacks {
+	return leaderelection.LeaderCallbacks{
+		OnStartedLeading: func(ctx context.Context) {
+			e.leading.Store(true)
+			e.l.Info().Str("operation", "Run").Msg("started leading, running the singleton duties")
+			for _, duty := range duties {
+				go duty(ctx)
+			}
+		},
+		OnStoppedLeading: func() {
+			if e.leading.Swap(false) {
+				e.l.Info().Str("operation", "Run").Msg("stopped leading")
+			}
+		},
+		OnNewLeader: func(identity string) {
+			e.mu.Lock()
+			e.leader = identity
+			e.mu.Unlock()
+			e.l.Info().Str("operation", "Run").Msg(fmt.Sprintf("%s is the leader", identity))
+		},
+	}
+}
+
+// IsLeader reports whether this replica holds the Lease.
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Leader returns the identity of the current leader, empty until one is observed.
+func (e *Elector) Leader() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Identity returns the identity this replica campaigns as.
+func (e *Elector) Identity() string {
+	return e.config.Lock.Identity()
+}
@@ -0,0 +1,90 @@
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	testLeaseDuration = time.Second
+	testRenewDeadline = 500 * time.Millisecond
+	testRetryPeriod   = 100 * time.Millisecond
+)
+
+func newTestElector(t *testing.T, clientset *fake.Clientset, identity string) *Elector {
+	t.Helper()
+	e, err := NewElector(clientset, "default", "sk8l-api", identity, WithTimings(testLeaseDuration, testRenewDeadline, testRetryPeriod))
+	if err != nil {
+		t.Fatalf("NewElector failed: %v", err)
+	}
+	return e
+}
+
+func eventually(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestElector(t *testing.T) {
+	clientset := fake.NewClientset()
+	a, b := newTestElector(t, clientset, "sk8l-api-a"), newTestElector(t, clientset, "sk8l-api-b")
+
+	var aDuties, bDuties atomic.Int32
+	aStopped := make(chan struct{})
+	aCtx, aCancel := context.WithCancel(t.Context())
+	aDone := make(chan struct{})
+	go func() {
+		defer close(aDone)
+		a.Run(aCtx, func(ctx context.Context) {
+			aDuties.Add(1)
+			<-ctx.Done()
+			close(aStopped)
+		})
+	}()
+	eventually(t, "a to lead", a.IsLeader)
+
+	go b.Run(t.Context(), func(context.Context) { bDuties.Add(1) })
+	eventually(t, "b to observe a", func() bool { return b.Leader() == "sk8l-api-a" })
+	// b keeps retrying while a renews the Lease.
+	time.Sleep(2 * testLeaseDuration)
+	if b.IsLeader() || bDuties.Load() != 0 {
+		t.Fatal("expected a single leader")
+	}
+	if aDuties.Load() != 1 {
+		t.Errorf("expected the duties of a to run once, got %d", aDuties.Load())
+	}
+
+	aCancel()
+	<-aDone
+	select {
+	case <-aStopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the duties of a to stop with its term")
+	}
+	if a.IsLeader() {
+		t.Error("expected a to stop leading")
+	}
+	// a released the Lease, b takes over without waiting for it to expire.
+	eventually(t, "b to lead", b.IsLeader)
+	eventually(t, "the duties of b to run", func() bool { return bDuties.Load() == 1 })
+	if b.Leader() != "sk8l-api-b" || b.Identity() != "sk8l-api-b" {
+		t.Errorf("expected b to be the leader, got %s", b.Leader())
+	}
+}
+
+func TestNewElector_InvalidTimings(t *testing.T) {
+	_, err := NewElector(fake.NewClientset(), "default", "sk8l-api", "sk8l-api-a", WithTimings(time.Second, 2*time.Second, time.Second))
+	if err == nil {
+		t.Fatal("expected a renew deadline longer than the lease duration to be rejected")
+	}
+}
@@ -1,54 +1,255 @@
 // Package logger provides zerolog configuration and the Badger logger adapter.
+// The loggers of the components returned by For have their own level, which
+// can be changed at runtime with SetLevel, and their debug and info logs can
+// be sampled.
 package logger
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
+// Components with a level of their own. Default is the level of the logs of
+// no component.
+const (
+	Default = "default"
+	Watcher = "watcher"
+	Store   = "store"
+	Metrics = "metrics"
+	GRPC    = "grpc"
+	Badger  = "badger"
+)
+
+// Formats of the logs.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+var (
+	ErrUnknownComponent = errors.New("unknown log component")
+	ErrUnknownFormat    = errors.New("unknown log format")
+	ErrInvalidLevel     = errors.New("invalid log level")
+
+	// Components lists the components of For.
+	Components = []string{Watcher, Store, Metrics, GRPC, Badger}
+
+	// levels holds the level of Default and of every component, it is only
+	// read once built so that the hooks don't lock.
+	levels = func() map[string]*atomic.Int32 {
+		m := map[string]*atomic.Int32{Default: {}}
+		for _, component := range Components {
+			m[component] = &atomic.Int32{}
+		}
+		return m
+	}()
+	samplers atomic.Pointer[map[string]*zerolog.BurstSampler]
+
+	// output is the writer of every logger, Configure switches the format of
+	// the loggers created before it.
+	output = &switchWriter{}
+	base   = zerolog.New(output).With().Timestamp().Logger()
+)
+
+func init() {
+	var w io.Writer = os.Stderr
+	output.w.Store(&w)
+	for _, level := range levels {
+		level.Store(int32(zerolog.InfoLevel))
+	}
+}
+
+// Settings configures the logs.
+type Settings struct {
+	Format string
+	// Level is the level of the components missing from Levels.
+	Level  string
+	Levels map[string]string
+	// SampleBurst is the number of debug and info logs each component writes
+	// every SamplePeriod, all of them when 0.
+	SampleBurst  int
+	SamplePeriod time.Duration
+}
+
+// SetupZeroLog sets up the JSON logs written until Configure is called.
 func SetupZeroLog() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	zerolog.TimestampFieldName = "t"
 	zerolog.LevelFieldName = "l"
 	zerolog.MessageFieldName = "m"
-	log.Info().Msg(fmt.Sprintf("log_level %d", zerolog.GlobalLevel()))
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	log.Info().Msg(fmt.Sprintf("log_level %d", zerolog.GlobalLevel()))
+	log.Logger = base.Hook(levelHook{component: Default})
+	updateGlobalLevel()
 }
 
+// Configure applies s to every logger, including those created before.
+func Configure(s Settings) error {
+	var w io.Writer
+	switch s.Format {
+	case FormatJSON:
+		w = os.Stderr
+	case FormatConsole:
+		w = zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}
+	default:
+		return fmt.Errorf("logger#Configure: %w %q", ErrUnknownFormat, s.Format)
+	}
+	parsed := map[string]zerolog.Level{}
+	for component := range levels {
+		level, err := ParseLevel(s.Level)
+		if l, ok := s.Levels[component]; ok {
+			level, err = ParseLevel(l)
+		}
+		if err != nil {
+			return fmt.Errorf("logger#Configure: %s: %w", component, err)
+		}
+		parsed[component] = level
+	}
+	for component := range s.Levels {
+		if _, ok := levels[component]; !ok {
+			return fmt.Errorf("logger#Configure: %w %q", ErrUnknownComponent, component)
+		}
+	}
+
+	output.w.Store(&w)
+	for component, level := range parsed {
+		levels[component].Store(int32(level))
+	}
+	updateGlobalLevel()
+	if s.SampleBurst <= 0 {
+		samplers.Store(nil)
+		return nil
+	}
+	burst := uint32(min(s.SampleBurst, math.MaxUint32))
+	sampled := map[string]*zerolog.BurstSampler{}
+	for _, component := range Components {
+		sampled[component] = &zerolog.BurstSampler{Burst: burst, Period: s.SamplePeriod}
+	}
+	samplers.Store(&sampled)
+	return nil
+}
+
+// For returns the logger of component.
+func For(component string) zerolog.Logger {
+	return base.With().Str("component", component).Logger().Hook(levelHook{component: component})
+}
+
+// ParseLevel parses the name of a level, like debug or info.
+func ParseLevel(s string) (zerolog.Level, error) {
+	level, err := zerolog.ParseLevel(s)
+	if err != nil || s == "" || level == zerolog.NoLevel {
+		return zerolog.NoLevel, fmt.Errorf("%w %q", ErrInvalidLevel, s)
+	}
+	return level, nil
+}
+
+// SetLevel changes the level of component, Default included.
+func SetLevel(component, level string) error {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("logger#SetLevel: %w", err)
+	}
+	current, ok := levels[component]
+	if !ok {
+		return fmt.Errorf("logger#SetLevel: %w %q", ErrUnknownComponent, component)
+	}
+	current.Store(int32(l))
+	updateGlobalLevel()
+	return nil
+}
+
+// Levels returns the level of Default and of every component.
+func Levels() map[string]string {
+	m := make(map[string]string, len(levels))
+	for component, level := range levels {
+		m[component] = zerolog.Level(level.Load()).String()
+	}
+	return m
+}
+
+// updateGlobalLevel lowers the global level to the lowest level of all, the
+// hooks drop the logs below the level of their component.
+func updateGlobalLevel() {
+	lowest := zerolog.Disabled
+	for _, level := range levels {
+		lowest = min(lowest, zerolog.Level(level.Load()))
+	}
+	zerolog.SetGlobalLevel(lowest)
+}
+
+// levelHook discards the logs below the level of its component, and the
+// debug and info logs of components that exceed their sample.
+type levelHook struct {
+	component string
+}
+
+func (h levelHook) Run(e *zerolog.Event, level zerolog.Level, _ string) {
+	current, ok := levels[h.component]
+	if !ok {
+		current = levels[Default]
+	}
+	if level < zerolog.Level(current.Load()) {
+		e.Discard()
+		return
+	}
+	if level > zerolog.InfoLevel {
+		return
+	}
+	if sampled := samplers.Load(); sampled != nil {
+		if s, ok := (*sampled)[h.component]; ok && !s.Sample(level) {
+			e.Discard()
+		}
+	}
+}
+
+// switchWriter writes to a writer that can be replaced while it's used.
+type switchWriter struct {
+	w atomic.Pointer[io.Writer]
+}
+
+func (s *switchWriter) Write(p []byte) (int, error) {
+	n, err := (*s.w.Load()).Write(p)
+	if err != nil {
+		return n, fmt.Errorf("logger#Write: %w", err)
+	}
+	return n, nil
+}
+
+// StandardZeroLogger adapts the Badger component logger to badger.Logger.
 type StandardZeroLogger struct {
 	zerolog.Logger
 }
 
-func NewBadgerLogger(level zerolog.Level) *StandardZeroLogger {
-	x := log.With().Str("component", "badger").Logger().Level(level)
+func NewBadgerLogger() *StandardZeroLogger {
 	return &StandardZeroLogger{
-		Logger: x,
+		Logger: For(Badger),
 	}
 }
 
 func (l *StandardZeroLogger) Errorf(f string, v ...any) {
-	if l.GetLevel() <= zerolog.ErrorLevel {
-		l.Printf("ERROR: "+f, v...)
-	}
+	l.Error().Msg(badgerMessage(f, v))
 }
 
 func (l *StandardZeroLogger) Warningf(f string, v ...any) {
-	if l.GetLevel() <= zerolog.WarnLevel {
-		l.Printf("WARNING: "+f, v...)
-	}
+	l.Warn().Msg(badgerMessage(f, v))
 }
 
 func (l *StandardZeroLogger) Infof(f string, v ...any) {
-	if l.GetLevel() <= zerolog.InfoLevel {
-		l.Printf("INFO: "+f, v...)
-	}
+	l.Info().Msg(badgerMessage(f, v))
 }
 
 func (l *StandardZeroLogger) Debugf(f string, v ...any) {
-	if l.GetLevel() <= zerolog.DebugLevel {
-		l.Printf("DEBUG: "+f, v...)
-	}
+	l.Debug().Msg(badgerMessage(f, v))
+}
+
+// badgerMessage formats a Badger log, which ends with a newline.
+func badgerMessage(f string, v []any) string {
+	return strings.TrimSpace(fmt.Sprintf(f, v...))
 }
@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// capture configures the logs with s and returns what they write.
+func capture(t *testing.T, s Settings) *bytes.Buffer {
+	t.Helper()
+	if err := Configure(s); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	var w io.Writer = buf
+	output.w.Store(&w)
+	t.Cleanup(func() {
+		if err := Configure(Settings{Format: FormatJSON, Level: "info"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return buf
+}
+
+func TestConfigure(t *testing.T) {
+	for name, tc := range map[string]struct {
+		settings Settings
+		err      error
+	}{
+		"format":    {Settings{Format: "xml", Level: "info"}, ErrUnknownFormat},
+		"level":     {Settings{Format: FormatJSON, Level: "loud"}, ErrInvalidLevel},
+		"no level":  {Settings{Format: FormatJSON}, ErrInvalidLevel},
+		"component": {Settings{Format: FormatJSON, Level: "info", Levels: map[string]string{"etcd": "info"}}, ErrUnknownComponent},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := Configure(tc.settings); !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestComponentLevels(t *testing.T) {
+	buf := capture(t, Settings{Format: FormatJSON, Level: "warn", Levels: map[string]string{Store: "debug"}})
+	store, watcher := For(Store), For(Watcher)
+	store.Debug().Msg("store debug")
+	watcher.Info().Msg("watcher info")
+	watcher.Warn().Msg("watcher warn")
+	for _, expected := range []string{`"component":"store"`, "store debug", "watcher warn"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %q in %s", expected, buf)
+		}
+	}
+	if strings.Contains(buf.String(), "watcher info") {
+		t.Errorf("expected the info log of the watcher to be dropped, got %s", buf)
+	}
+
+	if err := SetLevel(Watcher, "info"); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Info().Msg("watcher info")
+	if !strings.Contains(buf.String(), "watcher info") {
+		t.Errorf("expected the info log of the watcher after SetLevel, got %s", buf)
+	}
+	if got := Levels(); got[Watcher] != "info" || got[Store] != "debug" || got[Default] != "warn" || got[Badger] != "warn" {
+		t.Errorf("unexpected levels %v", got)
+	}
+	if err := SetLevel("etcd", "info"); !errors.Is(err, ErrUnknownComponent) {
+		t.Errorf("expected ErrUnknownComponent, got %v", err)
+	}
+	if err := SetLevel(Store, ""); !errors.Is(err, ErrInvalidLevel) {
+		t.Errorf("expected ErrInvalidLevel, got %v", err)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	buf := capture(t, Settings{Format: FormatJSON, Level: "info", SampleBurst: 2, SamplePeriod: time.Hour})
+	l := For(GRPC)
+	for range 5 {
+		l.Info().Msg("call finished")
+	}
+	l.Error().Msg("call failed")
+	if got := strings.Count(buf.String(), "call finished"); got != 2 {
+		t.Errorf("expected 2 sampled info logs, got %d", got)
+	}
+	if !strings.Contains(buf.String(), "call failed") {
+		t.Errorf("expected errors not to be sampled, got %s", buf)
+	}
+}
+
+func TestBadgerLogger(t *testing.T) {
+	buf := capture(t, Settings{Format: FormatJSON, Level: "info", Levels: map[string]string{Badger: "warn"}})
+	l := NewBadgerLogger()
+	l.Infof("All %d tables opened\n", 3)
+	l.Warningf("Value log %s is corrupt\n", "000001.vlog")
+	if strings.Contains(buf.String(), "tables opened") || !strings.Contains(buf.String(), `"Value log 000001.vlog is corrupt"`) {
+		t.Errorf("expected only the warning, trimmed, got %s", buf)
+	}
+}
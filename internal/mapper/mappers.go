@@ -414,3 +414,26 @@ func MapCustomJobStatus(s batchv1.JobStatus) *protos.JobStatus {
 		Ready:                   ready,
 	}
 }
+
+func MapEvent(e *corev1.Event) *protos.EventResponse {
+	source := e.Source.Component
+	if source == "" {
+		source = e.ReportingController
+	}
+	lastTimestamp := TimeToString(&e.LastTimestamp)
+	if lastTimestamp == "" && !e.EventTime.IsZero() {
+		lastTimestamp = e.EventTime.UTC().Format(time.RFC3339)
+	}
+	return &protos.EventResponse{
+		Type:               e.Type,
+		Reason:             e.Reason,
+		Message:            e.Message,
+		InvolvedObjectKind: e.InvolvedObject.Kind,
+		InvolvedObjectName: e.InvolvedObject.Name,
+		Namespace:          e.Namespace,
+		Count:              e.Count,
+		FirstTimestamp:     TimeToString(&e.FirstTimestamp),
+		LastTimestamp:      lastTimestamp,
+		Source:             source,
+	}
+}
@@ -0,0 +1,118 @@
+// Package policy authorizes authenticated callers: its rules map users and
+// groups to the namespaces they may act in and the verbs they may use.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/danroux/sk8l/internal/auth"
+	gyaml "sigs.k8s.io/yaml"
+)
+
+// Verb is an action on the CronJobs, Jobs and Pods of a namespace.
+type Verb string
+
+const (
+	// VerbRead lists and gets objects, their YAML, logs and events.
+	VerbRead    Verb = "read"
+	VerbTrigger Verb = "trigger"
+	VerbSuspend Verb = "suspend"
+	VerbDelete  Verb = "delete"
+	// VerbAudit reads the audit log.
+	VerbAudit Verb = "audit"
+	// VerbDebug reads the profiles and internal state of the debug listener.
+	VerbDebug Verb = "debug"
+)
+
+// Verbs are all the verbs a rule can grant.
+var Verbs = []Verb{VerbRead, VerbTrigger, VerbSuspend, VerbDelete, VerbAudit, VerbDebug}
+
+// Wildcard matches every authenticated user, namespace or verb.
+const Wildcard = "*"
+
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+// Rule grants its verbs in its namespaces to its users and the members of its groups.
+type Rule struct {
+	// Users are matched against the identity name and the SANs of client certificates.
+	Users      []string `json:"users"`
+	Groups     []string `json:"groups"`
+	Namespaces []string `json:"namespaces"`
+	Verbs      []Verb   `json:"verbs"`
+}
+
+// Policy is a set of rules, a caller is allowed what any rule grants it.
+// A nil Policy allows everything.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Load reads the YAML policy at path.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("policy#Load: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses and validates a YAML policy.
+func Parse(data []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := gyaml.UnmarshalStrict(data, p); err != nil {
+		return nil, fmt.Errorf("policy#Parse: %w: %w", ErrInvalidPolicy, err)
+	}
+	for i, rule := range p.Rules {
+		if len(rule.Users) == 0 && len(rule.Groups) == 0 {
+			return nil, fmt.Errorf("policy#Parse: %w: rule %d has neither users nor groups", ErrInvalidPolicy, i)
+		}
+		if len(rule.Namespaces) == 0 || len(rule.Verbs) == 0 {
+			return nil, fmt.Errorf("policy#Parse: %w: rule %d needs namespaces and verbs", ErrInvalidPolicy, i)
+		}
+		for _, verb := range rule.Verbs {
+			if verb != Wildcard && !slices.Contains(Verbs, verb) {
+				return nil, fmt.Errorf("policy#Parse: %w: rule %d has unknown verb %q", ErrInvalidPolicy, i, verb)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Allows reports whether id may use verb in namespace. Anonymous callers are
+// never allowed by a policy.
+func (p *Policy) Allows(id *auth.Identity, namespace string, verb Verb) bool {
+	if p == nil {
+		return true
+	}
+	if id == nil {
+		return false
+	}
+	for _, rule := range p.Rules {
+		if rule.matches(id) && matches(rule.Namespaces, namespace) && matches(rule.Verbs, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matches(id *auth.Identity) bool {
+	for _, user := range r.Users {
+		if user == Wildcard || user == id.Name || slices.Contains(id.SANs, user) {
+			return true
+		}
+	}
+	for _, group := range r.Groups {
+		if slices.Contains(id.Groups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches[T ~string](values []T, value T) bool {
+	return slices.Contains(values, Wildcard) || slices.Contains(values, value)
+}
@@ -0,0 +1,91 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/danroux/sk8l/internal/auth"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		valid  bool
+	}{
+		{"valid", "rules:\n  - users: [jane]\n    namespaces: ['*']\n    verbs: [read, trigger]\n", true},
+		{"empty", "", true},
+		{"without subjects", "rules:\n  - namespaces: [default]\n    verbs: [read]\n", false},
+		{"without verbs", "rules:\n  - groups: [sre]\n    namespaces: [default]\n", false},
+		{"unknown verb", "rules:\n  - groups: [sre]\n    namespaces: [default]\n    verbs: [write]\n", false},
+		{"unknown field", "rules:\n  - user: [jane]\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.policy))
+			if tt.valid && err != nil {
+				t.Errorf("Parse failed: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidPolicy) {
+				t.Errorf("expected ErrInvalidPolicy, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAllows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yml")
+	policy := `
+rules:
+  - users: ["jane", "ci.example.com"]
+    namespaces: ["default"]
+    verbs: ["read", "trigger"]
+  - groups: ["sre"]
+    namespaces: ["*"]
+    verbs: ["*"]
+  - users: ["*"]
+    namespaces: ["public"]
+    verbs: ["read"]
+`
+	if err := os.WriteFile(path, []byte(policy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	jane := &auth.Identity{Name: "jane"}
+	client := &auth.Identity{Name: "runner", SANs: []string{"ci.example.com"}, Method: auth.MethodClientCertificate}
+	sre := &auth.Identity{Name: "joe", Groups: []string{"sre"}}
+	other := &auth.Identity{Name: "other"}
+	tests := []struct {
+		id        *auth.Identity
+		namespace string
+		verb      Verb
+		allowed   bool
+	}{
+		{jane, "default", VerbRead, true},
+		{jane, "default", VerbTrigger, true},
+		{jane, "default", VerbDelete, false},
+		{jane, "batch", VerbRead, false},
+		{client, "default", VerbTrigger, true},
+		{sre, "batch", VerbDelete, true},
+		{other, "public", VerbRead, true},
+		{other, "public", VerbSuspend, false},
+		{other, "default", VerbRead, false},
+		{nil, "public", VerbRead, false},
+	}
+	for _, tt := range tests {
+		if got := p.Allows(tt.id, tt.namespace, tt.verb); got != tt.allowed {
+			t.Errorf("Allows(%v, %s, %s) = %v, expected %v", tt.id, tt.namespace, tt.verb, got, tt.allowed)
+		}
+	}
+
+	var none *Policy
+	if !none.Allows(nil, "default", VerbDelete) {
+		t.Error("expected a nil policy to allow everything")
+	}
+}
@@ -0,0 +1,87 @@
+package redact
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// OptOutHeader is the request metadata a privileged caller sets to "off" to
+// receive unredacted responses.
+const OptOutHeader = "sk8l-redaction"
+
+type privilegedKey struct{}
+
+// WithPrivileged marks the caller of ctx as allowed to opt out of redaction.
+func WithPrivileged(ctx context.Context) context.Context {
+	return context.WithValue(ctx, privilegedKey{}, true)
+}
+
+// Privileged reports whether the caller of ctx may opt out of redaction.
+func Privileged(ctx context.Context) bool {
+	privileged, _ := ctx.Value(privilegedKey{}).(bool)
+	return privileged
+}
+
+// For returns r, or nil when the privileged caller of ctx opted out.
+func (r *Redactor) For(ctx context.Context) *Redactor {
+	if r == nil || !Privileged(ctx) {
+		return r
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(OptOutHeader) {
+		if strings.EqualFold(value, "off") {
+			return nil
+		}
+	}
+	return r
+}
+
+// UnaryServerInterceptor redacts every response.
+func (r *Redactor) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+		return r.For(ctx).redactResponse(resp), nil
+	}
+}
+
+// StreamServerInterceptor redacts every message sent on a stream.
+func (r *Redactor) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		redactor := r.For(ss.Context())
+		if redactor == nil {
+			return handler(srv, ss)
+		}
+		return handler(srv, &redactingStream{ServerStream: ss, redactor: redactor})
+	}
+}
+
+type redactingStream struct {
+	grpc.ServerStream
+	redactor *Redactor
+}
+
+func (s *redactingStream) SendMsg(m any) error {
+	if err := s.ServerStream.SendMsg(s.redactor.redactResponse(m)); err != nil {
+		return fmt.Errorf("redact#SendMsg: %w", err)
+	}
+	return nil
+}
+
+// redactResponse redacts a copy, handlers may send the same message to several callers.
+func (r *Redactor) redactResponse(resp any) any {
+	m, ok := resp.(proto.Message)
+	if r == nil || !ok {
+		return resp
+	}
+	m = proto.Clone(m)
+	r.Message(m)
+	return m
+}
@@ -0,0 +1,225 @@
+// Package redact removes secrets from what sk8l serves: the values of
+// environment variables and similar name/value pairs whose name looks
+// sensitive or whose value looks like a credential, denied annotations and
+// credentials embedded in commands and arguments.
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	gyaml "sigs.k8s.io/yaml"
+)
+
+// Placeholder replaces every redacted value.
+const Placeholder = "[REDACTED]"
+
+const (
+	defaultEntropyThreshold = 4.0
+	defaultEntropyMinLength = 20
+)
+
+var ErrInvalidConfig = errors.New("invalid redaction config")
+
+var (
+	// DefaultKeyPatterns match the names of variables holding secrets.
+	DefaultKeyPatterns = []string{
+		`(?i)passw(or)?d|passphrase`,
+		`(?i)secret`,
+		`(?i)token`,
+		`(?i)api[_-]?key`,
+		`(?i)access[_-]?key`,
+		`(?i)private[_-]?key`,
+		`(?i)credentials?`,
+	}
+	// DefaultValuePatterns match well known credential formats.
+	DefaultValuePatterns = []string{
+		`AKIA[0-9A-Z]{16}`,
+		`gh[pousr]_[A-Za-z0-9]{36,}`,
+		`xox[abprs]-[A-Za-z0-9-]{10,}`,
+		`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`,
+		`-----BEGIN [A-Z ]*PRIVATE KEY-----`,
+		`://[^:/\s@]+:[^@/\s]+@`,
+		`(?i)--?(passw(or)?d|token|secret|api-?key)[= ]\S+`,
+	}
+	// DefaultDeniedAnnotations can hold a complete copy of the object, secrets included.
+	DefaultDeniedAnnotations = []string{
+		"kubectl.kubernetes.io/last-applied-configuration",
+	}
+)
+
+// tokenLike matches values that look like an encoded token rather than text,
+// paths or URLs, only those are checked for their entropy.
+var tokenLike = regexp.MustCompile(`^[A-Za-z0-9+/=_-]+$`)
+
+// Config are the redaction rules. The patterns and annotations are added to
+// the defaults.
+type Config struct {
+	// Disabled turns redaction off for every caller.
+	Disabled bool `json:"disabled"`
+	// KeyPatterns are regular expressions matched against variable names and annotation keys.
+	KeyPatterns []string `json:"keyPatterns"`
+	// ValuePatterns are regular expressions matched against values.
+	ValuePatterns []string `json:"valuePatterns"`
+	// DeniedAnnotations are annotation keys whose values are always redacted.
+	// A trailing * matches every key with that prefix.
+	DeniedAnnotations []string `json:"deniedAnnotations"`
+	// EntropyThreshold is the Shannon entropy in bits per character above
+	// which a token-like variable value is considered a secret, 0 uses the default.
+	EntropyThreshold float64 `json:"entropyThreshold"`
+	// EntropyMinLength is the length below which values are not checked for their entropy.
+	EntropyMinLength int `json:"entropyMinLength"`
+}
+
+// LoadConfig reads the YAML config at path.
+func LoadConfig(path string) (Config, error) {
+	config := Config{}
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return config, fmt.Errorf("redact#LoadConfig: %w", err)
+	}
+	if err := gyaml.UnmarshalStrict(data, &config); err != nil {
+		return config, fmt.Errorf("redact#LoadConfig: %w: %w", ErrInvalidConfig, err)
+	}
+	return config, nil
+}
+
+// Redactor applies a Config. A nil Redactor leaves everything untouched.
+type Redactor struct {
+	keyPatterns       []*regexp.Regexp
+	valuePatterns     []*regexp.Regexp
+	deniedAnnotations []string
+	entropyThreshold  float64
+	entropyMinLength  int
+}
+
+// New compiles config. It returns a nil Redactor when redaction is disabled.
+func New(config Config) (*Redactor, error) {
+	if config.Disabled {
+		return nil, nil
+	}
+	r := &Redactor{
+		deniedAnnotations: append(append([]string{}, DefaultDeniedAnnotations...), config.DeniedAnnotations...),
+		entropyThreshold:  config.EntropyThreshold,
+		entropyMinLength:  config.EntropyMinLength,
+	}
+	if r.entropyThreshold == 0 {
+		r.entropyThreshold = defaultEntropyThreshold
+	}
+	if r.entropyMinLength == 0 {
+		r.entropyMinLength = defaultEntropyMinLength
+	}
+	var err error
+	if r.keyPatterns, err = compile(append(append([]string{}, DefaultKeyPatterns...), config.KeyPatterns...)); err != nil {
+		return nil, err
+	}
+	if r.valuePatterns, err = compile(append(append([]string{}, DefaultValuePatterns...), config.ValuePatterns...)); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Default returns a Redactor with the default rules.
+func Default() *Redactor {
+	r, _ := New(Config{})
+	return r
+}
+
+func compile(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redact#New: %w: %w", ErrInvalidConfig, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Value returns the value of a name/value pair such as an environment
+// variable, redacted when either looks sensitive.
+func (r *Redactor) Value(name, value string) string {
+	if r == nil || value == "" {
+		return value
+	}
+	if r.sensitiveKey(name) || r.matchesValue(value) || r.highEntropy(value) {
+		return Placeholder
+	}
+	return value
+}
+
+// Text redacts the credentials found in free text such as commands and arguments.
+func (r *Redactor) Text(text string) string {
+	if r == nil {
+		return text
+	}
+	for _, re := range r.valuePatterns {
+		text = re.ReplaceAllString(text, Placeholder)
+	}
+	return text
+}
+
+// Annotation returns the value of an annotation, redacted when its key is
+// denied or looks sensitive.
+func (r *Redactor) Annotation(key, value string) string {
+	if r == nil {
+		return value
+	}
+	if r.deniedAnnotation(key) || r.sensitiveKey(key) {
+		return Placeholder
+	}
+	return r.Text(value)
+}
+
+func (r *Redactor) sensitiveKey(key string) bool {
+	for _, re := range r.keyPatterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) matchesValue(value string) bool {
+	for _, re := range r.valuePatterns {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) deniedAnnotation(key string) bool {
+	for _, denied := range r.deniedAnnotations {
+		if prefix, ok := strings.CutSuffix(denied, "*"); ok && strings.HasPrefix(key, prefix) || key == denied {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) highEntropy(value string) bool {
+	return len(value) >= r.entropyMinLength && tokenLike.MatchString(value) && entropy(value) >= r.entropyThreshold
+}
+
+// entropy is the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, c := range s {
+		counts[c]++
+		n++
+	}
+	h := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
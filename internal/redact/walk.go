@@ -0,0 +1,191 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Field names the walkers look for, in both the Kubernetes objects and the
+// responses mapped from them.
+const (
+	nameField        = "name"
+	valueField       = "value"
+	annotationsField = "annotations"
+)
+
+// textFields hold commands, arguments, captured logs and streamed log lines.
+var textFields = map[string]bool{
+	"command":  true,
+	"args":     true,
+	"commands": true,
+	"logs":     true,
+	"line":     true,
+}
+
+// Message redacts m in place: the value of every message with a name and a
+// value field, like EnvVarResponse, annotations maps and command lines.
+func (r *Redactor) Message(m proto.Message) {
+	if r == nil || m == nil {
+		return
+	}
+	r.walkMessage(m.ProtoReflect())
+}
+
+func (r *Redactor) walkMessage(m protoreflect.Message) {
+	fields := m.Descriptor().Fields()
+	name, value := fields.ByName(nameField), fields.ByName(valueField)
+	if isString(name) && isString(value) {
+		redacted := r.Value(m.Get(name).String(), m.Get(value).String())
+		if redacted != m.Get(value).String() {
+			m.Set(value, protoreflect.ValueOfString(redacted))
+		}
+	}
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsMap():
+			r.walkMap(fd, v.Map())
+		case fd.IsList():
+			r.walkList(fd, v.List())
+		case fd.Message() != nil:
+			r.walkMessage(v.Message())
+		case fd.Kind() == protoreflect.StringKind && textFields[string(fd.Name())]:
+			m.Set(fd, protoreflect.ValueOfString(r.Text(v.String())))
+		default: // default case to satisfy revive
+		}
+		return true
+	})
+}
+
+func (r *Redactor) walkMap(fd protoreflect.FieldDescriptor, m protoreflect.Map) {
+	if fd.MapValue().Message() != nil {
+		m.Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+			r.walkMessage(v.Message())
+			return true
+		})
+		return
+	}
+	if fd.Name() != annotationsField || fd.MapValue().Kind() != protoreflect.StringKind {
+		return
+	}
+	redacted := map[string]string{}
+	m.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+		redacted[k.String()] = r.Annotation(k.String(), v.String())
+		return true
+	})
+	for k, v := range redacted {
+		m.Set(protoreflect.ValueOfString(k).MapKey(), protoreflect.ValueOfString(v))
+	}
+}
+
+func (r *Redactor) walkList(fd protoreflect.FieldDescriptor, l protoreflect.List) {
+	for i := range l.Len() {
+		switch {
+		case fd.Message() != nil:
+			r.walkMessage(l.Get(i).Message())
+		case fd.Kind() == protoreflect.StringKind && textFields[string(fd.Name())]:
+			l.Set(i, protoreflect.ValueOfString(r.Text(l.Get(i).String())))
+		default: // default case to satisfy revive
+		}
+	}
+}
+
+func isString(fd protoreflect.FieldDescriptor) bool {
+	return fd != nil && fd.Kind() == protoreflect.StringKind && !fd.IsList() && !fd.IsMap()
+}
+
+// JSON redacts a JSON encoded Kubernetes object: env vars and other
+// name/value pairs, metadata annotations and container commands and args.
+func (r *Redactor) JSON(data []byte) ([]byte, error) {
+	if r == nil {
+		return data, nil
+	}
+	var obj any
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("redact#JSON: %w", err)
+	}
+	redacted, err := json.Marshal(r.walkJSON(obj))
+	if err != nil {
+		return nil, fmt.Errorf("redact#JSON: %w", err)
+	}
+	return redacted, nil
+}
+
+// Object redacts obj, any value that round-trips through JSON like the
+// Kubernetes API types.
+func (r *Redactor) Object(obj any) error {
+	if r == nil {
+		return nil
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("redact#Object: %w", err)
+	}
+	if data, err = r.JSON(data); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, obj); err != nil {
+		return fmt.Errorf("redact#Object: %w", err)
+	}
+	return nil
+}
+
+func (r *Redactor) walkJSON(obj any) any {
+	switch v := obj.(type) {
+	case map[string]any:
+		name, hasName := v[nameField].(string)
+		if value, ok := v[valueField].(string); ok && hasName {
+			v[valueField] = r.Value(name, value)
+		}
+		for key, child := range v {
+			switch {
+			case key == annotationsField:
+				v[key] = r.redactAnnotations(child)
+			case textFields[key]:
+				v[key] = r.redactText(child)
+			default:
+				v[key] = r.walkJSON(child)
+			}
+		}
+		return v
+	case []any:
+		for i, child := range v {
+			v[i] = r.walkJSON(child)
+		}
+		return v
+	default:
+		return obj
+	}
+}
+
+func (r *Redactor) redactAnnotations(obj any) any {
+	annotations, ok := obj.(map[string]any)
+	if !ok {
+		return r.walkJSON(obj)
+	}
+	for key, value := range annotations {
+		if s, ok := value.(string); ok {
+			annotations[key] = r.Annotation(key, s)
+		}
+	}
+	return annotations
+}
+
+func (r *Redactor) redactText(obj any) any {
+	switch v := obj.(type) {
+	case string:
+		return r.Text(v)
+	case []any:
+		for i, child := range v {
+			if s, ok := child.(string); ok {
+				v[i] = r.Text(s)
+			}
+		}
+		return v
+	default:
+		return r.walkJSON(obj)
+	}
+}
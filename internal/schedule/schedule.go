@@ -0,0 +1,221 @@
+// Package schedule parses the cron schedules of CronJobs and computes their
+// next runs, the way the Kubernetes CronJob controller reads them: five fields
+// (minute, hour, day of month, month, day of week) or a predefined schedule
+// like @hourly or @every 15m.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// fieldCount is the number of fields of a cron schedule.
+	fieldCount = 5
+	// searchLimit bounds the search of the next run in years, schedules like
+	// 0 0 30 2 * never run.
+	searchLimit = 5
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+var (
+	months = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	weekdays = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+	predefined = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField  = field{name: "minute", min: 0, max: 59}
+	hourField    = field{name: "hour", min: 0, max: 23}
+	domField     = field{name: "day of month", min: 1, max: 31}
+	monthField   = field{name: "month", min: 1, max: 12, names: months}
+	weekdayField = field{name: "day of week", min: 0, max: 6, names: weekdays}
+)
+
+// Schedule is a parsed cron schedule.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny are set for * and ?: days then have to match both
+	// fields instead of either.
+	domAny, dowAny bool
+	// every is the interval of @every schedules.
+	every    time.Duration
+	location *time.Location
+}
+
+// Parse parses spec, evaluated in the IANA timeZone, the local one when
+// empty, like spec.timeZone of a CronJob.
+func Parse(spec, timeZone string) (*Schedule, error) {
+	location := time.Local
+	if timeZone != "" {
+		var err error
+		location, err = time.LoadLocation(timeZone)
+		if err != nil {
+			return nil, fmt.Errorf("schedule#Parse: %w: %w", ErrInvalidSchedule, err)
+		}
+	}
+	spec = strings.TrimSpace(spec)
+	if every, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(every))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("schedule#Parse: %w: @every needs a duration of at least 1s, got %q", ErrInvalidSchedule, every)
+		}
+		return &Schedule{every: d, location: location}, nil
+	}
+	if expanded, ok := predefined[spec]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != fieldCount {
+		return nil, fmt.Errorf("schedule#Parse: %w: expected %d fields, got %d in %q", ErrInvalidSchedule, fieldCount, len(fields), spec)
+	}
+
+	s := &Schedule{location: location}
+	var err error
+	if s.minute, _, err = minuteField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if s.hour, _, err = hourField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if s.dom, s.domAny, err = domField.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if s.month, _, err = monthField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if s.dow, s.dowAny, err = weekdayField.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parse returns the values of the comma separated list expr as a bit set,
+// and whether it's * or ?.
+func (f field) parse(expr string) (set uint64, anyValue bool, err error) {
+	for part := range strings.SplitSeq(expr, ",") {
+		bits, err := f.parseRange(part)
+		if err != nil {
+			return 0, false, err
+		}
+		set |= bits
+	}
+	return set, expr == "*" || expr == "?", nil
+}
+
+// parseRange parses *, ?, a value or a range, optionally followed by /step.
+func (f field) parseRange(expr string) (uint64, error) {
+	rangeExpr, stepExpr, hasStep := strings.Cut(expr, "/")
+	start, end := f.min, f.max
+	switch {
+	case rangeExpr == "*" || rangeExpr == "?":
+	case strings.Contains(rangeExpr, "-"):
+		low, high, _ := strings.Cut(rangeExpr, "-")
+		var err error
+		if start, err = f.value(low); err != nil {
+			return 0, err
+		}
+		if end, err = f.value(high); err != nil {
+			return 0, err
+		}
+	default:
+		var err error
+		if start, err = f.value(rangeExpr); err != nil {
+			return 0, err
+		}
+		end = start
+		if hasStep {
+			end = f.max
+		}
+	}
+	if start > end {
+		return 0, fmt.Errorf("schedule#parseRange: %w: %s range %q ends before it starts", ErrInvalidSchedule, f.name, expr)
+	}
+
+	step := 1
+	if hasStep {
+		var err error
+		step, err = strconv.Atoi(stepExpr)
+		if err != nil || step <= 0 {
+			return 0, fmt.Errorf("schedule#parseRange: %w: invalid %s step %q", ErrInvalidSchedule, f.name, stepExpr)
+		}
+	}
+	var set uint64
+	for v := start; v <= end; v += step {
+		set |= 1 << uint(v)
+	}
+	return set, nil
+}
+
+func (f field) value(expr string) (int, error) {
+	if v, ok := f.names[strings.ToLower(expr)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(expr)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("schedule#value: %w: %s %q is not between %d and %d", ErrInvalidSchedule, f.name, expr, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first run of the schedule after t, in its time zone, or
+// the zero time when it never runs.
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every).Truncate(time.Second)
+	}
+	t = t.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + searchLimit
+	for t.Year() <= limit {
+		switch {
+		case !has(s.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+		case !has(s.hour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+		case !has(s.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches follows cron: when neither day field is * a day has to match
+// one of them, otherwise both.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom, dow := has(s.dom, t.Day()), has(s.dow, int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<uint(v)) != 0
+}
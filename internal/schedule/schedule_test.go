@@ -0,0 +1,67 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// A Wednesday.
+	from := time.Date(2026, 10, 14, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		spec     string
+		timeZone string
+		want     string
+	}{
+		{spec: "* * * * *", want: "2026-10-14T10:08:00Z"},
+		{spec: "*/15 * * * *", want: "2026-10-14T10:15:00Z"},
+		{spec: "5 * * * *", want: "2026-10-14T11:05:00Z"},
+		{spec: "0 9-17/4 * * *", want: "2026-10-14T13:00:00Z"},
+		{spec: "30 2 * * mon-fri", want: "2026-10-15T02:30:00Z"},
+		{spec: "0 0 * * SUN", want: "2026-10-18T00:00:00Z"},
+		{spec: "0 0 1,15 * *", want: "2026-10-15T00:00:00Z"},
+		// Either day field matches when neither is *.
+		{spec: "0 0 31 * 5", want: "2026-10-16T00:00:00Z"},
+		{spec: "0 0 29 feb ?", want: "2028-02-29T00:00:00Z"},
+		{spec: "@monthly", want: "2026-11-01T00:00:00Z"},
+		{spec: "@hourly", want: "2026-10-14T11:00:00Z"},
+		{spec: "@every 90s", want: "2026-10-14T10:09:00Z"},
+		{spec: "0 9 * * *", timeZone: "Europe/Berlin", want: "2026-10-15T09:00:00+02:00"},
+		{spec: "0 0 30 2 *", want: "0001-01-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec, tt.timeZone)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(from.In(time.UTC)).Format(time.RFC3339); got != tt.want {
+			t.Errorf("Parse(%q).Next() = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 7",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"@every 1ms",
+		"@reboot",
+	} {
+		if _, err := Parse(spec, ""); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("expected Parse(%q) to fail with ErrInvalidSchedule, got %v", spec, err)
+		}
+	}
+	if _, err := Parse("* * * * *", "Mars/Olympus_Mons"); !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("expected an unknown time zone to fail with ErrInvalidSchedule, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/danroux/sk8l/internal/k8s"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// ErrNoCluster is returned by the methods of Client that need a live cluster,
+// like watching or streaming logs.
+var ErrNoCluster = errors.New("snapshot client has no cluster")
+
+// Client is a read-only k8s.ClientInterface answering from a Snapshot, used
+// when the server runs from an imported archive instead of a cluster.
+type Client struct {
+	snap *Snapshot
+}
+
+var _ k8s.ClientInterface = (*Client)(nil)
+
+func NewClient(snap *Snapshot) *Client {
+	return &Client{snap: snap}
+}
+
+func (c *Client) Namespace() string {
+	return c.snap.Manifest.Namespace
+}
+
+func (c *Client) GetCronjob(_ context.Context, cronjobNamespace, cronjobName string) (*batchv1.CronJob, error) {
+	for i := range c.snap.Cronjobs {
+		if c.snap.Cronjobs[i].Namespace == cronjobNamespace && c.snap.Cronjobs[i].Name == cronjobName {
+			return c.snap.Cronjobs[i].DeepCopy(), nil
+		}
+	}
+	return nil, apierrors.NewNotFound(batchv1.Resource("cronjobs"), cronjobName)
+}
+
+func (c *Client) GetJob(_ context.Context, jobNamespace, jobName string) (*batchv1.Job, error) {
+	for i := range c.snap.Jobs {
+		if c.snap.Jobs[i].Namespace == jobNamespace && c.snap.Jobs[i].Name == jobName {
+			return c.snap.Jobs[i].DeepCopy(), nil
+		}
+	}
+	return nil, apierrors.NewNotFound(batchv1.Resource("jobs"), jobName)
+}
+
+func (c *Client) GetPod(_ context.Context, jobNamespace, podName string) (*corev1.Pod, error) {
+	for i := range c.snap.Pods {
+		if c.snap.Pods[i].Namespace == jobNamespace && c.snap.Pods[i].Name == podName {
+			return c.snap.Pods[i].DeepCopy(), nil
+		}
+	}
+	return nil, apierrors.NewNotFound(corev1.Resource("pods"), podName)
+}
+
+func (c *Client) ListCronjobs(context.Context, metav1.ListOptions) (*batchv1.CronJobList, error) {
+	list := &batchv1.CronJobList{}
+	for i := range c.snap.Cronjobs {
+		list.Items = append(list.Items, *c.snap.Cronjobs[i].DeepCopy())
+	}
+	return list, nil
+}
+
+func (c *Client) ListJobs(context.Context, metav1.ListOptions) (*batchv1.JobList, error) {
+	list := &batchv1.JobList{}
+	for i := range c.snap.Jobs {
+		list.Items = append(list.Items, *c.snap.Jobs[i].DeepCopy())
+	}
+	return list, nil
+}
+
+func (c *Client) GetAllJobs(ctx context.Context) (*batchv1.JobList, error) {
+	return c.ListJobs(ctx, metav1.ListOptions{})
+}
+
+func (c *Client) ListPods(context.Context, metav1.ListOptions) (*corev1.PodList, error) {
+	list := &corev1.PodList{}
+	for i := range c.snap.Pods {
+		list.Items = append(list.Items, *c.snap.Pods[i].DeepCopy())
+	}
+	return list, nil
+}
+
+// ListEvents returns no events, a snapshot does not include them.
+func (c *Client) ListEvents(context.Context, metav1.ListOptions) (*corev1.EventList, error) {
+	return &corev1.EventList{}, nil
+}
+
+func (c *Client) WatchEvents(context.Context, metav1.ListOptions) (watch.Interface, error) {
+	return nil, fmt.Errorf("snapshot#WatchEvents: %w", ErrNoCluster)
+}
+
+func (c *Client) WatchCronjobs(context.Context, metav1.ListOptions) (watch.Interface, error) {
+	return nil, fmt.Errorf("snapshot#WatchCronjobs: %w", ErrNoCluster)
+}
+
+func (c *Client) WatchJobs(context.Context, metav1.ListOptions) (watch.Interface, error) {
+	return nil, fmt.Errorf("snapshot#WatchJobs: %w", ErrNoCluster)
+}
+
+func (c *Client) WatchPods(context.Context, metav1.ListOptions) (watch.Interface, error) {
+	return nil, fmt.Errorf("snapshot#WatchPods: %w", ErrNoCluster)
+}
+
+func (c *Client) ReviewToken(context.Context, string, []string) (*authenticationv1.TokenReviewStatus, error) {
+	return nil, fmt.Errorf("snapshot#ReviewToken: %w", ErrNoCluster)
+}
+
+func (c *Client) StreamPodLogs(context.Context, string, string, *corev1.PodLogOptions) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("snapshot#StreamPodLogs: %w", ErrNoCluster)
+}
+
+func (c *Client) CreateJob(context.Context, *batchv1.Job) (*batchv1.Job, error) {
+	return nil, fmt.Errorf("snapshot#CreateJob: %w", ErrNoCluster)
+}
+
+func (c *Client) SuspendCronjob(context.Context, string, string, bool) (*batchv1.CronJob, error) {
+	return nil, fmt.Errorf("snapshot#SuspendCronjob: %w", ErrNoCluster)
+}
@@ -0,0 +1,249 @@
+// Package snapshot exports the state kept in a store.Storage into a versioned
+// archive and loads such an archive back, so sk8l's view of a cluster can be
+// inspected somewhere else without access to that cluster.
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/danroux/sk8l/internal/store"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Version is the archive format version written by Write. Read accepts
+// archives of this and earlier versions. Version 2 added the container logs.
+const Version = 2
+
+// Archive entries. The manifest always comes first so a reader can reject an
+// archive before decoding the rest of it.
+const (
+	ManifestFile = "manifest.json"
+	CronjobsFile = "cronjobs.json"
+	JobsFile     = "jobs.json"
+	PodsFile     = "pods.json"
+	HistoryFile  = "history.json"
+	LogsFile     = "logs.json"
+
+	fileMode = 0o644
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported snapshot version")
+	ErrMissingManifest    = errors.New("snapshot has no manifest")
+	errUnexpectedFile     = errors.New("unexpected file in snapshot")
+)
+
+// Manifest describes where and when a snapshot was taken.
+type Manifest struct {
+	CreatedAt      time.Time `json:"createdAt"`
+	Sk8lVersion    string    `json:"sk8lVersion"`
+	Namespace      string    `json:"namespace"`
+	StorageBackend string    `json:"storageBackend"`
+	Version        int       `json:"version"`
+	Cronjobs       int       `json:"cronjobs"`
+	Jobs           int       `json:"jobs"`
+	Pods           int       `json:"pods"`
+	RunRecords     int       `json:"runRecords"`
+	ContainerLogs  int       `json:"containerLogs"`
+}
+
+// Snapshot is the content of an archive.
+type Snapshot struct {
+	Manifest      Manifest
+	Cronjobs      []batchv1.CronJob
+	Jobs          []batchv1.Job
+	Pods          []corev1.Pod
+	RunRecords    []store.RunRecord
+	ContainerLogs []store.ContainerLogs
+}
+
+// Capture reads everything kept in storage. The counts and version of the
+// manifest are filled in, the remaining fields are taken from manifest.
+func Capture(storage store.Storage, manifest Manifest) (*Snapshot, error) {
+	cronjobs, err := storage.ListCronjobs()
+	if err != nil {
+		return nil, fmt.Errorf("snapshot#Capture: ListCronjobs() failed: %w", err)
+	}
+	jobs, err := storage.ListJobs()
+	if err != nil {
+		return nil, fmt.Errorf("snapshot#Capture: ListJobs() failed: %w", err)
+	}
+	pods, err := storage.ListPods()
+	if err != nil {
+		return nil, fmt.Errorf("snapshot#Capture: ListPods() failed: %w", err)
+	}
+	records, err := storage.ListRunRecords()
+	if err != nil {
+		return nil, fmt.Errorf("snapshot#Capture: ListRunRecords() failed: %w", err)
+	}
+	logs, err := storage.ListContainerLogs()
+	if err != nil {
+		return nil, fmt.Errorf("snapshot#Capture: ListContainerLogs() failed: %w", err)
+	}
+
+	manifest.Version = Version
+	manifest.StorageBackend = storage.Backend()
+	manifest.Cronjobs = len(cronjobs)
+	manifest.Jobs = len(jobs)
+	manifest.Pods = len(pods)
+	manifest.RunRecords = len(records)
+	manifest.ContainerLogs = len(logs)
+	return &Snapshot{
+		Manifest:      manifest,
+		Cronjobs:      cronjobs,
+		Jobs:          jobs,
+		Pods:          pods,
+		RunRecords:    records,
+		ContainerLogs: logs,
+	}, nil
+}
+
+// Write writes snap to w as a gzipped tar archive.
+func (snap *Snapshot) Write(w io.Writer) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	entries := []struct {
+		name  string
+		value any
+	}{
+		{ManifestFile, snap.Manifest},
+		{CronjobsFile, snap.Cronjobs},
+		{JobsFile, snap.Jobs},
+		{PodsFile, snap.Pods},
+		{HistoryFile, snap.RunRecords},
+		{LogsFile, snap.ContainerLogs},
+	}
+	for _, entry := range entries {
+		if err := writeEntry(tw, entry.name, entry.value, snap.Manifest.CreatedAt); err != nil {
+			return fmt.Errorf("snapshot#Write: %w", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("snapshot#Write: tar close failed: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("snapshot#Write: gzip close failed: %w", err)
+	}
+	return nil
+}
+
+func writeEntry(tw *tar.Writer, name string, value any, modTime time.Time) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("json.Marshal(%s) failed: %w", name, err)
+	}
+	header := &tar.Header{
+		Name:    name,
+		Mode:    fileMode,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("WriteHeader(%s) failed: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("Write(%s) failed: %w", name, err)
+	}
+	return nil
+}
+
+// Read decodes an archive written by Write.
+func Read(r io.Reader) (*Snapshot, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot#Read: gzip.NewReader() failed: %w", err)
+	}
+	defer gr.Close()
+
+	snap := &Snapshot{}
+	tr := tar.NewReader(gr)
+	seenManifest := false
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("snapshot#Read: tar.Next() failed: %w", err)
+		}
+
+		var target any
+		switch header.Name {
+		case ManifestFile:
+			target = &snap.Manifest
+		case CronjobsFile:
+			target = &snap.Cronjobs
+		case JobsFile:
+			target = &snap.Jobs
+		case PodsFile:
+			target = &snap.Pods
+		case HistoryFile:
+			target = &snap.RunRecords
+		case LogsFile:
+			target = &snap.ContainerLogs
+		default:
+			return nil, fmt.Errorf("snapshot#Read: %w %q", errUnexpectedFile, header.Name)
+		}
+		if header.Name != ManifestFile && !seenManifest {
+			return nil, fmt.Errorf("snapshot#Read: %w", ErrMissingManifest)
+		}
+
+		if err := json.NewDecoder(tr).Decode(target); err != nil {
+			return nil, fmt.Errorf("snapshot#Read: decoding %s failed: %w", header.Name, err)
+		}
+		if header.Name == ManifestFile {
+			seenManifest = true
+			if snap.Manifest.Version < 1 || snap.Manifest.Version > Version {
+				return nil, fmt.Errorf("snapshot#Read: %w %d, expected at most %d", ErrUnsupportedVersion, snap.Manifest.Version, Version)
+			}
+		}
+	}
+	if !seenManifest {
+		return nil, fmt.Errorf("snapshot#Read: %w", ErrMissingManifest)
+	}
+	return snap, nil
+}
+
+// ReadFile reads the archive at path.
+func ReadFile(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot#ReadFile: %w", err)
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Load replaces the content of storage with snap.
+func (snap *Snapshot) Load(storage store.Storage) error {
+	if err := storage.ReplaceCronjobs(snap.Cronjobs); err != nil {
+		return fmt.Errorf("snapshot#Load: ReplaceCronjobs() failed: %w", err)
+	}
+	if err := storage.ReplaceJobs(snap.Jobs); err != nil {
+		return fmt.Errorf("snapshot#Load: ReplaceJobs() failed: %w", err)
+	}
+	if err := storage.ReplacePods(snap.Pods); err != nil {
+		return fmt.Errorf("snapshot#Load: ReplacePods() failed: %w", err)
+	}
+	for _, record := range snap.RunRecords {
+		if err := storage.PutRunRecord(record); err != nil {
+			return fmt.Errorf("snapshot#Load: PutRunRecord() failed: %w", err)
+		}
+	}
+	for _, logs := range snap.ContainerLogs {
+		if err := storage.PutContainerLogs(logs); err != nil {
+			return fmt.Errorf("snapshot#Load: PutContainerLogs() failed: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/danroux/sk8l/internal/store"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func seededStorage(t *testing.T) store.Storage {
+	t.Helper()
+	s := store.NewMemoryStorage()
+	owner := []metav1.OwnerReference{{Name: "cj-1"}}
+	if err := s.PutCronjob(&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "cj-1", Namespace: "default"}}); err != nil {
+		t.Fatalf("PutCronjob failed: %v", err)
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "job-1", Namespace: "default", OwnerReferences: owner}}
+	if err := s.PutJob(job); err != nil {
+		t.Fatalf("PutJob failed: %v", err)
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:            "pod-1",
+		Namespace:       "default",
+		OwnerReferences: []metav1.OwnerReference{{Name: "job-1"}},
+	}}
+	if err := s.PutPod(pod); err != nil {
+		t.Fatalf("PutPod failed: %v", err)
+	}
+	record := store.RunRecord{
+		Namespace:   "default",
+		CronjobName: "cj-1",
+		JobName:     "job-1",
+		StartTime:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Succeeded:   true,
+	}
+	if err := s.PutRunRecord(record); err != nil {
+		t.Fatalf("PutRunRecord failed: %v", err)
+	}
+	return s
+}
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	snap, err := Capture(seededStorage(t), Manifest{Namespace: "default", Sk8lVersion: "test"})
+	if err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := snap.Write(buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	read, err := Read(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	m := read.Manifest
+	if m.Version != Version || m.StorageBackend != store.BackendMemory || m.Namespace != "default" {
+		t.Errorf("unexpected manifest %+v", m)
+	}
+	if m.Cronjobs != 1 || m.Jobs != 1 || m.Pods != 1 || m.RunRecords != 1 {
+		t.Errorf("unexpected counts in manifest %+v", m)
+	}
+
+	loaded := store.NewMemoryStorage()
+	if err := read.Load(loaded); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	jobs, err := loaded.JobsForCronjob("default", "cj-1")
+	if err != nil || len(jobs) != 1 || jobs[0].Name != "job-1" {
+		t.Errorf("expected job-1 indexed under cj-1, got %v, %v", jobs, err)
+	}
+	pods, err := loaded.PodsForJob("default", "job-1")
+	if err != nil || len(pods) != 1 || pods[0].Name != "pod-1" {
+		t.Errorf("expected pod-1 indexed under job-1, got %v, %v", pods, err)
+	}
+	history, err := loaded.RunHistory("default", "cj-1", 0)
+	if err != nil || len(history) != 1 || !history[0].Succeeded {
+		t.Errorf("expected the run record to be loaded, got %v, %v", history, err)
+	}
+}
+
+func TestRead_RejectsOtherVersions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	data, _ := json.Marshal(Manifest{Version: Version + 1})
+	if err := tw.WriteHeader(&tar.Header{Name: ManifestFile, Mode: fileMode, Size: int64(len(data))}); err != nil {
+		t.Fatalf("WriteHeader failed: %v", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	tw.Close()
+	gw.Close()
+
+	_, err := Read(buf)
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+func TestRead_RequiresManifest(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tar.NewWriter(gw).Close()
+	gw.Close()
+
+	_, err := Read(buf)
+	if !errors.Is(err, ErrMissingManifest) {
+		t.Errorf("expected ErrMissingManifest, got %v", err)
+	}
+}
+
+func TestClient(t *testing.T) {
+	snap, err := Capture(seededStorage(t), Manifest{Namespace: "default"})
+	if err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	c := NewClient(snap)
+	ctx := context.Background()
+
+	if c.Namespace() != "default" {
+		t.Errorf("expected namespace default, got %q", c.Namespace())
+	}
+	job, err := c.GetJob(ctx, "default", "job-1")
+	if err != nil || job.Name != "job-1" {
+		t.Errorf("expected job-1, got %v, %v", job, err)
+	}
+	_, err = c.GetPod(ctx, "other", "pod-1")
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected a NotFound error for a pod in another namespace, got %v", err)
+	}
+	_, err = c.WatchJobs(ctx, metav1.ListOptions{})
+	if !errors.Is(err, ErrNoCluster) {
+		t.Errorf("expected ErrNoCluster, got %v", err)
+	}
+}
@@ -0,0 +1,713 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danroux/sk8l/internal/logger"
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/rs/zerolog"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const DefaultBadgerPath = "/tmp/badger"
+
+type keyValue struct {
+	key   []byte
+	value []byte
+}
+
+// BadgerStorage is the default Storage. Objects are stored protobuf encoded
+// under their own key, index keys hold the primary key of the indexed object
+// as value, run records are stored JSON encoded and container logs gzipped.
+// Events are evicted by Badger once their TTL expires.
+type BadgerStorage struct {
+	db     *badger.DB
+	l      zerolog.Logger
+	limits Limits
+}
+
+// OpenBadgerStorage opens the Badger DB at path.
+func OpenBadgerStorage(path string) (*BadgerStorage, error) {
+	badgerLogger := logger.NewBadgerLogger()
+	badgerOpts := badger.DefaultOptions(path).WithLogger(badgerLogger)
+	db, err := badger.Open(badgerOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Badger DB: %w", err)
+	}
+	return NewBadgerStorage(db), nil
+}
+
+// NewBadgerStorage wraps an already opened Badger DB.
+func NewBadgerStorage(db *badger.DB) *BadgerStorage {
+	return &BadgerStorage{
+		db:     db,
+		l:      logger.For(logger.Store),
+		limits: DefaultLimits,
+	}
+}
+
+func (b *BadgerStorage) Backend() string {
+	return BackendBadger
+}
+
+// Ping verifies that the DB can be read.
+func (b *BadgerStorage) Ping() error {
+	err := b.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get([]byte(CronjobKeyPrefix))
+		if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+			return fmt.Errorf("txn.Get() failed: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("sk8l#ping: DB.View() failed: %w", err)
+	}
+	return nil
+}
+
+func (b *BadgerStorage) Close() error {
+	if err := b.db.Close(); err != nil {
+		return fmt.Errorf("sk8l#Close: DB.Close() failed: %w", err)
+	}
+	return nil
+}
+
+func cronjobEntries(cronjob *batchv1.CronJob) ([]keyValue, error) {
+	value, err := encode(cronjob)
+	if err != nil {
+		return nil, err
+	}
+	return []keyValue{{key: []byte(cronjobKey(cronjob.Namespace, cronjob.Name)), value: value}}, nil
+}
+
+func jobEntries(job *batchv1.Job) ([]keyValue, error) {
+	value, err := encode(job)
+	if err != nil {
+		return nil, err
+	}
+	key := []byte(jobKey(job.Namespace, job.Name))
+	entries := []keyValue{{key: key, value: value}}
+	for _, indexKey := range jobIndexKeys(job) {
+		entries = append(entries, keyValue{key: []byte(indexKey), value: key})
+	}
+	return entries, nil
+}
+
+func jobIndexKeys(job *batchv1.Job) []string {
+	keys := make([]string, 0, len(job.OwnerReferences))
+	for _, owner := range job.OwnerReferences {
+		keys = append(keys, cronjobJobsIndexPrefix(job.Namespace, owner.Name)+job.Name)
+	}
+	return keys
+}
+
+func podEntries(pod *corev1.Pod) ([]keyValue, error) {
+	value, err := encode(pod)
+	if err != nil {
+		return nil, err
+	}
+	key := []byte(podKey(pod.Namespace, pod.Name))
+	entries := []keyValue{{key: key, value: value}}
+	for _, indexKey := range podIndexKeys(pod) {
+		entries = append(entries, keyValue{key: []byte(indexKey), value: key})
+	}
+	return entries, nil
+}
+
+func podIndexKeys(pod *corev1.Pod) []string {
+	keys := make([]string, 0, len(pod.OwnerReferences))
+	for _, owner := range pod.OwnerReferences {
+		keys = append(keys, jobPodsIndexPrefix(pod.Namespace, owner.Name)+pod.Name)
+	}
+	return keys
+}
+
+func encode(obj runtime.Object) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := K8sSerialize(obj, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (b *BadgerStorage) PutCronjob(cronjob *batchv1.CronJob) error {
+	entries, err := cronjobEntries(cronjob)
+	if err != nil {
+		return fmt.Errorf("sk8l#PutCronjob: %w", err)
+	}
+	return b.put("sk8l#PutCronjob", entries)
+}
+
+func (b *BadgerStorage) DeleteCronjob(cronjob *batchv1.CronJob) error {
+	return b.delete("sk8l#DeleteCronjob", []string{cronjobKey(cronjob.Namespace, cronjob.Name)})
+}
+
+func (b *BadgerStorage) ReplaceCronjobs(cronjobs []batchv1.CronJob) error {
+	var entries []keyValue
+	for i := range cronjobs {
+		cronjobEntries, err := cronjobEntries(&cronjobs[i])
+		if err != nil {
+			return fmt.Errorf("sk8l#ReplaceCronjobs: %w", err)
+		}
+		entries = append(entries, cronjobEntries...)
+	}
+	return b.replace("sk8l#ReplaceCronjobs", []string{CronjobKeyPrefix}, entries)
+}
+
+func (b *BadgerStorage) GetCronjob(namespace, name string) (*batchv1.CronJob, error) {
+	cronjob := &batchv1.CronJob{}
+	err := b.db.View(func(txn *badger.Txn) error {
+		return getObject(txn, []byte(cronjobKey(namespace, name)), cronjob)
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, fmt.Errorf("sk8l#GetCronjob: %s/%s %w", namespace, name, ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("sk8l#GetCronjob: DB.View() failed: %w", err)
+	}
+	return cronjob, nil
+}
+
+func (b *BadgerStorage) ListCronjobs() ([]batchv1.CronJob, error) {
+	cronjobs := []batchv1.CronJob{}
+	err := b.db.View(func(txn *badger.Txn) error {
+		return scanPrefix(txn, []byte(CronjobKeyPrefix), func(_, value []byte) error {
+			cronjob := batchv1.CronJob{}
+			if _, _, err := K8sDeserialize(value, &cronjob); err != nil {
+				return err
+			}
+			cronjobs = append(cronjobs, cronjob)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#ListCronjobs: DB.View() failed: %w", err)
+	}
+	return cronjobs, nil
+}
+
+func (b *BadgerStorage) PutJob(job *batchv1.Job) error {
+	entries, err := jobEntries(job)
+	if err != nil {
+		return fmt.Errorf("sk8l#PutJob: %w", err)
+	}
+	return b.put("sk8l#PutJob", entries)
+}
+
+func (b *BadgerStorage) DeleteJob(job *batchv1.Job) error {
+	keys := append([]string{jobKey(job.Namespace, job.Name)}, jobIndexKeys(job)...)
+	return b.delete("sk8l#DeleteJob", keys)
+}
+
+func (b *BadgerStorage) ReplaceJobs(jobs []batchv1.Job) error {
+	var entries []keyValue
+	for i := range jobs {
+		jobEntries, err := jobEntries(&jobs[i])
+		if err != nil {
+			return fmt.Errorf("sk8l#ReplaceJobs: %w", err)
+		}
+		entries = append(entries, jobEntries...)
+	}
+	return b.replace("sk8l#ReplaceJobs", []string{JobKeyPrefix, CronjobJobsIndexPrefix}, entries)
+}
+
+func (b *BadgerStorage) ListJobs() ([]batchv1.Job, error) {
+	jobs := []batchv1.Job{}
+	err := b.db.View(func(txn *badger.Txn) error {
+		return scanPrefix(txn, []byte(JobKeyPrefix), func(_, value []byte) error {
+			job := batchv1.Job{}
+			if _, _, err := K8sDeserialize(value, &job); err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#ListJobs: DB.View() failed: %w", err)
+	}
+	return jobs, nil
+}
+
+func (b *BadgerStorage) JobsByOwner() (map[string][]*batchv1.Job, error) {
+	mapped := make(map[string][]*batchv1.Job)
+	err := b.db.View(func(txn *badger.Txn) error {
+		return scanPrefix(txn, []byte(CronjobJobsIndexPrefix), func(key, primaryKey []byte) error {
+			owner, _, err := parseIndexKey(string(key), CronjobJobsIndexPrefix)
+			if err != nil {
+				return err
+			}
+			job := &batchv1.Job{}
+			if err := getObject(txn, primaryKey, job); err != nil {
+				return err
+			}
+			mapped[owner] = append(mapped[owner], job)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#JobsByOwner: DB.View() failed: %w", err)
+	}
+	return mapped, nil
+}
+
+func (b *BadgerStorage) JobsForCronjob(namespace, cronjobName string) ([]*batchv1.Job, error) {
+	jobs := []*batchv1.Job{}
+	err := b.db.View(func(txn *badger.Txn) error {
+		return scanPrefix(txn, []byte(cronjobJobsIndexPrefix(namespace, cronjobName)), func(_, primaryKey []byte) error {
+			job := &batchv1.Job{}
+			if err := getObject(txn, primaryKey, job); err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#JobsForCronjob: DB.View() failed: %w", err)
+	}
+	return jobs, nil
+}
+
+func (b *BadgerStorage) PutPod(pod *corev1.Pod) error {
+	entries, err := podEntries(pod)
+	if err != nil {
+		return fmt.Errorf("sk8l#PutPod: %w", err)
+	}
+	return b.put("sk8l#PutPod", entries)
+}
+
+func (b *BadgerStorage) DeletePod(pod *corev1.Pod) error {
+	keys := append([]string{podKey(pod.Namespace, pod.Name)}, podIndexKeys(pod)...)
+	return b.delete("sk8l#DeletePod", keys)
+}
+
+func (b *BadgerStorage) ReplacePods(pods []corev1.Pod) error {
+	var entries []keyValue
+	for i := range pods {
+		podEntries, err := podEntries(&pods[i])
+		if err != nil {
+			return fmt.Errorf("sk8l#ReplacePods: %w", err)
+		}
+		entries = append(entries, podEntries...)
+	}
+	return b.replace("sk8l#ReplacePods", []string{PodKeyPrefix, JobPodsIndexPrefix}, entries)
+}
+
+func (b *BadgerStorage) ListPods() ([]corev1.Pod, error) {
+	pods := []corev1.Pod{}
+	err := b.db.View(func(txn *badger.Txn) error {
+		return scanPrefix(txn, []byte(PodKeyPrefix), func(_, value []byte) error {
+			pod := corev1.Pod{}
+			if _, _, err := K8sDeserialize(value, &pod); err != nil {
+				return err
+			}
+			pods = append(pods, pod)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#ListPods: DB.View() failed: %w", err)
+	}
+	return pods, nil
+}
+
+func (b *BadgerStorage) PodsForJob(namespace, jobName string) ([]corev1.Pod, error) {
+	pods := []corev1.Pod{}
+	err := b.db.View(func(txn *badger.Txn) error {
+		return scanPrefix(txn, []byte(jobPodsIndexPrefix(namespace, jobName)), func(_, primaryKey []byte) error {
+			pod := corev1.Pod{}
+			if err := getObject(txn, primaryKey, &pod); err != nil {
+				return err
+			}
+			pods = append(pods, pod)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#PodsForJob: DB.View() failed: %w", err)
+	}
+	return pods, nil
+}
+
+func (b *BadgerStorage) PutRunRecord(record RunRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("sk8l#PutRunRecord: json.Marshal() failed: %w", err)
+	}
+
+	err = b.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Set([]byte(historyKey(record)), value); err != nil {
+			return fmt.Errorf("txn.Set() failed: %w", err)
+		}
+		keys := []string{}
+		scanKeys(txn, []byte(historyPrefix(record.Namespace, record.CronjobName)), func(key []byte) {
+			keys = append(keys, string(key))
+		})
+		// keys are sorted oldest first
+		for len(keys) > b.limits.RunRecords {
+			if err := txn.Delete([]byte(keys[0])); err != nil {
+				return fmt.Errorf("txn.Delete() failed: %w", err)
+			}
+			logKeys := [][]byte{}
+			scanKeys(txn, []byte(historyKeyLogsPrefix(keys[0])), func(key []byte) {
+				logKeys = append(logKeys, key)
+			})
+			for _, key := range logKeys {
+				if err := txn.Delete(key); err != nil {
+					return fmt.Errorf("txn.Delete() failed: %w", err)
+				}
+			}
+			keys = keys[1:]
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("sk8l#PutRunRecord: DB.Update() failed: %w", err)
+	}
+	return nil
+}
+
+func (b *BadgerStorage) RunHistory(namespace, cronjobName string, limit int) ([]RunRecord, error) {
+	records := []RunRecord{}
+	err := b.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(historyPrefix(namespace, cronjobName))
+		opts.Reverse = true
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		// a reverse iteration has to seek past the last key under the prefix
+		for it.Seek(append(opts.Prefix, 0xff)); it.Valid(); it.Next() {
+			if limit > 0 && len(records) >= limit {
+				return nil
+			}
+			err := it.Item().Value(func(value []byte) error {
+				record := RunRecord{}
+				if err := json.Unmarshal(value, &record); err != nil {
+					return fmt.Errorf("json.Unmarshal() failed: %w", err)
+				}
+				records = append(records, record)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("item.Value() failed: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#RunHistory: DB.View() failed: %w", err)
+	}
+	return records, nil
+}
+
+func (b *BadgerStorage) ListRunRecords() ([]RunRecord, error) {
+	records := []RunRecord{}
+	err := b.db.View(func(txn *badger.Txn) error {
+		return scanPrefix(txn, []byte(HistoryKeyPrefix), func(_, value []byte) error {
+			record := RunRecord{}
+			if err := json.Unmarshal(value, &record); err != nil {
+				return fmt.Errorf("json.Unmarshal() failed: %w", err)
+			}
+			records = append(records, record)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#ListRunRecords: DB.View() failed: %w", err)
+	}
+	return records, nil
+}
+
+func (b *BadgerStorage) PutContainerLogs(logs ContainerLogs) error {
+	value, err := encodeContainerLogs(logs)
+	if err != nil {
+		return fmt.Errorf("sk8l#PutContainerLogs: %w", err)
+	}
+	return b.put("sk8l#PutContainerLogs", []keyValue{{key: []byte(logsKey(logs)), value: value}})
+}
+
+func (b *BadgerStorage) HasContainerLogs(namespace, jobName, podName, containerName string) (bool, error) {
+	key := logsKey(ContainerLogs{Namespace: namespace, JobName: jobName, PodName: podName, ContainerName: containerName})
+	err := b.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get([]byte(key))
+		return err
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("sk8l#HasContainerLogs: DB.View() failed: %w", err)
+	}
+	return true, nil
+}
+
+func (b *BadgerStorage) JobContainerLogs(namespace, jobName string) ([]ContainerLogs, error) {
+	logs, err := b.containerLogs(logsPrefix(namespace, jobName))
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#JobContainerLogs: %w", err)
+	}
+	return logs, nil
+}
+
+func (b *BadgerStorage) ListContainerLogs() ([]ContainerLogs, error) {
+	logs, err := b.containerLogs(LogsKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#ListContainerLogs: %w", err)
+	}
+	return logs, nil
+}
+
+func (b *BadgerStorage) containerLogs(prefix string) ([]ContainerLogs, error) {
+	list := []ContainerLogs{}
+	err := b.db.View(func(txn *badger.Txn) error {
+		return scanPrefix(txn, []byte(prefix), func(_, value []byte) error {
+			logs, err := decodeContainerLogs(value)
+			if err != nil {
+				return err
+			}
+			list = append(list, logs)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DB.View() failed: %w", err)
+	}
+	return list, nil
+}
+
+func (b *BadgerStorage) PutCronjobRevision(revision CronjobRevision) error {
+	value, err := json.Marshal(revision)
+	if err != nil {
+		return fmt.Errorf("sk8l#PutCronjobRevision: json.Marshal() failed: %w", err)
+	}
+
+	err = b.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Set([]byte(revisionKey(revision)), value); err != nil {
+			return fmt.Errorf("txn.Set() failed: %w", err)
+		}
+		keys := [][]byte{}
+		scanKeys(txn, []byte(revisionsPrefix(revision.Namespace, revision.CronjobName)), func(key []byte) {
+			keys = append(keys, key)
+		})
+		// keys are sorted oldest first
+		for len(keys) > b.limits.Revisions {
+			if err := txn.Delete(keys[0]); err != nil {
+				return fmt.Errorf("txn.Delete() failed: %w", err)
+			}
+			keys = keys[1:]
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("sk8l#PutCronjobRevision: DB.Update() failed: %w", err)
+	}
+	return nil
+}
+
+func (b *BadgerStorage) CronjobRevisions(namespace, cronjobName string) ([]CronjobRevision, error) {
+	revisions := []CronjobRevision{}
+	err := b.db.View(func(txn *badger.Txn) error {
+		return scanPrefix(txn, []byte(revisionsPrefix(namespace, cronjobName)), func(_, value []byte) error {
+			revision := CronjobRevision{}
+			if err := json.Unmarshal(value, &revision); err != nil {
+				return fmt.Errorf("json.Unmarshal() failed: %w", err)
+			}
+			revisions = append(revisions, revision)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#CronjobRevisions: DB.View() failed: %w", err)
+	}
+	return revisions, nil
+}
+
+func (b *BadgerStorage) DeleteCronjobRevisions(namespace, cronjobName string) error {
+	keys := []string{}
+	err := b.db.View(func(txn *badger.Txn) error {
+		scanKeys(txn, []byte(revisionsPrefix(namespace, cronjobName)), func(key []byte) {
+			keys = append(keys, string(key))
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("sk8l#DeleteCronjobRevisions: DB.View() failed: %w", err)
+	}
+	return b.delete("sk8l#DeleteCronjobRevisions", keys)
+}
+
+func (b *BadgerStorage) PutEvent(event *corev1.Event, ttl time.Duration) error {
+	value, err := encode(event)
+	if err != nil {
+		return fmt.Errorf("sk8l#PutEvent: %w", err)
+	}
+	err = b.db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry([]byte(eventKey(event)), value).WithTTL(ttl))
+	})
+	if err != nil {
+		return fmt.Errorf("sk8l#PutEvent: DB.Update() failed: %w", err)
+	}
+	return nil
+}
+
+func (b *BadgerStorage) DeleteEvent(event *corev1.Event) error {
+	return b.delete("sk8l#DeleteEvent", []string{eventKey(event)})
+}
+
+func (b *BadgerStorage) Events(namespace, kind, name string) ([]corev1.Event, error) {
+	events := []corev1.Event{}
+	err := b.db.View(func(txn *badger.Txn) error {
+		return scanPrefix(txn, []byte(eventsPrefix(namespace, kind, name)), func(_, value []byte) error {
+			event := corev1.Event{}
+			if _, _, err := K8sDeserialize(value, &event); err != nil {
+				return err
+			}
+			events = append(events, event)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#Events: DB.View() failed: %w", err)
+	}
+	SortEvents(events)
+	return events, nil
+}
+
+func (b *BadgerStorage) KeyCounts() (map[string]int, error) {
+	counts := make(map[string]int, len(keyPrefixes))
+	err := b.db.View(func(txn *badger.Txn) error {
+		for _, prefix := range keyPrefixes {
+			counts[prefix] = 0
+			scanKeys(txn, []byte(prefix), func([]byte) { counts[prefix]++ })
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#KeyCounts: DB.View() failed: %w", err)
+	}
+	return counts, nil
+}
+
+func (b *BadgerStorage) put(errContext string, entries []keyValue) error {
+	err := b.db.Update(func(txn *badger.Txn) error {
+		for _, entry := range entries {
+			if err := txn.Set(entry.key, entry.value); err != nil {
+				return fmt.Errorf("txn.Set() failed: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: DB.Update() failed: %w", errContext, err)
+	}
+	return nil
+}
+
+func (b *BadgerStorage) delete(errContext string, keys []string) error {
+	err := b.db.Update(func(txn *badger.Txn) error {
+		for _, key := range keys {
+			if err := txn.Delete([]byte(key)); err != nil {
+				return fmt.Errorf("txn.Delete() failed: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: DB.Update() failed: %w", errContext, err)
+	}
+	return nil
+}
+
+// replace writes entries and deletes every other key under prefixes. It goes
+// through a WriteBatch so a relist of a large cluster is not bound by the
+// size limit of a single transaction.
+func (b *BadgerStorage) replace(errContext string, prefixes []string, entries []keyValue) error {
+	wanted := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		wanted[string(entry.key)] = struct{}{}
+	}
+
+	var stale [][]byte
+	err := b.db.View(func(txn *badger.Txn) error {
+		for _, prefix := range prefixes {
+			scanKeys(txn, []byte(prefix), func(key []byte) {
+				if _, ok := wanted[string(key)]; !ok {
+					stale = append(stale, key)
+				}
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: DB.View() failed: %w", errContext, err)
+	}
+
+	wb := b.db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, entry := range entries {
+		if err := wb.Set(entry.key, entry.value); err != nil {
+			return fmt.Errorf("%s: wb.Set() failed: %w", errContext, err)
+		}
+	}
+	for _, key := range stale {
+		if err := wb.Delete(key); err != nil {
+			return fmt.Errorf("%s: wb.Delete() failed: %w", errContext, err)
+		}
+	}
+	if err := wb.Flush(); err != nil {
+		return fmt.Errorf("%s: wb.Flush() failed: %w", errContext, err)
+	}
+
+	b.l.Info().
+		Str("operation", "replace").
+		Msg(fmt.Sprintf("%s: stored %d keys, removed %d stale keys", errContext, len(entries), len(stale)))
+	return nil
+}
+
+// scanPrefix calls fn with the key and value of every item under prefix.
+func scanPrefix(txn *badger.Txn, prefix []byte, fn func(key, value []byte) error) error {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = prefix
+	it := txn.NewIterator(opts)
+	defer it.Close()
+	for it.Rewind(); it.Valid(); it.Next() {
+		item := it.Item()
+		err := item.Value(func(value []byte) error {
+			return fn(item.Key(), value)
+		})
+		if err != nil {
+			return fmt.Errorf("item.Value() failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// scanKeys calls fn with a copy of every key under prefix without reading values.
+func scanKeys(txn *badger.Txn, prefix []byte, fn func(key []byte)) {
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+	defer it.Close()
+	for it.Rewind(); it.Valid(); it.Next() {
+		fn(it.Item().KeyCopy(nil))
+	}
+}
+
+func getObject(txn *badger.Txn, key []byte, obj runtime.Object) error {
+	item, err := txn.Get(key)
+	if err != nil {
+		return fmt.Errorf("txn.Get(%q) failed: %w", key, err)
+	}
+	err = item.Value(func(value []byte) error {
+		_, _, err := K8sDeserialize(value, obj)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("item.Value() failed: %w", err)
+	}
+	return nil
+}
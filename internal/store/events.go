@@ -0,0 +1,54 @@
+package store
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultEventTTL is how long an Event is kept after it was last seen.
+const DefaultEventTTL = time.Hour
+
+// eventsPrefix narrows down from namespace to kind to the name of the
+// involved object, stopping at the first empty part.
+func eventsPrefix(namespace, kind, name string) string {
+	prefix := EventKeyPrefix + namespace + keySeparator
+	if kind == "" {
+		return prefix
+	}
+	prefix += kind + keySeparator
+	if name == "" {
+		return prefix
+	}
+	return prefix + name + keySeparator
+}
+
+func eventKey(event *corev1.Event) string {
+	return eventsPrefix(event.Namespace, event.InvolvedObject.Kind, event.InvolvedObject.Name) + event.Name
+}
+
+// EventTime is when an Event was last seen, falling back to older timestamps
+// for Events that don't set it.
+func EventTime(event *corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case event.Series != nil && !event.Series.LastObservedTime.IsZero():
+		return event.Series.LastObservedTime.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+// SortEvents sorts events oldest first.
+func SortEvents(events []corev1.Event) {
+	slices.SortStableFunc(events, func(a, b corev1.Event) int {
+		return cmp.Compare(EventTime(&a).UnixNano(), EventTime(&b).UnixNano())
+	})
+}
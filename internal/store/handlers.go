@@ -0,0 +1,201 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danroux/sk8l/internal/k8s"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+var errUnexpectedObject = errors.New("unexpected object type")
+
+// CronjobsHandler keeps the stored CronJobs in sync with a CronJob reflector
+// and records every spec revision it sees.
+func (c *CronJobDBStore) CronjobsHandler() k8s.EventHandler {
+	return k8s.EventHandlerFuncs{
+		ReplaceFunc: func(objs []runtime.Object) error {
+			cronjobs := make([]batchv1.CronJob, 0, len(objs))
+			for _, obj := range objs {
+				if cronjob, ok := obj.(*batchv1.CronJob); ok {
+					cronjobs = append(cronjobs, *cronjob)
+				}
+			}
+			if err := c.ReplaceCronjobs(cronjobs); err != nil {
+				return err
+			}
+			for i := range cronjobs {
+				if err := c.recordRevision(&cronjobs[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		HandleFunc: func(event watch.Event) error {
+			cronjob, ok := event.Object.(*batchv1.CronJob)
+			if !ok {
+				return fmt.Errorf("sk8l#CronjobsHandler: %w %T", errUnexpectedObject, event.Object)
+			}
+			if event.Type == watch.Deleted {
+				return c.DeleteCronjob(cronjob)
+			}
+			if err := c.PutCronjob(cronjob); err != nil {
+				return err
+			}
+			return c.recordRevision(cronjob)
+		},
+	}
+}
+
+// JobsHandler keeps the stored Jobs in sync with a Job reflector and records
+// a run in the history of its owner whenever a Job finishes.
+func (c *CronJobDBStore) JobsHandler() k8s.EventHandler {
+	return k8s.EventHandlerFuncs{
+		ReplaceFunc: func(objs []runtime.Object) error {
+			jobs := make([]batchv1.Job, 0, len(objs))
+			for _, obj := range objs {
+				if job, ok := obj.(*batchv1.Job); ok {
+					jobs = append(jobs, *job)
+				}
+			}
+			if err := c.ReplaceJobs(jobs); err != nil {
+				return err
+			}
+			// Jobs that finished while sk8l was not watching.
+			for i := range jobs {
+				if err := c.recordRun(&jobs[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		HandleFunc: func(event watch.Event) error {
+			job, ok := event.Object.(*batchv1.Job)
+			if !ok {
+				return fmt.Errorf("sk8l#JobsHandler: %w %T", errUnexpectedObject, event.Object)
+			}
+			if event.Type == watch.Deleted {
+				return c.DeleteJob(job)
+			}
+			if err := c.PutJob(job); err != nil {
+				return err
+			}
+			return c.recordRun(job)
+		},
+	}
+}
+
+// PodsHandler keeps the stored Pods in sync with a Pod reflector.
+func (c *CronJobDBStore) PodsHandler() k8s.EventHandler {
+	return k8s.EventHandlerFuncs{
+		ReplaceFunc: func(objs []runtime.Object) error {
+			pods := make([]corev1.Pod, 0, len(objs))
+			for _, obj := range objs {
+				if pod, ok := obj.(*corev1.Pod); ok {
+					pods = append(pods, *pod)
+				}
+			}
+			return c.ReplacePods(pods)
+		},
+		HandleFunc: func(event watch.Event) error {
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok {
+				return fmt.Errorf("sk8l#PodsHandler: %w %T", errUnexpectedObject, event.Object)
+			}
+			if event.Type == watch.Deleted {
+				return c.DeletePod(pod)
+			}
+			return c.PutPod(pod)
+		},
+	}
+}
+
+// EventsHandler stores the Events involving CronJobs, Jobs and Pods. Events
+// are kept for the store's event TTL after they were last seen, so Events
+// that disappear from the cluster simply expire instead of being replaced.
+func (c *CronJobDBStore) EventsHandler() k8s.EventHandler {
+	return k8s.EventHandlerFuncs{
+		ReplaceFunc: func(objs []runtime.Object) error {
+			for _, obj := range objs {
+				if event, ok := obj.(*corev1.Event); ok {
+					if err := c.putEvent(event); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		HandleFunc: func(event watch.Event) error {
+			k8sEvent, ok := event.Object.(*corev1.Event)
+			if !ok {
+				return fmt.Errorf("sk8l#EventsHandler: %w %T", errUnexpectedObject, event.Object)
+			}
+			if event.Type == watch.Deleted {
+				return c.DeleteEvent(k8sEvent)
+			}
+			return c.putEvent(k8sEvent)
+		},
+	}
+}
+
+func (c *CronJobDBStore) putEvent(event *corev1.Event) error {
+	if !trackedEventKinds[event.InvolvedObject.Kind] {
+		return nil
+	}
+	ttl := time.Until(EventTime(event).Add(c.eventTTL))
+	if ttl <= 0 {
+		return nil
+	}
+	return c.PutEvent(event, ttl)
+}
+
+// trackedEventKinds are the kinds of involved objects whose Events are stored.
+var trackedEventKinds = map[string]bool{
+	"CronJob": true,
+	"Job":     true,
+	"Pod":     true,
+}
+
+// recordRevision stores the spec of cronjob when its generation is new. A
+// CronJob that was deleted and created again starts a new history.
+func (c *CronJobDBStore) recordRevision(cronjob *batchv1.CronJob) error {
+	revisions, err := c.CronjobRevisions(cronjob.Namespace, cronjob.Name)
+	if err != nil {
+		return fmt.Errorf("sk8l#recordRevision: %w", err)
+	}
+	if n := len(revisions); n > 0 {
+		latest := revisions[n-1]
+		if latest.CronjobUID == string(cronjob.UID) && latest.Generation >= cronjob.Generation {
+			return nil
+		}
+		if latest.CronjobUID != string(cronjob.UID) {
+			if err := c.DeleteCronjobRevisions(cronjob.Namespace, cronjob.Name); err != nil {
+				return fmt.Errorf("sk8l#recordRevision: %w", err)
+			}
+		}
+	}
+	if err := c.PutCronjobRevision(NewCronjobRevision(cronjob)); err != nil {
+		return fmt.Errorf("sk8l#recordRevision: %w", err)
+	}
+	return nil
+}
+
+func (c *CronJobDBStore) recordRun(job *batchv1.Job) error {
+	record, finished := NewRunRecord(job)
+	if !finished {
+		return nil
+	}
+	revisions, err := c.CronjobRevisions(record.Namespace, record.CronjobName)
+	if err != nil {
+		return fmt.Errorf("sk8l#recordRun: %w", err)
+	}
+	record.Revision = RevisionAt(revisions, job.CreationTimestamp.Time)
+	if err := c.PutRunRecord(record); err != nil {
+		return fmt.Errorf("sk8l#recordRun: %w", err)
+	}
+	return nil
+}
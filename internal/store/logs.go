@@ -0,0 +1,70 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ContainerLogs holds the tail of a failed container's logs, captured before
+// Kubernetes garbage collects its pod.
+type ContainerLogs struct {
+	CapturedAt    time.Time `json:"capturedAt"`
+	Namespace     string    `json:"namespace"`
+	JobName       string    `json:"jobName"`
+	PodName       string    `json:"podName"`
+	ContainerName string    `json:"containerName"`
+	// Error is set instead of Logs when the logs could not be fetched for good.
+	Error string `json:"error,omitempty"`
+	Logs  []byte `json:"logs"`
+	// Truncated reports that only the tail of the logs was kept.
+	Truncated bool `json:"truncated"`
+}
+
+func logsPrefix(namespace, jobName string) string {
+	return LogsKeyPrefix + namespace + keySeparator + jobName + keySeparator
+}
+
+func logsKey(logs ContainerLogs) string {
+	return logsPrefix(logs.Namespace, logs.JobName) + logs.PodName + keySeparator + logs.ContainerName
+}
+
+// historyKeyLogsPrefix returns the prefix of the logs kept for the run stored under a history key.
+func historyKeyLogsPrefix(key string) string {
+	parts := strings.Split(strings.TrimPrefix(key, HistoryKeyPrefix), keySeparator)
+	return logsPrefix(parts[0], parts[len(parts)-1])
+}
+
+// encodeContainerLogs gzips the JSON encoded logs, both backends store them compressed.
+func encodeContainerLogs(logs ContainerLogs) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	if err := json.NewEncoder(gw).Encode(logs); err != nil {
+		return nil, fmt.Errorf("json.Encode() failed: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return nil, fmt.Errorf("gzip.Close() failed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeContainerLogs(value []byte) (ContainerLogs, error) {
+	logs := ContainerLogs{}
+	gr, err := gzip.NewReader(bytes.NewReader(value))
+	if err != nil {
+		return logs, fmt.Errorf("gzip.NewReader() failed: %w", err)
+	}
+	defer gr.Close()
+	data, err := io.ReadAll(gr)
+	if err != nil {
+		return logs, fmt.Errorf("gzip read failed: %w", err)
+	}
+	if err := json.Unmarshal(data, &logs); err != nil {
+		return logs, fmt.Errorf("json.Unmarshal() failed: %w", err)
+	}
+	return logs, nil
+}
@@ -0,0 +1,443 @@
+package store
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// MemoryStorage is a Storage kept entirely in process memory. It is meant for
+// tests and for small deployments that do not need the state to survive a
+// restart. It uses the same keys as BadgerStorage, so list results come back
+// in the same order.
+type MemoryStorage struct {
+	cronjobs map[string]*batchv1.CronJob
+	jobs     map[string]*batchv1.Job
+	pods     map[string]*corev1.Pod
+	// index holds the index keys of both owner indexes, mapped to the primary key.
+	index   map[string]string
+	history map[string]RunRecord
+	// logs holds container logs encoded as in BadgerStorage, so they are kept compressed.
+	logs      map[string][]byte
+	events    map[string]memoryEvent
+	revisions map[string]CronjobRevision
+	limits    Limits
+	now       func() time.Time
+	mu        sync.RWMutex
+}
+
+type memoryEvent struct {
+	expiresAt time.Time
+	event     *corev1.Event
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		cronjobs:  make(map[string]*batchv1.CronJob),
+		jobs:      make(map[string]*batchv1.Job),
+		pods:      make(map[string]*corev1.Pod),
+		index:     make(map[string]string),
+		history:   make(map[string]RunRecord),
+		logs:      make(map[string][]byte),
+		events:    make(map[string]memoryEvent),
+		revisions: make(map[string]CronjobRevision),
+		limits:    DefaultLimits,
+		now:       time.Now,
+	}
+}
+
+func (m *MemoryStorage) Backend() string {
+	return BackendMemory
+}
+
+func (m *MemoryStorage) Ping() error {
+	return nil
+}
+
+func (m *MemoryStorage) Close() error {
+	return nil
+}
+
+func (m *MemoryStorage) PutCronjob(cronjob *batchv1.CronJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cronjobs[cronjobKey(cronjob.Namespace, cronjob.Name)] = cronjob.DeepCopy()
+	return nil
+}
+
+func (m *MemoryStorage) DeleteCronjob(cronjob *batchv1.CronJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.cronjobs, cronjobKey(cronjob.Namespace, cronjob.Name))
+	return nil
+}
+
+func (m *MemoryStorage) ReplaceCronjobs(cronjobs []batchv1.CronJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.cronjobs)
+	for i := range cronjobs {
+		m.cronjobs[cronjobKey(cronjobs[i].Namespace, cronjobs[i].Name)] = cronjobs[i].DeepCopy()
+	}
+	return nil
+}
+
+func (m *MemoryStorage) GetCronjob(namespace, name string) (*batchv1.CronJob, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cronjob, ok := m.cronjobs[cronjobKey(namespace, name)]
+	if !ok {
+		return nil, fmt.Errorf("sk8l#GetCronjob: %s/%s %w", namespace, name, ErrNotFound)
+	}
+	return cronjob.DeepCopy(), nil
+}
+
+func (m *MemoryStorage) ListCronjobs() ([]batchv1.CronJob, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cronjobs := make([]batchv1.CronJob, 0, len(m.cronjobs))
+	for _, key := range slices.Sorted(maps.Keys(m.cronjobs)) {
+		cronjobs = append(cronjobs, *m.cronjobs[key].DeepCopy())
+	}
+	return cronjobs, nil
+}
+
+func (m *MemoryStorage) PutJob(job *batchv1.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.putJob(job)
+	return nil
+}
+
+func (m *MemoryStorage) putJob(job *batchv1.Job) {
+	key := jobKey(job.Namespace, job.Name)
+	m.jobs[key] = job.DeepCopy()
+	for _, indexKey := range jobIndexKeys(job) {
+		m.index[indexKey] = key
+	}
+}
+
+func (m *MemoryStorage) DeleteJob(job *batchv1.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.jobs, jobKey(job.Namespace, job.Name))
+	for _, indexKey := range jobIndexKeys(job) {
+		delete(m.index, indexKey)
+	}
+	return nil
+}
+
+func (m *MemoryStorage) ReplaceJobs(jobs []batchv1.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.jobs)
+	m.clearIndex(CronjobJobsIndexPrefix)
+	for i := range jobs {
+		m.putJob(&jobs[i])
+	}
+	return nil
+}
+
+func (m *MemoryStorage) ListJobs() ([]batchv1.Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	jobs := make([]batchv1.Job, 0, len(m.jobs))
+	for _, key := range slices.Sorted(maps.Keys(m.jobs)) {
+		jobs = append(jobs, *m.jobs[key].DeepCopy())
+	}
+	return jobs, nil
+}
+
+func (m *MemoryStorage) JobsByOwner() (map[string][]*batchv1.Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	mapped := make(map[string][]*batchv1.Job)
+	for _, indexKey := range m.indexKeys(CronjobJobsIndexPrefix) {
+		owner, _, err := parseIndexKey(indexKey, CronjobJobsIndexPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("sk8l#JobsByOwner: %w", err)
+		}
+		if job, ok := m.jobs[m.index[indexKey]]; ok {
+			mapped[owner] = append(mapped[owner], job.DeepCopy())
+		}
+	}
+	return mapped, nil
+}
+
+func (m *MemoryStorage) JobsForCronjob(namespace, cronjobName string) ([]*batchv1.Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	jobs := []*batchv1.Job{}
+	for _, indexKey := range m.indexKeys(cronjobJobsIndexPrefix(namespace, cronjobName)) {
+		if job, ok := m.jobs[m.index[indexKey]]; ok {
+			jobs = append(jobs, job.DeepCopy())
+		}
+	}
+	return jobs, nil
+}
+
+func (m *MemoryStorage) PutPod(pod *corev1.Pod) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.putPod(pod)
+	return nil
+}
+
+func (m *MemoryStorage) putPod(pod *corev1.Pod) {
+	key := podKey(pod.Namespace, pod.Name)
+	m.pods[key] = pod.DeepCopy()
+	for _, indexKey := range podIndexKeys(pod) {
+		m.index[indexKey] = key
+	}
+}
+
+func (m *MemoryStorage) DeletePod(pod *corev1.Pod) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pods, podKey(pod.Namespace, pod.Name))
+	for _, indexKey := range podIndexKeys(pod) {
+		delete(m.index, indexKey)
+	}
+	return nil
+}
+
+func (m *MemoryStorage) ReplacePods(pods []corev1.Pod) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.pods)
+	m.clearIndex(JobPodsIndexPrefix)
+	for i := range pods {
+		m.putPod(&pods[i])
+	}
+	return nil
+}
+
+func (m *MemoryStorage) ListPods() ([]corev1.Pod, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	pods := make([]corev1.Pod, 0, len(m.pods))
+	for _, key := range slices.Sorted(maps.Keys(m.pods)) {
+		pods = append(pods, *m.pods[key].DeepCopy())
+	}
+	return pods, nil
+}
+
+func (m *MemoryStorage) PodsForJob(namespace, jobName string) ([]corev1.Pod, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	pods := []corev1.Pod{}
+	for _, indexKey := range m.indexKeys(jobPodsIndexPrefix(namespace, jobName)) {
+		if pod, ok := m.pods[m.index[indexKey]]; ok {
+			pods = append(pods, *pod.DeepCopy())
+		}
+	}
+	return pods, nil
+}
+
+func (m *MemoryStorage) PutRunRecord(record RunRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.history[historyKey(record)] = record
+
+	keys := m.historyKeys(historyPrefix(record.Namespace, record.CronjobName))
+	for len(keys) > m.limits.RunRecords {
+		delete(m.history, keys[0])
+		for _, key := range prefixedKeys(m.logs, historyKeyLogsPrefix(keys[0])) {
+			delete(m.logs, key)
+		}
+		keys = keys[1:]
+	}
+	return nil
+}
+
+func (m *MemoryStorage) RunHistory(namespace, cronjobName string, limit int) ([]RunRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := m.historyKeys(historyPrefix(namespace, cronjobName))
+	slices.Reverse(keys)
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	records := make([]RunRecord, 0, len(keys))
+	for _, key := range keys {
+		records = append(records, m.history[key])
+	}
+	return records, nil
+}
+
+func (m *MemoryStorage) ListRunRecords() ([]RunRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := m.historyKeys(HistoryKeyPrefix)
+	records := make([]RunRecord, 0, len(keys))
+	for _, key := range keys {
+		records = append(records, m.history[key])
+	}
+	return records, nil
+}
+
+func (m *MemoryStorage) PutContainerLogs(logs ContainerLogs) error {
+	value, err := encodeContainerLogs(logs)
+	if err != nil {
+		return fmt.Errorf("sk8l#PutContainerLogs: %w", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logs[logsKey(logs)] = value
+	return nil
+}
+
+func (m *MemoryStorage) HasContainerLogs(namespace, jobName, podName, containerName string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.logs[logsKey(ContainerLogs{Namespace: namespace, JobName: jobName, PodName: podName, ContainerName: containerName})]
+	return ok, nil
+}
+
+func (m *MemoryStorage) JobContainerLogs(namespace, jobName string) ([]ContainerLogs, error) {
+	logs, err := m.containerLogs(logsPrefix(namespace, jobName))
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#JobContainerLogs: %w", err)
+	}
+	return logs, nil
+}
+
+func (m *MemoryStorage) ListContainerLogs() ([]ContainerLogs, error) {
+	logs, err := m.containerLogs(LogsKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#ListContainerLogs: %w", err)
+	}
+	return logs, nil
+}
+
+func (m *MemoryStorage) containerLogs(prefix string) ([]ContainerLogs, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	list := []ContainerLogs{}
+	for _, key := range prefixedKeys(m.logs, prefix) {
+		logs, err := decodeContainerLogs(m.logs[key])
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, logs)
+	}
+	return list, nil
+}
+
+func (m *MemoryStorage) PutCronjobRevision(revision CronjobRevision) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revisions[revisionKey(revision)] = revision
+
+	keys := prefixedKeys(m.revisions, revisionsPrefix(revision.Namespace, revision.CronjobName))
+	for len(keys) > m.limits.Revisions {
+		delete(m.revisions, keys[0])
+		keys = keys[1:]
+	}
+	return nil
+}
+
+func (m *MemoryStorage) CronjobRevisions(namespace, cronjobName string) ([]CronjobRevision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := prefixedKeys(m.revisions, revisionsPrefix(namespace, cronjobName))
+	revisions := make([]CronjobRevision, 0, len(keys))
+	for _, key := range keys {
+		revisions = append(revisions, m.revisions[key])
+	}
+	return revisions, nil
+}
+
+func (m *MemoryStorage) DeleteCronjobRevisions(namespace, cronjobName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range prefixedKeys(m.revisions, revisionsPrefix(namespace, cronjobName)) {
+		delete(m.revisions, key)
+	}
+	return nil
+}
+
+func (m *MemoryStorage) PutEvent(event *corev1.Event, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	// Evict expired events on write, reads skip them.
+	for key, stored := range m.events {
+		if !now.Before(stored.expiresAt) {
+			delete(m.events, key)
+		}
+	}
+	m.events[eventKey(event)] = memoryEvent{expiresAt: now.Add(ttl), event: event.DeepCopy()}
+	return nil
+}
+
+func (m *MemoryStorage) DeleteEvent(event *corev1.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.events, eventKey(event))
+	return nil
+}
+
+func (m *MemoryStorage) Events(namespace, kind, name string) ([]corev1.Event, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	now := m.now()
+	events := []corev1.Event{}
+	for _, key := range prefixedKeys(m.events, eventsPrefix(namespace, kind, name)) {
+		if stored := m.events[key]; now.Before(stored.expiresAt) {
+			events = append(events, *stored.event.DeepCopy())
+		}
+	}
+	SortEvents(events)
+	return events, nil
+}
+
+func (m *MemoryStorage) KeyCounts() (map[string]int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return map[string]int{
+		CronjobKeyPrefix:       len(m.cronjobs),
+		JobKeyPrefix:           len(m.jobs),
+		PodKeyPrefix:           len(m.pods),
+		HistoryKeyPrefix:       len(m.history),
+		LogsKeyPrefix:          len(m.logs),
+		EventKeyPrefix:         len(m.events),
+		RevisionKeyPrefix:      len(m.revisions),
+		CronjobJobsIndexPrefix: len(m.indexKeys(CronjobJobsIndexPrefix)),
+		JobPodsIndexPrefix:     len(m.indexKeys(JobPodsIndexPrefix)),
+	}, nil
+}
+
+// indexKeys returns the sorted index keys under prefix. Callers hold m.mu.
+func (m *MemoryStorage) indexKeys(prefix string) []string {
+	return prefixedKeys(m.index, prefix)
+}
+
+func (m *MemoryStorage) clearIndex(prefix string) {
+	for _, key := range m.indexKeys(prefix) {
+		delete(m.index, key)
+	}
+}
+
+// historyKeys returns the sorted history keys under prefix, oldest first. Callers hold m.mu.
+func (m *MemoryStorage) historyKeys(prefix string) []string {
+	return prefixedKeys(m.history, prefix)
+}
+
+// prefixedKeys returns the sorted keys of entries under prefix.
+func prefixedKeys[V any](entries map[string]V, prefix string) []string {
+	keys := []string{}
+	for key := range entries {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// MaxCronjobRevisions is the default number of spec revisions kept per CronJob.
+const MaxCronjobRevisions = 50
+
+// CronjobRevision is the spec of a CronJob at one generation.
+type CronjobRevision struct {
+	// RecordedAt is when the spec was changed to this revision.
+	RecordedAt  time.Time           `json:"recordedAt"`
+	Namespace   string              `json:"namespace"`
+	CronjobName string              `json:"cronjobName"`
+	CronjobUID  string              `json:"cronjobUID"`
+	Generation  int64               `json:"generation"`
+	Spec        batchv1.CronJobSpec `json:"spec"`
+}
+
+// NewCronjobRevision returns the revision cronjob is currently at.
+func NewCronjobRevision(cronjob *batchv1.CronJob) CronjobRevision {
+	return CronjobRevision{
+		RecordedAt:  specChangedAt(cronjob),
+		Namespace:   cronjob.Namespace,
+		CronjobName: cronjob.Name,
+		CronjobUID:  string(cronjob.UID),
+		Generation:  cronjob.Generation,
+		Spec:        *cronjob.Spec.DeepCopy(),
+	}
+}
+
+// specChangedAt approximates when the spec of cronjob last changed. Kubernetes
+// only keeps the time of the last write of each field manager, status writes
+// go through a subresource and are skipped.
+func specChangedAt(cronjob *batchv1.CronJob) time.Time {
+	if cronjob.Generation <= 1 && !cronjob.CreationTimestamp.IsZero() {
+		return cronjob.CreationTimestamp.UTC()
+	}
+	changedAt := time.Time{}
+	for _, entry := range cronjob.ManagedFields {
+		if entry.Subresource == "" && entry.Time != nil && entry.Time.After(changedAt) {
+			changedAt = entry.Time.Time
+		}
+	}
+	if changedAt.IsZero() {
+		return time.Now().UTC()
+	}
+	return changedAt.UTC()
+}
+
+// RevisionAt returns the generation of the latest of revisions, sorted oldest
+// first, recorded at or before t, or 0 when t predates all of them.
+func RevisionAt(revisions []CronjobRevision, t time.Time) int64 {
+	generation := int64(0)
+	for _, revision := range revisions {
+		if revision.RecordedAt.After(t) {
+			break
+		}
+		generation = revision.Generation
+	}
+	return generation
+}
+
+func revisionsPrefix(namespace, cronjobName string) string {
+	return RevisionKeyPrefix + namespace + keySeparator + cronjobName + keySeparator
+}
+
+// revisionKey orders the revisions of a CronJob by generation.
+func revisionKey(revision CronjobRevision) string {
+	return fmt.Sprintf("%s%020d", revisionsPrefix(revision.Namespace, revision.CronjobName), revision.Generation)
+}
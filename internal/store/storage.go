@@ -0,0 +1,257 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Every object is stored under its own key. Secondary index keys point from an
+// owner to the objects it owns and hold the owned object's primary key as value.
+const (
+	CronjobKeyPrefix       = "cj/"
+	JobKeyPrefix           = "job/"
+	PodKeyPrefix           = "pod/"
+	HistoryKeyPrefix       = "history/"
+	LogsKeyPrefix          = "logs/"
+	EventKeyPrefix         = "event/"
+	RevisionKeyPrefix      = "rev/"
+	CronjobJobsIndexPrefix = "idx/cj-jobs/"
+	JobPodsIndexPrefix     = "idx/job-pods/"
+	keySeparator           = "/"
+	indexKeyParts          = 3 // namespace, owner and name
+
+	// MaxRunRecords is the default number of run records kept per CronJob.
+	MaxRunRecords = 100
+)
+
+// keyPrefixes are the prefixes of every stored key.
+var keyPrefixes = []string{
+	CronjobKeyPrefix,
+	JobKeyPrefix,
+	PodKeyPrefix,
+	HistoryKeyPrefix,
+	LogsKeyPrefix,
+	EventKeyPrefix,
+	RevisionKeyPrefix,
+	CronjobJobsIndexPrefix,
+	JobPodsIndexPrefix,
+}
+
+const (
+	BackendBadger = "badger"
+	BackendMemory = "memory"
+)
+
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrUnknownBackend    = errors.New("unknown storage backend")
+	ErrInvalidLimits     = errors.New("limits must be positive")
+	errMalformedIndexKey = errors.New("malformed index key")
+)
+
+// Storage persists the CronJobs, Jobs and Pods tracked by the watchers, the
+// owner indexes between them, the run history of finished Jobs, the logs
+// captured from their failed containers, the spec revisions of the CronJobs
+// and the Events involving them.
+type Storage interface {
+	PutCronjob(cronjob *batchv1.CronJob) error
+	DeleteCronjob(cronjob *batchv1.CronJob) error
+	// ReplaceCronjobs makes the stored CronJobs match cronjobs, removing any that are missing.
+	ReplaceCronjobs(cronjobs []batchv1.CronJob) error
+	// GetCronjob returns ErrNotFound when the CronJob is not stored.
+	GetCronjob(namespace, name string) (*batchv1.CronJob, error)
+	ListCronjobs() ([]batchv1.CronJob, error)
+
+	// PutJob stores a Job and indexes it under each of its owners.
+	PutJob(job *batchv1.Job) error
+	DeleteJob(job *batchv1.Job) error
+	ReplaceJobs(jobs []batchv1.Job) error
+	ListJobs() ([]batchv1.Job, error)
+	// JobsByOwner returns every owned Job keyed by the OwnerKey of its owner.
+	JobsByOwner() (map[string][]*batchv1.Job, error)
+	JobsForCronjob(namespace, cronjobName string) ([]*batchv1.Job, error)
+
+	// PutPod stores a Pod and indexes it under each of its owners.
+	PutPod(pod *corev1.Pod) error
+	DeletePod(pod *corev1.Pod) error
+	ReplacePods(pods []corev1.Pod) error
+	ListPods() ([]corev1.Pod, error)
+	PodsForJob(namespace, jobName string) ([]corev1.Pod, error)
+
+	// PutRunRecord stores the outcome of a finished Job. Storing the same run
+	// again overwrites it.
+	PutRunRecord(record RunRecord) error
+	// RunHistory returns up to limit run records of a CronJob, newest first.
+	// A limit <= 0 returns all of them.
+	RunHistory(namespace, cronjobName string, limit int) ([]RunRecord, error)
+	// ListRunRecords returns the run records of every CronJob, oldest first per CronJob.
+	ListRunRecords() ([]RunRecord, error)
+
+	// PutContainerLogs stores the captured logs of a container. They are kept
+	// next to the run record of their Job and pruned along with it.
+	PutContainerLogs(logs ContainerLogs) error
+	HasContainerLogs(namespace, jobName, podName, containerName string) (bool, error)
+	// JobContainerLogs returns every stored container log of a Job, ordered by pod and container.
+	JobContainerLogs(namespace, jobName string) ([]ContainerLogs, error)
+	ListContainerLogs() ([]ContainerLogs, error)
+
+	// PutCronjobRevision stores a spec revision of a CronJob, overwriting one
+	// of the same generation and pruning the oldest beyond the Limits.
+	PutCronjobRevision(revision CronjobRevision) error
+	// CronjobRevisions returns the stored revisions of a CronJob, oldest first.
+	CronjobRevisions(namespace, cronjobName string) ([]CronjobRevision, error)
+	DeleteCronjobRevisions(namespace, cronjobName string) error
+
+	// PutEvent stores an Event for ttl, after which it is evicted.
+	PutEvent(event *corev1.Event, ttl time.Duration) error
+	DeleteEvent(event *corev1.Event) error
+	// Events returns the Events involving an object, oldest first. An empty name
+	// matches every object of kind, an empty kind every object in namespace.
+	Events(namespace, kind, name string) ([]corev1.Event, error)
+
+	// KeyCounts returns the number of stored keys under each key prefix.
+	KeyCounts() (map[string]int, error)
+
+	// Backend names the implementation, e.g. for logs and server info.
+	Backend() string
+	Ping() error
+	Close() error
+}
+
+// RunRecord is the outcome of a single finished Job run. It outlives the Job
+// itself, which Kubernetes garbage collects according to the CronJob's history limits.
+type RunRecord struct {
+	StartTime      time.Time `json:"startTime"`
+	CompletionTime time.Time `json:"completionTime"`
+	Namespace      string    `json:"namespace"`
+	CronjobName    string    `json:"cronjobName"`
+	JobName        string    `json:"jobName"`
+	JobUID         string    `json:"jobUID"`
+	// Revision is the generation of the CronJob spec the Job ran under, 0 when unknown.
+	Revision  int64  `json:"revision,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Succeeded bool   `json:"succeeded"`
+}
+
+// NewRunRecord returns the run record of job, or false while the job has not
+// finished or has no owner.
+func NewRunRecord(job *batchv1.Job) (RunRecord, bool) {
+	if len(job.OwnerReferences) == 0 {
+		return RunRecord{}, false
+	}
+
+	record := RunRecord{
+		Namespace:   job.Namespace,
+		CronjobName: job.OwnerReferences[0].Name,
+		JobName:     job.Name,
+		JobUID:      string(job.UID),
+	}
+	if job.Status.StartTime != nil {
+		record.StartTime = job.Status.StartTime.Time
+	} else {
+		record.StartTime = job.CreationTimestamp.Time
+	}
+
+	for _, condition := range job.Status.Conditions {
+		finished := condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed
+		if !finished || condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		record.Succeeded = condition.Type == batchv1.JobComplete
+		record.CompletionTime = condition.LastTransitionTime.Time
+		record.Reason = condition.Reason
+		record.Message = condition.Message
+		if job.Status.CompletionTime != nil {
+			record.CompletionTime = job.Status.CompletionTime.Time
+		}
+		return record, true
+	}
+	return RunRecord{}, false
+}
+
+// Limits bound what a Storage keeps per CronJob, older entries are pruned.
+type Limits struct {
+	RunRecords int
+	Revisions  int
+}
+
+// DefaultLimits keeps MaxRunRecords run records and MaxCronjobRevisions revisions.
+var DefaultLimits = Limits{RunRecords: MaxRunRecords, Revisions: MaxCronjobRevisions}
+
+// NewStorage returns the Storage implementation registered as backend. The
+// Badger backend is opened at path.
+func NewStorage(backend, path string, limits Limits) (Storage, error) {
+	if limits.RunRecords <= 0 || limits.Revisions <= 0 {
+		return nil, fmt.Errorf("NewStorage: %w: %+v", ErrInvalidLimits, limits)
+	}
+	switch backend {
+	case "", BackendBadger:
+		storage, err := OpenBadgerStorage(path)
+		if err != nil {
+			return nil, err
+		}
+		storage.limits = limits
+		return storage, nil
+	case BackendMemory:
+		storage := NewMemoryStorage()
+		storage.limits = limits
+		return storage, nil
+	default:
+		return nil, fmt.Errorf("NewStorage: %w %q", ErrUnknownBackend, backend)
+	}
+}
+
+// OwnerKey is the key of the Jobs of a CronJob in the map of JobsByOwner.
+func OwnerKey(namespace, name string) string {
+	return namespace + keySeparator + name
+}
+
+func cronjobKey(namespace, name string) string {
+	return CronjobKeyPrefix + namespace + keySeparator + name
+}
+
+func jobKey(namespace, name string) string {
+	return JobKeyPrefix + namespace + keySeparator + name
+}
+
+func podKey(namespace, name string) string {
+	return PodKeyPrefix + namespace + keySeparator + name
+}
+
+func cronjobJobsIndexPrefix(namespace, cronjobName string) string {
+	return CronjobJobsIndexPrefix + namespace + keySeparator + cronjobName + keySeparator
+}
+
+func jobPodsIndexPrefix(namespace, jobName string) string {
+	return JobPodsIndexPrefix + namespace + keySeparator + jobName + keySeparator
+}
+
+func historyPrefix(namespace, cronjobName string) string {
+	return HistoryKeyPrefix + namespace + keySeparator + cronjobName + keySeparator
+}
+
+// historyKey orders the runs of a CronJob by start time.
+func historyKey(record RunRecord) string {
+	return fmt.Sprintf("%s%020d%s%s",
+		historyPrefix(record.Namespace, record.CronjobName),
+		record.StartTime.UnixNano(),
+		keySeparator,
+		record.JobName,
+	)
+}
+
+// parseIndexKey splits <prefix><ns>/<owner>/<name> into the OwnerKey of its
+// owner and its name.
+func parseIndexKey(key, prefix string) (owner string, name string, err error) {
+	parts := strings.Split(strings.TrimPrefix(key, prefix), keySeparator)
+	if len(parts) != indexKeyParts {
+		return "", "", fmt.Errorf("%w: %q", errMalformedIndexKey, key)
+	}
+	return OwnerKey(parts[0], parts[1]), parts[2], nil
+}
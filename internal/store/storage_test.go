@@ -0,0 +1,524 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/danroux/sk8l/internal/k8s"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func storageBackends(t *testing.T) map[string]Storage {
+	t.Helper()
+	return map[string]Storage{
+		BackendBadger: NewBadgerStorage(setupTestDB(t)),
+		BackendMemory: NewMemoryStorage(),
+	}
+}
+
+func ownedJob(name, owner string) *batchv1.Job {
+	return &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       "default",
+		OwnerReferences: []metav1.OwnerReference{{Name: owner}},
+	}}
+}
+
+func ownedPod(name, owner string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       "default",
+		OwnerReferences: []metav1.OwnerReference{{Name: owner}},
+	}}
+}
+
+func TestStorage_Cronjobs(t *testing.T) {
+	for backend, s := range storageBackends(t) {
+		t.Run(backend, func(t *testing.T) {
+			if s.Backend() != backend {
+				t.Errorf("expected backend %q, got %q", backend, s.Backend())
+			}
+
+			_, err := s.GetCronjob("default", "missing")
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+
+			cj := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "cj-b", Namespace: "default"}}
+			if err := s.PutCronjob(cj); err != nil {
+				t.Fatalf("PutCronjob failed: %v", err)
+			}
+			err = s.ReplaceCronjobs([]batchv1.CronJob{
+				{ObjectMeta: metav1.ObjectMeta{Name: "cj-c", Namespace: "default"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "cj-a", Namespace: "default"}},
+			})
+			if err != nil {
+				t.Fatalf("ReplaceCronjobs failed: %v", err)
+			}
+
+			cronjobs, err := s.ListCronjobs()
+			if err != nil {
+				t.Fatalf("ListCronjobs failed: %v", err)
+			}
+			if len(cronjobs) != 2 || cronjobs[0].Name != "cj-a" || cronjobs[1].Name != "cj-c" {
+				t.Errorf("expected [cj-a cj-c], got %+v", cronjobs)
+			}
+
+			if err := s.DeleteCronjob(&cronjobs[0]); err != nil {
+				t.Fatalf("DeleteCronjob failed: %v", err)
+			}
+			found, err := s.GetCronjob("default", "cj-c")
+			if err != nil || found.Name != "cj-c" {
+				t.Errorf("expected cj-c, got %v, %v", found, err)
+			}
+		})
+	}
+}
+
+func TestStorage_JobAndPodIndexes(t *testing.T) {
+	for backend, s := range storageBackends(t) {
+		t.Run(backend, func(t *testing.T) {
+			err := s.ReplaceJobs([]batchv1.Job{*ownedJob("job-a", "cj-1"), *ownedJob("job-b", "cj-2")})
+			if err != nil {
+				t.Fatalf("ReplaceJobs failed: %v", err)
+			}
+			if err := s.PutJob(ownedJob("job-c", "cj-1")); err != nil {
+				t.Fatalf("PutJob failed: %v", err)
+			}
+			if err := s.DeleteJob(ownedJob("job-b", "cj-2")); err != nil {
+				t.Fatalf("DeleteJob failed: %v", err)
+			}
+			batchJob := ownedJob("job-d", "cj-1")
+			batchJob.Namespace = "batch"
+			if err := s.PutJob(batchJob); err != nil {
+				t.Fatalf("PutJob failed: %v", err)
+			}
+
+			mapped, err := s.JobsByOwner()
+			if err != nil {
+				t.Fatalf("JobsByOwner failed: %v", err)
+			}
+			if len(mapped) != 2 || len(mapped[OwnerKey("default", "cj-1")]) != 2 || len(mapped[OwnerKey("batch", "cj-1")]) != 1 {
+				t.Errorf("expected 2 jobs for cj-1 in default and 1 in batch, got %v", mapped)
+			}
+
+			jobs, err := s.JobsForCronjob("default", "cj-1")
+			if err != nil {
+				t.Fatalf("JobsForCronjob failed: %v", err)
+			}
+			if len(jobs) != 2 || jobs[0].Name != "job-a" || jobs[1].Name != "job-c" {
+				t.Errorf("expected [job-a job-c], got %v", jobs)
+			}
+
+			if err := s.ReplacePods([]corev1.Pod{*ownedPod("pod-a", "job-a")}); err != nil {
+				t.Fatalf("ReplacePods failed: %v", err)
+			}
+			if err := s.ReplacePods([]corev1.Pod{*ownedPod("pod-c", "job-c")}); err != nil {
+				t.Fatalf("ReplacePods failed: %v", err)
+			}
+			pods, err := s.PodsForJob("default", "job-a")
+			if err != nil {
+				t.Fatalf("PodsForJob failed: %v", err)
+			}
+			if len(pods) != 0 {
+				t.Errorf("expected pods of job-a to be replaced, got %d", len(pods))
+			}
+			pods, err = s.PodsForJob("default", "job-c")
+			if err != nil {
+				t.Fatalf("PodsForJob failed: %v", err)
+			}
+			if len(pods) != 1 || pods[0].Name != "pod-c" {
+				t.Errorf("expected pod-c, got %v", pods)
+			}
+			pods, err = s.ListPods()
+			if err != nil || len(pods) != 1 {
+				t.Errorf("expected 1 stored pod, got %v, %v", pods, err)
+			}
+		})
+	}
+}
+
+func TestStorage_KeyCounts(t *testing.T) {
+	for backend, s := range storageBackends(t) {
+		t.Run(backend, func(t *testing.T) {
+			if err := s.PutJob(ownedJob("job-a", "cj-1")); err != nil {
+				t.Fatalf("PutJob failed: %v", err)
+			}
+			if err := s.PutPod(ownedPod("pod-a", "job-a")); err != nil {
+				t.Fatalf("PutPod failed: %v", err)
+			}
+			counts, err := s.KeyCounts()
+			if err != nil {
+				t.Fatalf("KeyCounts failed: %v", err)
+			}
+			if len(counts) != len(keyPrefixes) || counts[CronjobKeyPrefix] != 0 {
+				t.Errorf("expected a count for every key prefix, got %v", counts)
+			}
+			for _, prefix := range []string{JobKeyPrefix, PodKeyPrefix, CronjobJobsIndexPrefix, JobPodsIndexPrefix} {
+				if counts[prefix] != 1 {
+					t.Errorf("expected 1 key under %s, got %v", prefix, counts)
+				}
+			}
+		})
+	}
+}
+
+func TestStorage_RunHistory(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for backend, s := range storageBackends(t) {
+		t.Run(backend, func(t *testing.T) {
+			for i := range MaxRunRecords + 5 {
+				record := RunRecord{
+					Namespace:   "default",
+					CronjobName: "cj-1",
+					JobName:     fmt.Sprintf("job-%d", i),
+					StartTime:   start.Add(time.Duration(i) * time.Minute),
+					Succeeded:   true,
+				}
+				if err := s.PutRunRecord(record); err != nil {
+					t.Fatalf("PutRunRecord failed: %v", err)
+				}
+			}
+
+			all, err := s.RunHistory("default", "cj-1", 0)
+			if err != nil {
+				t.Fatalf("RunHistory failed: %v", err)
+			}
+			if len(all) != MaxRunRecords {
+				t.Errorf("expected history to be capped at %d, got %d", MaxRunRecords, len(all))
+			}
+
+			latest, err := s.RunHistory("default", "cj-1", 2)
+			if err != nil {
+				t.Fatalf("RunHistory failed: %v", err)
+			}
+			last := fmt.Sprintf("job-%d", MaxRunRecords+4)
+			if len(latest) != 2 || latest[0].JobName != last {
+				t.Errorf("expected newest first starting with %s, got %+v", last, latest)
+			}
+
+			other, err := s.RunHistory("default", "cj-2", 0)
+			if err != nil || len(other) != 0 {
+				t.Errorf("expected no history for cj-2, got %v, %v", other, err)
+			}
+
+			records, err := s.ListRunRecords()
+			if err != nil || len(records) != MaxRunRecords || records[0].JobName != "job-5" {
+				t.Errorf("expected every kept run oldest first, got %d records, %v", len(records), err)
+			}
+		})
+	}
+}
+
+func TestJobsHandler_RecordsFinishedRuns(t *testing.T) {
+	k8sClient := k8s.NewClientWithInterface(fake.NewClientset())
+	s, err := NewCronJobDBStore(WithStorage(NewMemoryStorage()), WithK8sClient(k8sClient))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	handler := s.JobsHandler()
+
+	job := ownedJob("job-a", "cj-1")
+	if err := handler.Handle(watch.Event{Type: watch.Added, Object: job}); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	history, _ := s.RunHistory("default", "cj-1", 0)
+	if len(history) != 0 {
+		t.Fatalf("expected no run recorded for a running job, got %d", len(history))
+	}
+
+	finished := job.DeepCopy()
+	finished.Status.Conditions = []batchv1.JobCondition{{
+		Type:   batchv1.JobFailed,
+		Status: corev1.ConditionTrue,
+		Reason: "BackoffLimitExceeded",
+	}}
+	if err := handler.Handle(watch.Event{Type: watch.Modified, Object: finished}); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	if err := handler.Handle(watch.Event{Type: watch.Deleted, Object: finished}); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+
+	jobs, _ := s.ListJobs()
+	if len(jobs) != 0 {
+		t.Errorf("expected deleted job to be removed, got %d jobs", len(jobs))
+	}
+	history, _ = s.RunHistory("default", "cj-1", 0)
+	if len(history) != 1 {
+		t.Fatalf("expected 1 run recorded, got %d", len(history))
+	}
+	if history[0].Succeeded || history[0].Reason != "BackoffLimitExceeded" {
+		t.Errorf("expected a failed run with reason BackoffLimitExceeded, got %+v", history[0])
+	}
+}
+
+func TestStorage_ContainerLogs(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for backend, s := range storageBackends(t) {
+		t.Run(backend, func(t *testing.T) {
+			logs := ContainerLogs{
+				Namespace:     "default",
+				JobName:       "job-0",
+				PodName:       "pod-0",
+				ContainerName: "main",
+				Logs:          []byte("boom\n"),
+			}
+			if err := s.PutContainerLogs(logs); err != nil {
+				t.Fatalf("PutContainerLogs failed: %v", err)
+			}
+			captured, err := s.HasContainerLogs("default", "job-0", "pod-0", "main")
+			if err != nil || !captured {
+				t.Errorf("expected logs of main to be stored, got %v, %v", captured, err)
+			}
+			captured, err = s.HasContainerLogs("default", "job-0", "pod-0", "sidecar")
+			if err != nil || captured {
+				t.Errorf("expected no logs for sidecar, got %v, %v", captured, err)
+			}
+			stored, err := s.JobContainerLogs("default", "job-0")
+			if err != nil || len(stored) != 1 || string(stored[0].Logs) != "boom\n" {
+				t.Fatalf("expected the stored logs, got %+v, %v", stored, err)
+			}
+
+			// The logs are pruned along with the run record of their job.
+			for i := range MaxRunRecords + 1 {
+				record := RunRecord{
+					Namespace:   "default",
+					CronjobName: "cj-1",
+					JobName:     fmt.Sprintf("job-%d", i),
+					StartTime:   start.Add(time.Duration(i) * time.Minute),
+				}
+				if err := s.PutRunRecord(record); err != nil {
+					t.Fatalf("PutRunRecord failed: %v", err)
+				}
+			}
+			all, err := s.ListContainerLogs()
+			if err != nil || len(all) != 0 {
+				t.Errorf("expected the logs of the pruned run to be removed, got %+v, %v", all, err)
+			}
+		})
+	}
+}
+
+func involvedEvent(name, kind, involvedName string, lastSeen time.Time) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "default"},
+		InvolvedObject: corev1.ObjectReference{Kind: kind, Name: involvedName, Namespace: "default"},
+		LastTimestamp:  metav1.NewTime(lastSeen),
+	}
+}
+
+func TestStorage_Events(t *testing.T) {
+	now := time.Now()
+	for backend, s := range storageBackends(t) {
+		t.Run(backend, func(t *testing.T) {
+			events := []*corev1.Event{
+				involvedEvent("job-a.2", "Job", "job-a", now),
+				involvedEvent("job-a.1", "Job", "job-a", now.Add(-time.Minute)),
+				involvedEvent("job-ab.1", "Job", "job-ab", now),
+				involvedEvent("pod-a.1", "Pod", "pod-a", now),
+			}
+			for _, event := range events {
+				if err := s.PutEvent(event, time.Hour); err != nil {
+					t.Fatalf("PutEvent failed: %v", err)
+				}
+			}
+			// Expires before the end of the test.
+			if err := s.PutEvent(involvedEvent("job-a.0", "Job", "job-a", now), time.Second); err != nil {
+				t.Fatalf("PutEvent failed: %v", err)
+			}
+
+			stored, err := s.Events("default", "Job", "job-a")
+			if err != nil || len(stored) != 3 {
+				t.Fatalf("expected 3 events of job-a, got %d, %v", len(stored), err)
+			}
+			if stored[0].Name != "job-a.1" {
+				t.Errorf("expected the oldest event first, got %s", stored[0].Name)
+			}
+			stored, _ = s.Events("default", "Job", "")
+			if len(stored) != 4 {
+				t.Errorf("expected the events of every job, got %d", len(stored))
+			}
+			stored, _ = s.Events("default", "", "")
+			if len(stored) != 5 {
+				t.Errorf("expected every event in the namespace, got %d", len(stored))
+			}
+
+			if err := s.DeleteEvent(events[0]); err != nil {
+				t.Fatalf("DeleteEvent failed: %v", err)
+			}
+			time.Sleep(2 * time.Second)
+			stored, _ = s.Events("default", "Job", "job-a")
+			if len(stored) != 1 || stored[0].Name != "job-a.1" {
+				t.Errorf("expected deleted and expired events to be gone, got %+v", stored)
+			}
+		})
+	}
+}
+
+func TestEventsHandler(t *testing.T) {
+	k8sClient := k8s.NewClientWithInterface(fake.NewClientset())
+	s, err := NewCronJobDBStore(WithStorage(NewMemoryStorage()), WithK8sClient(k8sClient), WithEventTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	handler := s.EventsHandler()
+
+	now := time.Now()
+	err = handler.Replace([]runtime.Object{
+		involvedEvent("cj-1.1", "CronJob", "cj-1", now),
+		involvedEvent("node-1.1", "Node", "node-1", now),
+		// Last seen longer ago than the TTL.
+		involvedEvent("cj-1.0", "CronJob", "cj-1", now.Add(-2*time.Hour)),
+	})
+	if err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	stored, _ := s.Events("default", "", "")
+	if len(stored) != 1 || stored[0].Name != "cj-1.1" {
+		t.Fatalf("expected only the recent cronjob event, got %+v", stored)
+	}
+
+	if err := handler.Handle(watch.Event{Type: watch.Deleted, Object: &stored[0]}); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	stored, _ = s.Events("default", "", "")
+	if len(stored) != 0 {
+		t.Errorf("expected the deleted event to be removed, got %+v", stored)
+	}
+}
+
+func TestStorage_CronjobRevisions(t *testing.T) {
+	for backend, s := range storageBackends(t) {
+		t.Run(backend, func(t *testing.T) {
+			for generation := int64(1); generation <= MaxCronjobRevisions+1; generation++ {
+				revision := CronjobRevision{Namespace: "default", CronjobName: "cj-1", Generation: generation}
+				if err := s.PutCronjobRevision(revision); err != nil {
+					t.Fatalf("PutCronjobRevision failed: %v", err)
+				}
+			}
+			revisions, err := s.CronjobRevisions("default", "cj-1")
+			if err != nil || len(revisions) != MaxCronjobRevisions {
+				t.Fatalf("expected %d revisions, got %d, %v", MaxCronjobRevisions, len(revisions), err)
+			}
+			if revisions[0].Generation != 2 || revisions[len(revisions)-1].Generation != MaxCronjobRevisions+1 {
+				t.Errorf("expected the oldest revision to be pruned, got generations %d to %d",
+					revisions[0].Generation, revisions[len(revisions)-1].Generation)
+			}
+
+			if err := s.DeleteCronjobRevisions("default", "cj-1"); err != nil {
+				t.Fatalf("DeleteCronjobRevisions failed: %v", err)
+			}
+			revisions, _ = s.CronjobRevisions("default", "cj-1")
+			if len(revisions) != 0 {
+				t.Errorf("expected no revisions, got %d", len(revisions))
+			}
+		})
+	}
+}
+
+func TestCronjobsHandler_RecordsRevisions(t *testing.T) {
+	k8sClient := k8s.NewClientWithInterface(fake.NewClientset())
+	s, err := NewCronJobDBStore(WithStorage(NewMemoryStorage()), WithK8sClient(k8sClient))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	handler := s.CronjobsHandler()
+
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	changed := created.Add(time.Hour)
+	cronjob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "cj-1",
+			Namespace:         "default",
+			UID:               "uid-1",
+			Generation:        1,
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: batchv1.CronJobSpec{Schedule: "* * * * *"},
+	}
+	modified := cronjob.DeepCopy()
+	modified.Generation = 2
+	modified.Spec.Schedule = "*/5 * * * *"
+	modified.ManagedFields = []metav1.ManagedFieldsEntry{
+		{Manager: "kubectl", Time: &metav1.Time{Time: changed}},
+		{Manager: "kube-controller-manager", Subresource: "status", Time: &metav1.Time{Time: changed.Add(time.Hour)}},
+	}
+	// A status update does not change the generation.
+	for _, obj := range []*batchv1.CronJob{cronjob, cronjob, modified} {
+		if err := handler.Handle(watch.Event{Type: watch.Modified, Object: obj}); err != nil {
+			t.Fatalf("Handle failed: %v", err)
+		}
+	}
+
+	revisions, _ := s.CronjobRevisions("default", "cj-1")
+	if len(revisions) != 2 {
+		t.Fatalf("expected 2 revisions, got %+v", revisions)
+	}
+	if !revisions[0].RecordedAt.Equal(created) || !revisions[1].RecordedAt.Equal(changed) {
+		t.Errorf("unexpected revision times %s and %s", revisions[0].RecordedAt, revisions[1].RecordedAt)
+	}
+	if revisions[1].Spec.Schedule != "*/5 * * * *" {
+		t.Errorf("expected the modified spec, got %+v", revisions[1].Spec)
+	}
+
+	job := ownedJob("job-a", "cj-1")
+	job.CreationTimestamp = metav1.NewTime(created.Add(time.Minute))
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	if err := s.JobsHandler().Handle(watch.Event{Type: watch.Added, Object: job}); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	history, _ := s.RunHistory("default", "cj-1", 0)
+	if len(history) != 1 || history[0].Revision != 1 {
+		t.Errorf("expected the run to be annotated with revision 1, got %+v", history)
+	}
+
+	recreated := cronjob.DeepCopy()
+	recreated.UID = "uid-2"
+	if err := handler.Replace([]runtime.Object{recreated}); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	revisions, _ = s.CronjobRevisions("default", "cj-1")
+	if len(revisions) != 1 || revisions[0].CronjobUID != "uid-2" {
+		t.Errorf("expected a recreated cronjob to start a new history, got %+v", revisions)
+	}
+}
+
+func TestNewStorage_Limits(t *testing.T) {
+	if _, err := NewStorage(BackendMemory, "", Limits{RunRecords: 1}); !errors.Is(err, ErrInvalidLimits) {
+		t.Fatalf("expected ErrInvalidLimits, got %v", err)
+	}
+	s, err := NewStorage(BackendMemory, "", Limits{RunRecords: 2, Revisions: 1})
+	if err != nil {
+		t.Fatalf("NewStorage failed: %v", err)
+	}
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		record := RunRecord{
+			Namespace:   "default",
+			CronjobName: "cj-1",
+			JobName:     fmt.Sprintf("job-%d", i),
+			StartTime:   start.Add(time.Duration(i) * time.Minute),
+		}
+		if err := s.PutRunRecord(record); err != nil {
+			t.Fatalf("PutRunRecord failed: %v", err)
+		}
+		revision := CronjobRevision{Namespace: "default", CronjobName: "cj-1", Generation: int64(i + 1)}
+		if err := s.PutCronjobRevision(revision); err != nil {
+			t.Fatalf("PutCronjobRevision failed: %v", err)
+		}
+	}
+	if records, _ := s.RunHistory("default", "cj-1", 0); len(records) != 2 {
+		t.Errorf("expected 2 run records, got %+v", records)
+	}
+	if revisions, _ := s.CronjobRevisions("default", "cj-1"); len(revisions) != 1 || revisions[0].Generation != 3 {
+		t.Errorf("expected the last revision only, got %+v", revisions)
+	}
+}
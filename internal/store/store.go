@@ -1,4 +1,5 @@
-// Package store provides the Badger-backed cache layer for CronJob, Job and Pod data.
+// Package store provides the cache layer for CronJob, Job and Pod data on top
+// of a pluggable Storage backend.
 package store
 
 import (
@@ -6,7 +7,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"slices"
 	"time"
 
 	"github.com/danroux/sk8l/internal/k8s"
@@ -22,34 +22,25 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
-const (
-	JobPodsKeyFmt  = "jobs_pods_for_job_%s"
-	CronjobsKeyFmt = "sk8l_cronjob_%s_%s"
-	BadgerTTL      = 15 * time.Second
-	RefreshSeconds = 10
-)
-
 var (
 	ErrK8sClientRequired = errors.New("NewCronJobDBStore: K8sClient must be provided")
-	CronjobsCacheKey     = []byte("sk8l_cronjobs")
-	JobsMappedCacheKey   = []byte("sk8l_jobs_mapped")
-	JobsCacheKey         = []byte("sk8l_jobs")
+	ErrInvalidEventTTL   = errors.New("NewCronJobDBStore: event TTL must be positive")
 	k8sSerializer        = k8sproto.NewSerializer(scheme.Scheme, scheme.Scheme)
 )
 
-type APICall func() ([]byte, error)
-
 type CronJobDBStore struct {
 	K8sClient k8s.ClientInterface
-	*badger.DB
-	l zerolog.Logger
+	Storage
+	l        zerolog.Logger
+	eventTTL time.Duration
 }
 
 type CronJobDBStoreOptionFn func(*CronJobDBStore) error
 
 func NewCronJobDBStore(optsFn ...CronJobDBStoreOptionFn) (*CronJobDBStore, error) {
 	cjdbs := &CronJobDBStore{
-		l: log.With().Str("component", "db_store").Logger(),
+		l:        logger.For(logger.Store),
+		eventTTL: DefaultEventTTL,
 	}
 
 	for _, opt := range optsFn {
@@ -62,8 +53,8 @@ func NewCronJobDBStore(optsFn ...CronJobDBStoreOptionFn) (*CronJobDBStore, error
 		return nil, ErrK8sClientRequired
 	}
 
-	if cjdbs.DB == nil {
-		cjdbs.l.Info().Msg("NewCronJobDBStore: DB not provided, setting default one")
+	if cjdbs.Storage == nil {
+		cjdbs.l.Info().Msg("NewCronJobDBStore: Storage not provided, setting default one")
 		if err := WithDefaultDB()(cjdbs); err != nil {
 			return nil, err
 		}
@@ -72,9 +63,26 @@ func NewCronJobDBStore(optsFn ...CronJobDBStoreOptionFn) (*CronJobDBStore, error
 	return cjdbs, nil
 }
 
+// WithStorage sets the Storage backend.
+func WithStorage(storage Storage) CronJobDBStoreOptionFn {
+	return func(cjdbs *CronJobDBStore) error {
+		cjdbs.Storage = storage
+		return nil
+	}
+}
+
+// WithDB uses an already opened Badger DB as Storage.
 func WithDB(db *badger.DB) CronJobDBStoreOptionFn {
+	return WithStorage(NewBadgerStorage(db))
+}
+
+// WithEventTTL sets how long Events are kept after they were last seen.
+func WithEventTTL(ttl time.Duration) CronJobDBStoreOptionFn {
 	return func(cjdbs *CronJobDBStore) error {
-		cjdbs.DB = db
+		if ttl <= 0 {
+			return fmt.Errorf("%w: %s", ErrInvalidEventTTL, ttl)
+		}
+		cjdbs.eventTTL = ttl
 		return nil
 	}
 }
@@ -100,241 +108,86 @@ func WithDefaultK8sClient(k8sNamespace string) CronJobDBStoreOptionFn {
 	}
 }
 
+// WithDefaultDB opens the Badger Storage at DefaultBadgerPath.
 func WithDefaultDB() CronJobDBStoreOptionFn {
 	return func(cjdbs *CronJobDBStore) error {
-		badgerLogger := logger.NewBadgerLogger(zerolog.GlobalLevel())
-		badgerOpts := badger.DefaultOptions("/tmp/badger").WithLogger(badgerLogger)
-		db, err := badger.Open(badgerOpts)
-		if err != nil {
-			return fmt.Errorf("failed to open Badger DB: %w", err)
-		}
-		cjdbs.DB = db
-		return nil
-	}
-}
-
-func (c *CronJobDBStore) GetAndStore(key []byte, apiCall APICall) ([]byte, error) {
-	var valueResponse []byte
-	err := c.DB.Update(func(txn *badger.Txn) error {
-		item, err := txn.Get(key)
-		if errors.Is(err, badger.ErrKeyNotFound) {
-			apiResult, err := apiCall()
-			if err != nil {
-				return fmt.Errorf("apiCall failed: %w", err)
-			}
-			entry := badger.NewEntry(key, apiResult).WithTTL(BadgerTTL)
-			if err = txn.SetEntry(entry); err != nil {
-				c.l.Error().Err(err).Msg("Error: getAndStore#txn.SetEntry")
-				return fmt.Errorf("sk8l#getAndStore: txn.SetEntry() failed: %w", err)
-			}
-			valueResponse = append([]byte{}, apiResult...)
-			return nil
-		} else if err != nil {
-			return fmt.Errorf("sk8l#getAndStore: DB.Update() failed: %w", err)
-		}
-
-		err = item.Value(func(val []byte) error {
-			valueResponse = append([]byte{}, val...)
-			return nil
-		})
+		storage, err := OpenBadgerStorage(DefaultBadgerPath)
 		if err != nil {
-			return fmt.Errorf("sk8l#getAndStore: DB.Update() failed: %w", err)
+			return err
 		}
+		cjdbs.Storage = storage
 		return nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("sk8l#getAndStore: DB.Update() failed: %w", err)
 	}
-
-	return valueResponse, nil
-}
-
-func (c *CronJobDBStore) Get(key []byte) ([]byte, error) {
-	var valueResponse []byte
-	err := c.DB.View(func(txn *badger.Txn) error {
-		current, err := txn.Get(key)
-		if err != nil {
-			return fmt.Errorf("sk8l#get: txn.Get() failed: %w", err)
-		}
-
-		err = current.Value(func(val []byte) error {
-			valueResponse = append([]byte{}, val...)
-			return nil
-		})
-		if err != nil {
-			c.l.Error().Err(err).Msg("get#current.Value")
-			return fmt.Errorf("sk8l#get: current.Value() failed: %w", err)
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("sk8l#get: DB.View() failed: %w", err)
-	}
-
-	return valueResponse, nil
 }
 
 func (c *CronJobDBStore) FindCronjobs() (*batchv1.CronJobList, error) {
-	cronjobs, err := c.Get(CronjobsCacheKey)
+	cronjobs, err := c.ListCronjobs()
 	if err != nil {
-		if errors.Is(err, badger.ErrKeyNotFound) {
-			return &batchv1.CronJobList{}, nil
-		}
-		return nil, fmt.Errorf("findCronjobs#get: %w", err)
+		return nil, fmt.Errorf("findCronjobs#ListCronjobs: %w", err)
 	}
-
-	cronjobList := &batchv1.CronJobList{}
-	if _, _, err = k8sSerializer.Decode(cronjobs, nil, cronjobList); err != nil {
-		return nil, fmt.Errorf("findCronjobs#Decode: %w", err)
-	}
-	return cronjobList, nil
+	return &batchv1.CronJobList{Items: cronjobs}, nil
 }
 
+// FindCronjob returns the stored CronJob, falling back to the API server when
+// it has not been synced yet.
 func (c *CronJobDBStore) FindCronjob(ctx context.Context, cronjobNamespace, cronjobName string) (*batchv1.CronJob, error) {
-	gCjCall := func() ([]byte, error) {
-		cronjob, err := c.K8sClient.GetCronjob(ctx, cronjobNamespace, cronjobName)
+	cronjob, err := c.GetCronjob(cronjobNamespace, cronjobName)
+	if errors.Is(err, ErrNotFound) {
+		cronjob, err = c.K8sClient.GetCronjob(ctx, cronjobNamespace, cronjobName)
 		if err != nil {
-			return nil, fmt.Errorf("GetCronjob failed: %w", err)
-		}
-		var buf bytes.Buffer
-		if err := k8sSerializer.Encode(cronjob, &buf); err != nil {
-			c.l.Error().Err(err).Msg("findCronjob#k8sSerializer.Encode")
-			return nil, fmt.Errorf("k8sSerializer.Encode failed: %w", err)
+			return nil, fmt.Errorf("findCronjob#GetCronjob: %w", err)
 		}
-		return buf.Bytes(), nil
-	}
-
-	cacheKey := []byte(fmt.Sprintf(CronjobsKeyFmt, cronjobNamespace, cronjobName))
-	cronjobValue, err := c.GetAndStore(cacheKey, gCjCall)
-	if err != nil {
-		return nil, fmt.Errorf("findCronjob#getAndStore: %w", err)
-	}
-
-	cronjob := &batchv1.CronJob{}
-	if _, _, err = k8sSerializer.Decode(cronjobValue, nil, cronjob); err != nil {
-		return nil, fmt.Errorf("findCronjob#Decode: %w", err)
+		return cronjob, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("findCronjob#GetCronjob: %w", err)
 	}
 	return cronjob, nil
 }
 
 func (c *CronJobDBStore) FindJobs() (*batchv1.JobList, error) {
-	jobs, err := c.Get(JobsCacheKey)
+	jobs, err := c.ListJobs()
 	if err != nil {
-		if errors.Is(err, badger.ErrKeyNotFound) {
-			return &batchv1.JobList{}, nil
-		}
-		return nil, fmt.Errorf("findJobs#get: %w", err)
-	}
-
-	jobList := &batchv1.JobList{}
-	if _, _, err = k8sSerializer.Decode(jobs, nil, jobList); err != nil {
-		return nil, fmt.Errorf("findJobs#Decode: %w", err)
+		return nil, fmt.Errorf("findJobs#ListJobs: %w", err)
 	}
-	return jobList, nil
+	return &batchv1.JobList{Items: jobs}, nil
 }
 
-func (c *CronJobDBStore) FindJobsMapped(ctx context.Context) (map[string][]*batchv1.Job, error) {
-	jobs, err := c.GetAndStore(JobsMappedCacheKey, func() ([]byte, error) {
-		jobList, err := c.K8sClient.GetAllJobs(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("GetAllJobs failed: %w", err)
-		}
-		var buf bytes.Buffer
-		if err := k8sSerializer.Encode(jobList, &buf); err != nil {
-			log.Error().
-				Err(err).
-				Str("operation", "FindJobsMapped").
-				Msg("k8sSerializer.Encode")
-			return nil, fmt.Errorf("k8sSerializer.Encode failed: %w", err)
-		}
-		return buf.Bytes(), nil
-	})
-
+// FindJobsMapped returns every owned Job keyed by the OwnerKey of its owner.
+func (c *CronJobDBStore) FindJobsMapped(_ context.Context) (map[string][]*batchv1.Job, error) {
+	mapped, err := c.JobsByOwner()
 	if err != nil {
-		return nil, fmt.Errorf("FindJobsMapped#GetAndStore: %w", err)
-	}
-
-	jobList := &batchv1.JobList{}
-	if _, _, err := k8sSerializer.Decode(jobs, nil, jobList); err != nil {
-		return nil, fmt.Errorf("FindJobsMapped#Decode: %w", err)
+		return nil, fmt.Errorf("FindJobsMapped#JobsByOwner: %w", err)
 	}
+	return mapped, nil
+}
 
-	mapped := make(map[string][]*batchv1.Job)
-	for i := range jobList.Items {
-		job := &jobList.Items[i]
-		for _, owr := range job.OwnerReferences {
-			mapped[owr.Name] = append(mapped[owr.Name], job)
-		}
+// FindJobsForCronjob returns the Jobs owned by a single CronJob.
+func (c *CronJobDBStore) FindJobsForCronjob(cronjobNamespace, cronjobName string) ([]*batchv1.Job, error) {
+	jobs, err := c.JobsForCronjob(cronjobNamespace, cronjobName)
+	if err != nil {
+		return nil, fmt.Errorf("FindJobsForCronjob#JobsForCronjob: %w", err)
 	}
-	return mapped, nil
+	return jobs, nil
 }
 
+// FindJobPodsForJob returns the started Pods owned by job.
 func (c *CronJobDBStore) FindJobPodsForJob(job *batchv1.Job) (*corev1.PodList, error) {
-	fKey := fmt.Sprintf(JobPodsKeyFmt, job.Name)
-	key := []byte(fKey)
-	collection := &corev1.PodList{}
-
-	err := c.DB.View(func(txn *badger.Txn) error {
-		current, err := txn.Get(key)
-		if errors.Is(err, badger.ErrKeyNotFound) {
-			return nil
-		} else if err != nil {
-			return fmt.Errorf("sk8l#findJobPodsForJob: txn.Get() failed: %w", err)
-		}
-		err = current.Value(func(val []byte) error {
-			_, _, err = k8sSerializer.Decode(val, nil, collection)
-			if err != nil {
-				log.Error().
-					Err(err).
-					Str("operation", "findJobPodsForJob").
-					Msg("proto.Unmarshal")
-				return fmt.Errorf("sk8l#findJobPodsForJob: proto.Unmarshal() failed: %w", err)
-			}
-			return nil
-		})
-		if err != nil {
-			log.Error().
-				Err(err).
-				Str("operation", "findJobPodsForJob").
-				Msg("current.Value")
-			return fmt.Errorf("sk8l#findJobPodsForJob: current.Value() failed: %w", err)
-		}
-		return nil
-	})
-
+	pods, err := c.PodsForJob(job.Namespace, job.Name)
 	if err != nil {
-		return nil, fmt.Errorf("FindJobPodsForJob#DB.View: %w", err)
+		log.Error().
+			Err(err).
+			Str("operation", "findJobPodsForJob").
+			Msg("PodsForJob")
+		return nil, fmt.Errorf("FindJobPodsForJob#PodsForJob: %w", err)
 	}
 
-	podItems := []corev1.Pod{}
-	podMap := make(map[string][]corev1.Pod)
-	for _, pod := range collection.Items {
-		for _, ownr := range pod.OwnerReferences {
-			if ownr.Name == job.Name && pod.Status.StartTime != nil {
-				podMap[pod.Name] = append(podMap[pod.Name], pod)
-			}
+	podList := &corev1.PodList{Items: []corev1.Pod{}}
+	for i := range pods {
+		if pods[i].Status.StartTime != nil {
+			podList.Items = append(podList.Items, pods[i])
 		}
 	}
-
-	for _, pods := range podMap {
-		slices.SortFunc(pods,
-			func(a, b corev1.Pod) int {
-				if a.ResourceVersion < b.ResourceVersion {
-					return -1
-				}
-				if a.ResourceVersion > b.ResourceVersion {
-					return 1
-				}
-				return 0
-			})
-		latestVersion := pods[len(pods)-1]
-		podItems = append(podItems, latestVersion)
-	}
-
-	return &corev1.PodList{Items: podItems}, nil
+	return podList, nil
 }
 
 func K8sSerialize(obj runtime.Object, buf *bytes.Buffer) error {
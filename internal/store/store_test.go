@@ -68,21 +68,9 @@ func TestFindCronjobs(t *testing.T) {
 	}
 
 	// Store cronjobs and retrieve
-	cjList := &batchv1.CronJobList{
-		Items: []batchv1.CronJob{
-			{ObjectMeta: metav1.ObjectMeta{Name: "cj-1", Namespace: "default"}},
-		},
-	}
-	var buf bytes.Buffer
-	if err := K8sSerialize(cjList, &buf); err != nil {
-		t.Fatalf("failed to serialize: %v", err)
-	}
-
-	err = db.Update(func(txn *badger.Txn) error {
-		return txn.Set(CronjobsCacheKey, buf.Bytes())
-	})
-	if err != nil {
-		t.Fatalf("failed to set cache: %v", err)
+	cj := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "cj-1", Namespace: "default"}}
+	if err := s.PutCronjob(cj); err != nil {
+		t.Fatalf("failed to store cronjob: %v", err)
 	}
 
 	retrieved, err := s.FindCronjobs()
@@ -145,19 +133,22 @@ func TestFindJobsMapped(t *testing.T) {
 			},
 		},
 	}
-	fakeClientset := fake.NewClientset(job)
+	fakeClientset := fake.NewClientset()
 	k8sClient := k8s.NewClientWithInterface(fakeClientset, k8s.WithNamespace("default"))
 	s, err := NewCronJobDBStore(WithDB(db), WithK8sClient(k8sClient))
 	if err != nil {
 		t.Fatalf("failed to create store: %v", err)
 	}
+	if err := s.PutJob(job); err != nil {
+		t.Fatalf("PutJob failed: %v", err)
+	}
 
 	ctx := context.Background()
 	mapped, err := s.FindJobsMapped(ctx)
 	if err != nil {
 		t.Fatalf("expected no error from FindJobsMapped, got %v", err)
 	}
-	jobs, ok := mapped["parent-cronjob"]
+	jobs, ok := mapped[OwnerKey("default", "parent-cronjob")]
 	if !ok || len(jobs) != 1 {
 		t.Fatalf("expected 1 job for parent-cronjob, got %v", jobs)
 	}
@@ -175,7 +166,7 @@ func TestFindJobPodsForJob(t *testing.T) {
 		t.Fatalf("failed to create store: %v", err)
 	}
 
-	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "my-job"}}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "my-job", Namespace: "default"}}
 
 	// No pods stored yet
 	pods, err := s.FindJobPodsForJob(job)
@@ -187,37 +178,29 @@ func TestFindJobPodsForJob(t *testing.T) {
 	}
 
 	now := metav1.NewTime(time.Now())
-	podList := &corev1.PodList{
-		Items: []corev1.Pod{
-			{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:            "pod-v1",
-					ResourceVersion: "1",
-					OwnerReferences: []metav1.OwnerReference{{Name: "my-job"}},
-				},
-				Status: corev1.PodStatus{StartTime: &now},
-			},
-			{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:            "pod-v1",
-					ResourceVersion: "2",
-					OwnerReferences: []metav1.OwnerReference{{Name: "my-job"}},
-				},
-				Status: corev1.PodStatus{StartTime: &now},
+	for _, rv := range []string{"1", "2"} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "pod-v1",
+				Namespace:       "default",
+				ResourceVersion: rv,
+				OwnerReferences: []metav1.OwnerReference{{Name: "my-job"}},
 			},
-		},
+			Status: corev1.PodStatus{StartTime: &now},
+		}
+		if err := s.PutPod(pod); err != nil {
+			t.Fatalf("PutPod failed: %v", err)
+		}
 	}
-	var buf bytes.Buffer
-	if err := K8sSerialize(podList, &buf); err != nil {
-		t.Fatalf("serialize failed: %v", err)
+	notStarted := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "pod-pending",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{{Name: "my-job"}},
+		},
 	}
-
-	key := []byte("jobs_pods_for_job_my-job")
-	err = db.Update(func(txn *badger.Txn) error {
-		return txn.Set(key, buf.Bytes())
-	})
-	if err != nil {
-		t.Fatalf("failed to set pods in badger: %v", err)
+	if err := s.PutPod(notStarted); err != nil {
+		t.Fatalf("PutPod failed: %v", err)
 	}
 
 	pods, err = s.FindJobPodsForJob(job)
@@ -230,6 +213,65 @@ func TestFindJobPodsForJob(t *testing.T) {
 	if pods.Items[0].ResourceVersion != "2" {
 		t.Errorf("expected ResourceVersion 2, got %s", pods.Items[0].ResourceVersion)
 	}
+
+	if err := s.DeletePod(pods.Items[0].DeepCopy()); err != nil {
+		t.Fatalf("DeletePod failed: %v", err)
+	}
+	pods, err = s.FindJobPodsForJob(job)
+	if err != nil {
+		t.Fatalf("FindJobPodsForJob failed: %v", err)
+	}
+	if len(pods.Items) != 0 {
+		t.Errorf("expected deleted pod to be gone, got %d pods", len(pods.Items))
+	}
+}
+
+func TestReplaceJobs(t *testing.T) {
+	db := setupTestDB(t)
+	k8sClient := k8s.NewClientWithInterface(fake.NewClientset())
+	s, err := NewCronJobDBStore(WithDB(db), WithK8sClient(k8sClient))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	owned := func(name, owner string) batchv1.Job {
+		return batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{{Name: owner}},
+		}}
+	}
+
+	if err := s.ReplaceJobs([]batchv1.Job{owned("job-a", "cj-1"), owned("job-b", "cj-2")}); err != nil {
+		t.Fatalf("ReplaceJobs failed: %v", err)
+	}
+	if err := s.ReplaceJobs([]batchv1.Job{owned("job-c", "cj-1")}); err != nil {
+		t.Fatalf("ReplaceJobs failed: %v", err)
+	}
+
+	jobs, err := s.FindJobs()
+	if err != nil {
+		t.Fatalf("FindJobs failed: %v", err)
+	}
+	if len(jobs.Items) != 1 || jobs.Items[0].Name != "job-c" {
+		t.Errorf("expected only job-c after replace, got %+v", jobs.Items)
+	}
+
+	mapped, err := s.FindJobsMapped(context.Background())
+	if err != nil {
+		t.Fatalf("FindJobsMapped failed: %v", err)
+	}
+	if cj1 := mapped[OwnerKey("default", "cj-1")]; len(mapped) != 1 || len(cj1) != 1 || cj1[0].Name != "job-c" {
+		t.Errorf("expected stale index keys to be removed, got %v", mapped)
+	}
+
+	forCronjob, err := s.FindJobsForCronjob("default", "cj-1")
+	if err != nil {
+		t.Fatalf("FindJobsForCronjob failed: %v", err)
+	}
+	if len(forCronjob) != 1 || forCronjob[0].Name != "job-c" {
+		t.Errorf("expected job-c for cj-1, got %v", forCronjob)
+	}
 }
 
 func TestK8sSerializeAndDeserialize(t *testing.T) {
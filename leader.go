@@ -0,0 +1,98 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/danroux/sk8l/internal/leader"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"k8s.io/client-go/kubernetes"
+)
+
+// newElector returns the Elector campaigning for this replica, nil when the
+// leader election is disabled.
+func newElector(client kubernetes.Interface) (*leader.Elector, error) {
+	le := cfg.LeaderElection
+	if !le.Enabled {
+		return nil, nil
+	}
+	// Replicas of a Deployment share their hostname when it's set in the pod spec.
+	identity := le.Identity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("sk8l#newElector: %w", err)
+		}
+		identity = hostname
+	}
+	elector, err := leader.NewElector(
+		client,
+		cmp.Or(le.LeaseNamespace, cfg.Namespace),
+		le.LeaseName,
+		identity,
+		leader.WithTimings(le.LeaseDuration.Duration, le.RenewDeadline.Duration, le.RetryPeriod.Duration),
+		leader.WithLogger(log.With().Str("component", "leader").Logger()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#newElector: %w", err)
+	}
+	return elector, nil
+}
+
+// runSingletonDuties runs the duties that must not run once per replica:
+// collecting the metrics, so that they aren't reported once per replica.
+// With the leader election enabled they only run while this replica is the
+// leader. Every replica records the history and the failure logs in its own
+// storage. The returned channel is closed once the elector has stopped and
+// released the Lease.
+func (s *Sk8lServer) runSingletonDuties(ctx context.Context) <-chan struct{} {
+	duties := []leader.Duty{s.reportLeadership, s.collectMetrics}
+
+	stopped := make(chan struct{})
+	if s.elector == nil {
+		for _, duty := range duties {
+			go duty(ctx)
+		}
+		close(stopped)
+		return stopped
+	}
+	go func() {
+		defer close(stopped)
+		s.elector.Run(ctx, duties...)
+	}()
+	return stopped
+}
+
+// collectMetrics collects the metrics while this replica leads, then resets
+// them so only the leader reports them.
+func (s *Sk8lServer) collectMetrics(ctx context.Context) {
+	recordMetrics(ctx, s, s.metricsNamesMap)
+	resetMetrics()
+}
+
+// reportLeadership sets the leader gauge while this replica leads.
+func (s *Sk8lServer) reportLeadership(ctx context.Context) {
+	leaderGauge.Set(1)
+	<-ctx.Done()
+	leaderGauge.Set(0)
+}
+
+// resetMetrics zeroes the metrics collected from the cronjobs.
+func resetMetrics() {
+	for _, gauge := range []prometheus.Gauge{
+		registeredCronjobsGauge,
+		completedCronjobsGauge,
+		runningCronjobsGauge,
+		failingCronjobsGauge,
+	} {
+		gauge.Set(0)
+	}
+	failuresByCategoryGauge.Reset()
+	summaryMap.Range(func(_, gauge any) bool {
+		gauge.(prometheus.Gauge).Set(0)
+		return true
+	})
+}
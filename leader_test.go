@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/danroux/sk8l/internal/k8s"
+	"github.com/danroux/sk8l/internal/leader"
+	"github.com/danroux/sk8l/internal/store"
+	"github.com/danroux/sk8l/protos"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNewElector(t *testing.T) {
+	if elector, err := newElector(fake.NewClientset()); elector != nil || err != nil {
+		t.Fatalf("expected no elector while the leader election is disabled, got %v and %v", elector, err)
+	}
+
+	le := cfg.LeaderElection
+	t.Cleanup(func() { cfg.LeaderElection = le })
+	cfg.LeaderElection.Enabled = true
+	elector, err := newElector(fake.NewClientset())
+	if err != nil {
+		t.Fatalf("newElector failed: %v", err)
+	}
+	hostname, _ := os.Hostname()
+	if elector.Identity() != hostname {
+		t.Errorf("expected the hostname as identity, got %q", elector.Identity())
+	}
+
+	cfg.LeaderElection.Identity = "sk8l-api-0"
+	if elector, _ = newElector(fake.NewClientset()); elector.Identity() != "sk8l-api-0" {
+		t.Errorf("expected the configured identity, got %q", elector.Identity())
+	}
+}
+
+// newMetricsServer returns a server collecting its metrics from sk8lServer.
+func newMetricsServer(t *testing.T) *Sk8lServer {
+	t.Helper()
+	st, err := store.NewCronJobDBStore(
+		store.WithStorage(store.NewMemoryStorage()),
+		store.WithK8sClient(k8s.NewClientWithInterface(fake.NewClientset())),
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	return NewSk8lServer("passthrough:///bufnet", st, nil, &sync.Map{},
+		grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+}
+
+func TestRunSingletonDuties(t *testing.T) {
+	clientset := fake.NewClientset()
+	elector, err := leader.NewElector(clientset, "default", "sk8l-api", "sk8l-api-0",
+		leader.WithTimings(time.Second, 500*time.Millisecond, 100*time.Millisecond))
+	if err != nil {
+		t.Fatalf("leader.NewElector failed: %v", err)
+	}
+	server := newMetricsServer(t)
+	server.readOnly = true
+	server.elector = elector
+
+	ctx, cancel := context.WithCancel(t.Context())
+	stopped := server.runSingletonDuties(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(leaderGauge) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the leader gauge to be set while leading")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	processCronjobsResponse([]*protos.CronjobResponse{{Name: "leader-test", RunningJobs: []*protos.JobResponse{{}}}},
+		cfg.Namespace, &sync.Map{})
+	if testutil.ToFloat64(registeredCronjobsGauge) != 1 || testutil.ToFloat64(runningCronjobsGauge) != 1 {
+		t.Fatal("expected the leader to report the metrics")
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the elector to stop")
+	}
+	lease, err := clientset.CoordinationV1().Leases("default").Get(t.Context(), "sk8l-api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get the Lease: %v", err)
+	}
+	if holder := lease.Spec.HolderIdentity; holder != nil && *holder != "" {
+		t.Errorf("expected the Lease to be released once stopped, held by %s", *holder)
+	}
+	deadline = time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(leaderGauge) != 0 || testutil.ToFloat64(registeredCronjobsGauge) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected a former leader to stop reporting the metrics")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCollectMetrics(t *testing.T) {
+	processCronjobsResponse([]*protos.CronjobResponse{{Name: "collect-test", RunningJobs: []*protos.JobResponse{{}}}},
+		cfg.Namespace, &sync.Map{})
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	// Returns once the collection stopped, nothing sets the gauges after the reset.
+	server := newMetricsServer(t)
+	server.collectMetrics(ctx)
+	if testutil.ToFloat64(registeredCronjobsGauge) != 0 || testutil.ToFloat64(runningCronjobsGauge) != 0 {
+		t.Error("expected the metrics to be reset once the collection stopped")
+	}
+}
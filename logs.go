@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/danroux/sk8l/internal/store"
+	"github.com/danroux/sk8l/protos"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// failureLogsLimit is how much of the end of a failed container's logs is kept.
+	failureLogsLimit  = 64 << 10
+	logsReadChunkSize = 32 << 10
+	// maxLogLineSize bounds the lines of GetPodLogs, longer ones are sent in parts.
+	maxLogLineSize = 64 << 10
+)
+
+var ErrInvalidLogOptions = errors.New("invalid log options")
+
+// GetPodLogs streams the log lines of a pod container. With follow set it keeps
+// streaming until the container stops or the client goes away.
+func (s *Sk8lServer) GetPodLogs(in *protos.PodLogsRequest, stream protos.Cronjob_GetPodLogsServer) error {
+	if s.readOnly {
+		return status.Error(codes.FailedPrecondition, "sk8l#GetPodLogs: pod logs are not part of an imported snapshot")
+	}
+	opts, err := podLogOptions(in)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "sk8l#GetPodLogs: %v", err)
+	}
+
+	ctx := stream.Context()
+	logs, err := s.K8sClient.StreamPodLogs(ctx, in.GetPodNamespace(), in.GetPodName(), opts)
+	if err != nil {
+		return fmt.Errorf("sk8l#GetPodLogs: %w", err)
+	}
+	defer logs.Close()
+	// A follow stream only returns once new output arrives, close it as soon as the client goes away.
+	stop := context.AfterFunc(ctx, func() { logs.Close() })
+	defer stop()
+
+	reader := bufio.NewReaderSize(logs, maxLogLineSize)
+	for {
+		line, _, err := reader.ReadLine()
+		if err == nil {
+			if sendErr := stream.Send(&protos.PodLogsResponse{Line: validLogText(line)}); sendErr != nil {
+				return fmt.Errorf("sk8l#GetPodLogs: stream.Send failed: %w", sendErr)
+			}
+		}
+		if ctx.Err() != nil {
+			log.Info().
+				Str("operation", "GetPodLogs").
+				Msg(fmt.Sprintf("log stream of pod %s/%s closed by the client", in.GetPodNamespace(), in.GetPodName()))
+			return nil
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("sk8l#GetPodLogs: reading logs failed: %w", err)
+		}
+	}
+}
+
+// validLogText replaces the invalid UTF-8 of logs, which a proto3 string can't carry.
+func validLogText(logs []byte) string {
+	return strings.ToValidUTF8(string(logs), "\uFFFD")
+}
+
+func podLogOptions(in *protos.PodLogsRequest) (*corev1.PodLogOptions, error) {
+	opts := &corev1.PodLogOptions{
+		Container: in.GetContainer(),
+		Follow:    in.GetFollow(),
+		Previous:  in.GetPrevious(),
+	}
+	tailLines := in.GetTailLines()
+	if tailLines < 0 {
+		return nil, fmt.Errorf("%w: tailLines must not be negative", ErrInvalidLogOptions)
+	}
+	if tailLines > 0 {
+		opts.TailLines = &tailLines
+	}
+	if in.GetSinceTime() != "" {
+		sinceTime, err := time.Parse(time.RFC3339, in.GetSinceTime())
+		if err != nil {
+			return nil, fmt.Errorf("%w: sinceTime: %w", ErrInvalidLogOptions, err)
+		}
+		opts.SinceTime = &metav1.Time{Time: sinceTime}
+	}
+	return opts, nil
+}
+
+// GetJobFailureLogs returns the logs captured from the failed containers of a
+// Job. They are served from storage, so they remain available after
+// Kubernetes deleted the Job and its pods.
+func (s *Sk8lServer) GetJobFailureLogs(_ context.Context, in *protos.JobRequest) (*protos.JobFailureLogsResponse, error) {
+	logs, err := s.JobContainerLogs(in.GetJobNamespace(), in.GetJobName())
+	if err != nil {
+		log.Error().Err(err).Str("operation", "GetJobFailureLogs").Msg("JobContainerLogs")
+		return nil, fmt.Errorf("sk8l#GetJobFailureLogs: %w", err)
+	}
+
+	containers := make([]*protos.ContainerLogsResponse, 0, len(logs))
+	for _, containerLogs := range logs {
+		containers = append(containers, &protos.ContainerLogsResponse{
+			PodName:       containerLogs.PodName,
+			ContainerName: containerLogs.ContainerName,
+			Logs:          validLogText(containerLogs.Logs),
+			Truncated:     containerLogs.Truncated,
+			CapturedAt:    containerLogs.CapturedAt.UTC().Format(time.RFC3339),
+			Error:         containerLogs.Error,
+		})
+	}
+	return &protos.JobFailureLogsResponse{
+		JobName:      in.GetJobName(),
+		JobNamespace: in.GetJobNamespace(),
+		Containers:   containers,
+	}, nil
+}
+
+// captureFailureLogs periodically stores the tail of the logs of failed
+// containers, before their pods are garbage collected along with the Job.
+func (s *Sk8lServer) captureFailureLogs(ctx context.Context) {
+	go func() {
+		if err := s.waitForSync(ctx); err != nil {
+			return
+		}
+		ticker := time.NewTicker(cfg.Server.RefreshInterval.Duration)
+		defer ticker.Stop()
+		for {
+			s.captureFailedJobsLogs(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *Sk8lServer) captureFailedJobsLogs(ctx context.Context) {
+	jobs, err := s.ListJobs()
+	if err != nil {
+		log.Error().Err(err).Str("operation", "captureFailedJobsLogs").Msg("ListJobs")
+		return
+	}
+	for i := range jobs {
+		job := &jobs[i]
+		pods, err := s.FindJobPodsForJob(job)
+		if err != nil {
+			log.Error().Err(err).Str("operation", "captureFailedJobsLogs").Msg("FindJobPodsForJob")
+			continue
+		}
+		podResponses := buildJobPodsResponses(pods)
+		failed, _, _ := jobFailed(job, podResponses)
+		for j := range pods.Items {
+			for _, containerName := range failedContainerNames(&pods.Items[j], podResponses[j], failed) {
+				s.captureContainerLogs(ctx, job, &pods.Items[j], containerName)
+			}
+		}
+	}
+}
+
+// failedContainerNames returns the terminated containers of pod whose logs are
+// worth keeping: the ones reported in its failedContainers and, once the Job
+// failed, every container that exited with a non-zero code.
+func failedContainerNames(pod *corev1.Pod, podResponse *protos.PodResponse, jobFailed bool) []string {
+	exitCodes := make(map[string]int32)
+	for _, statuses := range [][]corev1.ContainerStatus{
+		pod.Status.InitContainerStatuses,
+		pod.Status.ContainerStatuses,
+		pod.Status.EphemeralContainerStatuses,
+	} {
+		for _, containerStatus := range statuses {
+			if containerStatus.State.Terminated != nil {
+				exitCodes[containerStatus.Name] = containerStatus.State.Terminated.ExitCode
+			}
+		}
+	}
+
+	names := []string{}
+	// Containers that never started, e.g. with a CreateContainerConfigError, have no logs.
+	for _, reason := range podResponse.GetFailedContainers().GetTerminationReasons() {
+		if _, ok := exitCodes[reason.GetContainerName()]; ok {
+			names = append(names, reason.GetContainerName())
+		}
+	}
+	if jobFailed {
+		for name, exitCode := range exitCodes {
+			if exitCode != 0 {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+func (s *Sk8lServer) captureContainerLogs(ctx context.Context, job *batchv1.Job, pod *corev1.Pod, containerName string) {
+	captured, err := s.HasContainerLogs(job.Namespace, job.Name, pod.Name, containerName)
+	if err != nil {
+		log.Error().Err(err).Str("operation", "captureContainerLogs").Msg("HasContainerLogs")
+		return
+	}
+	if captured {
+		return
+	}
+
+	containerLogs := store.ContainerLogs{
+		CapturedAt:    time.Now().UTC(),
+		Namespace:     job.Namespace,
+		JobName:       job.Name,
+		PodName:       pod.Name,
+		ContainerName: containerName,
+	}
+	stream, err := s.K8sClient.StreamPodLogs(ctx, pod.Namespace, pod.Name, &corev1.PodLogOptions{Container: containerName})
+	switch {
+	case err == nil:
+		containerLogs.Logs, containerLogs.Truncated, err = tailBytes(stream, failureLogsLimit)
+		stream.Close()
+		if err != nil {
+			log.Error().Err(err).Str("operation", "captureContainerLogs").Msg("tailBytes")
+			return
+		}
+	case apierrors.IsNotFound(err) || apierrors.IsBadRequest(err):
+		// The logs are gone for good, remember why instead of retrying.
+		containerLogs.Error = err.Error()
+	default:
+		// Try again on the next round.
+		return
+	}
+
+	if err := s.PutContainerLogs(containerLogs); err != nil {
+		log.Error().Err(err).Str("operation", "captureContainerLogs").Msg("PutContainerLogs")
+		return
+	}
+	log.Info().
+		Str("operation", "captureContainerLogs").
+		Msg(fmt.Sprintf("captured logs of container %s in pod %s/%s of failed job %s",
+			containerName, pod.Namespace, pod.Name, job.Name))
+}
+
+// tailBytes reads r to the end and returns its last limit bytes, without the
+// part of a rune cut at their start, and whether anything before them was
+// dropped.
+func tailBytes(r io.Reader, limit int) ([]byte, bool, error) {
+	buf := make([]byte, 0, 2*limit)
+	chunk := make([]byte, logsReadChunkSize)
+	total := 0
+	for {
+		n, err := r.Read(chunk)
+		total += n
+		buf = append(buf, chunk[:n]...)
+		if len(buf) > 2*limit {
+			buf = buf[:copy(buf, buf[len(buf)-limit:])]
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("reading logs failed: %w", err)
+		}
+	}
+	if len(buf) > limit {
+		buf = buf[len(buf)-limit:]
+		for i := 0; i < utf8.UTFMax-1 && len(buf) > 0 && !utf8.RuneStart(buf[0]); i++ {
+			buf = buf[1:]
+		}
+	}
+	return buf, total > limit, nil
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danroux/sk8l/internal/k8s"
+	"github.com/danroux/sk8l/internal/store"
+	"github.com/danroux/sk8l/protos"
+	"github.com/google/go-cmp/cmp"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// followingClient serves a log stream that only ends once it is closed.
+type followingClient struct {
+	*k8s.Client
+	opts   chan *corev1.PodLogOptions
+	closed chan struct{}
+}
+
+func (c *followingClient) StreamPodLogs(_ context.Context, _, _ string, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+	c.opts <- opts
+	r, w := io.Pipe()
+	go func() {
+		_, _ = w.Write([]byte("first line\n"))
+	}()
+	return &closeNotifier{ReadCloser: r, closed: c.closed}, nil
+}
+
+// staticLogsClient serves the same logs for every container.
+type staticLogsClient struct {
+	*k8s.Client
+	logs string
+}
+
+func (c *staticLogsClient) StreamPodLogs(context.Context, string, string, *corev1.PodLogOptions) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(c.logs)), nil
+}
+
+type closeNotifier struct {
+	io.ReadCloser
+	closed chan struct{}
+}
+
+func (c *closeNotifier) Close() error {
+	select {
+	case <-c.closed:
+	default:
+		close(c.closed)
+	}
+	return c.ReadCloser.Close()
+}
+
+func logsClient(t *testing.T, k8sClient k8s.ClientInterface) protos.CronjobClient {
+	t.Helper()
+	st, err := store.NewCronJobDBStore(store.WithStorage(store.NewMemoryStorage()), store.WithK8sClient(k8sClient))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	sk8lServer.CronJobDBStore = st
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(bufDialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return protos.NewCronjobClient(conn)
+}
+
+func TestGetPodLogs(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}}
+	client := logsClient(t, k8s.NewClientWithInterface(fake.NewClientset(pod), k8s.WithNamespace("default")))
+
+	stream, err := client.GetPodLogs(context.Background(), &protos.PodLogsRequest{
+		PodName:      "test-pod",
+		PodNamespace: "default",
+		TailLines:    5,
+	})
+	if err != nil {
+		t.Fatalf("GetPodLogs failed: %v", err)
+	}
+	response, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	if response.GetLine() != "fake logs" {
+		t.Errorf("expected fake logs, got %q", response.GetLine())
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("expected the stream to end, got %v", err)
+	}
+}
+
+func TestGetPodLogs_LongAndInvalidLines(t *testing.T) {
+	long := strings.Repeat("a", maxLogLineSize+10)
+	client := logsClient(t, &staticLogsClient{
+		Client: k8s.NewClientWithInterface(fake.NewClientset()),
+		logs:   "caf\xe9\n\n" + long + "\nlast",
+	})
+
+	stream, err := client.GetPodLogs(context.Background(), &protos.PodLogsRequest{PodName: "test-pod"})
+	if err != nil {
+		t.Fatalf("GetPodLogs failed: %v", err)
+	}
+	lines := []string{}
+	for {
+		response, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		lines = append(lines, response.GetLine())
+	}
+	expected := []string{"caf\uFFFD", "", long[:maxLogLineSize], long[maxLogLineSize:], "last"}
+	if diff := cmp.Diff(expected, lines); diff != "" {
+		t.Errorf("unexpected lines (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetPodLogs_InvalidOptions(t *testing.T) {
+	client := logsClient(t, k8s.NewClientWithInterface(fake.NewClientset()))
+
+	for name, in := range map[string]*protos.PodLogsRequest{
+		"sinceTime": {PodName: "test-pod", SinceTime: "yesterday"},
+		"tailLines": {PodName: "test-pod", TailLines: -1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			stream, err := client.GetPodLogs(context.Background(), in)
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("expected InvalidArgument, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGetPodLogs_FollowStopsOnCancel(t *testing.T) {
+	k8sClient := &followingClient{
+		Client: k8s.NewClientWithInterface(fake.NewClientset()),
+		opts:   make(chan *corev1.PodLogOptions, 1),
+		closed: make(chan struct{}),
+	}
+	client := logsClient(t, k8sClient)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.GetPodLogs(ctx, &protos.PodLogsRequest{
+		PodName:   "test-pod",
+		Container: "main",
+		Follow:    true,
+		Previous:  true,
+		SinceTime: "2026-01-01T00:00:00Z",
+	})
+	if err != nil {
+		t.Fatalf("GetPodLogs failed: %v", err)
+	}
+	response, err := stream.Recv()
+	if err != nil || response.GetLine() != "first line" {
+		t.Fatalf("expected first line, got %v, %v", response, err)
+	}
+
+	opts := <-k8sClient.opts
+	if !opts.Follow || !opts.Previous || opts.Container != "main" || opts.SinceTime == nil || opts.TailLines != nil {
+		t.Errorf("unexpected log options %+v", opts)
+	}
+
+	cancel()
+	select {
+	case <-k8sClient.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the log stream to be closed after the client cancelled")
+	}
+}
+
+func TestCaptureFailedJobsLogs(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "failed-pod",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{{Name: "failed-job"}},
+		},
+		Status: corev1.PodStatus{
+			StartTime: &metav1.Time{Time: time.Now()},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:  "main",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}},
+				},
+				{
+					Name:  "sidecar",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"}},
+				},
+			},
+		},
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "failed-job",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{{Name: "cj-1"}},
+		},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}},
+		},
+	}
+	client := logsClient(t, k8s.NewClientWithInterface(fake.NewClientset(pod), k8s.WithNamespace("default")))
+	if err := sk8lServer.PutJob(job); err != nil {
+		t.Fatalf("PutJob failed: %v", err)
+	}
+	if err := sk8lServer.PutPod(pod); err != nil {
+		t.Fatalf("PutPod failed: %v", err)
+	}
+
+	sk8lServer.captureFailedJobsLogs(context.Background())
+	// Kubernetes garbage collects the pod, its logs stay available.
+	if err := sk8lServer.DeletePod(pod); err != nil {
+		t.Fatalf("DeletePod failed: %v", err)
+	}
+
+	response, err := client.GetJobFailureLogs(context.Background(), &protos.JobRequest{
+		JobName:      "failed-job",
+		JobNamespace: "default",
+	})
+	if err != nil {
+		t.Fatalf("GetJobFailureLogs failed: %v", err)
+	}
+	containers := response.GetContainers()
+	if len(containers) != 1 {
+		t.Fatalf("expected logs of the failed container only, got %+v", containers)
+	}
+	if containers[0].GetContainerName() != "main" || containers[0].GetLogs() != "fake logs" {
+		t.Errorf("unexpected container logs %+v", containers[0])
+	}
+}
+
+func TestGetJobFailureLogs_InvalidUTF8(t *testing.T) {
+	client := logsClient(t, k8s.NewClientWithInterface(fake.NewClientset()))
+	err := sk8lServer.PutContainerLogs(store.ContainerLogs{
+		Namespace: "default", JobName: "job-a", PodName: "pod-a", ContainerName: "main",
+		Logs: []byte("caf\xe9"), CapturedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("PutContainerLogs failed: %v", err)
+	}
+
+	response, err := client.GetJobFailureLogs(context.Background(), &protos.JobRequest{JobName: "job-a", JobNamespace: "default"})
+	if err != nil {
+		t.Fatalf("GetJobFailureLogs failed: %v", err)
+	}
+	if containers := response.GetContainers(); len(containers) != 1 || containers[0].GetLogs() != "caf\uFFFD" {
+		t.Errorf("expected the invalid byte to be replaced, got %+v", containers)
+	}
+}
+
+func TestTailBytes(t *testing.T) {
+	data := strings.Repeat("0123456789", 10)
+
+	tail, truncated, err := tailBytes(strings.NewReader(data), 15)
+	if err != nil {
+		t.Fatalf("tailBytes failed: %v", err)
+	}
+	if string(tail) != data[len(data)-15:] || !truncated {
+		t.Errorf("expected the last 15 bytes truncated, got %q, %v", tail, truncated)
+	}
+
+	tail, truncated, err = tailBytes(strings.NewReader(data), 1000)
+	if err != nil {
+		t.Fatalf("tailBytes failed: %v", err)
+	}
+	if string(tail) != data || truncated {
+		t.Errorf("expected every byte, got %q, %v", tail, truncated)
+	}
+
+	// "é" is 2 bytes, the last 4 bytes start with its second byte.
+	tail, truncated, err = tailBytes(strings.NewReader("café!!!"), 4)
+	if err != nil {
+		t.Fatalf("tailBytes failed: %v", err)
+	}
+	if string(tail) != "!!!" || !truncated {
+		t.Errorf("expected the cut rune to be dropped, got %q, %v", tail, truncated)
+	}
+}
@@ -3,106 +3,169 @@ package main
 
 import (
 	"context"
-	"crypto/x509"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/danroux/sk8l/internal/dashboard"
+	"github.com/danroux/sk8l/internal/k8s"
+	"github.com/danroux/sk8l/internal/leader"
 	"github.com/danroux/sk8l/internal/logger"
 	"github.com/danroux/sk8l/internal/store"
 	"github.com/danroux/sk8l/protos"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
-)
-
-const (
-	ReadTimeoutSeconds  = 10
-	WriteTimeoutSeconds = 30
-	ReadTimeout         = ReadTimeoutSeconds * time.Second
-	WriteTimeout        = WriteTimeoutSeconds * time.Second
-)
-
-var (
-	K8Namespace   = os.Getenv("K8_NAMESPACE")
-	APIPort       = os.Getenv("SK8L_SERVICE_PORT_SK8L_API")
-	APIHealthPort = os.Getenv("SK8L_SERVICE_PORT_SK8L_API_HEALTH")
-	MetricsPort   = os.Getenv("SK8L_SERVICE_PORT_SK8L_API_METRICS")
-	certFile      = filepath.Join("/etc", "sk8l-certs", "server-cert.pem")
-	certKeyFile   = filepath.Join("/etc", "sk8l-certs", "server-key.pem")
-	caFile        = filepath.Join("/etc", "sk8l-certs", "ca-cert.pem")
-	MetricPrefix  = fmt.Sprintf("sk8l_%s", K8Namespace)
+	"google.golang.org/grpc/reflection"
 )
 
 func main() {
 	logger.SetupZeroLog()
-	certPool := x509.NewCertPool()
-	serverTLSConfig, err := setupTLS(certFile, certKeyFile, caFile, certPool)
+	rootCtx := context.Background()
+	importMode, args := false, os.Args[1:]
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			if err := runExport(rootCtx, os.Args[2:]); err != nil {
+				log.Fatal().Err(err).Msg("export")
+			}
+			return
+		case "import":
+			importMode, args = true, os.Args[2:]
+		case "openapi":
+			if err := runOpenAPI(); err != nil {
+				log.Fatal().Err(err).Msg("openapi")
+			}
+			return
+		default: // default case to satisfy revive
+		}
+	}
+	snapshotPath, err := loadConfig(importMode, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("config")
+	}
+	if err := logger.Configure(cfg.LogSettings()); err != nil {
+		log.Fatal().Err(err).Msg("logger")
+	}
+	registerMetrics(cfg.Namespace)
+
+	transports, err := newTransports(cfg)
 	if err != nil {
 		log.Fatal().Err(err).Msg("setupTLS")
 	}
-	target := fmt.Sprintf("0.0.0.0:%s", APIPort)
+	target := fmt.Sprintf("0.0.0.0:%d", cfg.Server.APIPort)
 	lc := net.ListenConfig{}
-	rootCtx := context.Background()
 	ln, err := lc.Listen(rootCtx, "tcp", target)
 	if err != nil {
 		log.Fatal().Err(err).Msg("tlsListen")
 	}
-	healthTarget := fmt.Sprintf("0.0.0.0:%s", APIHealthPort)
+	healthTarget := fmt.Sprintf("0.0.0.0:%d", cfg.Server.HealthPort)
 	healthLn, err := lc.Listen(rootCtx, "tcp", healthTarget)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Health Probe Listen")
 	}
-	serverCreds := credentials.NewTLS(serverTLSConfig)
-	creds := grpc.Creds(serverCreds)
-	grpcS := grpc.NewServer(creds)
+	// Client certificates are only asked for on the API listener, unless a
+	// listener uses mtls.
+	clientAuth, err := clientAuthType(cfg.TLS.ClientAuth)
+	if err != nil {
+		log.Fatal().Err(err).Msg("tls.clientAuth")
+	}
 	probeS := grpc.NewServer()
 
 	metricsNamesMap := &sync.Map{}
 	dashboardGen := dashboard.NewGenerator(
-		MetricPrefix,
-		K8Namespace,
+		metricPrefix(),
+		cfg.Namespace,
 		TotalMetricNames,
 	)
 
-	cronjobDBStore, err := store.NewCronJobDBStore(store.WithDefaultK8sClient(K8Namespace))
+	cronjobDBStore, elector, err := newCronJobDBStore(snapshotPath)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to initialize cronjobDBStore")
 	}
+	// internalToken authenticates the metrics collection calling the API.
+	internalToken := rand.Text()
 	sk8lServer := NewSk8lServer(
 		target,
 		cronjobDBStore,
 		dashboardGen,
 		metricsNamesMap,
-		grpc.WithTransportCredentials(serverCreds),
+		transports.dialOptions(cfg.TLS.API, internalToken)...,
 	)
+	sk8lServer.readOnly = snapshotPath != ""
+	sk8lServer.elector = elector
+	sk8lServer.actions = cfg.Server.Actions
+	if sk8lServer.redactor, err = newRedactor(); err != nil {
+		log.Fatal().Err(err).Msg("failed to initialize redaction")
+	}
+	if sk8lServer.authenticator, err = newAuthenticator(sk8lServer.K8sClient, internalToken); err != nil {
+		log.Fatal().Err(err).Msg("failed to initialize authentication")
+	}
+	if sk8lServer.policy, err = newPolicy(sk8lServer.authenticator); err != nil {
+		log.Fatal().Err(err).Msg("failed to initialize authorization")
+	}
+	if sk8lServer.auditLog, err = newAuditLog(); err != nil {
+		log.Fatal().Err(err).Msg("failed to initialize the audit log")
+	}
+	grpcS := grpc.NewServer(append(transports.serverOptions(cfg.TLS.API, clientAuth), sk8lServer.ServerOptions()...)...)
 
 	healthgrpc.RegisterHealthServer(probeS, sk8lServer)
 	protos.RegisterCronjobServer(grpcS, sk8lServer)
+	// Lets clients like grpcurl discover the API without a copy of sk8l.proto.
+	reflection.Register(grpcS)
 	mux := &http.ServeMux{}
 	mux.Handle("/metrics", promhttp.Handler())
 	httpS := &http.Server{
-		Addr:         fmt.Sprintf("0.0.0.0:%s", MetricsPort),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  ReadTimeout,
-		WriteTimeout: WriteTimeout,
-		TLSConfig:    serverTLSConfig,
+		Addr:         fmt.Sprintf("0.0.0.0:%d", cfg.Server.MetricsPort),
+		IdleTimeout:  cfg.Server.IdleTimeout.Duration,
+		ReadTimeout:  cfg.Server.ReadTimeout.Duration,
+		WriteTimeout: cfg.Server.WriteTimeout.Duration,
+		TLSConfig:    transports.serverConfig(cfg.TLS.Metrics, tls.NoClientCert, httpNextProtos),
 		Handler:      mux,
 	}
+	httpServers := []*http.Server{httpS}
+	if cfg.Server.GatewayPort != 0 {
+		gw, err := sk8lServer.newGateway()
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to initialize the HTTP gateway")
+		}
+		// The gateway has the transport of the API, its streams lift the
+		// write timeout.
+		httpServers = append(httpServers, &http.Server{
+			Addr:         fmt.Sprintf("0.0.0.0:%d", cfg.Server.GatewayPort),
+			IdleTimeout:  cfg.Server.IdleTimeout.Duration,
+			ReadTimeout:  cfg.Server.ReadTimeout.Duration,
+			WriteTimeout: cfg.Server.WriteTimeout.Duration,
+			TLSConfig:    transports.serverConfig(cfg.TLS.API, clientAuth, httpNextProtos),
+			Handler:      gw,
+		})
+	}
+	if cfg.Server.DebugPort != 0 {
+		httpServers = append(httpServers, sk8lServer.newDebugServer(transports.serverConfig(cfg.TLS.Debug, clientAuth, httpNextProtos)))
+	}
 	log.Info().
-		Msg(fmt.Sprintf("Starting %s server %s on %s", "sk8l", Version(), ln.Addr().String()))
-	errCh := startServers(httpS, probeS, grpcS, ln, healthLn)
+		Msg(fmt.Sprintf("Starting %s server %s on %s with %s storage", "sk8l", Version(), ln.Addr().String(), cronjobDBStore.Backend()))
+	// With gRPC-Web, grpcS serves the API port through apiS.
+	apiS := newAPIServer(grpcS, transports.serverConfig(cfg.TLS.API, clientAuth, httpNextProtos))
+	probeHTTP := sk8lServer.newProbeServer(probeS, transports.serverConfig(cfg.TLS.Health, tls.NoClientCert, httpNextProtos))
+	sk8lServer.watchCluster()
+	errCh := startServers(httpServers, probeHTTP, grpcS, apiS, ln, healthLn)
 	metricsCxt, metricsCancel := context.WithCancel(rootCtx)
-	sk8lServer.Run(metricsCxt)
+	dutiesStopped := sk8lServer.Run(metricsCxt)
+	transports.watch(metricsCxt, cfg.TLS.ReloadInterval.Duration)
 	log.Info().Msg("Shutdown: setting up")
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
@@ -114,22 +177,82 @@ func main() {
 		log.Info().
 			Msg(fmt.Sprintf("Shutdown: Got %v signal. sk8l will shut down shortly", sig))
 	}
-	shutdownServers(rootCtx, httpS, grpcS, probeS, metricsCancel)
+	shutdownServers(rootCtx, httpServers, apiS, probeHTTP, grpcS, probeS, metricsCancel, dutiesStopped)
+	if err := cronjobDBStore.Close(); err != nil {
+		log.Error().Err(err).Msg("Shutdown: error closing storage")
+	}
+	if err := sk8lServer.auditLog.Close(); err != nil {
+		log.Error().Err(err).Msg("Shutdown: error closing the audit log")
+	}
 }
 
-func startServers(httpS *http.Server, probeS, grpcS *grpc.Server, ln, healthLn net.Listener) <-chan error {
-	errCh := make(chan error, 3)
-	go func() {
-		if err := httpS.ListenAndServeTLS(certFile, certKeyFile); err != nil {
-			errCh <- fmt.Errorf("httpS error: %w", err)
+// newCronJobDBStore connects to the cluster, or loads the snapshot at
+// snapshotPath when sk8l was started in import mode. The store only records
+// the history while the returned Elector leads, when it isn't nil.
+func newCronJobDBStore(snapshotPath string) (*store.CronJobDBStore, *leader.Elector, error) {
+	if snapshotPath != "" {
+		cronjobDBStore, manifest, err := newSnapshotStore(snapshotPath)
+		if err != nil {
+			return nil, nil, err
 		}
-	}()
+		log.Info().
+			Msg(fmt.Sprintf("Import mode: serving snapshot of namespace %s taken at %s by sk8l %s, read-only",
+				manifest.Namespace, manifest.CreatedAt.Format(time.RFC3339), manifest.Sk8lVersion))
+		return cronjobDBStore, nil, nil
+	}
+
+	k8sClient, err := k8s.NewClient(
+		k8s.WithNamespace(cfg.Namespace),
+		k8s.WithLogger(log.With().Str("component", "k8s").Logger()),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	elector, err := newElector(k8sClient)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize the leader election: %w", err)
+	}
+	storage, err := store.NewStorage(cfg.Storage.Backend, cfg.Storage.BadgerPath, cfg.StorageLimits())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	cronjobDBStore, err := store.NewCronJobDBStore(
+		store.WithK8sClient(k8sClient),
+		store.WithStorage(storage),
+		store.WithEventTTL(cfg.Storage.EventTTL.Duration),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize cronjobDBStore: %w", err)
+	}
+	return cronjobDBStore, elector, nil
+}
+
+func startServers(httpServers []*http.Server, probeS *http.Server, grpcS *grpc.Server, apiS *http.Server, ln, healthLn net.Listener) <-chan error {
+	errCh := make(chan error, len(httpServers)+2)
+	for _, httpS := range httpServers {
+		go func() {
+			// The certificates come from the reloading TLSConfig, plaintext without one.
+			serve := func() error { return httpS.ListenAndServeTLS("", "") }
+			if httpS.TLSConfig == nil {
+				serve = httpS.ListenAndServe
+			}
+			if err := serve(); err != nil {
+				errCh <- fmt.Errorf("httpS %s error: %w", httpS.Addr, err)
+			}
+		}()
+	}
 	go func() {
-		if err := probeS.Serve(healthLn); err != nil {
+		if err := serveHTTP(probeS, healthLn); err != nil {
 			errCh <- fmt.Errorf("probeS error: %w", err)
 		}
 	}()
 	go func() {
+		if apiS != nil {
+			if err := serveHTTP(apiS, ln); err != nil {
+				errCh <- fmt.Errorf("apiS error: %w", err)
+			}
+			return
+		}
 		if err := grpcS.Serve(ln); err != nil {
 			errCh <- fmt.Errorf("grpcS error: %w", err)
 		}
@@ -137,19 +260,58 @@ func startServers(httpS *http.Server, probeS, grpcS *grpc.Server, ln, healthLn n
 	return errCh
 }
 
+// serveHTTP serves srv on ln, with TLS when srv has a TLSConfig.
+func serveHTTP(srv *http.Server, ln net.Listener) error {
+	var err error
+	if srv.TLSConfig == nil {
+		err = srv.Serve(ln)
+	} else {
+		err = srv.ServeTLS(ln, "", "")
+	}
+	if err != nil {
+		return fmt.Errorf("sk8l#serveHTTP: %w", err)
+	}
+	return nil
+}
+
 func shutdownServers(
 	rootCtx context.Context,
-	httpS *http.Server,
+	httpServers []*http.Server,
+	apiS, probeHTTP *http.Server,
 	grpcS, probeS *grpc.Server,
 	metricsCancel context.CancelFunc,
+	dutiesStopped <-chan struct{},
 ) {
-	shutdownCtx, shutdownCancel := context.WithTimeout(rootCtx, 5*time.Second)
+	// Waits for the elector to release the Lease, letting a follower take
+	// over, before the servers: their open streams can hold them until the
+	// shutdown timeout expires.
+	metricsCancel()
+	leaseCtx, leaseCancel := context.WithTimeout(rootCtx, cfg.Server.ShutdownTimeout.Duration)
+	defer leaseCancel()
+	select {
+	case <-dutiesStopped:
+	case <-leaseCtx.Done():
+		log.Error().Err(leaseCtx.Err()).Msg("Shutdown: the singleton duties did not stop in time")
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(rootCtx, cfg.Server.ShutdownTimeout.Duration)
 	defer shutdownCancel()
-	if err := httpS.Shutdown(shutdownCtx); err != nil {
-		log.Error().Err(err).Msg("Shutdown: error during httpS shutdown")
+	for _, httpS := range httpServers {
+		if err := httpS.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Str("addr", httpS.Addr).Msg("Shutdown: error during httpS shutdown")
+		}
 	}
-	metricsCancel()
+	// Stopping grpcS and probeS first ends the streams they serve through
+	// apiS and probeHTTP.
 	grpcS.GracefulStop()
+	if apiS != nil {
+		if err := apiS.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("Shutdown: error during apiS shutdown")
+		}
+	}
 	probeS.GracefulStop()
+	if err := probeHTTP.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Shutdown: error during probeHTTP shutdown")
+	}
 	log.Info().Msg("Shutdown: sk8l has stopped")
 }
@@ -5,48 +5,63 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"regexp"
 	"sync"
 	"time"
 
+	"github.com/danroux/sk8l/internal/failure"
+	"github.com/danroux/sk8l/internal/logger"
 	"github.com/danroux/sk8l/protos"
 	"google.golang.org/grpc"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
-	"github.com/rs/zerolog/log"
 )
 
 var (
-	namespace           = os.Getenv("K8_NAMESPACE")
+	metricsLog          = logger.For(logger.Metrics)
 	optNamespace        = "sk8l"
 	summaryMap          = &sync.Map{}
 	failingCronjobsOpts = prometheus.GaugeOpts{
 		Namespace: optNamespace,
 		Name:      "failing_cronjobs_total",
-		Subsystem: namespace,
 	}
 	runningCronjobsOpts = prometheus.GaugeOpts{
 		Namespace: optNamespace,
 		Name:      "running_cronjobs_total",
-		Subsystem: namespace,
 	}
 	completedCronjobsOpts = prometheus.GaugeOpts{
 		Namespace: optNamespace,
 		Name:      "completed_cronjobs_total",
-		Subsystem: namespace,
 	}
 	registeredCronjobsOpts = prometheus.GaugeOpts{
 		Namespace: optNamespace,
 		Name:      "registered_cronjobs_total",
-		Subsystem: namespace,
+	}
+	failuresByCategoryOpts = prometheus.GaugeOpts{
+		Namespace: optNamespace,
+		Name:      "failures_by_category_total",
+		Help:      "Failed jobs by root-cause category",
+	}
+	leaderOpts = prometheus.GaugeOpts{
+		Namespace: optNamespace,
+		Name:      "leader",
+		Help:      "1 on the replica collecting the metrics, the leader when the leader election is enabled",
+	}
+	certificateExpiryOpts = prometheus.GaugeOpts{
+		Namespace: optNamespace,
+		Name:      "tls_certificate_expiry_timestamp_seconds",
+		Help:      "When the server and CA certificates expire, in seconds since the epoch",
 	}
 
-	failingCronjobsGauge    = promauto.NewGauge(failingCronjobsOpts)
-	runningCronjobsGauge    = promauto.NewGauge(runningCronjobsOpts)
-	completedCronjobsGauge  = promauto.NewGauge(completedCronjobsOpts)
-	registeredCronjobsGauge = promauto.NewGauge(registeredCronjobsOpts)
+	// The gauges are registered by registerMetrics once the namespace is configured.
+	failingCronjobsGauge    prometheus.Gauge
+	runningCronjobsGauge    prometheus.Gauge
+	completedCronjobsGauge  prometheus.Gauge
+	registeredCronjobsGauge prometheus.Gauge
+	failuresByCategoryGauge *prometheus.GaugeVec
+	leaderGauge             prometheus.Gauge
+	certificateExpiryGauge  *prometheus.GaugeVec
 
 	metricNameRegex = regexp.MustCompile(`_*[^0-9A-Za-z_]+_*`)
 
@@ -58,6 +73,27 @@ var (
 	}
 )
 
+// registerMetrics registers the gauges summing up the cronjobs, under the
+// watched namespace as subsystem.
+func registerMetrics(subsystem string) {
+	withSubsystem := func(opts prometheus.GaugeOpts) prometheus.GaugeOpts {
+		opts.Subsystem = subsystem
+		return opts
+	}
+	failingCronjobsGauge = promauto.NewGauge(withSubsystem(failingCronjobsOpts))
+	runningCronjobsGauge = promauto.NewGauge(withSubsystem(runningCronjobsOpts))
+	completedCronjobsGauge = promauto.NewGauge(withSubsystem(completedCronjobsOpts))
+	registeredCronjobsGauge = promauto.NewGauge(withSubsystem(registeredCronjobsOpts))
+	failuresByCategoryGauge = promauto.NewGaugeVec(withSubsystem(failuresByCategoryOpts), []string{"category"})
+	leaderGauge = promauto.NewGauge(withSubsystem(leaderOpts))
+	certificateExpiryGauge = promauto.NewGaugeVec(withSubsystem(certificateExpiryOpts), []string{"certificate"})
+}
+
+// metricPrefix prefixes the names of the per cronjob metrics.
+func metricPrefix() string {
+	return fmt.Sprintf("sk8l_%s", cfg.Namespace)
+}
+
 func setGaugeInMap(key string, opts prometheus.GaugeOpts, val float64) {
 	if gauge, ok := summaryMap.Load(key); ok {
 		gauge.(prometheus.Gauge).Set(val)
@@ -116,9 +152,9 @@ func recordSingleCronjobMetrics(
 	durationMetricName := fmt.Sprintf("%s_duration_seconds", sanitizedCjName)
 
 	metricNames := []string{
-		fmt.Sprintf("%s_%s", MetricPrefix, completionMetricName),
-		fmt.Sprintf("%s_%s", MetricPrefix, failureMetricName),
-		fmt.Sprintf("%s_%s", MetricPrefix, durationMetricName),
+		fmt.Sprintf("%s_%s", metricPrefix(), completionMetricName),
+		fmt.Sprintf("%s_%s", metricPrefix(), failureMetricName),
+		fmt.Sprintf("%s_%s", metricPrefix(), durationMetricName),
 	}
 	metricsNamesMap.Store(sanitizedCjName, metricNames)
 
@@ -179,6 +215,26 @@ func processCronjobsResponse(cronjobs []*protos.CronjobResponse, subSystem strin
 	runningCronjobsGauge.Set(totalRunning)
 	failingCronjobsGauge.Set(totalFailing)
 	completedCronjobsGauge.Set(totalCompleted)
+	recordFailuresByCategory(cronjobs)
+}
+
+// Sets the number of failed jobs per failure category. Categories without failures report 0.
+func recordFailuresByCategory(cronjobs []*protos.CronjobResponse) {
+	counts := make(map[protos.FailureCategory]float64)
+	for _, cj := range cronjobs {
+		for _, job := range cj.Jobs {
+			if job.Failed {
+				counts[job.FailureCategory]++
+			}
+		}
+	}
+	for value := range protos.FailureCategory_name {
+		category := protos.FailureCategory(value)
+		if category == protos.FailureCategory_FAILURE_CATEGORY_UNSPECIFIED {
+			continue
+		}
+		failuresByCategoryGauge.WithLabelValues(failure.Label(category)).Set(counts[category])
+	}
 }
 
 func collectMetricsStream(ctx context.Context, c protos.CronjobClient, subSystem string, metricsNamesMap *sync.Map) error {
@@ -196,8 +252,7 @@ func collectMetricsStream(ctx context.Context, c protos.CronjobClient, subSystem
 
 		cronjobsResponse, err := cronjobsClient.Recv()
 		if errors.Is(err, io.EOF) {
-			log.Info().
-				Str("component", "metrics").
+			metricsLog.Info().
 				Msg("GetCronjobs stream closed (EOF), reconnecting")
 			return nil
 		}
@@ -213,15 +268,16 @@ func collectMetricsStream(ctx context.Context, c protos.CronjobClient, subSystem
 		select {
 		case <-ctx.Done():
 			return fmt.Errorf("metrics collection canceled: %w", ctx.Err())
-		case <-time.After(10 * time.Second):
+		case <-time.After(cfg.Metrics.Interval.Duration):
 		}
 	}
 }
 
+// recordMetrics collects the metrics until ctx is done.
 func recordMetrics(ctx context.Context, svr *Sk8lServer, metricsNamesMap *sync.Map) {
 	conn, err := grpc.NewClient(svr.GetTarget(), svr.GetDialOptions()...)
 	if err != nil {
-		log.Error().
+		metricsLog.Error().
 			Err(err).
 			Str("operation", "recordMetrics").
 			Msg(fmt.Sprintf("grpc.NewClient(%s) failed", svr.GetTarget()))
@@ -231,42 +287,38 @@ func recordMetrics(ctx context.Context, svr *Sk8lServer, metricsNamesMap *sync.M
 	c := protos.NewCronjobClient(conn)
 	subSystem := svr.K8sClient.Namespace()
 
-	log.Info().
-		Str("component", "metrics").
+	metricsLog.Info().
 		Str("operation", "recordMetrics").
 		Msg("Starting metrics collection")
 
-	go func() {
-		defer func() {
-			_ = conn.Close()
-		}()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			metricsLog.Info().
+				Msg("Stopping metrics collection")
+			return
+		default:
+		}
 
-		for {
+		if err := collectMetricsStream(ctx, c, subSystem, metricsNamesMap); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			metricsLog.Error().
+				Err(err).
+				Str("operation", "recordMetrics").
+				Msg(fmt.Sprintf("metrics collection error, retrying in %s", cfg.Metrics.RetryDelay.Duration))
 			select {
 			case <-ctx.Done():
-				log.Info().
-					Str("component", "metrics").
-					Msg("Stopping metrics collection")
 				return
-			default:
-			}
-
-			if err := collectMetricsStream(ctx, c, subSystem, metricsNamesMap); err != nil {
-				if errors.Is(err, context.Canceled) {
-					return
-				}
-				log.Error().
-					Err(err).
-					Str("operation", "recordMetrics").
-					Msg("metrics collection error, retrying in 5s")
-				select {
-				case <-ctx.Done():
-					return
-				case <-time.After(5 * time.Second):
-				}
+			case <-time.After(cfg.Metrics.RetryDelay.Duration):
 			}
 		}
-	}()
+	}
 }
 
 // https://github.com/prometheus/node_exporter/blob/4a1b77600c1873a8233f3ffb55afcedbb63b8d84/collector/helper.go#L48
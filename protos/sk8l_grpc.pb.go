@@ -26,6 +26,15 @@ type CronjobClient interface {
 	GetJobYAML(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (*JobYAMLResponse, error)
 	GetPodYAML(ctx context.Context, in *PodRequest, opts ...grpc.CallOption) (*PodYAMLResponse, error)
 	GetDashboardAnnotations(ctx context.Context, in *DashboardAnnotationsRequest, opts ...grpc.CallOption) (*DashboardAnnotationsResponse, error)
+	ExportSnapshot(ctx context.Context, in *ExportSnapshotRequest, opts ...grpc.CallOption) (Cronjob_ExportSnapshotClient, error)
+	GetPodLogs(ctx context.Context, in *PodLogsRequest, opts ...grpc.CallOption) (Cronjob_GetPodLogsClient, error)
+	GetJobFailureLogs(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (*JobFailureLogsResponse, error)
+	GetEvents(ctx context.Context, in *EventsRequest, opts ...grpc.CallOption) (*EventsResponse, error)
+	GetCronjobRevisions(ctx context.Context, in *CronjobRevisionsRequest, opts ...grpc.CallOption) (*CronjobRevisionsResponse, error)
+	GetAuditLog(ctx context.Context, in *AuditLogRequest, opts ...grpc.CallOption) (*AuditLogResponse, error)
+	GetServerInfo(ctx context.Context, in *ServerInfoRequest, opts ...grpc.CallOption) (*ServerInfoResponse, error)
+	TriggerCronjob(ctx context.Context, in *CronjobRequest, opts ...grpc.CallOption) (*TriggerCronjobResponse, error)
+	SuspendCronjob(ctx context.Context, in *SuspendCronjobRequest, opts ...grpc.CallOption) (*SuspendCronjobResponse, error)
 }
 
 type cronjobClient struct {
@@ -200,6 +209,133 @@ func (c *cronjobClient) GetDashboardAnnotations(ctx context.Context, in *Dashboa
 	return out, nil
 }
 
+func (c *cronjobClient) ExportSnapshot(ctx context.Context, in *ExportSnapshotRequest, opts ...grpc.CallOption) (Cronjob_ExportSnapshotClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Cronjob_ServiceDesc.Streams[4], "/sk8l.Cronjob/ExportSnapshot", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cronjobExportSnapshotClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Cronjob_ExportSnapshotClient interface {
+	Recv() (*SnapshotChunk, error)
+	grpc.ClientStream
+}
+
+type cronjobExportSnapshotClient struct {
+	grpc.ClientStream
+}
+
+func (x *cronjobExportSnapshotClient) Recv() (*SnapshotChunk, error) {
+	m := new(SnapshotChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *cronjobClient) GetPodLogs(ctx context.Context, in *PodLogsRequest, opts ...grpc.CallOption) (Cronjob_GetPodLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Cronjob_ServiceDesc.Streams[5], "/sk8l.Cronjob/GetPodLogs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cronjobGetPodLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Cronjob_GetPodLogsClient interface {
+	Recv() (*PodLogsResponse, error)
+	grpc.ClientStream
+}
+
+type cronjobGetPodLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *cronjobGetPodLogsClient) Recv() (*PodLogsResponse, error) {
+	m := new(PodLogsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *cronjobClient) GetJobFailureLogs(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (*JobFailureLogsResponse, error) {
+	out := new(JobFailureLogsResponse)
+	err := c.cc.Invoke(ctx, "/sk8l.Cronjob/GetJobFailureLogs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cronjobClient) GetEvents(ctx context.Context, in *EventsRequest, opts ...grpc.CallOption) (*EventsResponse, error) {
+	out := new(EventsResponse)
+	err := c.cc.Invoke(ctx, "/sk8l.Cronjob/GetEvents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cronjobClient) GetCronjobRevisions(ctx context.Context, in *CronjobRevisionsRequest, opts ...grpc.CallOption) (*CronjobRevisionsResponse, error) {
+	out := new(CronjobRevisionsResponse)
+	err := c.cc.Invoke(ctx, "/sk8l.Cronjob/GetCronjobRevisions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cronjobClient) GetAuditLog(ctx context.Context, in *AuditLogRequest, opts ...grpc.CallOption) (*AuditLogResponse, error) {
+	out := new(AuditLogResponse)
+	err := c.cc.Invoke(ctx, "/sk8l.Cronjob/GetAuditLog", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cronjobClient) GetServerInfo(ctx context.Context, in *ServerInfoRequest, opts ...grpc.CallOption) (*ServerInfoResponse, error) {
+	out := new(ServerInfoResponse)
+	err := c.cc.Invoke(ctx, "/sk8l.Cronjob/GetServerInfo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cronjobClient) TriggerCronjob(ctx context.Context, in *CronjobRequest, opts ...grpc.CallOption) (*TriggerCronjobResponse, error) {
+	out := new(TriggerCronjobResponse)
+	err := c.cc.Invoke(ctx, "/sk8l.Cronjob/TriggerCronjob", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cronjobClient) SuspendCronjob(ctx context.Context, in *SuspendCronjobRequest, opts ...grpc.CallOption) (*SuspendCronjobResponse, error) {
+	out := new(SuspendCronjobResponse)
+	err := c.cc.Invoke(ctx, "/sk8l.Cronjob/SuspendCronjob", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CronjobServer is the server API for Cronjob service.
 // All implementations must embed UnimplementedCronjobServer
 // for forward compatibility
@@ -212,6 +348,15 @@ type CronjobServer interface {
 	GetJobYAML(context.Context, *JobRequest) (*JobYAMLResponse, error)
 	GetPodYAML(context.Context, *PodRequest) (*PodYAMLResponse, error)
 	GetDashboardAnnotations(context.Context, *DashboardAnnotationsRequest) (*DashboardAnnotationsResponse, error)
+	ExportSnapshot(*ExportSnapshotRequest, Cronjob_ExportSnapshotServer) error
+	GetPodLogs(*PodLogsRequest, Cronjob_GetPodLogsServer) error
+	GetJobFailureLogs(context.Context, *JobRequest) (*JobFailureLogsResponse, error)
+	GetEvents(context.Context, *EventsRequest) (*EventsResponse, error)
+	GetCronjobRevisions(context.Context, *CronjobRevisionsRequest) (*CronjobRevisionsResponse, error)
+	GetAuditLog(context.Context, *AuditLogRequest) (*AuditLogResponse, error)
+	GetServerInfo(context.Context, *ServerInfoRequest) (*ServerInfoResponse, error)
+	TriggerCronjob(context.Context, *CronjobRequest) (*TriggerCronjobResponse, error)
+	SuspendCronjob(context.Context, *SuspendCronjobRequest) (*SuspendCronjobResponse, error)
 	mustEmbedUnimplementedCronjobServer()
 }
 
@@ -243,6 +388,33 @@ func (UnimplementedCronjobServer) GetPodYAML(context.Context, *PodRequest) (*Pod
 func (UnimplementedCronjobServer) GetDashboardAnnotations(context.Context, *DashboardAnnotationsRequest) (*DashboardAnnotationsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetDashboardAnnotations not implemented")
 }
+func (UnimplementedCronjobServer) ExportSnapshot(*ExportSnapshotRequest, Cronjob_ExportSnapshotServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExportSnapshot not implemented")
+}
+func (UnimplementedCronjobServer) GetPodLogs(*PodLogsRequest, Cronjob_GetPodLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetPodLogs not implemented")
+}
+func (UnimplementedCronjobServer) GetJobFailureLogs(context.Context, *JobRequest) (*JobFailureLogsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJobFailureLogs not implemented")
+}
+func (UnimplementedCronjobServer) GetEvents(context.Context, *EventsRequest) (*EventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEvents not implemented")
+}
+func (UnimplementedCronjobServer) GetCronjobRevisions(context.Context, *CronjobRevisionsRequest) (*CronjobRevisionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCronjobRevisions not implemented")
+}
+func (UnimplementedCronjobServer) GetAuditLog(context.Context, *AuditLogRequest) (*AuditLogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAuditLog not implemented")
+}
+func (UnimplementedCronjobServer) GetServerInfo(context.Context, *ServerInfoRequest) (*ServerInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServerInfo not implemented")
+}
+func (UnimplementedCronjobServer) TriggerCronjob(context.Context, *CronjobRequest) (*TriggerCronjobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TriggerCronjob not implemented")
+}
+func (UnimplementedCronjobServer) SuspendCronjob(context.Context, *SuspendCronjobRequest) (*SuspendCronjobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SuspendCronjob not implemented")
+}
 func (UnimplementedCronjobServer) mustEmbedUnimplementedCronjobServer() {}
 
 // UnsafeCronjobServer may be embedded to opt out of forward compatibility for this service.
@@ -412,6 +584,174 @@ func _Cronjob_GetDashboardAnnotations_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Cronjob_ExportSnapshot_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportSnapshotRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CronjobServer).ExportSnapshot(m, &cronjobExportSnapshotServer{stream})
+}
+
+type Cronjob_ExportSnapshotServer interface {
+	Send(*SnapshotChunk) error
+	grpc.ServerStream
+}
+
+type cronjobExportSnapshotServer struct {
+	grpc.ServerStream
+}
+
+func (x *cronjobExportSnapshotServer) Send(m *SnapshotChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Cronjob_GetPodLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PodLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CronjobServer).GetPodLogs(m, &cronjobGetPodLogsServer{stream})
+}
+
+type Cronjob_GetPodLogsServer interface {
+	Send(*PodLogsResponse) error
+	grpc.ServerStream
+}
+
+type cronjobGetPodLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *cronjobGetPodLogsServer) Send(m *PodLogsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Cronjob_GetJobFailureLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CronjobServer).GetJobFailureLogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sk8l.Cronjob/GetJobFailureLogs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CronjobServer).GetJobFailureLogs(ctx, req.(*JobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cronjob_GetEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CronjobServer).GetEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sk8l.Cronjob/GetEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CronjobServer).GetEvents(ctx, req.(*EventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cronjob_GetCronjobRevisions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CronjobRevisionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CronjobServer).GetCronjobRevisions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sk8l.Cronjob/GetCronjobRevisions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CronjobServer).GetCronjobRevisions(ctx, req.(*CronjobRevisionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cronjob_GetAuditLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuditLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CronjobServer).GetAuditLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sk8l.Cronjob/GetAuditLog",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CronjobServer).GetAuditLog(ctx, req.(*AuditLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cronjob_GetServerInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServerInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CronjobServer).GetServerInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sk8l.Cronjob/GetServerInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CronjobServer).GetServerInfo(ctx, req.(*ServerInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cronjob_TriggerCronjob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CronjobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CronjobServer).TriggerCronjob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sk8l.Cronjob/TriggerCronjob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CronjobServer).TriggerCronjob(ctx, req.(*CronjobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cronjob_SuspendCronjob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SuspendCronjobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CronjobServer).SuspendCronjob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sk8l.Cronjob/SuspendCronjob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CronjobServer).SuspendCronjob(ctx, req.(*SuspendCronjobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Cronjob_ServiceDesc is the grpc.ServiceDesc for Cronjob service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -435,6 +775,34 @@ var Cronjob_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetDashboardAnnotations",
 			Handler:    _Cronjob_GetDashboardAnnotations_Handler,
 		},
+		{
+			MethodName: "GetJobFailureLogs",
+			Handler:    _Cronjob_GetJobFailureLogs_Handler,
+		},
+		{
+			MethodName: "GetEvents",
+			Handler:    _Cronjob_GetEvents_Handler,
+		},
+		{
+			MethodName: "GetCronjobRevisions",
+			Handler:    _Cronjob_GetCronjobRevisions_Handler,
+		},
+		{
+			MethodName: "GetAuditLog",
+			Handler:    _Cronjob_GetAuditLog_Handler,
+		},
+		{
+			MethodName: "GetServerInfo",
+			Handler:    _Cronjob_GetServerInfo_Handler,
+		},
+		{
+			MethodName: "TriggerCronjob",
+			Handler:    _Cronjob_TriggerCronjob_Handler,
+		},
+		{
+			MethodName: "SuspendCronjob",
+			Handler:    _Cronjob_SuspendCronjob_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -457,6 +825,16 @@ var Cronjob_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Cronjob_GetJobs_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "ExportSnapshot",
+			Handler:       _Cronjob_ExportSnapshot_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetPodLogs",
+			Handler:       _Cronjob_GetPodLogs_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "sk8l.proto",
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/danroux/sk8l/internal/redact"
+)
+
+// newRedactor builds the redactor configured by cfg.Redaction, the default
+// rules apply without a rules file.
+func newRedactor() (*redact.Redactor, error) {
+	rules := redact.Config{}
+	if cfg.Redaction.Config != "" {
+		var err error
+		if rules, err = redact.LoadConfig(cfg.Redaction.Config); err != nil {
+			return nil, fmt.Errorf("sk8l#newRedactor: %w", err)
+		}
+	}
+	if !cfg.Redaction.Enabled {
+		rules.Disabled = true
+	}
+	r, err := redact.New(rules)
+	if err != nil {
+		return nil, fmt.Errorf("sk8l#newRedactor: %w", err)
+	}
+	return r, nil
+}
+
+// redactedJSON encodes a Kubernetes object for the YAML responses, redacted
+// unless the caller opted out.
+func (s *Sk8lServer) redactedJSON(ctx context.Context, obj any) ([]byte, error) {
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("json.MarshalIndent failed: %w", err)
+	}
+	data, err = s.redactor.For(ctx).JSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("redacting failed: %w", err)
+	}
+	return data, nil
+}
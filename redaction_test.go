@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danroux/sk8l/internal/k8s"
+	"github.com/danroux/sk8l/internal/redact"
+	"github.com/danroux/sk8l/internal/store"
+	"github.com/danroux/sk8l/protos"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetPodYAML_Redacted(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-pod",
+			Namespace:   "default",
+			Annotations: map[string]string{"kubectl.kubernetes.io/last-applied-configuration": `{"env":"hunter2"}`},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "main",
+			Env:  []corev1.EnvVar{{Name: "DB_PASSWORD", Value: "hunter2"}},
+		}}},
+	}
+	client := logsClient(t, k8s.NewClientWithInterface(fake.NewClientset(pod), k8s.WithNamespace("default")))
+
+	response, err := client.GetPodYAML(context.Background(), &protos.PodRequest{PodName: "test-pod", PodNamespace: "default"})
+	if err != nil {
+		t.Fatalf("GetPodYAML failed: %v", err)
+	}
+	if strings.Contains(response.GetPod(), "hunter2") {
+		t.Errorf("expected the secret to be redacted\n%s", response.GetPod())
+	}
+	if !strings.Contains(response.GetPod(), redact.Placeholder) || !strings.Contains(response.GetPod(), "DB_PASSWORD") {
+		t.Errorf("expected the variable to be kept with a redacted value\n%s", response.GetPod())
+	}
+}
+
+func TestGetJobFailureLogs_Redacted(t *testing.T) {
+	client := logsClient(t, k8s.NewClientWithInterface(fake.NewClientset()))
+	err := sk8lServer.PutContainerLogs(store.ContainerLogs{
+		Namespace: "default", JobName: "job-a", PodName: "pod-a", ContainerName: "main",
+		Logs: []byte("connecting with --password=hunter2"), CapturedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("PutContainerLogs failed: %v", err)
+	}
+
+	response, err := client.GetJobFailureLogs(context.Background(), &protos.JobRequest{JobName: "job-a", JobNamespace: "default"})
+	if err != nil {
+		t.Fatalf("GetJobFailureLogs failed: %v", err)
+	}
+	if containers := response.GetContainers(); len(containers) != 1 || strings.Contains(containers[0].GetLogs(), "hunter2") {
+		t.Errorf("expected the logs to be redacted, got %+v", containers)
+	}
+}
+
+func TestGetPodLogs_Redacted(t *testing.T) {
+	client := logsClient(t, &staticLogsClient{
+		Client: k8s.NewClientWithInterface(fake.NewClientset()),
+		logs:   "connecting with --password=hunter2\n",
+	})
+
+	stream, err := client.GetPodLogs(context.Background(), &protos.PodLogsRequest{PodName: "test-pod", PodNamespace: "default"})
+	if err != nil {
+		t.Fatalf("GetPodLogs failed: %v", err)
+	}
+	response, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("stream.Recv failed: %v", err)
+	}
+	if strings.Contains(response.GetLine(), "hunter2") || !strings.Contains(response.GetLine(), "connecting with") {
+		t.Errorf("expected the line to be redacted, got %q", response.GetLine())
+	}
+}
+
+func TestNewRedactor(t *testing.T) {
+	cfg.Redaction.Enabled = false
+	t.Cleanup(func() { cfg.Redaction.Enabled = true })
+	r, err := newRedactor()
+	if err != nil {
+		t.Fatalf("newRedactor failed: %v", err)
+	}
+	if r != nil {
+		t.Error("expected redaction.enabled false to disable redaction")
+	}
+}
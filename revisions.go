@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/danroux/sk8l/internal/diff"
+	"github.com/danroux/sk8l/internal/store"
+	"github.com/danroux/sk8l/protos"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	gyaml "sigs.k8s.io/yaml"
+)
+
+// GetCronjobRevisions returns the recorded spec revisions of a CronJob and the
+// unified diff between two of them.
+func (s *Sk8lServer) GetCronjobRevisions(
+	ctx context.Context,
+	in *protos.CronjobRevisionsRequest,
+) (*protos.CronjobRevisionsResponse, error) {
+	revisions, err := s.CronjobRevisions(in.GetCronjobNamespace(), in.GetCronjobName())
+	if err != nil {
+		log.Error().Err(err).Str("operation", "GetCronjobRevisions").Msg("CronjobRevisions")
+		return nil, fmt.Errorf("sk8l#GetCronjobRevisions: %w", err)
+	}
+
+	response := &protos.CronjobRevisionsResponse{
+		Revisions: make([]*protos.CronjobRevisionResponse, 0, len(revisions)),
+	}
+	specs := make(map[int64]string, len(revisions))
+	redactor := s.redactor.For(ctx)
+	for _, revision := range revisions {
+		if err := redactor.Object(&revision.Spec); err != nil {
+			return nil, fmt.Errorf("sk8l#GetCronjobRevisions: %w", err)
+		}
+		spec, err := gyaml.Marshal(revision.Spec)
+		if err != nil {
+			return nil, fmt.Errorf("sk8l#GetCronjobRevisions: yaml.Marshal() failed: %w", err)
+		}
+		specs[revision.Generation] = string(spec)
+		response.Revisions = append(response.Revisions, &protos.CronjobRevisionResponse{
+			Generation: revision.Generation,
+			RecordedAt: revision.RecordedAt.UTC().Format(time.RFC3339),
+			Spec:       string(spec),
+		})
+	}
+
+	from, to := in.GetFromGeneration(), in.GetToGeneration()
+	if from == 0 && to == 0 {
+		from, to = latestGenerations(revisions)
+	}
+	if from == 0 && to == 0 {
+		return response, nil
+	}
+	for _, generation := range []int64{from, to} {
+		if _, ok := specs[generation]; !ok {
+			return nil, status.Errorf(codes.NotFound, "sk8l#GetCronjobRevisions: no revision of cronjob %s/%s at generation %d",
+				in.GetCronjobNamespace(), in.GetCronjobName(), generation)
+		}
+	}
+	response.FromGeneration = from
+	response.ToGeneration = to
+	response.Diff = diff.Unified(
+		fmt.Sprintf("%s generation %d", in.GetCronjobName(), from),
+		fmt.Sprintf("%s generation %d", in.GetCronjobName(), to),
+		specs[from],
+		specs[to],
+	)
+	return response, nil
+}
+
+// latestGenerations returns the generations of the two latest revisions, or
+// the only one twice.
+func latestGenerations(revisions []store.CronjobRevision) (int64, int64) {
+	switch n := len(revisions); n {
+	case 0:
+		return 0, 0
+	case 1:
+		return revisions[0].Generation, revisions[0].Generation
+	default:
+		return revisions[n-2].Generation, revisions[n-1].Generation
+	}
+}
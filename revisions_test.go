@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/danroux/sk8l/internal/k8s"
+	"github.com/danroux/sk8l/internal/store"
+	"github.com/danroux/sk8l/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetCronjobRevisions(t *testing.T) {
+	client := logsClient(t, k8s.NewClientWithInterface(fake.NewClientset(), k8s.WithNamespace("default")))
+	for generation, schedule := range []string{"* * * * *", "*/5 * * * *", "*/10 * * * *"} {
+		err := sk8lServer.PutCronjobRevision(store.CronjobRevision{
+			Namespace:   "default",
+			CronjobName: "cj-1",
+			Generation:  int64(generation + 1),
+			Spec:        batchv1.CronJobSpec{Schedule: schedule},
+		})
+		if err != nil {
+			t.Fatalf("PutCronjobRevision failed: %v", err)
+		}
+	}
+
+	response, err := client.GetCronjobRevisions(context.Background(), &protos.CronjobRevisionsRequest{
+		CronjobName:      "cj-1",
+		CronjobNamespace: "default",
+	})
+	if err != nil {
+		t.Fatalf("GetCronjobRevisions failed: %v", err)
+	}
+	if len(response.GetRevisions()) != 3 || response.GetFromGeneration() != 2 || response.GetToGeneration() != 3 {
+		t.Fatalf("expected 3 revisions diffing the latest two, got %+v", response)
+	}
+	if !strings.Contains(response.GetDiff(), "-schedule: '*/5 * * * *'\n+schedule: '*/10 * * * *'\n") {
+		t.Errorf("unexpected diff\n%s", response.GetDiff())
+	}
+
+	response, err = client.GetCronjobRevisions(context.Background(), &protos.CronjobRevisionsRequest{
+		CronjobName:      "cj-1",
+		CronjobNamespace: "default",
+		FromGeneration:   1,
+		ToGeneration:     3,
+	})
+	if err != nil {
+		t.Fatalf("GetCronjobRevisions failed: %v", err)
+	}
+	if !strings.Contains(response.GetDiff(), "-schedule: '* * * * *'\n+schedule: '*/10 * * * *'\n") {
+		t.Errorf("unexpected diff\n%s", response.GetDiff())
+	}
+
+	_, err = client.GetCronjobRevisions(context.Background(), &protos.CronjobRevisionsRequest{
+		CronjobName:      "cj-1",
+		CronjobNamespace: "default",
+		FromGeneration:   7,
+		ToGeneration:     3,
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for an unknown generation, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"slices"
+
+	"github.com/danroux/sk8l/protos"
+)
+
+// The optional features reported by GetServerInfo.
+const (
+	featureActions        = "actions"
+	featureAuditLog       = "audit-log"
+	featureAuthentication = "authentication"
+	featureAuthorization  = "authorization"
+	featureGRPCWeb        = "grpc-web"
+	featureHTTPGateway    = "http-gateway"
+	featureLeaderElection = "leader-election"
+	featureReadOnly       = "read-only"
+	featureRedaction      = "redaction"
+	featureReflection     = "reflection"
+)
+
+// GetServerInfo describes the server, so clients can negotiate what they use
+// instead of guessing from its version.
+func (s *Sk8lServer) GetServerInfo(_ context.Context, _ *protos.ServerInfoRequest) (*protos.ServerInfoResponse, error) {
+	return &protos.ServerInfoResponse{
+		Version:        Version(),
+		Commit:         Commit(),
+		Namespaces:     []string{s.K8sClient.Namespace()},
+		StorageBackend: s.Backend(),
+		Features:       s.features(),
+		Watchers:       s.health.watcherStatuses(),
+		Rpcs:           rpcs(),
+	}, nil
+}
+
+// features returns the optional features enabled, sorted.
+func (s *Sk8lServer) features() []string {
+	features := []string{featureReflection}
+	for feature, enabled := range map[string]bool{
+		featureActions:        s.actions,
+		featureAuditLog:       s.auditLog != nil,
+		featureAuthentication: s.authenticator.Enabled(),
+		featureAuthorization:  s.policy != nil,
+		featureGRPCWeb:        cfg.GRPCWeb.Enabled,
+		featureHTTPGateway:    cfg.Server.GatewayPort != 0,
+		featureLeaderElection: s.elector != nil,
+		featureReadOnly:       s.readOnly,
+		featureRedaction:      s.redactor != nil,
+	} {
+		if enabled {
+			features = append(features, feature)
+		}
+	}
+	slices.Sort(features)
+	return features
+}
+
+// rpcs returns the names of the RPCs of the Cronjob service, sorted.
+func rpcs() []string {
+	desc := protos.Cronjob_ServiceDesc
+	names := make([]string, 0, len(desc.Methods)+len(desc.Streams))
+	for _, method := range desc.Methods {
+		names = append(names, method.MethodName)
+	}
+	for _, stream := range desc.Streams {
+		names = append(names, stream.StreamName)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/danroux/sk8l/internal/store"
+	"github.com/danroux/sk8l/protos"
+	"google.golang.org/grpc/codes"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetServerInfo(t *testing.T) {
+	server, conn := authorizedConn(t)
+	client := protos.NewCronjobClient(conn)
+	server.health.watcherSynced(cronjobsWatcher)
+	server.health.watcherFailed(podsWatcher, errors.New("watch pods: connection refused"))
+
+	if _, err := client.GetServerInfo(context.Background(), &protos.ServerInfoRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without a token, got %v", err)
+	}
+
+	info, err := client.GetServerInfo(asCaller("jane"), &protos.ServerInfoRequest{})
+	if err != nil {
+		t.Fatalf("GetServerInfo failed: %v", err)
+	}
+	if info.GetVersion() != Version() || info.GetCommit() != Commit() || info.GetStorageBackend() != store.BackendMemory ||
+		!slices.Equal(info.GetNamespaces(), []string{"default"}) {
+		t.Errorf("unexpected server info %v", info)
+	}
+	expected := []string{featureAuthentication, featureAuthorization, featureRedaction, featureReflection}
+	if !slices.Equal(info.GetFeatures(), expected) {
+		t.Errorf("expected features %v, got %v", expected, info.GetFeatures())
+	}
+	if !slices.Contains(info.GetRpcs(), "GetServerInfo") || !slices.Contains(info.GetRpcs(), "GetCronjobs") {
+		t.Errorf("expected every RPC to be listed, got %v", info.GetRpcs())
+	}
+
+	watchers := info.GetWatchers()
+	if len(watchers) != 4 || watchers[0].GetName() != cronjobsWatcher || !watchers[0].GetSynced() {
+		t.Fatalf("unexpected watchers %v", watchers)
+	}
+	pods := watchers[3]
+	if pods.GetName() != podsWatcher || pods.GetSynced() || pods.GetFailingSince() == "" || pods.GetError() != "watch pods: connection refused" {
+		t.Errorf("unexpected pods watcher %v", pods)
+	}
+
+	if _, err := client.GetServerInfo(asCaller("joe"), &protos.ServerInfoRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected callers who can't read the server namespace to be denied, got %v", err)
+	}
+}
+
+func TestReflection(t *testing.T) {
+	_, conn := authorizedConn(t)
+	client := reflectionpb.NewServerReflectionClient(conn)
+
+	listServices := func(ctx context.Context) (*reflectionpb.ServerReflectionResponse, error) {
+		stream, err := client.ServerReflectionInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+		}); err != nil {
+			return nil, err
+		}
+		return stream.Recv()
+	}
+
+	if _, err := listServices(context.Background()); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without a token, got %v", err)
+	}
+	// joe may not read the server namespace, but the API is no secret.
+	resp, err := listServices(asCaller("joe"))
+	if err != nil {
+		t.Fatalf("ServerReflectionInfo failed: %v", err)
+	}
+	services := []string{}
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	if !slices.Contains(services, protos.Cronjob_ServiceDesc.ServiceName) {
+		t.Errorf("expected the Cronjob service to be listed, got %v", services)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"context"
 	"embed"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"slices"
 	"strings"
@@ -14,11 +13,19 @@ import (
 	"text/template"
 	"time"
 
+	"github.com/danroux/sk8l/internal/audit"
+	"github.com/danroux/sk8l/internal/auth"
 	"github.com/danroux/sk8l/internal/dashboard"
+	"github.com/danroux/sk8l/internal/failure"
+	"github.com/danroux/sk8l/internal/k8s"
+	"github.com/danroux/sk8l/internal/leader"
+	"github.com/danroux/sk8l/internal/logger"
 	"github.com/danroux/sk8l/internal/mapper"
+	"github.com/danroux/sk8l/internal/policy"
+	"github.com/danroux/sk8l/internal/redact"
+	"github.com/danroux/sk8l/internal/schedule"
 	"github.com/danroux/sk8l/internal/store"
 	"github.com/danroux/sk8l/protos"
-	badger "github.com/dgraph-io/badger/v4"
 	"google.golang.org/grpc/health/grpc_health_v1"
 	gyaml "sigs.k8s.io/yaml"
 
@@ -34,16 +41,35 @@ import (
 //go:embed annotations.tmpl
 var content embed.FS
 
-var refreshInterval = time.Second * store.RefreshSeconds
-
 type Sk8lServer struct {
 	grpc_health_v1.UnimplementedHealthServer
 	protos.UnimplementedCronjobServer
 	*store.CronJobDBStore
 	dashboardGen    *dashboard.Generator
 	metricsNamesMap *sync.Map
+	health          *healthTracker
+	streams         *streamTracker
 	target          string
 	dialOptions     []grpc.DialOption
+	// synced holds the synced channels of the reflectors, pending the
+	// reflectors watchCluster created that Run hasn't started yet.
+	synced  []<-chan struct{}
+	pending []*k8s.Reflector
+	syncMu  sync.Mutex
+	// redactor removes secrets from every response, nil disables redaction.
+	redactor *redact.Redactor
+	// authenticator authenticates the callers, nil lets every call through.
+	authenticator *auth.Authenticator
+	// policy authorizes the callers, nil allows everything.
+	policy *policy.Policy
+	// auditLog records the audited calls, nil disables auditing.
+	auditLog *audit.Log
+	// readOnly is set when serving an imported snapshot instead of a cluster.
+	readOnly bool
+	// actions lets callers trigger and suspend CronJobs.
+	actions bool
+	// elector elects the replica running the singleton duties, nil runs them on this one.
+	elector *leader.Elector
 }
 
 func NewSk8lServer(
@@ -53,13 +79,24 @@ func NewSk8lServer(
 	metricsNamesMap *sync.Map,
 	dialOptions ...grpc.DialOption,
 ) *Sk8lServer {
-	return &Sk8lServer{
+	s := &Sk8lServer{
 		target:          target,
 		CronJobDBStore:  cronJobDBStore,
 		dashboardGen:    dashboardGen,
 		metricsNamesMap: metricsNamesMap,
 		dialOptions:     dialOptions,
-	}
+		redactor:        redact.Default(),
+		streams:         newStreamTracker(),
+	}
+	s.health = newHealthTracker(
+		func() error { return s.Ping() },
+		cfg.Watchers.FailureThreshold.Duration,
+		cronjobsWatcher,
+		jobsWatcher,
+		podsWatcher,
+		eventsWatcher,
+	)
+	return s
 }
 
 func (s *Sk8lServer) GetTarget() string {
@@ -70,39 +107,28 @@ func (s *Sk8lServer) GetDialOptions() []grpc.DialOption {
 	return s.dialOptions
 }
 
-func (s Sk8lServer) Check(
-	ctx context.Context,
-	req *grpc_health_v1.HealthCheckRequest,
-) (*grpc_health_v1.HealthCheckResponse, error) {
-	log.Info().
-		Str("component", "probe").
-		Str("operation", "health").
-		Msg("serving health")
-	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
-}
-
-func (s Sk8lServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
-	response := &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}
-
-	if err := stream.Send(response); err != nil {
-		log.Error().
-			Err(err).
-			Str("operation", "Watch#stream.Send").
-			Send()
-		return fmt.Errorf("sk8l#Watch: stream.Send failed: %w", err)
+// Run starts watching the cluster, capturing the failure logs and the
+// singleton duties. The returned channel is closed once the duties have
+// released the leadership.
+func (s *Sk8lServer) Run(metricsCxt context.Context) <-chan struct{} {
+	s.health.run(metricsCxt, cfg.Watchers.HealthInterval.Duration)
+	if s.readOnly {
+		// A snapshot never changes, so there is nothing to watch and it is synced from the start.
+		for _, name := range []string{cronjobsWatcher, jobsWatcher, podsWatcher, eventsWatcher} {
+			s.health.watcherSynced(name)
+		}
+	} else {
+		s.startReflectors(metricsCxt)
+		s.captureFailureLogs(metricsCxt)
 	}
-
-	return nil
-}
-
-func (s *Sk8lServer) Run(metricsCxt context.Context) {
-	s.collectCronjobs(metricsCxt)
-	s.collectJobs(metricsCxt)
-	s.collectPods(metricsCxt)
-	recordMetrics(metricsCxt, s, s.metricsNamesMap)
+	return s.runSingletonDuties(metricsCxt)
 }
 
 func (s *Sk8lServer) GetCronjobs(in *protos.CronjobsRequest, stream protos.Cronjob_GetCronjobsServer) error {
+	if err := s.waitForSync(stream.Context()); err != nil {
+		return fmt.Errorf("sk8l#GetCronjobs: %w", err)
+	}
+
 	for {
 		ctx := stream.Context()
 		cronJobList, err := s.FindCronjobs()
@@ -126,7 +152,7 @@ func (s *Sk8lServer) GetCronjobs(in *protos.CronjobsRequest, stream protos.Cronj
 		for _, cronjobItem := range cronJobList.Items {
 			go func(cronjobItem batchv1.CronJob) {
 				defer wg.Done()
-				jobsForCronjob := s.jobsForCronjob(jobsMapped, cronjobItem.Name)
+				jobsForCronjob := s.jobsForCronjob(jobsMapped, cronjobItem.Namespace, cronjobItem.Name)
 				cronjob := s.cronJobResponse(cronjobItem, jobsForCronjob)
 				mu.Lock()
 				cronjobs = append(cronjobs, cronjob)
@@ -156,12 +182,16 @@ func (s *Sk8lServer) GetCronjobs(in *protos.CronjobsRequest, stream protos.Cronj
 			if err := stream.Send(y); err != nil {
 				return fmt.Errorf("sk8l#GetCronjobs: stream.Send() failed: %w", err)
 			}
-			time.Sleep(refreshInterval)
+			time.Sleep(cfg.Server.RefreshInterval.Duration)
 		}
 	}
 }
 
 func (s *Sk8lServer) GetCronjob(in *protos.CronjobRequest, stream protos.Cronjob_GetCronjobServer) error {
+	if err := s.waitForSync(stream.Context()); err != nil {
+		return fmt.Errorf("sk8l#GetCronjob: %w", err)
+	}
+
 	for {
 		ctx := stream.Context()
 		cronjob, err := s.FindCronjob(ctx, in.CronjobNamespace, in.CronjobName)
@@ -170,23 +200,26 @@ func (s *Sk8lServer) GetCronjob(in *protos.CronjobRequest, stream protos.Cronjob
 			return fmt.Errorf("sk8l#GetCronjob: FindCronjob() failed: %w", err)
 		}
 
-		jobsMapped, err := s.FindJobsMapped(ctx)
+		jobsForCronjob, err := s.FindJobsForCronjob(cronjob.Namespace, cronjob.Name)
 		if err != nil {
-			log.Error().Err(err).Str("operation", "GetCronjob").Msg("FindJobsMapped")
-			return fmt.Errorf("sk8l#GetCronjob: FindJobsMapped() failed: %w", err)
+			log.Error().Err(err).Str("operation", "GetCronjob").Msg("FindJobsForCronjob")
+			return fmt.Errorf("sk8l#GetCronjob: FindJobsForCronjob() failed: %w", err)
 		}
 
-		jobsForCronjob := s.jobsForCronjob(jobsMapped, cronjob.Name)
 		cronJobResponse := s.cronJobResponse(*cronjob, jobsForCronjob)
 		if err := stream.Send(cronJobResponse); err != nil {
 			return fmt.Errorf("sk8l#GetCronjob: stream.Send() failed: %w", err)
 		}
 
-		time.Sleep(refreshInterval)
+		time.Sleep(cfg.Server.RefreshInterval.Duration)
 	}
 }
 
 func (s *Sk8lServer) GetCronjobPods(in *protos.CronjobPodsRequest, stream protos.Cronjob_GetCronjobPodsServer) error {
+	if err := s.waitForSync(stream.Context()); err != nil {
+		return fmt.Errorf("sk8l#GetCronjobPods: %w", err)
+	}
+
 	for {
 		ctx := stream.Context()
 		cronjob, err := s.FindCronjob(ctx, in.CronjobNamespace, in.CronjobName)
@@ -194,13 +227,11 @@ func (s *Sk8lServer) GetCronjobPods(in *protos.CronjobPodsRequest, stream protos
 			log.Error().Err(err).Str("operation", "GetCronjobPods").Msg("FindCronjob")
 		}
 
-		jobsMapped, err := s.FindJobsMapped(ctx)
+		jobs, err := s.FindJobsForCronjob(cronjob.Namespace, cronjob.Name)
 		if err != nil {
-			log.Error().Err(err).Str("operation", "GetCronjobPods").Msg("FindJobsMapped")
+			log.Error().Err(err).Str("operation", "GetCronjobPods").Msg("FindJobsForCronjob")
 		}
 
-		jobs := s.jobsForCronjob(jobsMapped, cronjob.Name)
-
 		cronjobResponse := s.cronJobResponse(*cronjob, jobs)
 		lightweightCronjobPodsResponse := &protos.CronjobResponse{
 			Name:      cronjob.Name,
@@ -222,11 +253,15 @@ func (s *Sk8lServer) GetCronjobPods(in *protos.CronjobPodsRequest, stream protos
 			return fmt.Errorf("sk8l#GetCronjobPods: stream.Send() failed: %w", err)
 		}
 
-		time.Sleep(refreshInterval)
+		time.Sleep(cfg.Server.RefreshInterval.Duration)
 	}
 }
 
 func (s *Sk8lServer) GetJobs(in *protos.JobsRequest, stream protos.Cronjob_GetJobsServer) error {
+	if err := s.waitForSync(stream.Context()); err != nil {
+		return fmt.Errorf("sk8l#GetJobs: %w", err)
+	}
+
 	for {
 		jobList, err := s.FindJobs()
 		if err != nil {
@@ -256,7 +291,7 @@ func (s *Sk8lServer) GetJobs(in *protos.JobsRequest, stream protos.Cronjob_GetJo
 			return fmt.Errorf("sk8l#GetJobs: stream.Send() failed: %w", err)
 		}
 
-		time.Sleep(refreshInterval)
+		time.Sleep(cfg.Server.RefreshInterval.Duration)
 	}
 }
 
@@ -268,14 +303,14 @@ func (s *Sk8lServer) GetCronjobYAML(
 	if err != nil {
 		return nil, fmt.Errorf("sk8l#GetCronjobYAML: %w", err)
 	}
-	prettyJSON, err := json.MarshalIndent(cronjob, "", "  ")
+	prettyJSON, err := s.redactedJSON(ctx, cronjob)
 
 	if err != nil {
 		log.Error().
 			Err(err).
 			Str("operation", "sk8l#GetCronjobYAML").
-			Msg("json.MarshalIndent() failed")
-		return nil, fmt.Errorf("sk8l#GetCronjobYAML: json.MarshalIndent failed: %w", err)
+			Msg("redactedJSON() failed")
+		return nil, fmt.Errorf("sk8l#GetCronjobYAML: %w", err)
 	}
 
 	y, _ := gyaml.JSONToYAML(prettyJSON)
@@ -292,14 +327,14 @@ func (s *Sk8lServer) GetJobYAML(ctx context.Context, in *protos.JobRequest) (*pr
 	if err != nil {
 		return nil, fmt.Errorf("sk8l#GetJobYAML: %w", err)
 	}
-	prettyJSON, err := json.MarshalIndent(job, "", "  ")
+	prettyJSON, err := s.redactedJSON(ctx, job)
 
 	if err != nil {
 		log.Error().
 			Err(err).
 			Str("operation", "sk8l#GetJobYAML").
-			Msg("json.MarshalIndent() failed")
-		return nil, fmt.Errorf("sk8l#GetJobYAML: json.MarshalIndent failed: %w", err)
+			Msg("redactedJSON() failed")
+		return nil, fmt.Errorf("sk8l#GetJobYAML: %w", err)
 	}
 
 	y, _ := gyaml.JSONToYAML(prettyJSON)
@@ -316,14 +351,14 @@ func (s *Sk8lServer) GetPodYAML(ctx context.Context, in *protos.PodRequest) (*pr
 	if err != nil {
 		return nil, fmt.Errorf("sk8l#GetPodYAML: %w", err)
 	}
-	prettyJSON, err := json.MarshalIndent(pod, "", "  ")
+	prettyJSON, err := s.redactedJSON(ctx, pod)
 
 	if err != nil {
 		log.Error().
 			Err(err).
 			Str("operation", "sk8l#GetPodYAML").
-			Msg("json.MarshalIndent() failed")
-		return nil, fmt.Errorf("sk8l#GetPodYAML: json.MarshalIndent failed: %w", err)
+			Msg("redactedJSON() failed")
+		return nil, fmt.Errorf("sk8l#GetPodYAML: %w", err)
 	}
 
 	y, _ := gyaml.JSONToYAML(prettyJSON)
@@ -423,6 +458,10 @@ func (s *Sk8lServer) buildJobResponse(batchJob *batchv1.Job) *protos.JobResponse
 		terminationReasons = append(terminationReasons, podResponse.TerminationReasons...)
 	}
 	jobWithSidecar := s.jobWithSidecarContainer(batchJob)
+	var classification failure.Classification
+	if jobFailed {
+		classification = failure.Classify(batchJob, jobPodsForJob.Items)
+	}
 
 	startTimeInS := int64(0)
 	if batchJob.Status.StartTime != nil {
@@ -452,400 +491,75 @@ func (s *Sk8lServer) buildJobResponse(batchJob *batchv1.Job) *protos.JobResponse
 		Pods:                  jobPodsResponses,
 		TerminationReasons:    terminationReasons,
 		WithSidecarContainers: jobWithSidecar,
+		FailureCategory:       classification.Category,
+		FailureHint:           classification.Hint,
+		Events:                s.jobEvents(batchJob, jobPodsForJob.Items),
 	}
 	return jobResponse
 }
 
-func (s *Sk8lServer) collectCronjobs(ctx context.Context) {
-	go func() {
-		for {
-			x, err := s.K8sClient.WatchCronjobs(ctx)
-			if err != nil {
-				log.Error().
-					Err(err).
-					Str("operation", "collectCronjobs").
-					Msg("WatchCronjobs failed, retrying in 5s")
-				select {
-				case <-ctx.Done():
-					log.Info().
-						Str("operation", "collectCronjobs").
-						Msg("context canceled, stopping watcher")
-					return
-				case <-time.After(5 * time.Second):
-					continue
-				}
-			}
-
-			running := true
-			for running {
-				select {
-				case <-ctx.Done():
-					x.Stop()
-					log.Info().
-						Str("operation", "collectCronjobs").
-						Msg("context canceled, stopping watcher")
-					return
-				case event, more := <-x.ResultChan():
-					if !more {
-						log.Error().
-							Str("operation", "collectCronjobs").
-							Msg("WatchCronjobs: Received all Cronjobs. Opening again")
-						running = false
-						break
-					}
-
-					eventCronjob, ok := event.Object.(*batchv1.CronJob)
-					if !ok {
-						log.Error().
-							Str("operation", "collectCronjobs").
-							Msg("event.Object.(*batchv1.CronJob) type assertion failed")
-						continue
-					}
-					err := s.DB.Update(func(txn *badger.Txn) error {
-						return handleCronJobEvent(txn, event, eventCronjob)
-					})
-					if err != nil {
-						log.Error().
-							Err(err).
-							Str("operation", "collectCronjobs").
-							Msg("handleCronJobEvent failed")
-					}
-				}
-			}
-		}
-	}()
-}
-
-func (s *Sk8lServer) collectJobs(ctx context.Context) {
-	go func() {
-		for {
-			x, err := s.K8sClient.WatchJobs(ctx)
-			if err != nil {
-				log.Error().
-					Err(err).
-					Str("operation", "collectJobs").
-					Msg("WatchJobs failed, retrying in 5s")
-				select {
-				case <-ctx.Done():
-					log.Info().
-						Str("operation", "collectJobs").
-						Msg("context canceled, stopping watcher")
-					return
-				case <-time.After(5 * time.Second):
-					continue
-				}
-			}
-
-			running := true
-			for running {
-				select {
-				case <-ctx.Done():
-					x.Stop()
-					log.Info().
-						Str("operation", "collectJobs").
-						Msg("context canceled, stopping watcher")
-					return
-				case event, more := <-x.ResultChan():
-					if !more {
-						log.Error().
-							Str("operation", "collectJobs").
-							Msg("WatchJobs: Received all Jobs. Opening again")
-						running = false
-						break
-					}
-
-					eventJob, ok := event.Object.(*batchv1.Job)
-					if !ok {
-						log.Error().
-							Str("operation", "collectJobs").
-							Msg("event.Object.(*batchv1.Job) type assertion failed")
-						continue
-					}
-					err := s.DB.Update(func(txn *badger.Txn) error {
-						return handleJobEvent(txn, event, eventJob)
-					})
-					if err != nil {
-						log.Error().
-							Err(err).
-							Str("operation", "collectJobs").
-							Msg("handleJobEvent failed")
-					}
-				}
-			}
-		}
-	}()
-}
-
-func (s *Sk8lServer) collectPods(ctx context.Context) {
-	go func() {
-		for {
-			x, err := s.K8sClient.WatchPods(ctx)
-			if err != nil {
-				log.Error().
-					Err(err).
-					Str("operation", "collectPods").
-					Msg("WatchPods failed, retrying in 5s")
-				select {
-				case <-ctx.Done():
-					log.Info().
-						Str("operation", "collectPods").
-						Msg("context canceled, stopping watcher")
-					return
-				case <-time.After(5 * time.Second):
-					continue
-				}
-			}
-
-			running := true
-			for running {
-				select {
-				case <-ctx.Done():
-					x.Stop()
-					log.Info().
-						Str("operation", "collectPods").
-						Msg("context canceled, stopping watcher")
-					return
-				case event, more := <-x.ResultChan():
-					if !more {
-						log.Error().
-							Str("operation", "collectPods").
-							Msg("WatchPods: Received all Pods. Opening again")
-						running = false
-						break
-					}
-
-					eventPod, ok := event.Object.(*corev1.Pod)
-					if !ok {
-						log.Error().
-							Str("operation", "collectPods").
-							Msg("event.Object.(*corev1.Pod) type assertion failed")
-						continue
-					}
-					err := s.DB.Update(func(txn *badger.Txn) error {
-						return handlePodEvent(txn, event, eventPod)
-					})
-					if err != nil {
-						log.Error().
-							Err(err).
-							Str("operation", "collectPods").
-							Msg("handlePodEvent failed")
-					}
-				}
-			}
-		}
-	}()
-}
-
-func handleCronJobEvent(txn *badger.Txn, event watch.Event, eventCronJob *batchv1.CronJob) error {
-	item, err := txn.Get(store.CronjobsCacheKey)
-	if errors.Is(err, badger.ErrKeyNotFound) {
-		cronJob := *eventCronJob
-		log.Error().
-			Err(badger.ErrKeyNotFound).
-			Str("operation", "handleCronJobEvent").
-			Msg(fmt.Sprintf("storing eventCronJob %s", cronJob.Name))
-		cjList := &batchv1.CronJobList{
-			Items: []batchv1.CronJob{cronJob},
-		}
-		var buf bytes.Buffer
-		if err := store.K8sSerialize(cjList, &buf); err != nil {
-			log.Error().
-				Err(err).
-				Str("operation", "handleCronJobEvent").
-				Msg("k8sSerializer.Encode")
-			return fmt.Errorf("%s: Encode() failed: %w", "sk8l#collectCronjobs", err)
-		}
-		return storeEntry(txn, store.CronjobsCacheKey, buf.Bytes(), "sk8l#collectCronjobs")
-	}
-
-	err = item.Value(func(stored []byte) error {
-		return updateStoredCronjobList(txn, stored, event, eventCronJob)
-	})
-	if err != nil {
-		return fmt.Errorf("sk8l#collectCronjobs: item.Value() failed: %w", err)
-	}
-	return nil
-}
-
-func handleJobEvent(txn *badger.Txn, event watch.Event, eventJob *batchv1.Job) error {
-	item, err := txn.Get(store.JobsCacheKey)
-	if err != nil {
-		jList := &batchv1.JobList{
-			Items: []batchv1.Job{*eventJob},
-		}
-		var buf bytes.Buffer
-		if err := store.K8sSerialize(jList, &buf); err != nil {
-			log.Error().
-				Err(err).
-				Str("operation", "handleJobEvent").
-				Msg("k8sSerializer.Encode")
-			return fmt.Errorf("%s: Encode() failed: %w", "sk8l#collectJobs", err)
-		}
-		return storeEntry(txn, store.JobsCacheKey, buf.Bytes(), "sk8l#collectJobs")
-	}
-	err = item.Value(func(stored []byte) error {
-		return updateStoredJobList(txn, stored, event, eventJob)
-	})
-	if err != nil {
-		return fmt.Errorf("sk8l#collectJobs: item.Value() failed: %w", err)
-	}
-	return nil
-}
-
-func handlePodEvent(txn *badger.Txn, event watch.Event, eventPod *corev1.Pod) error {
-	fKey := fmt.Sprintf(store.JobPodsKeyFmt, eventPod.Labels["job-name"])
-	key := []byte(fKey)
-	item, err := txn.Get(key)
-	if err != nil {
-		podList := &corev1.PodList{
-			Items: []corev1.Pod{*eventPod},
-		}
-		var buf bytes.Buffer
-		if err = store.K8sSerialize(podList, &buf); err != nil {
-			log.Error().
-				Err(err).
-				Str("operation", "handlePodEvent").
-				Msg("k8sSerializer.Encode")
-			return fmt.Errorf("%s: Encode() failed: %w", "sk8l#collectPods", err)
-		}
-		return storeEntry(txn, key, buf.Bytes(), "sk8l#collectPods")
-	}
-
-	err = item.Value(func(val []byte) error {
-		return updateStoredPodList(txn, val, key, eventPod)
-	})
-	if err != nil {
-		return fmt.Errorf("sk8l#collectPods: item.Value() failed: %w", err)
-	}
-	return nil
-}
-
-func updateStoredCronjobList(txn *badger.Txn, stored []byte, event watch.Event, eventCronJob *batchv1.CronJob) error {
-	log.Info().
-		Str("operation", "updateStoredCronjobList").
-		Msg(fmt.Sprintf("Updating with %s", eventCronJob.Name))
-	storedCjList := &batchv1.CronJobList{}
-	if _, _, err := store.K8sDeserialize(stored, storedCjList); err != nil {
-		log.Error().
-			Err(err).
-			Str("operation", "updateStoredCronjobList").
-			Msg("k8sSerializer.Decode")
-		return fmt.Errorf("sk8l#collectCronjobs: Decode() failed: %w", err)
-	}
-
-	//revive:disable:identical-switch-branches
-	switch event.Type {
-	case watch.Added, watch.Modified:
-		filterCronJobsList(storedCjList, eventCronJob)
-		storedCjList.Items = append(storedCjList.Items, *eventCronJob)
-	case watch.Deleted:
-		filterCronJobsList(storedCjList, eventCronJob)
-	case watch.Bookmark, watch.Error:
-		// no-op: explicitly ignored
-	default:
-		// default case to satisfy revive
-	}
-	//revive:enable:identical-switch-branches
-
-	var buf bytes.Buffer
-	if err := store.K8sSerialize(storedCjList, &buf); err != nil {
-		log.Error().
-			Err(err).
-			Str("operation", "updateStoredCronjobList").
-			Msg("k8sSerializer.Encode")
-		return fmt.Errorf("sk8l#collectCronjobs: Encode() failed: %w", err)
-	}
-	return storeEntry(txn, store.CronjobsCacheKey, buf.Bytes(), "sk8l#collectJobs")
-}
-
-func updateStoredJobList(txn *badger.Txn, stored []byte, event watch.Event, eventJob *batchv1.Job) error {
-	storedJList := &batchv1.JobList{}
-	if _, _, err := store.K8sDeserialize(stored, storedJList); err != nil {
-		log.Error().
-			Err(err).
-			Str("operation", "updateStoredJobList").
-			Msg("k8sSerializer.Decode")
-		return fmt.Errorf("sk8l#collectJobs: Decode() failed: %w", err)
-	}
-
-	//revive:disable:identical-switch-branches
-	switch event.Type {
-	case watch.Added, watch.Modified:
-		filterStoredJobList(storedJList, eventJob)
-		storedJList.Items = append(storedJList.Items, *eventJob)
-	case watch.Deleted:
-		filterStoredJobList(storedJList, eventJob)
-	case watch.Bookmark, watch.Error:
-		// no-op: explicitly ignored
-	default:
-		// default case to satisfy revive
-	}
-	//revive:enable:identical-switch-branches
-
-	var buf bytes.Buffer
-	if err := store.K8sSerialize(storedJList, &buf); err != nil {
-		log.Error().
-			Err(err).
-			Str("operation", "updateStoredJobList").
-			Msg("k8sSerializer.Encode")
-		return fmt.Errorf("sk8l#collectJobs: Encode() failed: %w", err)
-	}
-	return storeEntry(txn, store.JobsCacheKey, buf.Bytes(), "sk8l#collectJobs")
-}
-
-func updateStoredPodList(txn *badger.Txn, stored []byte, key []byte, eventPod *corev1.Pod) error {
-	podList := &corev1.PodList{}
-	if _, _, err := store.K8sDeserialize(stored, podList); err != nil {
-		log.Error().
-			Err(err).
-			Str("operation", "updateStoredPodList").
-			Msg("k8sSerializer.Decode")
-		return fmt.Errorf("operation#k8sSerializer.Decode: %w", err)
-	}
-	podList.Items = append(podList.Items, *eventPod)
-	var buf bytes.Buffer
-	if err := store.K8sSerialize(podList, &buf); err != nil {
-		log.Error().
-			Err(err).
-			Str("operation", "updateStoredPodList").
-			Msg("k8sSerializer.Encode")
-		return fmt.Errorf("operation#k8sSerializer.Encode: %w", err)
-	}
-	return storeEntry(txn, key, buf.Bytes(), "sk8l#collectPods")
+// watchCluster creates the reflectors of the watchers, which Run starts. It is
+// called before the servers start so that the streams opened before Run hold
+// their first message until the reflectors have synced.
+func (s *Sk8lServer) watchCluster() {
+	if s.readOnly {
+		return
+	}
+	s.addReflector(cronjobsWatcher, k8s.NewCronjobsListerWatcher(s.K8sClient), s.CronjobsHandler())
+	s.addReflector(jobsWatcher, k8s.NewJobsListerWatcher(s.K8sClient), s.JobsHandler())
+	s.addReflector(podsWatcher, k8s.NewPodsListerWatcher(s.K8sClient), s.PodsHandler())
+	s.addReflector(eventsWatcher, k8s.NewEventsListerWatcher(s.K8sClient), s.EventsHandler())
+}
+
+// addReflector creates a list-then-watch reflector feeding handler and registers
+// it so streams hold their first message until it has synced.
+func (s *Sk8lServer) addReflector(name string, lw k8s.ListerWatcher, handler k8s.EventHandler) {
+	// The time of the last watch event is part of /debug/state.
+	tracked := k8s.EventHandlerFuncs{
+		ReplaceFunc: handler.Replace,
+		HandleFunc: func(event watch.Event) error {
+			s.health.watcherEvent(name)
+			return handler.Handle(event)
+		},
+	}
+	r := k8s.NewReflector(name, lw, tracked,
+		k8s.WithReflectorLogger(logger.For(logger.Watcher)),
+		k8s.WithRetryDelay(cfg.Watchers.RetryDelay.Duration),
+		k8s.WithWatchTimeout(cfg.Watchers.WatchTimeout.Duration),
+		k8s.WithHealthHooks(
+			func() { s.health.watcherSynced(name) },
+			func(err error) { s.health.watcherFailed(name, err) },
+		),
+	)
+	s.syncMu.Lock()
+	s.synced = append(s.synced, r.Synced())
+	s.pending = append(s.pending, r)
+	s.syncMu.Unlock()
 }
 
-func filterCronJobsList(storedCjList *batchv1.CronJobList, eventCronJob *batchv1.CronJob) {
-	log.Info().
-		Str("operation", "filterCronJobsList").
-		Msg(fmt.Sprintf("filtering out %s", eventCronJob.Name))
-	cjList := &batchv1.CronJobList{}
-	for _, cronjob := range storedCjList.Items {
-		if cronjob.Name != eventCronJob.Name {
-			cjList.Items = append(cjList.Items, cronjob)
-		}
+// startReflectors runs the reflectors added since the last call until ctx is canceled.
+func (s *Sk8lServer) startReflectors(ctx context.Context) {
+	s.syncMu.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.syncMu.Unlock()
+	for _, r := range pending {
+		go r.Run(ctx)
 	}
-	storedCjList.Items = cjList.Items
 }
 
-func filterStoredJobList(storedJList *batchv1.JobList, eventJob *batchv1.Job) {
-	jList := &batchv1.JobList{}
-	for _, job := range storedJList.Items {
-		if job.Name != eventJob.Name {
-			jList.Items = append(jList.Items, job)
+// waitForSync blocks until every started reflector has completed its initial list.
+func (s *Sk8lServer) waitForSync(ctx context.Context) error {
+	s.syncMu.Lock()
+	synced := slices.Clone(s.synced)
+	s.syncMu.Unlock()
+	for _, ch := range synced {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return fmt.Errorf("sk8l#waitForSync: %w", ctx.Err())
 		}
 	}
-	storedJList.Items = jList.Items
-}
-
-func storeEntry(txn *badger.Txn, key []byte, result []byte, errContext string) error {
-	log.Info().
-		Str("operation", "storeEntry").
-		Msg(errContext)
-	entry := badger.NewEntry(key, result)
-	if err := txn.SetEntry(entry); err != nil {
-		log.Printf("Error: %s#txn.SetEntry: %v", errContext, err)
-		return fmt.Errorf("%s: txn.SetEntry() failed: %w", errContext, err)
-	}
 	return nil
 }
 
@@ -905,8 +619,8 @@ func (s *Sk8lServer) allAndRunningJobsAnPods(
 	return allJobsForCronJob, allJobPodsForCronjob, runningJobs, runningPods
 }
 
-func (s *Sk8lServer) jobsForCronjob(jobsMapped map[string][]*batchv1.Job, cronjobName string) []*batchv1.Job {
-	if jobs, ok := jobsMapped[cronjobName]; ok {
+func (s *Sk8lServer) jobsForCronjob(jobsMapped map[string][]*batchv1.Job, namespace, cronjobName string) []*batchv1.Job {
+	if jobs, ok := jobsMapped[store.OwnerKey(namespace, cronjobName)]; ok {
 		return jobs
 	}
 	return []*batchv1.Job{}
@@ -922,6 +636,7 @@ func (s *Sk8lServer) cronJobResponse(cronJob batchv1.CronJob, jobsForCronjob []*
 	currentDuration := getCurrentDuration(runningJobs)
 	commands := buildCronJobCommand(cronJob)
 	lastSuccessfulTime, lastScheduleTime := buildLastTimes(cronJob)
+	spec := mapper.MapCronJobSpec(cronJob.Spec)
 
 	var cjFailed bool
 	for _, job := range allJobsForCronJob {
@@ -947,8 +662,10 @@ func (s *Sk8lServer) cronJobResponse(cronJob batchv1.CronJob, jobsForCronjob []*
 		RunningJobs:        runningJobs,
 		RunningJobsPods:    runningJobPods,
 		JobsPods:           jobPodsForCronJob,
-		Spec:               mapper.MapCronJobSpec(cronJob.Spec),
+		Spec:               spec,
 		Failed:             cjFailed,
+		Events:             s.cronjobEvents(&cronJob),
+		NextScheduleTime:   buildNextScheduleTime(cronJob.Name, spec, time.Now()),
 	}
 }
 
@@ -1113,6 +830,24 @@ func buildLastTimes(cronJob batchv1.CronJob) (lastSuccessfulTime string, lastSch
 	return lastSuccessfulTime, lastScheduleTime
 }
 
+// buildNextScheduleTime returns the next run of spec after now, or an empty
+// string when it's suspended or its schedule can't be parsed.
+func buildNextScheduleTime(name string, spec *protos.CronJobSpecResponse, now time.Time) string {
+	if spec.GetSuspend() {
+		return ""
+	}
+	s, err := schedule.Parse(spec.GetSchedule(), spec.GetTimezone())
+	if err != nil {
+		log.Debug().Err(err).Str("operation", "buildNextScheduleTime").Str("cronjob", name).Msg("schedule.Parse")
+		return ""
+	}
+	next := s.Next(now)
+	if next.IsZero() {
+		return ""
+	}
+	return next.UTC().Format(time.RFC3339)
+}
+
 func buildCronJobCommand(cronJob batchv1.CronJob) map[string]*protos.ContainerCommands {
 	commands := make(map[string]*protos.ContainerCommands)
 	n := len(cronJob.Spec.JobTemplate.Spec.Template.Spec.InitContainers)
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -39,7 +38,7 @@ const bufSize = 1 << 20
 
 var (
 	lis        = &bufconn.Listener{}
-	sk8lServer = &Sk8lServer{}
+	sk8lServer = NewSk8lServer("bufnet", nil, nil, nil)
 )
 
 func setupBadger(t *testing.T) *badger.DB {
@@ -52,16 +51,11 @@ func setupBadger(t *testing.T) *badger.DB {
 	return db
 }
 
-func putCronjobsToBadger(t *testing.T, db *badger.DB, cronjobList *batchv1.CronJobList) {
-	var buf bytes.Buffer
-	if err := store.K8sSerialize(cronjobList, &buf); err != nil {
-		t.Fatalf("failed to encode cronjob list: %v", err)
-	}
-	err := db.Update(func(txn *badger.Txn) error {
-		return txn.Set(store.CronjobsCacheKey, buf.Bytes())
-	})
-	if err != nil {
-		t.Fatalf("failed to write cronjobs to badger: %v", err)
+func putCronjobs(t *testing.T, storage store.Storage, cronjobList *batchv1.CronJobList) {
+	for i := range cronjobList.Items {
+		if err := storage.PutCronjob(&cronjobList.Items[i]); err != nil {
+			t.Fatalf("failed to store cronjob: %v", err)
+		}
 	}
 }
 
@@ -74,8 +68,9 @@ func bufDialer(context.Context, string) (net.Conn, error) {
 }
 
 func TestMain(m *testing.M) {
+	registerMetrics(cfg.Namespace)
 	lis = bufconn.Listen(bufSize)
-	s := grpc.NewServer()
+	s := grpc.NewServer(sk8lServer.ServerOptions()...)
 
 	protos.RegisterCronjobServer(s, sk8lServer)
 
@@ -130,11 +125,11 @@ func TestGetCronjobYAML(t *testing.T) {
 
 	k8sClient := k8s.NewClientWithInterface(clientSet)
 	st := &store.CronJobDBStore{
-		DB:        db,
+		Storage:   store.NewBadgerStorage(db),
 		K8sClient: k8sClient,
 	}
 	sk8lServer.CronJobDBStore = st
-	putCronjobsToBadger(t, sk8lServer.DB, cronjobList)
+	putCronjobs(t, sk8lServer.Storage, cronjobList)
 
 	yamlResp, err := client.GetCronjobYAML(ctx, &protos.CronjobRequest{CronjobName: cronjob1.Name, CronjobNamespace: cronjob1.Namespace})
 	if err != nil {
@@ -203,7 +198,7 @@ func TestGetCronjobYAML_NotFound(t *testing.T) {
 	clientSet := fake.NewClientset()
 	k8sClient := k8s.NewClientWithInterface(clientSet)
 	st := &store.CronJobDBStore{
-		DB:        db,
+		Storage:   store.NewBadgerStorage(db),
 		K8sClient: k8sClient,
 	}
 	sk8lServer.CronJobDBStore = st
@@ -243,7 +238,7 @@ func TestGetJobYAML(t *testing.T) {
 	clientSet := fake.NewClientset(job)
 	k8sClient := k8s.NewClientWithInterface(clientSet)
 	st := &store.CronJobDBStore{
-		DB:        db,
+		Storage:   store.NewBadgerStorage(db),
 		K8sClient: k8sClient,
 	}
 	sk8lServer.CronJobDBStore = st
@@ -299,7 +294,7 @@ func TestGetPodYAML(t *testing.T) {
 	clientSet := fake.NewClientset(pod)
 	k8sClient := k8s.NewClientWithInterface(clientSet)
 	st := &store.CronJobDBStore{
-		DB:        db,
+		Storage:   store.NewBadgerStorage(db),
 		K8sClient: k8sClient,
 	}
 	sk8lServer.CronJobDBStore = st
@@ -366,11 +361,11 @@ func TestGetCronjosbDB(t *testing.T) {
 	clientSet := fake.NewClientset()
 	k8sClient := k8s.NewClientWithInterface(clientSet)
 	st := &store.CronJobDBStore{
-		DB:        db,
+		Storage:   store.NewBadgerStorage(db),
 		K8sClient: k8sClient,
 	}
 	sk8lServer.CronJobDBStore = st
-	putCronjobsToBadger(t, sk8lServer.DB, cronjobList)
+	putCronjobs(t, sk8lServer.Storage, cronjobList)
 
 	stream, err := client.GetCronjobs(ctx, &protos.CronjobsRequest{})
 	if err != nil {
@@ -486,12 +481,13 @@ func TestGetCronjobsService(t *testing.T) {
 
 	k8sClient := k8s.NewClientWithInterface(clientSet, k8s.WithNamespace("default"))
 	st := &store.CronJobDBStore{
-		DB:        db,
+		Storage:   store.NewBadgerStorage(db),
 		K8sClient: k8sClient,
 	}
 
 	sk8lServer.CronJobDBStore = st
-	sk8lServer.collectCronjobs(context.Background())
+	sk8lServer.addReflector(cronjobsWatcher, k8s.NewCronjobsListerWatcher(k8sClient), sk8lServer.CronjobsHandler())
+	sk8lServer.startReflectors(context.Background())
 
 	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -633,11 +629,11 @@ func TestGetCronjobs_NoConcurrentRace(t *testing.T) {
 	clientSet := fake.NewClientset()
 	k8sClient := k8s.NewClientWithInterface(clientSet, k8s.WithNamespace("default"))
 	st := &store.CronJobDBStore{
-		DB:        db,
+		Storage:   store.NewBadgerStorage(db),
 		K8sClient: k8sClient,
 	}
 	sk8lServer.CronJobDBStore = st
-	putCronjobsToBadger(t, db, cronjobList)
+	putCronjobs(t, st.Storage, cronjobList)
 
 	jobsMapped := map[string][]*batchv1.Job{}
 
@@ -649,7 +645,7 @@ func TestGetCronjobs_NoConcurrentRace(t *testing.T) {
 	for _, item := range cronjobList.Items {
 		go func(cj batchv1.CronJob) {
 			defer wg.Done()
-			jobs := sk8lServer.jobsForCronjob(jobsMapped, cj.Name)
+			jobs := sk8lServer.jobsForCronjob(jobsMapped, cj.Namespace, cj.Name)
 			resp := sk8lServer.cronJobResponse(cj, jobs)
 			mu.Lock()
 			cronjobs = append(cronjobs, resp)
@@ -672,7 +668,7 @@ func TestAllAndRunningJobsAnPods_NoConcurrentRace(t *testing.T) {
 	clientSet := fake.NewClientset()
 	k8sClient := k8s.NewClientWithInterface(clientSet, k8s.WithNamespace("default"))
 	st := &store.CronJobDBStore{
-		DB:        db,
+		Storage:   store.NewBadgerStorage(db),
 		K8sClient: k8sClient,
 	}
 	sk8lServer.CronJobDBStore = st
@@ -696,3 +692,111 @@ func TestAllAndRunningJobsAnPods_NoConcurrentRace(t *testing.T) {
 		t.Error("expected non-nil slice results from allAndRunningJobsAnPods")
 	}
 }
+
+func TestCollectPods_RelistReplacesStaleEntries(t *testing.T) {
+	db := setupBadger(t)
+	defer db.Close()
+
+	startTime := metav1.Now()
+	stalePod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: "stale-pod", Namespace: "default", Labels: map[string]string{"job-name": "stale-job"},
+		OwnerReferences: []metav1.OwnerReference{{Name: "stale-job"}},
+	}, Status: corev1.PodStatus{StartTime: &startTime}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: "job-one-pod", Namespace: "default", Labels: map[string]string{"job-name": "job-one"},
+		OwnerReferences: []metav1.OwnerReference{{Name: "job-one"}},
+	}, Status: corev1.PodStatus{StartTime: &startTime}}
+
+	clientSet := fake.NewClientset(pod)
+	k8sClient := k8s.NewClientWithInterface(clientSet, k8s.WithNamespace("default"))
+	cronJobDBStore, err := store.NewCronJobDBStore(store.WithDB(db), store.WithK8sClient(k8sClient))
+	if err != nil {
+		t.Fatalf("NewCronJobDBStore failed: %v", err)
+	}
+
+	if err := cronJobDBStore.PutPod(stalePod); err != nil {
+		t.Fatalf("PutPod failed: %v", err)
+	}
+
+	server := NewSk8lServer("bufnet", cronJobDBStore, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.addReflector(podsWatcher, k8s.NewPodsListerWatcher(k8sClient), server.PodsHandler())
+	server.startReflectors(ctx)
+
+	syncCtx, syncCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer syncCancel()
+	if err := server.waitForSync(syncCtx); err != nil {
+		t.Fatalf("waitForSync failed: %v", err)
+	}
+
+	staleJob := testutil.NewJobBuilder().WithName("stale-job").WithNamespace("default").Build()
+	stalePods, err := cronJobDBStore.FindJobPodsForJob(staleJob)
+	if err != nil {
+		t.Fatalf("FindJobPodsForJob failed: %v", err)
+	}
+	if len(stalePods.Items) != 0 {
+		t.Errorf("expected stale pods to be dropped on relist, got %d", len(stalePods.Items))
+	}
+
+	jobOne := testutil.NewJobBuilder().WithName("job-one").WithNamespace("default").Build()
+	pods, err := cronJobDBStore.FindJobPodsForJob(jobOne)
+	if err != nil {
+		t.Fatalf("FindJobPodsForJob failed: %v", err)
+	}
+	if len(pods.Items) != 1 || pods.Items[0].Name != "job-one-pod" {
+		t.Errorf("expected job-one-pod from the initial list, got %+v", pods.Items)
+	}
+}
+
+func TestGetCronjobs_BeforeRun(t *testing.T) {
+	server, client := authorizedClient(t)
+	cronjob := testutil.NewCronJobBuilder().WithName("backup").WithNamespace("default").WithSchedule("0 * * * *").Build()
+	clientset := server.K8sClient.(*k8s.Client).Interface
+	ctx, cancel := context.WithTimeout(asCaller("jane"), 5*time.Second)
+	defer cancel()
+	if _, err := clientset.BatchV1().CronJobs("default").Create(ctx, cronjob, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to create cronjob: %v", err)
+	}
+	server.watchCluster()
+
+	stream, err := client.GetCronjobs(ctx, &protos.CronjobsRequest{})
+	if err != nil {
+		t.Fatalf("GetCronjobs failed: %v", err)
+	}
+	responses := make(chan *protos.CronjobsResponse, 1)
+	go func() {
+		response, err := stream.Recv()
+		if err != nil {
+			t.Errorf("Recv failed: %v", err)
+		}
+		responses <- response
+	}()
+	select {
+	case response := <-responses:
+		t.Fatalf("expected the stream to wait for the reflectors, got %v", response)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	server.startReflectors(ctx)
+	if response := <-responses; len(response.GetCronjobs()) != 1 || response.GetCronjobs()[0].GetName() != "backup" {
+		t.Errorf("expected the synced cronjob, got %v", response)
+	}
+}
+
+func TestBuildNextScheduleTime(t *testing.T) {
+	now := time.Date(2026, 10, 14, 10, 7, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		spec *protos.CronJobSpecResponse
+		want string
+	}{
+		{spec: &protos.CronJobSpecResponse{Schedule: "*/15 * * * *", Timezone: "UTC"}, want: "2026-10-14T10:15:00Z"},
+		{spec: &protos.CronJobSpecResponse{Schedule: "0 9 * * *", Timezone: "Europe/Berlin"}, want: "2026-10-15T07:00:00Z"},
+		{spec: &protos.CronJobSpecResponse{Schedule: "*/15 * * * *", Suspend: true}, want: ""},
+		{spec: &protos.CronJobSpecResponse{Schedule: "not a schedule"}, want: ""},
+	} {
+		if got := buildNextScheduleTime("backup", tt.spec, now); got != tt.want {
+			t.Errorf("buildNextScheduleTime(%v) = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
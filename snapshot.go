@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/danroux/sk8l/internal/config"
+	"github.com/danroux/sk8l/internal/redact"
+	"github.com/danroux/sk8l/internal/snapshot"
+	"github.com/danroux/sk8l/internal/store"
+	"github.com/danroux/sk8l/protos"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const snapshotChunkSize = 64 << 10
+
+var ErrSnapshotUsage = errors.New("usage: sk8l import [flags] <snapshot.tar.gz>")
+
+// ExportSnapshot streams an archive of everything in the store. In import mode
+// it re-exports the loaded snapshot.
+func (s *Sk8lServer) ExportSnapshot(_ *protos.ExportSnapshotRequest, stream protos.Cronjob_ExportSnapshotServer) error {
+	if err := s.waitForSync(stream.Context()); err != nil {
+		return fmt.Errorf("sk8l#ExportSnapshot: %w", err)
+	}
+
+	snap, err := snapshot.Capture(s.Storage, snapshot.Manifest{
+		CreatedAt:   time.Now().UTC(),
+		Sk8lVersion: Version(),
+		Namespace:   s.K8sClient.Namespace(),
+	})
+	if err != nil {
+		log.Error().Err(err).Str("operation", "ExportSnapshot").Msg("snapshot.Capture")
+		return fmt.Errorf("sk8l#ExportSnapshot: %w", err)
+	}
+	if err := redactSnapshot(s.redactor.For(stream.Context()), snap); err != nil {
+		return fmt.Errorf("sk8l#ExportSnapshot: %w", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := snap.Write(buf); err != nil {
+		log.Error().Err(err).Str("operation", "ExportSnapshot").Msg("snapshot.Write")
+		return fmt.Errorf("sk8l#ExportSnapshot: %w", err)
+	}
+
+	for chunk := range slices.Chunk(buf.Bytes(), snapshotChunkSize) {
+		if err := stream.Send(&protos.SnapshotChunk{Data: chunk}); err != nil {
+			return fmt.Errorf("sk8l#ExportSnapshot: stream.Send failed: %w", err)
+		}
+	}
+	log.Info().
+		Str("operation", "ExportSnapshot").
+		Msg(fmt.Sprintf("exported snapshot with %d cronjobs, %d jobs, %d pods and %d run records",
+			snap.Manifest.Cronjobs, snap.Manifest.Jobs, snap.Manifest.Pods, snap.Manifest.RunRecords))
+	return nil
+}
+
+// redactSnapshot redacts the objects and the captured logs of snap.
+func redactSnapshot(r *redact.Redactor, snap *snapshot.Snapshot) error {
+	for i := range snap.ContainerLogs {
+		snap.ContainerLogs[i].Logs = []byte(r.Text(string(snap.ContainerLogs[i].Logs)))
+	}
+	objects := make([]any, 0, len(snap.Cronjobs)+len(snap.Jobs)+len(snap.Pods))
+	for i := range snap.Cronjobs {
+		objects = append(objects, &snap.Cronjobs[i])
+	}
+	for i := range snap.Jobs {
+		objects = append(objects, &snap.Jobs[i])
+	}
+	for i := range snap.Pods {
+		objects = append(objects, &snap.Pods[i])
+	}
+	for _, obj := range objects {
+		if err := r.Object(obj); err != nil {
+			return fmt.Errorf("redacting snapshot failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// runExport implements `sk8l export`: it asks a running sk8l for a snapshot
+// and writes the archive to a file or stdout.
+func runExport(ctx context.Context, args []string) error {
+	// The API address and certificates default to the ones of the server.
+	c, _, err := config.Load("sk8l", nil, os.Getenv)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := fs.String("o", "", "file to write the snapshot to, - for stdout (default sk8l-snapshot-<timestamp>.tar.gz)")
+	target := fs.String("target", fmt.Sprintf("0.0.0.0:%d", c.Server.APIPort), "address of the sk8l API")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	if *output == "" {
+		*output = fmt.Sprintf("sk8l-snapshot-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+	}
+
+	tlsConfig, err := setupTLS(c.TLS.CertFile, c.TLS.KeyFile, c.TLS.CAFile, x509.NewCertPool())
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	conn, err := grpc.NewClient(*target, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	if err != nil {
+		return fmt.Errorf("export: grpc.NewClient(%s) failed: %w", *target, err)
+	}
+	defer conn.Close()
+
+	if *output == "-" {
+		return exportSnapshot(ctx, protos.NewCronjobClient(conn), os.Stdout)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	if err := exportSnapshot(ctx, protos.NewCronjobClient(conn), f); err != nil {
+		f.Close()
+		os.Remove(*output)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	log.Info().Msg(fmt.Sprintf("snapshot written to %s", *output))
+	return nil
+}
+
+func exportSnapshot(ctx context.Context, client protos.CronjobClient, w io.Writer) error {
+	stream, err := client.ExportSnapshot(ctx, &protos.ExportSnapshotRequest{})
+	if err != nil {
+		return fmt.Errorf("export: ExportSnapshot failed: %w", err)
+	}
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("export: stream.Recv failed: %w", err)
+		}
+		if _, err := w.Write(chunk.GetData()); err != nil {
+			return fmt.Errorf("export: write failed: %w", err)
+		}
+	}
+}
+
+// importArgs returns the archive given to `sk8l import`.
+func importArgs(args []string) (string, error) {
+	if len(args) != 1 || args[0] == "" {
+		return "", ErrSnapshotUsage
+	}
+	return args[0], nil
+}
+
+// newSnapshotStore loads the archive at path into memory storage behind a
+// snapshot client, so the server can run without any Kubernetes connection.
+func newSnapshotStore(path string) (*store.CronJobDBStore, *snapshot.Manifest, error) {
+	snap, err := snapshot.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sk8l#newSnapshotStore: %w", err)
+	}
+	storage := store.NewMemoryStorage()
+	if err := snap.Load(storage); err != nil {
+		return nil, nil, fmt.Errorf("sk8l#newSnapshotStore: %w", err)
+	}
+	cronjobDBStore, err := store.NewCronJobDBStore(
+		store.WithK8sClient(snapshot.NewClient(snap)),
+		store.WithStorage(storage),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sk8l#newSnapshotStore: %w", err)
+	}
+	return cronjobDBStore, &snap.Manifest, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danroux/sk8l/internal/k8s"
+	"github.com/danroux/sk8l/internal/snapshot"
+	"github.com/danroux/sk8l/internal/store"
+	"github.com/danroux/sk8l/protos"
+	"github.com/danroux/sk8l/testutil"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestExportSnapshot_ImportServesSnapshot(t *testing.T) {
+	k8sClient := k8s.NewClientWithInterface(fake.NewClientset(), k8s.WithNamespace("default"))
+	st, err := store.NewCronJobDBStore(store.WithStorage(store.NewMemoryStorage()), store.WithK8sClient(k8sClient))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	cronjob := testutil.NewCronJobBuilder().
+		WithName("snapshot-cronjob").
+		WithNamespace("default").
+		WithSchedule("*/5 * * * *").
+		Build()
+	if err := st.PutCronjob(cronjob); err != nil {
+		t.Fatalf("PutCronjob failed: %v", err)
+	}
+	err = st.PutContainerLogs(store.ContainerLogs{
+		Namespace: "default", JobName: "job-a", PodName: "pod-a", ContainerName: "main",
+		Logs: []byte("connecting with --password=hunter2"), CapturedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("PutContainerLogs failed: %v", err)
+	}
+	sk8lServer.CronJobDBStore = st
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(bufDialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer conn.Close()
+	client := protos.NewCronjobClient(conn)
+
+	buf := &bytes.Buffer{}
+	if err := exportSnapshot(context.Background(), client, buf); err != nil {
+		t.Fatalf("exportSnapshot failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	imported, manifest, err := newSnapshotStore(path)
+	if err != nil {
+		t.Fatalf("newSnapshotStore failed: %v", err)
+	}
+	if manifest.Version != snapshot.Version || manifest.Cronjobs != 1 {
+		t.Errorf("unexpected manifest %+v", manifest)
+	}
+	if _, ok := imported.K8sClient.(*snapshot.Client); !ok {
+		t.Errorf("expected import mode to use the snapshot client, got %T", imported.K8sClient)
+	}
+	logs, err := imported.JobContainerLogs("default", "job-a")
+	if err != nil {
+		t.Fatalf("JobContainerLogs failed: %v", err)
+	}
+	if len(logs) != 1 || strings.Contains(string(logs[0].Logs), "hunter2") {
+		t.Errorf("expected the exported logs to be redacted, got %+v", logs)
+	}
+
+	sk8lServer.CronJobDBStore = imported
+	response, err := client.GetCronjobYAML(context.Background(), &protos.CronjobRequest{
+		CronjobName:      "snapshot-cronjob",
+		CronjobNamespace: "default",
+	})
+	if err != nil {
+		t.Fatalf("GetCronjobYAML failed: %v", err)
+	}
+	if !strings.Contains(response.GetCronjob(), "*/5 * * * *") {
+		t.Errorf("expected the imported cronjob spec, got %s", response.GetCronjob())
+	}
+}
+
+func TestImportArgs(t *testing.T) {
+	if _, err := importArgs(nil); err == nil {
+		t.Error("expected an error without an archive")
+	}
+	path, err := importArgs([]string{"snap.tar.gz"})
+	if err != nil || path != "snap.tar.gz" {
+		t.Errorf("expected snap.tar.gz, got %q, %v", path, err)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/danroux/sk8l/internal/config"
 )
 
 type CertPool interface {
@@ -18,6 +20,7 @@ var (
 	ErrTLS            = errors.New("TLS Error")
 	ErrCertPool       = errors.New("certPool Error")
 	ErrCACertFile     = errors.New("caCertFile Error")
+	ErrClientAuth     = errors.New("unknown client certificate mode")
 )
 
 type CertError struct {
@@ -93,3 +96,18 @@ func setupTLS(certFile, certKeyFile, caFile string, certPool CertPool) (*tls.Con
 
 	return tlsConfig, nil
 }
+
+// clientAuthType maps a tls.clientAuth mode to the client certificate policy
+// of the API listener. Client certificates are verified against the CA.
+func clientAuthType(mode string) (tls.ClientAuthType, error) {
+	switch mode {
+	case "", config.ClientAuthNone:
+		return tls.NoClientCert, nil
+	case config.ClientAuthVerifyIfGiven:
+		return tls.VerifyClientCertIfGiven, nil
+	case config.ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("%w %q", ErrClientAuth, mode)
+	}
+}
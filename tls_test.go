@@ -132,3 +132,25 @@ func TestSetupTLS(t *testing.T) {
 		})
 	}
 }
+
+func TestClientAuthType(t *testing.T) {
+	testCases := []struct {
+		mode     string
+		expected tls.ClientAuthType
+		err      error
+	}{
+		{mode: "", expected: tls.NoClientCert},
+		{mode: "none", expected: tls.NoClientCert},
+		{mode: "verify-if-given", expected: tls.VerifyClientCertIfGiven},
+		{mode: "require", expected: tls.RequireAndVerifyClientCert},
+		{mode: "optional", expected: tls.NoClientCert, err: ErrClientAuth},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.mode, func(t *testing.T) {
+			got, err := clientAuthType(tc.mode)
+			if !errors.Is(err, tc.err) || got != tc.expected {
+				t.Errorf("clientAuthType(%q) = %v, %v, expected %v, %v", tc.mode, got, err, tc.expected, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/danroux/sk8l/internal/auth"
+	"github.com/danroux/sk8l/internal/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// transports serves the TLS configs of the listeners. Listeners serving the
+// same certificate files share their certReloader.
+type transports struct {
+	settings  config.TLS
+	reloaders map[[3]string]*certReloader
+}
+
+// newTransports loads the certificates of every enabled listener that isn't
+// plaintext, none when they all are.
+func newTransports(c *config.Config) (*transports, error) {
+	t := &transports{settings: c.TLS, reloaders: map[[3]string]*certReloader{}}
+	listeners := c.TLS.Listeners()
+	if c.Server.DebugPort == 0 {
+		delete(listeners, "debug")
+	}
+	for _, name := range slices.Sorted(maps.Keys(listeners)) {
+		l := listeners[name]
+		files := t.files(l)
+		if l.Transport == config.TransportPlaintext || t.reloaders[files] != nil {
+			continue
+		}
+		// The certificates of the tls section keep the server and ca names.
+		prefix := ""
+		if files != t.files(config.Listener{}) {
+			prefix = name + "-"
+		}
+		r, err := newCertReloader(prefix, files[0], files[1], files[2])
+		if err != nil {
+			return nil, fmt.Errorf("sk8l#newTransports: %s listener: %w", name, err)
+		}
+		t.reloaders[files] = r
+	}
+	return t, nil
+}
+
+func (t *transports) files(l config.Listener) [3]string {
+	certFile, keyFile, caFile := t.settings.Files(l)
+	return [3]string{certFile, keyFile, caFile}
+}
+
+// serverConfig returns the TLS config of a listener, nil when it's plaintext.
+// clientAuth applies to the tls transport, mtls requires client certificates.
+func (t *transports) serverConfig(l config.Listener, clientAuth tls.ClientAuthType, nextProtos []string) *tls.Config {
+	switch l.Transport {
+	case config.TransportPlaintext:
+		return nil
+	case config.TransportMTLS:
+		clientAuth = tls.RequireAndVerifyClientCert
+	default: // default case to satisfy revive
+	}
+	return t.reloaders[t.files(l)].serverConfig(clientAuth, nextProtos)
+}
+
+// serverOptions returns the credentials of a gRPC server listening with l.
+func (t *transports) serverOptions(l config.Listener, clientAuth tls.ClientAuthType) []grpc.ServerOption {
+	tlsConfig := t.serverConfig(l, clientAuth, grpcNextProtos)
+	if tlsConfig == nil {
+		return []grpc.ServerOption{}
+	}
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(tlsConfig))}
+}
+
+// dialOptions returns how sk8l calls its own API, served with l, with token
+// as bearer token.
+func (t *transports) dialOptions(l config.Listener, token string) []grpc.DialOption {
+	if l.Transport == config.TransportPlaintext {
+		return []grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithPerRPCCredentials(auth.InsecureBearerCredentials(token)),
+		}
+	}
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(t.reloaders[t.files(l)].clientCredentials()),
+		grpc.WithPerRPCCredentials(auth.BearerCredentials(token)),
+	}
+}
+
+// watch reloads the certificates of every listener every interval until ctx
+// is done.
+func (t *transports) watch(ctx context.Context, interval time.Duration) {
+	for _, r := range t.reloaders {
+		go r.watch(ctx, interval)
+	}
+}
+
+// h2cProtocols lets a plaintext HTTP server serve gRPC, over HTTP/2 without
+// TLS, next to HTTP/1.1. TLS servers negotiate HTTP/2 through ALPN.
+func h2cProtocols(tlsConfig *tls.Config) *http.Protocols {
+	if tlsConfig != nil {
+		return nil
+	}
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return protocols
+}
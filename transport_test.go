@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/tls"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/danroux/sk8l/internal/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTransports(t *testing.T) {
+	dir, metricsDir := t.TempDir(), t.TempDir()
+	ca := newTestCA(t)
+	ca.issue(t, dir, 2, time.Now().Add(90*24*time.Hour))
+	notAfter := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+	ca.issue(t, metricsDir, 3, notAfter)
+
+	c := config.Default()
+	settings := &c.TLS
+	settings.CertFile = filepath.Join(dir, "server-cert.pem")
+	settings.KeyFile = filepath.Join(dir, "server-key.pem")
+	settings.CAFile = filepath.Join(dir, "ca-cert.pem")
+	settings.Metrics = config.Listener{
+		Transport: config.TransportMTLS,
+		CertFile:  filepath.Join(metricsDir, "server-cert.pem"),
+		KeyFile:   filepath.Join(metricsDir, "server-key.pem"),
+	}
+	transports, err := newTransports(c)
+	if err != nil {
+		t.Fatalf("newTransports failed: %v", err)
+	}
+	if len(transports.reloaders) != 2 {
+		t.Errorf("expected a reloader for the API and one for the metrics certificates, got %d", len(transports.reloaders))
+	}
+
+	if transports.serverConfig(settings.Health, tls.NoClientCert, httpNextProtos) != nil {
+		t.Error("expected no TLS config for the plaintext health listener")
+	}
+	if len(transports.serverOptions(settings.Health, tls.NoClientCert)) != 0 {
+		t.Error("expected no credentials for a plaintext gRPC server")
+	}
+	for name, tc := range map[string]struct {
+		listener   config.Listener
+		clientAuth tls.ClientAuthType
+	}{
+		"tls":  {settings.API, tls.VerifyClientCertIfGiven},
+		"mtls": {settings.Metrics, tls.RequireAndVerifyClientCert},
+	} {
+		tlsConfig, err := transports.serverConfig(tc.listener, tls.VerifyClientCertIfGiven, httpNextProtos).GetConfigForClient(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tlsConfig.ClientAuth != tc.clientAuth {
+			t.Errorf("expected %s to ask for client certificates with %s, got %s", name, tc.clientAuth, tlsConfig.ClientAuth)
+		}
+	}
+	if got := testutil.ToFloat64(certificateExpiryGauge.WithLabelValues("metrics-server")); got != float64(notAfter.Unix()) {
+		t.Errorf("expected the expiry of the metrics certificate to be %d, got %v", notAfter.Unix(), got)
+	}
+
+	settings.API.Transport = config.TransportPlaintext
+	settings.Metrics.Transport = config.TransportPlaintext
+	settings.Debug.CertFile = filepath.Join(metricsDir, "missing.pem")
+	if transports, err = newTransports(c); err != nil || len(transports.reloaders) != 0 {
+		t.Errorf("expected plaintext and disabled listeners not to load certificates, got %v, %v", transports, err)
+	}
+	c.Server.DebugPort = 8592
+	if _, err := newTransports(c); err == nil {
+		t.Error("expected the enabled debug listener to load its certificates")
+	}
+	c.Server.DebugPort = 0
+	settings.Metrics.Transport = config.TransportTLS
+	settings.Metrics.KeyFile = filepath.Join(metricsDir, "missing.pem")
+	if _, err := newTransports(c); err == nil {
+		t.Error("expected missing certificate files to fail")
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/danroux/sk8l/internal/cli"
+	"github.com/danroux/sk8l/internal/k8s"
+	"github.com/danroux/sk8l/protos"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// terminal records what a TUI draws.
+type terminal struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (term *terminal) Write(p []byte) (int, error) {
+	term.mu.Lock()
+	defer term.mu.Unlock()
+	return term.buf.Write(p)
+}
+
+// screen returns the last frame drawn, without escape sequences.
+func (term *terminal) screen() string {
+	term.mu.Lock()
+	defer term.mu.Unlock()
+	_, out, found := cutLast(term.buf.String(), "\x1b[H")
+	if !found {
+		return ""
+	}
+	for _, sequence := range []string{"\x1b[K", "\x1b[J", "\x1b[7m", "\x1b[0m", "\x1b[?25h", "\x1b[?1049l"} {
+		out = strings.ReplaceAll(out, sequence, "")
+	}
+	return strings.ReplaceAll(out, "\r\n", "\n")
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+func (term *terminal) waitFor(t *testing.T, expected ...string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		screen := term.screen()
+		found := true
+		for _, s := range expected {
+			found = found && strings.Contains(screen, s)
+		}
+		if found {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %q on the screen:\n%s", expected, screen)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTUI(t *testing.T) {
+	server, conn := authorizedConn(t)
+	cronjob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default", UID: "cronjob-uid"},
+		Spec:       batchv1.CronJobSpec{Schedule: "0 * * * *"},
+	}
+	started := metav1.NewTime(time.Now().Add(-time.Minute))
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "backup-1", Namespace: "default", UID: "job-uid", CreationTimestamp: started,
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "CronJob", Name: "backup", UID: "cronjob-uid"}},
+		},
+		Status: batchv1.JobStatus{StartTime: &started, Active: 1},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "backup-1-x2k9d", Namespace: "default",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "Job", Name: "backup-1", UID: "job-uid"}},
+		},
+		Spec:   corev1.PodSpec{Containers: []corev1.Container{{Name: "main", Image: "busybox"}}},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning, StartTime: &started},
+	}
+	ctx, cancel := context.WithCancel(asCaller("jane"))
+	defer cancel()
+	clientset := server.K8sClient.(*k8s.Client).Interface
+	if _, err := clientset.BatchV1().CronJobs("default").Create(ctx, cronjob, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to create cronjob: %v", err)
+	}
+	if _, err := clientset.BatchV1().Jobs("default").Create(ctx, job, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	if _, err := clientset.CoreV1().Pods("default").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to create pod: %v", err)
+	}
+	server.watchCluster()
+	server.startReflectors(ctx)
+
+	term := &terminal{}
+	keys, pressed := io.Pipe()
+	c := cli.New(protos.NewCronjobClient(conn), &cli.Options{Server: "bufnet"}, term)
+	tui := cli.NewTUI(c, keys, func() (int, int, error) { return 160, 20, nil })
+	done := make(chan error, 1)
+	go func() { done <- tui.Run(ctx) }()
+	press := func(key string) {
+		t.Helper()
+		if _, err := pressed.Write([]byte(key)); err != nil {
+			t.Fatalf("failed to press %q: %v", key, err)
+		}
+	}
+
+	term.waitFor(t, "NEXT RUN", "backup", "0 * * * *", "Scheduled", "in ")
+	press("\r")
+	term.waitFor(t, "cronjobs > cronjob backup", "backup-1", "Running")
+	press("\r")
+	term.waitFor(t, "cronjob backup > job backup-1", "backup-1-x2k9d", "Running")
+	press("\r")
+	term.waitFor(t, "pod backup-1-x2k9d logs", "fake logs")
+	press("\x1b")
+	press("y")
+	term.waitFor(t, "pod backup-1-x2k9d yaml", "kind: Pod")
+	press("\x1b")
+	press("\x1b")
+	press("\x1b")
+	press("y")
+	term.waitFor(t, "cronjob backup yaml", "kind: CronJob")
+	press("q")
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected q to quit")
+	}
+}
@@ -1,8 +1,29 @@
 package main
 
+import "runtime/debug"
+
 // Set with LDFLAGS.
-var version = "unset"
+var (
+	version = "unset"
+	commit  = "unset"
+)
 
 func Version() string {
 	return version
 }
+
+// Commit returns the commit sk8l was built from, the one recorded by the Go
+// toolchain when it isn't set with LDFLAGS.
+func Commit() string {
+	if commit != "unset" {
+		return commit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+	return commit
+}